@@ -2,30 +2,31 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"flag"
 	"log"
-	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"kvazar/internal/bot"
+	"kvazar/internal/config"
+	"kvazar/internal/media"
+	"kvazar/internal/metrics"
+	"kvazar/internal/server"
 )
 
 func main() {
-	cfg := bot.Config{
-		Token:      pickToken(),
-		FFMpegPath: os.Getenv("KVZ_FFMPEG_PATH"),
-		YTDLPPath:  os.Getenv("KVZ_YTDLP_PATH"),
-		Status:     os.Getenv("KVZ_STATUS"),
-	}
+	configPath := flag.String("config", os.Getenv("KVZ_CONFIG"), "path to a YAML config file (env KVZ_CONFIG)")
+	flag.Parse()
 
-	if cfg.Token == "" {
-		log.Fatal("kvazar: please provide a Discord bot token via KVZ_DISCORD_TOKEN or DISCORD_TOKEN")
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("kvazar: %v", err)
 	}
 
-	instance, err := bot.New(cfg)
+	instance, err := bot.New(botConfig(cfg))
 	if err != nil {
 		log.Fatalf("kvazar: failed to initialise bot: %v", err)
 	}
@@ -34,20 +35,39 @@ func main() {
 		log.Fatalf("kvazar: failed to open session: %v", err)
 	}
 
-	// Start health check HTTP server
-	port := os.Getenv("KVZ_HEALTH_PORT")
-	if port == "" {
-		port = "8080"
-	}
-	go startHealthServer(port, instance)
+	metrics.Registry.MustRegister(instance.Collector())
+	httpServer := server.New(cfg.HTTP.Listen, instance, server.Options{
+		APIToken: cfg.HTTP.APIToken,
+		OAuth:    cfg.HTTP.OAuth,
+	})
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil {
+			log.Printf("kvazar: %v", err)
+		}
+	}()
 
 	log.Println("kvazar is online — press Ctrl+C to exit")
 
-	waitForShutdown()
+	waitForShutdown(func() {
+		next, err := config.Load(*configPath)
+		if err != nil {
+			log.Printf("kvazar: keeping the current configuration: %v", err)
+			return
+		}
+		if changed := cfg.RestartRequired(next); len(changed) > 0 {
+			log.Printf("kvazar: changes to %s take effect after a restart", strings.Join(changed, ", "))
+		}
+		cfg = cfg.Reloaded(next)
+		instance.Reload(botSettings(cfg))
+		log.Println("kvazar: configuration reloaded")
+	})
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("kvazar: failed to stop the HTTP server: %v", err)
+	}
 	if err := instance.Close(shutdownCtx); err != nil {
 		log.Printf("kvazar: graceful shutdown encountered errors: %v", err)
 	}
@@ -55,36 +75,60 @@ func main() {
 	log.Println("kvazar stopped. stay cosmic.")
 }
 
-func pickToken() string {
-	if token := os.Getenv("KVZ_DISCORD_TOKEN"); token != "" {
-		return token
+func botConfig(cfg config.Config) bot.Config {
+	return bot.Config{
+		Token:               cfg.Discord.Token,
+		FFMpegPath:          cfg.Media.FFMpegPath,
+		FFProbePath:         cfg.Media.FFProbePath,
+		YTDLPPath:           cfg.Media.YTDLPPath,
+		DataDir:             cfg.Storage.DataDir,
+		ResolveTimeout:      cfg.Media.ResolveTimeout,
+		ResolveWorkers:      cfg.Media.ResolveWorkers,
+		ResolverBackend:     cfg.Media.ResolverBackend,
+		PythonPath:          cfg.Media.PythonPath,
+		YTDLP:               media.YTDLPOptions(cfg.Media.YTDLP),
+		LibraryDir:          cfg.Media.LibraryDir,
+		SpotifyClientID:     cfg.Media.Spotify.ClientID,
+		SpotifyClientSecret: cfg.Media.Spotify.ClientSecret,
+		CacheEntries:        cfg.Media.Cache.MaxEntries,
+		CacheTTL:            cfg.Media.Cache.TTL,
+		PersistCache:        cfg.Media.Cache.Persist,
+		PublicURL:           cfg.HTTP.PublicURL,
+
+		CommandGuildID:  cfg.Discord.CommandGuildID,
+		CleanupCommands: cfg.Discord.CleanupCommands,
+
+		Settings: botSettings(cfg),
 	}
-	return os.Getenv("DISCORD_TOKEN")
 }
 
-func waitForShutdown() {
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
-	signal.Stop(sigCh)
+func botSettings(cfg config.Config) bot.Settings {
+	return bot.Settings{
+		Status:          cfg.Discord.Status,
+		VoteSkipRatio:   cfg.Playback.VoteSkipRatio,
+		Bitrate:         cfg.Audio.BitrateKbps * 1000,
+		DisconnectDelay: cfg.Audio.DisconnectDelay,
+		Locale:          cfg.Playback.Locale,
+		Limits: bot.QueueLimits{
+			MaxQueue:     cfg.Limits.MaxQueue,
+			MaxPerUser:   cfg.Limits.MaxPerUser,
+			MaxDuration:  cfg.Limits.MaxDuration,
+			PlayCooldown: cfg.Limits.PlayCooldown,
+			RejectLive:   cfg.Limits.RejectLive,
+		},
+	}
 }
 
-func startHealthServer(port string, instance *bot.Kvazar) {
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, `{"status":"ok","service":"kvazar"}`)
-	})
-
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/plain")
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, "Kvazar Discord Bot - Health Check OK")
-	})
-
-	addr := ":" + port
-	log.Printf("Health check server listening on %s", addr)
-	if err := http.ListenAndServe(addr, nil); err != nil {
-		log.Printf("Health check server error: %v", err)
+// waitForShutdown blocks until SIGINT or SIGTERM, calling reload on every SIGHUP.
+func waitForShutdown(reload func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	for sig := range sigCh {
+		if sig != syscall.SIGHUP {
+			return
+		}
+		reload()
 	}
 }
@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+)
+
+const (
+	announcementsBucket = "announcements"
+
+	announceTrack  = "track"
+	announceSingle = "single"
+	announceThread = "thread"
+	announceOff    = "off"
+
+	threadArchiveMinutes = 60
+)
+
+// announceModes lists the accepted announcement modes, default first.
+var announceModes = []string{announceTrack, announceSingle, announceThread, announceOff}
+
+var announceModeKeys = map[string]i18n.Key{
+	announceTrack:  i18n.SettingsModeTrack,
+	announceSingle: i18n.SettingsModeSingle,
+	announceThread: i18n.SettingsModeThread,
+	announceOff:    i18n.SettingsModeOff,
+}
+
+func (g guildSettings) announceMode() string {
+	if _, ok := announceModeKeys[g.AnnounceMode]; ok {
+		return g.AnnounceMode
+	}
+	return announceTrack
+}
+
+// announcement records the messages a player has posted. It is persisted so
+// that cards left behind by a crash or restart can be removed on startup.
+type announcement struct {
+	ChannelID string `json:"channel_id,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+	ThreadID  string `json:"thread_id,omitempty"`
+}
+
+func (a announcement) empty() bool {
+	return a.MessageID == "" && a.ThreadID == ""
+}
+
+// announceNowPlaying posts or updates the now-playing card according to the
+// guild's announcement mode.
+func (k *Kvazar) announceNowPlaying(p *Player, track *media.Track, loop bool) {
+	settings := k.guildSettings(p.guild)
+	mode := settings.announceMode()
+
+	p.mu.Lock()
+	previous := p.nowPlaying
+	threadID := p.threadID
+	p.mu.Unlock()
+
+	channelID := track.RequestChannelID
+	if settings.AnnounceChannelID != "" {
+		channelID = settings.AnnounceChannelID
+	}
+	if mode == announceOff || channelID == "" {
+		k.retireCard(previous, threadID)
+		k.recordAnnouncement(p, nil, threadID)
+		return
+	}
+
+	lang := k.guildLang(p.guild)
+	embed := buildNowPlayingEmbed(lang, track, loop)
+	loopLabel := lang.T(i18n.ButtonRepeat)
+	if loop {
+		loopLabel = lang.T(i18n.ButtonLoopOff)
+	}
+	components := buildPlayerComponents(lang, loopLabel, loop)
+
+	switch mode {
+	case announceSingle:
+		if previous != nil && previous.ChannelID == channelID {
+			msg, err := k.session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+				ID:         previous.ID,
+				Channel:    previous.ChannelID,
+				Embeds:     []*discordgo.MessageEmbed{embed},
+				Components: components,
+			})
+			if err == nil {
+				k.recordAnnouncement(p, msg, threadID)
+				return
+			}
+			// The message was probably deleted by hand; post a new one.
+			log.Printf("failed to update player message in guild %s: %v", p.guild, err)
+		}
+	case announceThread:
+		if threadID == "" {
+			threadID = k.startSessionThread(lang, channelID)
+		}
+		if threadID != "" {
+			channelID = threadID
+		}
+	}
+
+	k.retireCard(previous, threadID)
+
+	msg, err := k.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	})
+	if err != nil {
+		log.Printf("failed to send now playing message: %v", err)
+		msg = nil
+	}
+	k.recordAnnouncement(p, msg, threadID)
+}
+
+// startSessionThread opens the thread that holds a session's cards. It returns
+// an empty ID when threads cannot be created, so cards go to the channel instead.
+func (k *Kvazar) startSessionThread(lang i18n.Lang, channelID string) string {
+	name := lang.T(i18n.AnnounceThreadName, time.Now().Format("2006-01-02 15:04"))
+	thread, err := k.session.ThreadStart(channelID, name, discordgo.ChannelTypeGuildPublicThread, threadArchiveMinutes)
+	if err != nil {
+		log.Printf("failed to start announcement thread in channel %s: %v", channelID, err)
+		return ""
+	}
+	return thread.ID
+}
+
+// retireCard removes a card that has been replaced. Cards inside the session
+// thread stay as a log of what was played, without their buttons.
+func (k *Kvazar) retireCard(msg *discordgo.Message, threadID string) {
+	if msg == nil {
+		return
+	}
+	if msg.ChannelID == threadID {
+		empty := []discordgo.MessageComponent{}
+		if _, err := k.session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:         msg.ID,
+			Channel:    msg.ChannelID,
+			Components: empty,
+		}); err != nil {
+			log.Printf("failed to clear buttons on message %s: %v", msg.ID, err)
+		}
+		return
+	}
+	if err := k.session.ChannelMessageDelete(msg.ChannelID, msg.ID); err != nil {
+		log.Printf("failed to delete stale now playing message %s: %v", msg.ID, err)
+	}
+}
+
+func (k *Kvazar) recordAnnouncement(p *Player, msg *discordgo.Message, threadID string) {
+	record := announcement{ThreadID: threadID}
+	if msg != nil {
+		record.ChannelID = msg.ChannelID
+		record.MessageID = msg.ID
+	}
+
+	p.mu.Lock()
+	p.nowPlaying = msg
+	p.threadID = threadID
+	p.mu.Unlock()
+
+	var err error
+	if record.empty() {
+		err = k.store.Delete(announcementsBucket, p.guild)
+	} else {
+		err = k.store.Save(announcementsBucket, p.guild, record)
+	}
+	if err != nil {
+		log.Printf("failed to record announcement for guild %s: %v", p.guild, err)
+	}
+}
+
+// endAnnouncements cleans up after a session: the last card is deleted and the
+// session thread is archived.
+func (k *Kvazar) endAnnouncements(p *Player) {
+	p.mu.Lock()
+	record := announcement{ThreadID: p.threadID}
+	if p.nowPlaying != nil {
+		record.ChannelID = p.nowPlaying.ChannelID
+		record.MessageID = p.nowPlaying.ID
+	}
+	p.nowPlaying = nil
+	p.threadID = ""
+	p.mu.Unlock()
+
+	if record.empty() {
+		return
+	}
+	k.closeAnnouncement(p.guild, record)
+}
+
+func (k *Kvazar) closeAnnouncement(guildID string, record announcement) {
+	if record.MessageID != "" && record.ChannelID != record.ThreadID {
+		if err := k.session.ChannelMessageDelete(record.ChannelID, record.MessageID); err != nil {
+			log.Printf("failed to delete now playing message %s: %v", record.MessageID, err)
+		}
+	}
+	if record.ThreadID != "" {
+		if _, err := k.session.ChannelMessageSend(record.ThreadID, k.guildLang(guildID).T(i18n.AnnounceSessionEnded)); err != nil {
+			log.Printf("failed to post to announcement thread %s: %v", record.ThreadID, err)
+		}
+		closed := true
+		if _, err := k.session.ChannelEdit(record.ThreadID, &discordgo.ChannelEdit{Archived: &closed, Locked: &closed}); err != nil {
+			log.Printf("failed to archive announcement thread %s: %v", record.ThreadID, err)
+		}
+	}
+	if err := k.store.Delete(announcementsBucket, guildID); err != nil {
+		log.Printf("failed to clear announcement for guild %s: %v", guildID, err)
+	}
+}
+
+// cleanupStaleAnnouncements closes announcements left over from a previous
+// run, whose players no longer exist.
+func (k *Kvazar) cleanupStaleAnnouncements() {
+	guildIDs, err := k.store.Keys(announcementsBucket)
+	if err != nil {
+		log.Printf("failed to list stale announcements: %v", err)
+		return
+	}
+	for _, guildID := range guildIDs {
+		if k.findPlayer(guildID) != nil {
+			continue
+		}
+		var record announcement
+		if _, err := k.store.Load(announcementsBucket, guildID, &record); err != nil {
+			log.Printf("failed to load announcement for guild %s: %v", guildID, err)
+			continue
+		}
+		k.closeAnnouncement(guildID, record)
+	}
+}
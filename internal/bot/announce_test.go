@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	"kvazar/internal/media"
+)
+
+// newAnnounceTest returns a bot whose test guild announces in mode, and a
+// player there that has not announced anything yet.
+func newAnnounceTest(t *testing.T, mode string) (*Kvazar, *fakeSession, *Player) {
+	t.Helper()
+	k, s := newTestBot(t)
+	if err := k.updateGuildSettings(testGuild, func(g *guildSettings) { g.AnnounceMode = mode }); err != nil {
+		t.Fatal(err)
+	}
+	return k, s, k.getPlayer(testGuild)
+}
+
+func announce(k *Kvazar, p *Player, title string) {
+	k.announceNowPlaying(p, &media.Track{Title: title, RequestChannelID: testText}, false)
+}
+
+func expectActions(t *testing.T, s *fakeSession, want ...string) {
+	t.Helper()
+	if got := s.takeActions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("actions = %q, want %q", got, want)
+	}
+}
+
+// storedAnnouncement returns what the bot recorded for the test guild.
+func storedAnnouncement(t *testing.T, k *Kvazar) (announcement, bool) {
+	t.Helper()
+	var record announcement
+	ok, err := k.store.Load(announcementsBucket, testGuild, &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record, ok
+}
+
+func TestAnnounceTrack(t *testing.T) {
+	k, s, p := newAnnounceTest(t, announceTrack)
+
+	announce(k, p, "Orbit")
+	expectActions(t, s)
+	announce(k, p, "Gravity")
+	expectActions(t, s, "delete text/message-1")
+	if s.sent(testText) != 2 {
+		t.Errorf("sent %d cards, want one per track", s.sent(testText))
+	}
+	if record, _ := storedAnnouncement(t, k); record != (announcement{ChannelID: testText, MessageID: "message-2"}) {
+		t.Errorf("recorded %+v", record)
+	}
+
+	k.endAnnouncements(p)
+	expectActions(t, s, "delete text/message-2")
+	if _, ok := storedAnnouncement(t, k); ok {
+		t.Error("the announcement is still recorded after the session")
+	}
+}
+
+func TestAnnounceSingle(t *testing.T) {
+	k, s, p := newAnnounceTest(t, announceSingle)
+
+	announce(k, p, "Orbit")
+	announce(k, p, "Gravity")
+	expectActions(t, s, "edit text/message-1")
+	if s.sent(testText) != 1 {
+		t.Errorf("sent %d cards, want the first one edited", s.sent(testText))
+	}
+
+	// A card deleted by hand is replaced with a new one.
+	s.deleteByHand("message-1")
+	announce(k, p, "Comet")
+	expectActions(t, s, "delete text/message-1")
+	if s.sent(testText) != 2 {
+		t.Errorf("sent %d cards, want a new one after the deletion", s.sent(testText))
+	}
+	announce(k, p, "Nebula")
+	expectActions(t, s, "edit text/message-2")
+}
+
+func TestAnnounceThread(t *testing.T) {
+	k, s, p := newAnnounceTest(t, announceThread)
+	thread := testText + "-thread"
+
+	announce(k, p, "Orbit")
+	expectActions(t, s, "start thread "+testText)
+	announce(k, p, "Gravity")
+	// The played card stays in the thread, without its buttons.
+	expectActions(t, s, "clear buttons "+thread+"/message-1")
+	if s.sent(thread) != 2 || s.sent(testText) != 0 {
+		t.Errorf("sent %d cards to the thread and %d to the channel, want both in the thread", s.sent(thread), s.sent(testText))
+	}
+	if record, _ := storedAnnouncement(t, k); record != (announcement{ChannelID: thread, MessageID: "message-2", ThreadID: thread}) {
+		t.Errorf("recorded %+v", record)
+	}
+
+	k.endAnnouncements(p)
+	expectActions(t, s, "archive "+thread)
+	if s.sent(thread) != 3 {
+		t.Error("no session-ended message in the thread")
+	}
+}
+
+func TestAnnounceOff(t *testing.T) {
+	k, s, p := newAnnounceTest(t, announceTrack)
+	announce(k, p, "Orbit")
+
+	if err := k.updateGuildSettings(testGuild, func(g *guildSettings) { g.AnnounceMode = announceOff }); err != nil {
+		t.Fatal(err)
+	}
+	announce(k, p, "Gravity")
+	expectActions(t, s, "delete text/message-1")
+	if s.sent(testText) != 1 {
+		t.Errorf("sent %d cards, want none after turning announcements off", s.sent(testText))
+	}
+	if _, ok := storedAnnouncement(t, k); ok {
+		t.Error("an announcement is recorded with announcements off")
+	}
+}
+
+func TestCleanupStaleAnnouncements(t *testing.T) {
+	k, s := newTestBot(t)
+	stale := map[string]announcement{
+		"gone":    {ChannelID: "cards", MessageID: "message-7"},
+		"thread":  {ChannelID: "log-thread", MessageID: "message-8", ThreadID: "log-thread"},
+		testGuild: {ChannelID: testText, MessageID: "message-9"},
+	}
+	for guildID, record := range stale {
+		if err := k.store.Save(announcementsBucket, guildID, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The test guild's player is still running; its card stays.
+	k.getPlayer(testGuild)
+
+	k.cleanupStaleAnnouncements()
+	actions := s.takeActions()
+	want := map[string]bool{"delete cards/message-7": true, "archive log-thread": true}
+	if len(actions) != len(want) {
+		t.Errorf("actions = %q, want %v", actions, want)
+	}
+	for _, action := range actions {
+		if !want[action] {
+			t.Errorf("unexpected action %q", action)
+		}
+	}
+	keys, err := k.store.Keys(announcementsBucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{testGuild}) {
+		t.Errorf("recorded announcements = %v, want only the running player's", keys)
+	}
+}
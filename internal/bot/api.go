@@ -0,0 +1,350 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/media"
+)
+
+// Errors returned by the player controls used by the HTTP API.
+var (
+	ErrNoPlayer        = errors.New("no active player in this guild")
+	ErrNothingPlaying  = errors.New("nothing is playing")
+	ErrNotConnected    = errors.New("not in a voice channel; a voice channel ID is required")
+	ErrInvalidPosition = errors.New("no track at that queue position")
+	ErrQueueLimit      = errors.New("queue limit reached")
+	ErrResolve         = errors.New("could not resolve the query")
+	ErrInvalidSetting  = errors.New("invalid setting")
+)
+
+// apiRequester labels tracks queued through the API that name no member.
+const apiRequester = "API"
+
+// PlayerState is a snapshot of a guild player.
+type PlayerState struct {
+	GuildID        string     `json:"guild_id"`
+	GuildName      string     `json:"guild_name,omitempty"`
+	VoiceChannelID string     `json:"voice_channel_id,omitempty"`
+	Playing        bool       `json:"playing"`
+	Paused         bool       `json:"paused"`
+	Loop           bool       `json:"loop"`
+	Current        *TrackInfo `json:"current,omitempty"`
+	// PositionSeconds is how far playback of Current has got.
+	PositionSeconds int `json:"position_seconds"`
+	// StreamTitle is the song a radio station reports for Current.
+	StreamTitle string      `json:"stream_title,omitempty"`
+	Queue       []TrackInfo `json:"queue"`
+	// History lists recently played tracks, most recent first.
+	History []TrackInfo   `json:"history"`
+	Audio   AudioSettings `json:"audio"`
+}
+
+// AudioSettings are the guild's playback settings, as in /settings.
+type AudioSettings struct {
+	Volume         int     `json:"volume"`
+	Normalize      bool    `json:"normalize"`
+	LoudnessTarget float64 `json:"loudness_target"`
+}
+
+// AudioUpdate changes some of the guild's audio settings; nil fields are kept.
+// Changes apply from the next track.
+type AudioUpdate struct {
+	Volume         *int
+	Normalize      *bool
+	LoudnessTarget *float64
+}
+
+// GuildInfo describes a guild the bot is in.
+type GuildInfo struct {
+	ID            string        `json:"id"`
+	Name          string        `json:"name"`
+	Icon          string        `json:"icon,omitempty"`
+	Active        bool          `json:"active"`
+	VoiceChannels []ChannelInfo `json:"voice_channels"`
+}
+
+// ChannelInfo names a voice channel the bot can be sent to.
+type ChannelInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// TrackInfo describes a queued or playing track.
+type TrackInfo struct {
+	Title           string `json:"title"`
+	Author          string `json:"author,omitempty"`
+	URL             string `json:"url"`
+	Thumbnail       string `json:"thumbnail,omitempty"`
+	DurationSeconds int    `json:"duration_seconds"`
+	Live            bool   `json:"live"`
+	Source          string `json:"source"`
+	RequestedBy     string `json:"requested_by,omitempty"`
+}
+
+// EnqueueOptions describe a track request made outside Discord.
+type EnqueueOptions struct {
+	Query string
+	// VoiceChannelID is required when the bot is not in a voice channel yet.
+	VoiceChannelID string
+	// TextChannelID receives the now-playing card unless the guild set an
+	// announcement channel.
+	TextChannelID string
+	// UserID credits a member for the request, which also applies their
+	// per-member queue limit.
+	UserID string
+}
+
+func trackInfo(t *media.Track) TrackInfo {
+	return TrackInfo{
+		Title:           t.Title,
+		Author:          t.Author,
+		URL:             t.WebURL,
+		Thumbnail:       t.Thumbnail,
+		DurationSeconds: int(t.Duration / time.Second),
+		Live:            t.Duration == 0,
+		Source:          string(t.Source),
+		RequestedBy:     t.RequestedBy,
+	}
+}
+
+func (k *Kvazar) playerState(p *Player) PlayerState {
+	p.mu.Lock()
+	state := PlayerState{
+		GuildID: p.guild,
+		Playing: p.playing,
+		Paused:  p.paused,
+		Loop:    p.loop,
+		Queue:   make([]TrackInfo, 0, len(p.queue)),
+		History: make([]TrackInfo, 0, len(p.history)),
+	}
+	if p.voice != nil {
+		state.VoiceChannelID = p.voice.ChannelID()
+	}
+	if p.current != nil {
+		current := trackInfo(p.current)
+		state.Current = &current
+		state.PositionSeconds = int(p.elapsed / time.Second)
+		state.StreamTitle = p.streamTitle
+	}
+	for _, track := range p.queue {
+		state.Queue = append(state.Queue, trackInfo(track))
+	}
+	for i := len(p.history) - 1; i >= 0; i-- {
+		state.History = append(state.History, trackInfo(p.history[i]))
+	}
+	p.mu.Unlock()
+
+	settings := k.guildSettings(p.guild)
+	state.Audio = AudioSettings{
+		Volume:         settings.volume(),
+		Normalize:      settings.normalize(),
+		LoudnessTarget: settings.loudnessTarget(),
+	}
+
+	if guild, err := k.session.CachedGuild(p.guild); err == nil {
+		state.GuildName = guild.Name
+	}
+	return state
+}
+
+// Players lists the guilds that currently have a player, ordered by guild ID.
+func (k *Kvazar) Players() []PlayerState {
+	players := k.snapshotPlayers()
+	states := make([]PlayerState, 0, len(players))
+	for _, player := range players {
+		states = append(states, k.playerState(player))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].GuildID < states[j].GuildID })
+	return states
+}
+
+// PlayerState returns the state of the guild's player.
+func (k *Kvazar) PlayerState(guildID string) (PlayerState, error) {
+	player := k.findPlayer(guildID)
+	if player == nil {
+		return PlayerState{}, ErrNoPlayer
+	}
+	return k.playerState(player), nil
+}
+
+// EnqueueQuery resolves the query and queues it like /play does, returning the
+// track and its queue position.
+func (k *Kvazar) EnqueueQuery(ctx context.Context, guildID string, opts EnqueueOptions) (TrackInfo, int, error) {
+	query := strings.TrimSpace(opts.Query)
+	if query == "" {
+		return TrackInfo{}, 0, fmt.Errorf("%w: the query is empty", ErrResolve)
+	}
+
+	player := k.findPlayer(guildID)
+	if opts.VoiceChannelID == "" && (player == nil || player.VoiceChannelID() == "") {
+		return TrackInfo{}, 0, ErrNotConnected
+	}
+	if player == nil {
+		player = k.getPlayer(guildID)
+	}
+	if opts.VoiceChannelID != "" {
+		if err := player.EnsureConnected(opts.VoiceChannelID); err != nil {
+			return TrackInfo{}, 0, err
+		}
+	}
+
+	requestedBy := apiRequester
+	if opts.UserID != "" {
+		requestedBy = fmt.Sprintf("<@%s>", opts.UserID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 45*time.Second)
+	defer cancel()
+	track, err := k.sources.Resolve(ctx, "", query, requestedBy, opts.TextChannelID)
+	if err != nil {
+		return TrackInfo{}, 0, fmt.Errorf("%w: %w", ErrResolve, err)
+	}
+
+	position, err := player.Enqueue(track)
+	if err != nil {
+		return TrackInfo{}, 0, err
+	}
+	return trackInfo(track), position, nil
+}
+
+// SkipTrack skips the current track without a vote.
+func (k *Kvazar) SkipTrack(guildID string) (PlayerState, error) {
+	return k.control(guildID, func(p *Player) error {
+		if !p.Skip() {
+			return ErrNothingPlaying
+		}
+		return nil
+	})
+}
+
+// TogglePause pauses or resumes playback, like the pause button.
+func (k *Kvazar) TogglePause(guildID string) (PlayerState, error) {
+	return k.control(guildID, func(p *Player) error {
+		p.mu.Lock()
+		idle := p.current == nil
+		p.mu.Unlock()
+		if idle {
+			return ErrNothingPlaying
+		}
+		p.Pause()
+		return nil
+	})
+}
+
+// StopPlayer clears the queue and stops playback.
+func (k *Kvazar) StopPlayer(guildID string) (PlayerState, error) {
+	return k.control(guildID, func(p *Player) error {
+		if !p.Stop() {
+			return ErrNothingPlaying
+		}
+		return nil
+	})
+}
+
+// SetLoop turns repeating the current track on or off.
+func (k *Kvazar) SetLoop(guildID string, enabled bool) (PlayerState, error) {
+	return k.control(guildID, func(p *Player) error {
+		if p.ToggleLoop(&enabled) != enabled {
+			return ErrNothingPlaying
+		}
+		return nil
+	})
+}
+
+// MoveTrack moves a queued track between two one-based queue positions.
+func (k *Kvazar) MoveTrack(guildID string, from, to int) (PlayerState, error) {
+	return k.control(guildID, func(p *Player) error {
+		if _, err := p.Move(from-1, to-1); err != nil {
+			return ErrInvalidPosition
+		}
+		return nil
+	})
+}
+
+// SetAudio changes the guild's volume and loudness normalization within the
+// bounds /settings allows. Changes apply from the next track.
+func (k *Kvazar) SetAudio(guildID string, update AudioUpdate) (PlayerState, error) {
+	switch {
+	case update.Volume != nil && (*update.Volume < 1 || *update.Volume > 200):
+		return PlayerState{}, fmt.Errorf("%w: volume must be between 1 and 200", ErrInvalidSetting)
+	case update.LoudnessTarget != nil && (*update.LoudnessTarget < -40 || *update.LoudnessTarget > -5):
+		return PlayerState{}, fmt.Errorf("%w: loudness target must be between -40 and -5 LUFS", ErrInvalidSetting)
+	}
+	return k.control(guildID, func(p *Player) error {
+		return k.updateGuildSettings(guildID, func(s *guildSettings) {
+			if update.Volume != nil {
+				s.Volume = *update.Volume
+			}
+			if update.Normalize != nil {
+				normalize := *update.Normalize
+				s.Normalize = &normalize
+			}
+			if update.LoudnessTarget != nil {
+				s.LoudnessTarget = *update.LoudnessTarget
+			}
+		})
+	})
+}
+
+// Search lists tracks matching a free-text query without queueing them.
+func (k *Kvazar) Search(ctx context.Context, query string, limit int) ([]TrackInfo, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("%w: the query is empty", ErrResolve)
+	}
+	tracks, err := k.resolver.Search(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrResolve, err)
+	}
+	results := make([]TrackInfo, 0, len(tracks))
+	for _, track := range tracks {
+		results = append(results, trackInfo(track))
+	}
+	return results, nil
+}
+
+// Guilds lists every guild the bot is in with its voice channels, ordered by
+// name.
+func (k *Kvazar) Guilds() []GuildInfo {
+	cached := k.session.CachedGuilds()
+	guilds := make([]GuildInfo, 0, len(cached))
+	for _, guild := range cached {
+		info := GuildInfo{ID: guild.ID, Name: guild.Name, VoiceChannels: []ChannelInfo{}}
+		if guild.Icon != "" {
+			info.Icon = guild.IconURL("64")
+		}
+		channels := append([]*discordgo.Channel(nil), guild.Channels...)
+		sort.SliceStable(channels, func(i, j int) bool { return channels[i].Position < channels[j].Position })
+		for _, channel := range channels {
+			if channel.Type == discordgo.ChannelTypeGuildVoice || channel.Type == discordgo.ChannelTypeGuildStageVoice {
+				info.VoiceChannels = append(info.VoiceChannels, ChannelInfo{ID: channel.ID, Name: channel.Name})
+			}
+		}
+		guilds = append(guilds, info)
+	}
+
+	for i := range guilds {
+		guilds[i].Active = k.findPlayer(guilds[i].ID) != nil
+	}
+	sort.Slice(guilds, func(i, j int) bool {
+		return strings.ToLower(guilds[i].Name) < strings.ToLower(guilds[j].Name)
+	})
+	return guilds
+}
+
+func (k *Kvazar) control(guildID string, fn func(*Player) error) (PlayerState, error) {
+	player := k.findPlayer(guildID)
+	if player == nil {
+		return PlayerState{}, ErrNoPlayer
+	}
+	if err := fn(player); err != nil {
+		return PlayerState{}, err
+	}
+	return k.playerState(player), nil
+}
@@ -11,7 +11,9 @@ import (
 
     "github.com/bwmarrin/discordgo"
 
+    "kvazar/internal/i18n"
     "kvazar/internal/media"
+    "kvazar/internal/store"
 )
 
 // Config encapsulates boot parameters for the Kvazar bot.
@@ -19,18 +21,84 @@ type Config struct {
     Token      string
     FFMpegPath string
     YTDLPPath  string
-    Status     string
+    DataDir    string
+
+    // ResolveTimeout bounds a single yt-dlp lookup.
+    ResolveTimeout time.Duration
+    // ResolveWorkers caps how many yt-dlp processes run at once; 0 means no
+    // cap.
+    ResolveWorkers int
+    // ResolverBackend is media.BackendExec or media.BackendHelper, which
+    // runs the helper with PythonPath.
+    ResolverBackend string
+    PythonPath      string
+    // YTDLP are the cookies, proxy, source address and extractor arguments
+    // every lookup uses.
+    YTDLP media.YTDLPOptions
+
+    // LibraryDir enables the local library; FFProbePath reads its tags.
+    LibraryDir  string
+    FFProbePath string
+    // PublicURL is where Discord reaches the HTTP server, for cover art.
+    PublicURL string
+    // SpotifyClientID and SpotifyClientSecret let Spotify links be read
+    // through the Web API instead of the embed player pages.
+    SpotifyClientID     string
+    SpotifyClientSecret string
+    // CacheEntries enables the resolver cache with room for this many
+    // tracks, trusted for CacheTTL. PersistCache keeps it across restarts.
+    CacheEntries int
+    CacheTTL     time.Duration
+    PersistCache bool
+
+    // CommandGuildID registers the commands in a single guild, where changes
+    // show up instantly, instead of globally. Meant for development.
+    CommandGuildID string
+    // CleanupCommands removes the registered commands on shutdown.
+    CleanupCommands bool
+
+    // Settings can be changed later with Reload.
+    Settings
 }
 
 // Kvazar represents the runtime bot instance.
 type Kvazar struct {
-    session    *discordgo.Session
+    session    Session
     resolver   *media.Resolver
+    sources    *media.Registry
+    // library is nil unless a library directory is configured.
+    library    *media.Library
     ffmpegPath string
     players    map[string]*Player
     playersMu  sync.RWMutex
     commands   []*discordgo.ApplicationCommand
-    status     string
+    store      *store.Store
+
+    commandGuildID  string
+    cleanupCommands bool
+    persistCache    bool
+
+    runtime   Settings
+    runtimeMu sync.RWMutex
+
+    favoritesMu sync.Mutex
+
+    settings   map[string]*guildSettings
+    settingsMu sync.Mutex
+
+    // cooldowns holds when each guild:user play cooldown ends.
+    cooldowns  map[string]time.Time
+    cooldownMu sync.Mutex
+
+	events *EventBus
+
+	calls   map[string]*commandCall
+	callsMu sync.Mutex
+
+	startedAt time.Time
+	gateway   gatewayState
+	playback  playbackLog
+	tools     toolProbe
 }
 
 // New constructs a Kvazar bot from the provided configuration.
@@ -46,20 +114,85 @@ func New(cfg Config) (*Kvazar, error) {
 
     sess.Identify.Intents = discordgo.IntentsGuilds | discordgo.IntentsGuildVoiceStates
 
-    bot := &Kvazar{
-        session:    sess,
-        resolver:   media.NewResolver(cfg.YTDLPPath),
-        ffmpegPath: pickOrDefault(cfg.FFMpegPath, "ffmpeg"),
-        players:    make(map[string]*Player),
-        status:     cfg.Status,
+    bot, err := newKvazar(cfg, discordSession{sess})
+    if err != nil {
+        return nil, err
     }
 
     sess.AddHandler(bot.onReady)
+	sess.AddHandler(bot.onConnect)
+	sess.AddHandler(bot.onDisconnect)
     sess.AddHandler(bot.onInteractionCreate)
 
     return bot, nil
 }
 
+// newKvazar builds the bot around an existing session without registering
+// any gateway handlers.
+func newKvazar(cfg Config, session Session) (*Kvazar, error) {
+    st, err := store.Open(cfg.DataDir)
+    if err != nil {
+        return nil, err
+    }
+
+    resolver := media.NewResolver(cfg.YTDLPPath)
+    if cfg.ResolveTimeout > 0 {
+        resolver.Timeout = cfg.ResolveTimeout
+    }
+	resolver.Workers = cfg.ResolveWorkers
+	resolver.Options = cfg.YTDLP
+	if cfg.YTDLP.Proxy != "" && cfg.YTDLP.FFMpegProxy() == "" {
+		log.Printf("warning: ffmpeg cannot use the proxy %s; streams are fetched without it", cfg.YTDLP.Proxy)
+	}
+	if cfg.ResolverBackend == media.BackendHelper {
+		resolver.Helper = media.NewHelper(cfg.PythonPath)
+	}
+	if cfg.CacheEntries > 0 {
+		resolver.Cache = media.NewResolveCache(cfg.CacheEntries, cfg.CacheTTL)
+	}
+	sources, err := media.NewRegistry(
+		media.NewYouTubeProvider(resolver),
+		media.NewSoundCloudProvider(resolver),
+		media.NewSpotifyProvider(resolver, nil, cfg.SpotifyClientID, cfg.SpotifyClientSecret),
+		media.NewAppleMusicProvider(resolver, nil),
+		media.NewDeezerProvider(resolver, nil),
+		media.NewDirectProvider(nil),
+	)
+	if err != nil {
+		return nil, err
+	}
+	library := newLibrary(cfg)
+	if library != nil {
+		if err := sources.Register(library); err != nil {
+			return nil, err
+		}
+	}
+
+    bot := &Kvazar{
+        session:    session,
+        resolver:   resolver,
+        sources:    sources,
+        library:    library,
+        ffmpegPath: pickOrDefault(cfg.FFMpegPath, "ffmpeg"),
+        players:    make(map[string]*Player),
+        settings:   make(map[string]*guildSettings),
+        cooldowns:  make(map[string]time.Time),
+        store:      st,
+        runtime:    cfg.Settings.normalized(),
+
+        commandGuildID:  strings.TrimSpace(cfg.CommandGuildID),
+        cleanupCommands: cfg.CleanupCommands,
+        persistCache:    cfg.PersistCache,
+
+		events:    NewEventBus(),
+		calls:     make(map[string]*commandCall),
+		startedAt: time.Now(),
+    }
+	bot.restoreLibrary()
+	bot.restoreCache()
+    return bot, nil
+}
+
 // Open starts the Discord session and registers commands.
 func (k *Kvazar) Open(ctx context.Context) error {
     if err := k.session.Open(); err != nil {
@@ -70,21 +203,31 @@ func (k *Kvazar) Open(ctx context.Context) error {
         return err
     }
 
+	k.updatePresence()
+	go k.cleanupStaleAnnouncements()
+	go k.checkTools(ctx)
+	if k.library != nil {
+		go k.rescanLibrary(context.Background())
+	}
+	return nil
+}
+
+// updatePresence shows the configured status as "Listening to ...".
+func (k *Kvazar) updatePresence() {
 	// Set Do Not Disturb status
 	_ = k.session.UpdateStatusComplex(discordgo.UpdateStatusData{
 		Status: "dnd",
 		Activities: []*discordgo.Activity{
 			{
-				Name: pickOrDefault(k.status, "/play"),
+				Name: pickOrDefault(k.currentSettings().Status, "/play"),
 				Type: discordgo.ActivityTypeListening,
 			},
 		},
 	})
-	
-	return nil
 }
 
-// Close deregisters commands and closes the Discord session.
+// Close stops every player and closes the Discord session. Commands stay
+// registered unless CleanupCommands is set.
 func (k *Kvazar) Close(ctx context.Context) error {
     if err := k.unregisterCommands(ctx); err != nil {
         log.Printf("warning: failed to cleanup commands: %v", err)
@@ -92,37 +235,27 @@ func (k *Kvazar) Close(ctx context.Context) error {
     for _, player := range k.snapshotPlayers() {
         player.Shutdown()
     }
+	k.saveCache()
+	if k.resolver.Helper != nil {
+		_ = k.resolver.Helper.Close()
+	}
     return k.session.Close()
 }
 
 func (k *Kvazar) registerCommands(ctx context.Context) error {
-    appID := k.session.State.User.ID
+    appID := k.session.BotUserID()
     if appID == "" {
         return errors.New("application ID unavailable; ensure session is open")
     }
-    k.commands = make([]*discordgo.ApplicationCommand, 0, len(globalCommands))
-    for _, cmd := range globalCommands {
-        created, err := k.session.ApplicationCommandCreate(appID, "", cmd)
-        if err != nil {
-            return fmt.Errorf("register command %s: %w", cmd.Name, err)
-        }
-        k.commands = append(k.commands, created)
-    }
-    return nil
+    return k.syncCommands(appID)
 }
 
 func (k *Kvazar) unregisterCommands(ctx context.Context) error {
-    appID := k.session.State.User.ID
-    if appID == "" {
+    appID := k.session.BotUserID()
+    if appID == "" || !k.cleanupCommands {
         return nil
     }
-    for _, cmd := range k.commands {
-        if err := k.session.ApplicationCommandDelete(appID, "", cmd.ID); err != nil {
-            log.Printf("warning: failed to delete command %s: %v", cmd.Name, err)
-        }
-    }
-    k.commands = nil
-    return nil
+    return k.clearCommands(appID)
 }
 
 func (k *Kvazar) onReady(_ *discordgo.Session, event *discordgo.Ready) {
@@ -130,9 +263,16 @@ func (k *Kvazar) onReady(_ *discordgo.Session, event *discordgo.Ready) {
 }
 
 func (k *Kvazar) onInteractionCreate(s *discordgo.Session, ic *discordgo.InteractionCreate) {
+	k.beginCommand(ic)
+	defer k.finishCommand(ic)
+
     switch ic.Type {
     case discordgo.InteractionApplicationCommand:
-        switch ic.ApplicationCommandData().Name {
+        data := ic.ApplicationCommandData()
+        if !k.authorize(ic, data.Name, subcommandName(data)) {
+            return
+        }
+        switch data.Name {
         case commandPlay:
             k.handlePlay(ic)
         case commandPlayer:
@@ -145,6 +285,22 @@ func (k *Kvazar) onInteractionCreate(s *discordgo.Session, ic *discordgo.Interac
             k.handleSkip(ic)
         case commandLoop:
             k.handleLoop(ic)
+        case commandQueue:
+            k.handleQueue(ic)
+        case commandFavorites:
+            k.handleFavorites(ic)
+        case commandRemove:
+            k.handleRemove(ic)
+        case commandPermissions:
+            k.handlePermissions(ic)
+        case commandLimits:
+            k.handleLimits(ic)
+        case commandLanguage:
+            k.handleLanguage(ic)
+        case commandSettings:
+            k.handleSettings(ic)
+        case commandLibrary:
+            k.handleLibrary(ic)
         }
     case discordgo.InteractionMessageComponent:
         k.handleButtonClick(ic)
@@ -153,34 +309,59 @@ func (k *Kvazar) onInteractionCreate(s *discordgo.Session, ic *discordgo.Interac
 
 func (k *Kvazar) handlePlay(ic *discordgo.InteractionCreate) {
     data := ic.ApplicationCommandData()
-	if len(data.Options) == 0 {
-		k.respondError(ic, "Молим те унеси упит или URL адресу.")
+	lang := k.lang(ic)
+	queryOption := findOption(data.Options, "query")
+	if queryOption == nil {
+		k.respondError(ic, lang.T(i18n.PlayMissingQuery))
 		return
 	}
 
-	query := strings.TrimSpace(data.Options[0].StringValue())
+	query := strings.TrimSpace(queryOption.StringValue())
+	source := ""
+	if opt := findOption(data.Options, "source"); opt != nil {
+		source = opt.StringValue()
+	}
 	if query == "" {
-		k.respondError(ic, "Молим те унеси упит.")
+		k.respondError(ic, lang.T(i18n.PlayEmptyQuery))
 		return
 	}
+	k.startPlay(ic, source, query)
+}
 
+// startPlay checks that the member can queue a track, then looks the query
+// up with the given source in the background.
+func (k *Kvazar) startPlay(ic *discordgo.InteractionCreate, source, query string) {
+	lang := k.lang(ic)
 	guildID := ic.GuildID
 	if guildID == "" {
-		k.respondError(ic, "Ова команда се може користити само на серверу.")
+		k.respondError(ic, lang.T(i18n.ErrGuildOnly))
 		return
 	}
 
 	userID := ic.Member.User.ID
 	voiceChannel, err := locateVoiceChannel(k.session, guildID, userID)
 	if err != nil {
-		k.respondError(ic, "Мораш бити повезан на гласовни канал да би користио /play.")
+		k.respondError(ic, lang.T(i18n.PlayNeedVoice))
+		return
+	}
+
+	if player := k.findPlayer(guildID); player != nil {
+		if err := player.CheckCapacity(fmt.Sprintf("<@%s>", userID)); err != nil {
+			message, _ := limitMessage(lang, err)
+			k.respondError(ic, message)
+			return
+		}
+	}
+	if err := k.checkPlayCooldown(guildID, userID); err != nil {
+		message, _ := limitMessage(lang, err)
+		k.respondError(ic, message)
 		return
 	}
 	
 	if err := k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: "Припремам песму…",
+			Content: lang.T(i18n.PlayPreparing),
 			Flags:   discordgo.MessageFlagsEphemeral,
 		},
 	}); err != nil {
@@ -190,31 +371,53 @@ func (k *Kvazar) handlePlay(ic *discordgo.InteractionCreate) {
 
     requestedBy := fmt.Sprintf("<@%s>", userID)
 
-    go k.fulfilPlay(ic, query, voiceChannel, requestedBy)
+    k.runDeferred(ic, func() { k.fulfilPlay(ic, source, query, voiceChannel, requestedBy) })
 }
 
-func (k *Kvazar) fulfilPlay(ic *discordgo.InteractionCreate, query, voiceChannel, requestedBy string) {
+func (k *Kvazar) fulfilPlay(ic *discordgo.InteractionCreate, source, query, voiceChannel, requestedBy string) {
     guildID := ic.GuildID
     player := k.getPlayer(guildID)
+    lang := k.lang(ic)
 
     if err := player.EnsureConnected(voiceChannel); err != nil {
-        k.editInteractionError(ic, fmt.Sprintf("Неуспело повезивање на гласовни канал: %v", err))
+        k.editInteractionError(ic, lang.T(i18n.ErrVoiceConnect, err))
         return
     }
 
     ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
     defer cancel()
 
-    track, err := k.resolver.Resolve(ctx, query, requestedBy, ic.ChannelID)
+	collection, err := k.sources.Expand(ctx, source, query)
+	if err != nil {
+		log.Printf("failed to expand %q: %v", query, err)
+		k.editInteractionError(ic, resolveMessage(lang, err))
+		return
+	}
+	if collection != nil {
+		k.enqueueCollection(ic, player, collection, requestedBy)
+		return
+	}
+
+    track, err := k.sources.Resolve(ctx, source, query, requestedBy, ic.ChannelID)
     if err != nil {
-        k.editInteractionError(ic, fmt.Sprintf("Не могу да пронађем песму: %v", err))
+		log.Printf("failed to resolve %q: %v", query, err)
+		k.editInteractionError(ic, resolveMessage(lang, err))
         return
     }
 
-    position := player.Enqueue(track)
+    position, err := player.Enqueue(track)
+    if err != nil {
+        message, ok := limitMessage(lang, err)
+        if !ok {
+            message = lang.T(i18n.PlayEnqueueFailed, err)
+        }
+        k.editInteractionError(ic, message)
+        return
+    }
+	k.startPlayCooldown(guildID, ic.Member.User.ID)
 
-    message := fmt.Sprintf("У реду **%s** — позиција #%d.", track.Title, position)
-    embed := buildQueuedEmbed(track, position)
+    message := lang.T(i18n.PlayQueued, track.Title, position)
+    embed := buildQueuedEmbed(lang, track, position)
     embeds := []*discordgo.MessageEmbed{embed}
 
     if _, err := k.session.InteractionResponseEdit(ic.Interaction, &discordgo.WebhookEdit{
@@ -225,7 +428,38 @@ func (k *Kvazar) fulfilPlay(ic *discordgo.InteractionCreate, query, voiceChannel
     }
 }
 
+// enqueueCollection queues the tracks of an album or playlist link, each
+// found and resolved on its own.
+func (k *Kvazar) enqueueCollection(ic *discordgo.InteractionCreate, player *Player, collection *media.Collection, requestedBy string) {
+	lang := k.lang(ic)
+	queries := make([]string, 0, len(collection.Tracks))
+	for _, track := range collection.Tracks {
+		queries = append(queries, track.URL)
+	}
+	added, failed, limitErr := k.resolveAndEnqueue(player, queries, requestedBy, ic.ChannelID)
+
+	message := lang.T(i18n.PlayQueuedCollection, added, collection.Title)
+	if failed > 0 {
+		message += " " + lang.T(i18n.FailedCount, failed)
+	}
+	if limitErr != nil {
+		message += " " + limitErr.userMessage(lang)
+	}
+	if added == 0 {
+		k.editInteractionError(ic, message)
+		return
+	}
+	k.startPlayCooldown(ic.GuildID, ic.Member.User.ID)
+	k.editInteractionContent(ic, message)
+}
+
 func (k *Kvazar) editInteractionError(ic *discordgo.InteractionCreate, message string) {
+	k.markCommand(ic, outcomeError)
+    k.editInteractionContent(ic, message)
+}
+
+// editInteractionContent replaces a deferred response with plain text, dropping any embeds.
+func (k *Kvazar) editInteractionContent(ic *discordgo.InteractionCreate, message string) {
     empty := []*discordgo.MessageEmbed{}
     if _, err := k.session.InteractionResponseEdit(ic.Interaction, &discordgo.WebhookEdit{
         Content: stringPtr(message),
@@ -238,22 +472,24 @@ func (k *Kvazar) editInteractionError(ic *discordgo.InteractionCreate, message s
 func (k *Kvazar) handleSkip(ic *discordgo.InteractionCreate) {
 	player := k.findPlayer(ic.GuildID)
 	if player == nil {
-		k.respondError(ic, "Ништа тренутно не свира.")
+		k.respondError(ic, k.lang(ic).T(i18n.ErrNothingPlaying))
 		return
 	}
 
-	if !player.Skip() {
-		k.respondError(ic, "Нема активне песме за прескакање.")
+	message, ok := k.requestSkip(ic, player)
+	if !ok {
+		k.respondError(ic, message)
 		return
 	}
 
-	k.respondSuccess(ic, "Прескочена је тренутна песма.")
+	k.respondSuccess(ic, message)
 }
 
 func (k *Kvazar) handlePlayer(ic *discordgo.InteractionCreate) {
+	lang := k.lang(ic)
 	player := k.findPlayer(ic.GuildID)
 	if player == nil {
-		k.respondError(ic, "Ништа тренутно не свира.")
+		k.respondError(ic, lang.T(i18n.ErrNothingPlaying))
 		return
 	}
 
@@ -262,80 +498,51 @@ func (k *Kvazar) handlePlayer(ic *discordgo.InteractionCreate) {
 	queueLen := len(player.queue)
 	loop := player.loop
 	paused := player.paused
+	votes := len(player.skipVotes)
+	streamTitle := player.streamTitle
+	voiceChannel := ""
+	if player.voice != nil {
+		voiceChannel = player.voice.ChannelID()
+	}
 	player.mu.Unlock()
 
 	if current == nil {
-		k.respondError(ic, "Ништа тренутно не свира.")
+		k.respondError(ic, lang.T(i18n.ErrNothingPlaying))
 		return
 	}
 
-	embed := buildNowPlayingEmbed(current, loop)
+	embed := buildNowPlayingEmbed(lang, current, loop)
+	appendStreamTitleField(lang, embed, streamTitle)
 	
 	// Add queue info
 	if queueLen > 0 {
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-			Name:   "У реду",
-			Value:  fmt.Sprintf("%d песама", queueLen),
+			Name:   lang.T(i18n.PlayerFieldQueue),
+			Value:  lang.T(i18n.PlayerQueueCount, queueLen),
 			Inline: true,
 		})
 	}
 	
+	if votes > 0 {
+		appendSkipVotesField(lang, embed, votes, requiredSkipVotes(k.countListeners(ic.GuildID, voiceChannel), k.currentSettings().VoteSkipRatio))
+	}
+
 	// Add pause state
 	if paused {
 		embed.Color = 0xFFA500 // Orange for paused
 		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
-			Name:   "Статус",
-			Value:  "⏸️ Паузирано",
+			Name:   lang.T(i18n.PlayerFieldStatus),
+			Value:  lang.T(i18n.PlayerPaused),
 			Inline: true,
 		})
 	}
 
 	// Add control buttons
-	loopLabel := "Укључи понављање"
-	loopStyle := discordgo.SecondaryButton
+	loopLabel := lang.T(i18n.ButtonLoopOn)
 	if loop {
-		loopLabel = "Искључи понављање"
-		loopStyle = discordgo.SuccessButton
-	}
-
-	components := []discordgo.MessageComponent{
-		discordgo.ActionsRow{
-			Components: []discordgo.MessageComponent{
-				discordgo.Button{
-					Label:    "Пауза",
-					Style:    discordgo.SecondaryButton,
-					CustomID: "pause_button",
-					Emoji: discordgo.ComponentEmoji{
-						Name: "⏸️",
-					},
-				},
-				discordgo.Button{
-					Label:    "Заустави",
-					Style:    discordgo.DangerButton,
-					CustomID: "stop_button",
-					Emoji: discordgo.ComponentEmoji{
-						Name: "⏹️",
-					},
-				},
-				discordgo.Button{
-					Label:    "Прескочи",
-					Style:    discordgo.PrimaryButton,
-					CustomID: "skip_button",
-					Emoji: discordgo.ComponentEmoji{
-						Name: "⏭️",
-					},
-				},
-				discordgo.Button{
-					Label:    loopLabel,
-					Style:    loopStyle,
-					CustomID: "loop_button",
-					Emoji: discordgo.ComponentEmoji{
-						Name: "🔁",
-					},
-				},
-			},
-		},
+		loopLabel = lang.T(i18n.ButtonLoopOff)
 	}
+	components := buildPlayerComponents(lang, loopLabel, loop)
 
 	_ = k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -347,39 +554,42 @@ func (k *Kvazar) handlePlayer(ic *discordgo.InteractionCreate) {
 }
 
 func (k *Kvazar) handlePause(ic *discordgo.InteractionCreate) {
+	lang := k.lang(ic)
 	player := k.findPlayer(ic.GuildID)
 	if player == nil {
-		k.respondError(ic, "Ништа тренутно не свира.")
+		k.respondError(ic, lang.T(i18n.ErrNothingPlaying))
 		return
 	}
 
 	paused := player.Pause()
 	if paused {
-		k.respondSuccess(ic, "⏸️ Репродукција паузирана.")
+		k.respondSuccess(ic, lang.T(i18n.PausePaused))
 	} else {
-		k.respondSuccess(ic, "▶️ Репродукција настављена.")
+		k.respondSuccess(ic, lang.T(i18n.PauseResumed))
 	}
 }
 
 func (k *Kvazar) handleStop(ic *discordgo.InteractionCreate) {
+	lang := k.lang(ic)
 	player := k.findPlayer(ic.GuildID)
 	if player == nil {
-		k.respondError(ic, "Ништа тренутно не свира.")
+		k.respondError(ic, lang.T(i18n.ErrNothingPlaying))
 		return
 	}
 
 	if !player.Stop() {
-		k.respondError(ic, "Нема ничега за заустављање.")
+		k.respondError(ic, lang.T(i18n.StopNothing))
 		return
 	}
 
-	k.respondSuccess(ic, "⏹️ Репродукција заустављена и ред испражњен.")
+	k.respondSuccess(ic, lang.T(i18n.StopDone))
 }
 
 func (k *Kvazar) handleLoop(ic *discordgo.InteractionCreate) {
+	lang := k.lang(ic)
 	player := k.findPlayer(ic.GuildID)
 	if player == nil {
-		k.respondError(ic, "Ништа не свира да би се понављало.")
+		k.respondError(ic, lang.T(i18n.LoopNothing))
 		return
 	}
 	
@@ -391,13 +601,19 @@ func (k *Kvazar) handleLoop(ic *discordgo.InteractionCreate) {
 
 	state := player.ToggleLoop(explicit)
 	if state {
-		k.respondSuccess(ic, "Понављање је укључено.")
+		k.respondSuccess(ic, lang.T(i18n.LoopEnabled))
 	} else {
-		k.respondSuccess(ic, "Понављање је искључено.")
+		k.respondSuccess(ic, lang.T(i18n.LoopDisabled))
 	}
 }
 
 func (k *Kvazar) respondError(ic *discordgo.InteractionCreate, message string) {
+	k.markCommand(ic, outcomeError)
+    k.respondEphemeral(ic, message)
+}
+
+// respondEphemeral replies with a message only the invoking user can see.
+func (k *Kvazar) respondEphemeral(ic *discordgo.InteractionCreate, message string) {
     _ = k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
         Type: discordgo.InteractionResponseChannelMessageWithSource,
         Data: &discordgo.InteractionResponseData{
@@ -447,76 +663,25 @@ func (k *Kvazar) snapshotPlayers() []*Player {
     return res
 }
 
-func (k *Kvazar) announceNowPlaying(track *media.Track, loop bool) {
-    if track.RequestChannelID == "" {
+func (k *Kvazar) handleButtonClick(ic *discordgo.InteractionCreate) {
+    customID := ic.MessageComponentData().CustomID
+
+    if command, ok := buttonCommands[customID]; ok && !k.authorize(ic, command, "") {
         return
     }
-    embed := buildNowPlayingEmbed(track, loop)
-    
-    // Add buttons for skip and loop
-    loopLabel := "Понови"
-    loopStyle := discordgo.SecondaryButton
-    if loop {
-        loopLabel = "Искључи понављање"
-        loopStyle = discordgo.SuccessButton
-    }
-    
-    components := []discordgo.MessageComponent{
-        discordgo.ActionsRow{
-            Components: []discordgo.MessageComponent{
-                discordgo.Button{
-                    Label:    "Пауза",
-                    Style:    discordgo.SecondaryButton,
-                    CustomID: "pause_button",
-                    Emoji: discordgo.ComponentEmoji{
-                        Name: "⏸️",
-                    },
-                },
-                discordgo.Button{
-                    Label:    "Заустави",
-                    Style:    discordgo.DangerButton,
-                    CustomID: "stop_button",
-                    Emoji: discordgo.ComponentEmoji{
-                        Name: "⏹️",
-                    },
-                },
-                discordgo.Button{
-                    Label:    "Прескочи",
-                    Style:    discordgo.PrimaryButton,
-                    CustomID: "skip_button",
-                    Emoji: discordgo.ComponentEmoji{
-                        Name: "⏭️",
-                    },
-                },
-                discordgo.Button{
-                    Label:    loopLabel,
-                    Style:    loopStyle,
-                    CustomID: "loop_button",
-                    Emoji: discordgo.ComponentEmoji{
-                        Name: "🔁",
-                    },
-                },
-            },
-        },
-    }
-    
-    if _, err := k.session.ChannelMessageSendComplex(track.RequestChannelID, &discordgo.MessageSend{
-        Embeds:     []*discordgo.MessageEmbed{embed},
-        Components: components,
-    }); err != nil {
-        log.Printf("failed to send now playing message: %v", err)
+
+    if customID == "like_button" {
+        k.handleLikeButton(ic)
+        return
     }
-}
 
-func (k *Kvazar) handleButtonClick(ic *discordgo.InteractionCreate) {
-    customID := ic.MessageComponentData().CustomID
-    
+    lang := k.lang(ic)
     player := k.findPlayer(ic.GuildID)
     if player == nil {
         _ = k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
             Type: discordgo.InteractionResponseChannelMessageWithSource,
             Data: &discordgo.InteractionResponseData{
-                Content: "Ништа тренутно не свира.",
+                Content: lang.T(i18n.ErrNothingPlaying),
                 Flags:   discordgo.MessageFlagsEphemeral,
             },
         })
@@ -529,9 +694,9 @@ func (k *Kvazar) handleButtonClick(ic *discordgo.InteractionCreate) {
             Type: discordgo.InteractionResponseDeferredMessageUpdate,
         })
         paused := player.Pause()
-        message := "⏸️ Репродукција паузирана."
+        message := lang.T(i18n.PausePaused)
         if !paused {
-            message = "▶️ Репродукција настављена."
+            message = lang.T(i18n.PauseResumed)
         }
         _, _ = k.session.FollowupMessageCreate(ic.Interaction, true, &discordgo.WebhookParams{
             Content: message,
@@ -543,12 +708,12 @@ func (k *Kvazar) handleButtonClick(ic *discordgo.InteractionCreate) {
         })
         if player.Stop() {
             _, _ = k.session.FollowupMessageCreate(ic.Interaction, true, &discordgo.WebhookParams{
-                Content: "⏹️ Репродукција заустављена и ред испражњен.",
+                Content: lang.T(i18n.StopDone),
                 Flags:   discordgo.MessageFlagsEphemeral,
             })
         } else {
             _, _ = k.session.FollowupMessageCreate(ic.Interaction, true, &discordgo.WebhookParams{
-                Content: "Нема ничега за заустављање.",
+                Content: lang.T(i18n.StopNothing),
                 Flags:   discordgo.MessageFlagsEphemeral,
             })
         }
@@ -556,26 +721,20 @@ func (k *Kvazar) handleButtonClick(ic *discordgo.InteractionCreate) {
         _ = k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
             Type: discordgo.InteractionResponseDeferredMessageUpdate,
         })
-        if player.Skip() {
-            _, _ = k.session.FollowupMessageCreate(ic.Interaction, true, &discordgo.WebhookParams{
-                Content: "⏭️ Прескочена је тренутна песма.",
-                Flags:   discordgo.MessageFlagsEphemeral,
-            })
-        } else {
-            _, _ = k.session.FollowupMessageCreate(ic.Interaction, true, &discordgo.WebhookParams{
-                Content: "Нема активне песме за прескакање.",
-                Flags:   discordgo.MessageFlagsEphemeral,
-            })
-        }
+        message, _ := k.requestSkip(ic, player)
+        _, _ = k.session.FollowupMessageCreate(ic.Interaction, true, &discordgo.WebhookParams{
+            Content: message,
+            Flags:   discordgo.MessageFlagsEphemeral,
+        })
     case "loop_button":
         _ = k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
             Type: discordgo.InteractionResponseDeferredMessageUpdate,
         })
         state := player.ToggleLoop(nil)
         emoji := "🔁"
-        message := "Понављање је укључено."
+        message := lang.T(i18n.LoopEnabled)
         if !state {
-            message = "Понављање је искључено."
+            message = lang.T(i18n.LoopDisabled)
         }
         _, _ = k.session.FollowupMessageCreate(ic.Interaction, true, &discordgo.WebhookParams{
             Content: emoji + " " + message,
@@ -584,6 +743,69 @@ func (k *Kvazar) handleButtonClick(ic *discordgo.InteractionCreate) {
     }
 }
 
+// buildPlayerComponents renders the playback control row shared by /player and the now-playing card.
+func buildPlayerComponents(lang i18n.Lang, loopLabel string, loop bool) []discordgo.MessageComponent {
+	loopStyle := discordgo.SecondaryButton
+	if loop {
+		loopStyle = discordgo.SuccessButton
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    lang.T(i18n.ButtonPause),
+					Style:    discordgo.SecondaryButton,
+					CustomID: "pause_button",
+					Emoji: discordgo.ComponentEmoji{
+						Name: "⏸️",
+					},
+				},
+				discordgo.Button{
+					Label:    lang.T(i18n.ButtonStop),
+					Style:    discordgo.DangerButton,
+					CustomID: "stop_button",
+					Emoji: discordgo.ComponentEmoji{
+						Name: "⏹️",
+					},
+				},
+				discordgo.Button{
+					Label:    lang.T(i18n.ButtonSkip),
+					Style:    discordgo.PrimaryButton,
+					CustomID: "skip_button",
+					Emoji: discordgo.ComponentEmoji{
+						Name: "⏭️",
+					},
+				},
+				discordgo.Button{
+					Label:    loopLabel,
+					Style:    loopStyle,
+					CustomID: "loop_button",
+					Emoji: discordgo.ComponentEmoji{
+						Name: "🔁",
+					},
+				},
+				discordgo.Button{
+					Style:    discordgo.SecondaryButton,
+					CustomID: "like_button",
+					Emoji: discordgo.ComponentEmoji{
+						Name: "❤️",
+					},
+				},
+			},
+		},
+	}
+}
+
+// webLink returns the URL when Discord can link to it. Tracks from the local
+// library have none.
+func webLink(rawURL string) string {
+	if strings.HasPrefix(rawURL, "https://") || strings.HasPrefix(rawURL, "http://") {
+		return rawURL
+	}
+	return ""
+}
+
 func pickOrDefault(value, fallback string) string {
     if strings.TrimSpace(value) == "" {
         return fallback
@@ -591,24 +813,24 @@ func pickOrDefault(value, fallback string) string {
     return value
 }
 
-func buildQueuedEmbed(track *media.Track, position int) *discordgo.MessageEmbed {
-    title := fmt.Sprintf("У реду • %s", track.Title)
+func buildQueuedEmbed(lang i18n.Lang, track *media.Track, position int) *discordgo.MessageEmbed {
+    title := lang.T(i18n.EmbedQueuedTitle, track.Title)
     if position == 1 {
-        title = fmt.Sprintf("Следеће • %s", track.Title)
+        title = lang.T(i18n.EmbedNextTitle, track.Title)
     }
 
     fields := []*discordgo.MessageEmbedField{
-        {Name: "Трајање", Value: track.HumanDuration(), Inline: true},
-        {Name: "Извор", Value: string(track.Source), Inline: true},
+        {Name: lang.T(i18n.EmbedDuration), Value: track.HumanDuration(), Inline: true},
+        {Name: lang.T(i18n.EmbedSource), Value: string(track.Source), Inline: true},
     }
     if track.RequestedBy != "" {
-        fields = append(fields, &discordgo.MessageEmbedField{Name: "Захтевао", Value: track.RequestedBy, Inline: true})
+        fields = append(fields, &discordgo.MessageEmbedField{Name: lang.T(i18n.EmbedRequestedBy), Value: track.RequestedBy, Inline: true})
     }
-    fields = append(fields, &discordgo.MessageEmbedField{Name: "Позиција", Value: fmt.Sprintf("#%d", position), Inline: true})
+    fields = append(fields, &discordgo.MessageEmbedField{Name: lang.T(i18n.EmbedPosition), Value: fmt.Sprintf("#%d", position), Inline: true})
 
 	return &discordgo.MessageEmbed{
 		Title:     title,
-		URL:       track.WebURL,
+		URL:       webLink(track.WebURL),
 		Color:     0x5865F2,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Thumbnail: &discordgo.MessageEmbedThumbnail{
@@ -618,21 +840,21 @@ func buildQueuedEmbed(track *media.Track, position int) *discordgo.MessageEmbed
 	}
 }
 
-func buildNowPlayingEmbed(track *media.Track, loop bool) *discordgo.MessageEmbed {
-    status := "Сада"
+func buildNowPlayingEmbed(lang i18n.Lang, track *media.Track, loop bool) *discordgo.MessageEmbed {
+    title := lang.T(i18n.EmbedNow, track.Title)
     if loop {
-        status = "Понавља"
+        title = lang.T(i18n.EmbedRepeating, track.Title)
     }
 
 	return &discordgo.MessageEmbed{
-		Title:     fmt.Sprintf("%s • %s", status, track.Title),
-		URL:       track.WebURL,
+		Title:     title,
+		URL:       webLink(track.WebURL),
 		Color:     0x1ABC9C,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Thumbnail: &discordgo.MessageEmbedThumbnail{URL: track.Thumbnail},
 		Fields: []*discordgo.MessageEmbedField{
-			{Name: "Трајање", Value: track.HumanDuration(), Inline: true},
-			{Name: "Извор", Value: string(track.Source), Inline: true},
+			{Name: lang.T(i18n.EmbedDuration), Value: track.HumanDuration(), Inline: true},
+			{Name: lang.T(i18n.EmbedSource), Value: string(track.Source), Inline: true},
 		},
 	}
 }
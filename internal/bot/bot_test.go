@@ -0,0 +1,472 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+)
+
+// nextEvent skips ahead to the next event of the given type.
+func nextEvent(t *testing.T, events <-chan Event, typ EventType) Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type == typ {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", typ)
+		}
+	}
+}
+
+func trackTitle(event Event) string {
+	return event.Data.(TrackEvent).Track.Title
+}
+
+// dispatch delivers an interaction and returns the replies it got right away.
+func dispatch(k *Kvazar, s *fakeSession, ic *discordgo.InteractionCreate) []reply {
+	k.onInteractionCreate(nil, ic)
+	return s.repliesTo(ic.ID)
+}
+
+// play runs /play and waits for the deferred response to be filled in.
+func play(t *testing.T, k *Kvazar, s *fakeSession, userID, query string) string {
+	t.Helper()
+	ic := slash(userID, commandPlay, query)
+	k.onInteractionCreate(nil, ic)
+	var replies []reply
+	waitFor(t, "the /play response", func() bool {
+		replies = s.repliesTo(ic.ID)
+		return len(replies) == 2
+	})
+	if replies[0].typ != discordgo.InteractionResponseDeferredChannelMessageWithSource || replies[1].kind != "edit" {
+		t.Fatalf("/play %s: replies = %+v", query, replies)
+	}
+	return replies[1].content
+}
+
+// expectReply checks that an interaction got exactly the reply wanted as its
+// last message.
+func expectReply(t *testing.T, replies []reply, kind, content string) {
+	t.Helper()
+	if len(replies) == 0 {
+		t.Fatalf("no reply, want %s %q", kind, content)
+	}
+	last := replies[len(replies)-1]
+	if last.kind != kind || last.content != content {
+		t.Fatalf("reply = %s %q, want %s %q", last.kind, last.content, kind, content)
+	}
+}
+
+func TestPlayStartsPlayback(t *testing.T) {
+	k, s := newTestBot(t, "alice")
+	lang := k.defaultLang()
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	if got, want := play(t, k, s, "alice", "intro"), lang.T(i18n.PlayQueued, "intro", 1); got != want {
+		t.Fatalf("/play = %q, want %q", got, want)
+	}
+	if event := nextEvent(t, events, EventVoiceConnected); event.Data.(VoiceEvent).ChannelID != testVoice {
+		t.Fatalf("joined %+v, want the requester's channel", event.Data)
+	}
+	if title := trackTitle(nextEvent(t, events, EventTrackStarted)); title != "intro" {
+		t.Fatalf("started %q", title)
+	}
+	nextEvent(t, events, EventTrackEnded)
+
+	voice := s.voice()
+	waitFor(t, "every frame to be sent", func() bool { return voice.sentFrames() == 10 })
+	if s.sent(testText) != 1 {
+		t.Errorf("now-playing cards = %d, want 1", s.sent(testText))
+	}
+	if _, _, history := k.findPlayer(testGuild).QueueSnapshot(); len(history) != 1 || history[0].Title != "intro" {
+		t.Errorf("history = %v", history)
+	}
+}
+
+func TestPlaySourceOption(t *testing.T) {
+	k, s := newTestBot(t, "alice")
+	lang := k.defaultLang()
+
+	// The fake yt-dlp names tracks after the query it was given, minus any
+	// YouTube search prefix.
+	ic := slash("alice", commandPlay, "song")
+	data := ic.Data.(discordgo.ApplicationCommandInteractionData)
+	data.Options = append(data.Options, &discordgo.ApplicationCommandInteractionDataOption{
+		Name: "source", Type: discordgo.ApplicationCommandOptionString, Value: "soundcloud",
+	})
+	ic.Data = data
+	k.onInteractionCreate(nil, ic)
+	waitFor(t, "the /play response", func() bool { return len(s.repliesTo(ic.ID)) == 2 })
+	expectReply(t, s.repliesTo(ic.ID), "edit", lang.T(i18n.PlayQueued, "scsearch:song", 1))
+
+	if got, want := play(t, k, s, "alice", "sc tune"), lang.T(i18n.PlayQueued, "scsearch:tune", 1); got != want {
+		t.Errorf("/play sc tune = %q, want %q", got, want)
+	}
+}
+
+func TestRadioStreamTitle(t *testing.T) {
+	// A station that announces one song, then keeps streaming silence.
+	radio := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("icy-name", "Kosmos FM")
+		if r.Header.Get("Icy-MetaData") != "1" {
+			return
+		}
+		w.Header().Set("icy-metaint", "16")
+		meta := "StreamTitle='Artist - Song';"
+		w.Write(make([]byte, 16))
+		w.Write(append([]byte{2}, (meta + strings.Repeat("\x00", 32-len(meta)))...))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer radio.Close()
+
+	k, s := newTestBot(t, "alice")
+	lang := k.defaultLang()
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	// The fake ffmpeg streams for as long as the URL mentions "forever".
+	if got, want := play(t, k, s, "alice", radio.URL+"/forever"), lang.T(i18n.PlayQueued, "Kosmos FM", 1); got != want {
+		t.Fatalf("/play = %q, want %q", got, want)
+	}
+	if event := nextEvent(t, events, EventStreamTitle); event.Data.(StreamTitleEvent).Title != "Artist - Song" {
+		t.Fatalf("stream title = %+v", event.Data)
+	}
+	state := k.playerState(k.findPlayer(testGuild))
+	if state.StreamTitle != "Artist - Song" || state.Current == nil || !state.Current.Live || state.Current.Source != "Radio" {
+		t.Errorf("player = %+v, current = %+v", state, state.Current)
+	}
+
+	expectReply(t, dispatch(k, s, slash("alice", commandStop)), "respond", lang.T(i18n.StopDone))
+	nextEvent(t, events, EventTrackSkipped)
+}
+
+func TestPlayRejections(t *testing.T) {
+	k, s := newTestBot(t, "alice")
+	lang := k.defaultLang()
+
+	dm := slash("alice", commandPlay, "song")
+	dm.GuildID, dm.Member = "", nil
+	dm.User = &discordgo.User{ID: "alice"}
+
+	tests := []struct {
+		name string
+		ic   *discordgo.InteractionCreate
+		want string
+	}{
+		{"missing query", slash("alice", commandPlay), lang.T(i18n.PlayMissingQuery)},
+		{"blank query", slash("alice", commandPlay, "   "), lang.T(i18n.PlayEmptyQuery)},
+		{"not in voice", slash("bob", commandPlay, "song"), lang.T(i18n.PlayNeedVoice)},
+		{"direct message", dm, lang.T(i18n.ErrGuildOnly)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replies := dispatch(k, s, tt.ic)
+			expectReply(t, replies, "respond", tt.want)
+			if !replies[0].ephemeral {
+				t.Error("rejection is visible to everyone")
+			}
+		})
+	}
+	if k.findPlayer(testGuild) != nil {
+		t.Error("a rejected /play created a player")
+	}
+}
+
+// TestPlayExplainsResolveErrors checks that /play explains why yt-dlp could
+// not find a track instead of showing what it printed.
+func TestPlayExplainsResolveErrors(t *testing.T) {
+	k, s := newTestBot(t, "alice")
+	lang := k.defaultLang()
+
+	for query, want := range map[string]i18n.Key{
+		"private mix":     i18n.ResolvePrivate,
+		"restricted song": i18n.ResolveAgeRestricted,
+		"broken song":     i18n.ResolveFailed,
+	} {
+		if got := play(t, k, s, "alice", query); got != lang.T(want) {
+			t.Errorf("/play %s = %q, want %q", query, got, lang.T(want))
+		}
+	}
+	if current, queue, _ := k.findPlayer(testGuild).QueueSnapshot(); current != nil || len(queue) != 0 {
+		t.Errorf("queued %v and %v after failed lookups", current, queue)
+	}
+}
+
+// TestResolveMessage checks that errors other than yt-dlp's are explained
+// without showing their text.
+func TestResolveMessage(t *testing.T) {
+	lang := i18n.Default
+	tests := []struct {
+		err  error
+		want i18n.Key
+	}{
+		{fmt.Errorf("%w: %q", media.ErrUnknownSource, "bandcamp"), i18n.ResolveUnknownSource},
+		{fmt.Errorf("%w: %q", media.ErrNotInLibrary, "orbit"), i18n.ResolveNotInLibrary},
+		{fmt.Errorf("resolve: %w", media.ErrResolverBusy), i18n.ResolveBusy},
+		{media.ErrNoMatch, i18n.ResolveNoMatch},
+		{context.DeadlineExceeded, i18n.ResolveTimeout},
+		{&media.ResolveError{Kind: media.ResolveGeoBlocked, Detail: "ERROR: not in your country"}, i18n.ResolveGeoBlocked},
+		{errors.New("media: api.spotify.com answered 401 Unauthorized"), i18n.PlayNotFound},
+	}
+	for _, tt := range tests {
+		if got := resolveMessage(lang, tt.err); got != lang.T(tt.want) {
+			t.Errorf("resolveMessage(%v) = %q, want %q", tt.err, got, lang.T(tt.want))
+		}
+	}
+}
+
+func TestQueueProgression(t *testing.T) {
+	k, s := newTestBot(t, "alice")
+	lang := k.defaultLang()
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	play(t, k, s, "alice", "slow-one")
+	nextEvent(t, events, EventTrackStarted)
+	if got, want := play(t, k, s, "alice", "slow-two"), lang.T(i18n.PlayQueued, "slow-two", 1); got != want {
+		t.Fatalf("second /play = %q, want %q", got, want)
+	}
+	if got, want := play(t, k, s, "alice", "three"), lang.T(i18n.PlayQueued, "three", 2); got != want {
+		t.Fatalf("third /play = %q, want %q", got, want)
+	}
+
+	for _, want := range []string{"slow-two", "three"} {
+		if title := trackTitle(nextEvent(t, events, EventTrackStarted)); title != want {
+			t.Fatalf("started %q, want %q", title, want)
+		}
+	}
+	nextEvent(t, events, EventTrackEnded)
+
+	player := k.findPlayer(testGuild)
+	waitFor(t, "the player to go idle", func() bool {
+		current, _, _ := player.QueueSnapshot()
+		return current == nil
+	})
+	_, queue, history := player.QueueSnapshot()
+	if len(queue) != 0 || len(history) != 3 {
+		t.Fatalf("queue = %v, history = %v", queue, history)
+	}
+	for i, want := range []string{"slow-one", "slow-two", "three"} {
+		if history[i].Title != want {
+			t.Errorf("history[%d] = %q, want %q", i, history[i].Title, want)
+		}
+	}
+}
+
+func TestHistorySkipsTracksThatNeverPlayed(t *testing.T) {
+	k, s := newTestBot(t, "alice")
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	play(t, k, s, "alice", "dead-air")
+	nextEvent(t, events, EventTrackStarted)
+	play(t, k, s, "alice", "outro")
+	if title := trackTitle(nextEvent(t, events, EventTrackStarted)); title != "outro" {
+		t.Fatalf("started %q, want outro", title)
+	}
+	nextEvent(t, events, EventTrackEnded)
+
+	player := k.findPlayer(testGuild)
+	waitFor(t, "the player to go idle", func() bool {
+		current, _, _ := player.QueueSnapshot()
+		return current == nil
+	})
+	if _, _, history := player.QueueSnapshot(); len(history) != 1 || history[0].Title != "outro" {
+		t.Errorf("history = %v, want only the track that played", history)
+	}
+}
+
+func TestSkipAndStop(t *testing.T) {
+	k, s := newTestBot(t, "alice", "bob")
+	lang := k.defaultLang()
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	play(t, k, s, "alice", "forever-a")
+	play(t, k, s, "alice", "forever-b")
+	nextEvent(t, events, EventTrackStarted)
+
+	expectReply(t, dispatch(k, s, slash("alice", commandSkip)), "respond", lang.T(i18n.SkipDone))
+	if title := trackTitle(nextEvent(t, events, EventTrackSkipped)); title != "forever-a" {
+		t.Fatalf("skipped %q", title)
+	}
+	if title := trackTitle(nextEvent(t, events, EventTrackStarted)); title != "forever-b" {
+		t.Fatalf("started %q after the skip", title)
+	}
+
+	// Bob is neither a DJ nor alone with the bot.
+	expectReply(t, dispatch(k, s, slash("bob", commandStop)), "respond", lang.T(i18n.PermDJOnly))
+	expectReply(t, dispatch(k, s, asDJ(slash("bob", commandStop))), "respond", lang.T(i18n.StopDone))
+	nextEvent(t, events, EventTrackSkipped)
+
+	player := k.findPlayer(testGuild)
+	waitFor(t, "playback to stop", func() bool {
+		player.mu.Lock()
+		defer player.mu.Unlock()
+		return !player.playing
+	})
+	if current, queue, _ := player.QueueSnapshot(); current != nil || len(queue) != 0 {
+		t.Fatalf("after /stop: current = %v, queue = %v", current, queue)
+	}
+	expectReply(t, dispatch(k, s, asDJ(slash("bob", commandStop))), "respond", lang.T(i18n.StopNothing))
+}
+
+func TestPipedInputKeepsCookiesOffCommandLine(t *testing.T) {
+	stream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "SID=secret" {
+			http.Error(w, "sign in", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, "audio")
+	}))
+	defer stream.Close()
+
+	plain := media.Input{URL: stream.URL, Headers: map[string]string{"Referer": "https://www.youtube.com/"}}
+	if input, body, err := pipedInput(context.Background(), plain); err != nil || body != nil || input.URL != stream.URL {
+		t.Errorf("pipedInput(plain) = %+v, %v, %v; want it untouched", input, body, err)
+	}
+
+	signed := media.Input{URL: stream.URL, Headers: map[string]string{"Cookie": "SID=secret"}}
+	input, body, err := pipedInput(context.Background(), signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if args := strings.Join(buildFFMpegArgs(input, ""), " "); strings.Contains(args, "secret") || !strings.Contains(args, "-i pipe:0") {
+		t.Errorf("ffmpeg args = %q", args)
+	}
+	if content, _ := io.ReadAll(body); string(content) != "audio" {
+		t.Errorf("stdin = %q, want the stream", content)
+	}
+}
+
+func TestStopEndsImport(t *testing.T) {
+	k, s := newTestBot(t, "alice")
+	lang := k.defaultLang()
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	file := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\nforever-a\nstuck-b\nafter-c\n")
+	}))
+	defer file.Close()
+
+	ic := newInteraction(discordgo.InteractionApplicationCommand, "alice", discordgo.ApplicationCommandInteractionData{
+		Name: commandQueue,
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+			Name: "import", Type: discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "file", Type: discordgo.ApplicationCommandOptionAttachment, Value: "queue"},
+			},
+		}},
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Attachments: map[string]*discordgo.MessageAttachment{
+				"queue": {ID: "queue", URL: file.URL + "/queue.m3u", Filename: "queue.m3u", Size: 64},
+			},
+		},
+	})
+	k.onInteractionCreate(nil, ic)
+	if title := trackTitle(nextEvent(t, events, EventTrackStarted)); title != "forever-a" {
+		t.Fatalf("started %q", title)
+	}
+
+	// The import is now waiting on stuck-b, with after-c already found.
+	expectReply(t, dispatch(k, s, asDJ(slash("alice", commandStop))), "respond", lang.T(i18n.StopDone))
+	var replies []reply
+	waitFor(t, "the import to report back", func() bool {
+		replies = s.repliesTo(ic.ID)
+		return len(replies) == 2
+	})
+	expectReply(t, replies, "edit", lang.T(i18n.QueueImported, 1))
+
+	current, queue, _ := k.findPlayer(testGuild).QueueSnapshot()
+	if current != nil || len(queue) != 0 {
+		t.Errorf("after /stop current = %v, queue = %v", current, queue)
+	}
+}
+
+func TestLoopRepeatsTrack(t *testing.T) {
+	k, s := newTestBot(t, "alice")
+	lang := k.defaultLang()
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	expectReply(t, dispatch(k, s, slash("alice", commandLoop)), "respond", lang.T(i18n.LoopNothing))
+
+	play(t, k, s, "alice", "slow-song")
+	nextEvent(t, events, EventTrackStarted)
+	expectReply(t, dispatch(k, s, slash("alice", commandLoop)), "respond", lang.T(i18n.LoopEnabled))
+
+	nextEvent(t, events, EventTrackEnded)
+	if title := trackTitle(nextEvent(t, events, EventTrackStarted)); title != "slow-song" {
+		t.Fatalf("started %q, want the looped track again", title)
+	}
+
+	expectReply(t, dispatch(k, s, slash("alice", commandLoop)), "respond", lang.T(i18n.LoopDisabled))
+	nextEvent(t, events, EventTrackEnded)
+	waitFor(t, "the player to go idle", func() bool {
+		current, _, _ := k.findPlayer(testGuild).QueueSnapshot()
+		return current == nil
+	})
+}
+
+func TestPlayerButtons(t *testing.T) {
+	k, s := newTestBot(t, "alice", "bob", "carol")
+	lang := k.defaultLang()
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	expectReply(t, dispatch(k, s, button("alice", "pause_button")), "respond", lang.T(i18n.ErrNothingPlaying))
+
+	play(t, k, s, "alice", "forever-x")
+	play(t, k, s, "alice", "forever-y")
+	nextEvent(t, events, EventTrackStarted)
+
+	replies := dispatch(k, s, button("alice", "pause_button"))
+	if replies[0].typ != discordgo.InteractionResponseDeferredMessageUpdate {
+		t.Fatalf("pause acknowledged with %v, want a deferred update", replies[0].typ)
+	}
+	expectReply(t, replies, "followup", lang.T(i18n.PausePaused))
+	nextEvent(t, events, EventPaused)
+	expectReply(t, dispatch(k, s, button("alice", "pause_button")), "followup", lang.T(i18n.PauseResumed))
+	nextEvent(t, events, EventResumed)
+
+	expectReply(t, dispatch(k, s, button("alice", "loop_button")), "followup", "🔁 "+lang.T(i18n.LoopEnabled))
+	if event := nextEvent(t, events, EventLoopChanged); !event.Data.(LoopEvent).Enabled {
+		t.Fatal("loop was not enabled")
+	}
+
+	// Two of the three listeners have to vote to skip someone else's track.
+	expectReply(t, dispatch(k, s, button("bob", "skip_button")), "followup", lang.T(i18n.SkipVoteRecorded, 1, 2))
+	expectReply(t, dispatch(k, s, button("carol", "skip_button")), "followup", lang.T(i18n.SkipVotePassed, 2, 2))
+	if title := trackTitle(nextEvent(t, events, EventTrackSkipped)); title != "forever-x" {
+		t.Fatalf("skipped %q", title)
+	}
+	if title := trackTitle(nextEvent(t, events, EventTrackStarted)); title != "forever-y" {
+		t.Fatalf("started %q after the vote", title)
+	}
+
+	expectReply(t, dispatch(k, s, button("bob", "stop_button")), "respond", lang.T(i18n.PermDJOnly))
+	expectReply(t, dispatch(k, s, asDJ(button("bob", "stop_button"))), "followup", lang.T(i18n.StopDone))
+	if title := trackTitle(nextEvent(t, events, EventTrackSkipped)); title != "forever-y" {
+		t.Fatalf("stop ended %q", title)
+	}
+}
@@ -0,0 +1,29 @@
+package bot
+
+import "log"
+
+const (
+	cacheBucket = "cache"
+	cacheKey    = "resolver"
+)
+
+// restoreCache loads the resolver cache saved at the last shutdown, when the
+// cache is kept across restarts.
+func (k *Kvazar) restoreCache() {
+	if !k.persistCache || k.resolver.Cache == nil {
+		return
+	}
+	if _, err := k.store.Load(cacheBucket, cacheKey, k.resolver.Cache); err != nil {
+		log.Printf("failed to load resolver cache: %v", err)
+	}
+}
+
+// saveCache saves the resolver cache for the next start.
+func (k *Kvazar) saveCache() {
+	if !k.persistCache || k.resolver.Cache == nil {
+		return
+	}
+	if err := k.store.Save(cacheBucket, cacheKey, k.resolver.Cache); err != nil {
+		log.Printf("failed to save resolver cache: %v", err)
+	}
+}
@@ -1,6 +1,10 @@
 package bot
 
-import "github.com/bwmarrin/discordgo"
+import (
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+)
 
 const (
 	commandPlay   = "play"
@@ -9,47 +13,489 @@ const (
 	commandStop   = "stop"
 	commandSkip   = "skip"
 	commandLoop   = "loop"
+	commandQueue  = "queue"
+
+	commandFavorites   = "favorites"
+	commandRemove      = "remove"
+	commandPermissions = "permissions"
+	commandLimits      = "limits"
+	commandLanguage    = "language"
+	commandSettings    = "settings"
+	commandLibrary     = "library"
 )
 
-var globalCommands = []*discordgo.ApplicationCommand{
+// globalCommands get their descriptions and translations from the i18n catalogs.
+var globalCommands = localizeCommands([]*discordgo.ApplicationCommand{
+	{
+		Name: commandPlay,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:     discordgo.ApplicationCommandOptionString,
+				Name:     "query",
+				Required: true,
+			},
+			{
+				// The choices list the registered providers; see applicationCommands.
+				Type:     discordgo.ApplicationCommandOptionString,
+				Name:     "source",
+				Required: false,
+			},
+		},
+	},
+	{
+		Name: commandPlayer,
+	},
+	{
+		Name: commandPause,
+	},
+	{
+		Name: commandStop,
+	},
+	{
+		Name: commandSkip,
+	},
 	{
-		Name:        commandPlay,
-		Description: "Пусти музику са YouTube-а или SoundCloud-а, или претражи.",
+		Name: commandLoop,
 		Options: []*discordgo.ApplicationCommandOption{
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "query",
-				Description: "URL адреса или упит за претрагу (префикс 'sc' за SoundCloud)",
-				Required:    true,
+				Type:     discordgo.ApplicationCommandOptionBoolean,
+				Name:     "enabled",
+				Required: false,
+			},
+		},
+	},
+	{
+		Name: commandQueue,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "export",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionString,
+						Name:     "format",
+						Required: false,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "M3U8", Value: "m3u"},
+							{Name: "XSPF", Value: "xspf"},
+							{Name: "JSON", Value: "json"},
+						},
+					},
+				},
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "import",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionAttachment,
+						Name:     "file",
+						Required: true,
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionBoolean,
+						Name:     "history",
+						Required: false,
+					},
+				},
+			},
+		},
+	},
+	{
+		Name: commandFavorites,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "list",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionInteger,
+						Name:     "page",
+						Required: false,
+						MinValue: floatPtr(1),
+					},
+				},
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "play",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionInteger,
+						Name:     "count",
+						Required: false,
+						MinValue: floatPtr(1),
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionBoolean,
+						Name:     "shuffle",
+						Required: false,
+					},
+				},
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "remove",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionInteger,
+						Name:     "position",
+						Required: true,
+						MinValue: floatPtr(1),
+					},
+				},
+			},
+		},
+	},
+	{
+		Name: commandLibrary,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "search",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionString,
+						Name:     "query",
+						Required: true,
+					},
+				},
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "play",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionString,
+						Name:     "query",
+						Required: true,
+					},
+				},
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "album",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionString,
+						Name:     "query",
+						Required: true,
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionBoolean,
+						Name:     "shuffle",
+						Required: false,
+					},
+				},
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "artist",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionString,
+						Name:     "query",
+						Required: true,
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionBoolean,
+						Name:     "shuffle",
+						Required: false,
+					},
+				},
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "rescan",
 			},
 		},
 	},
 	{
-		Name:        commandPlayer,
-		Description: "Прикажи тренутно стање плејера.",
+		Name: commandRemove,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:     discordgo.ApplicationCommandOptionInteger,
+				Name:     "position",
+				Required: true,
+				MinValue: floatPtr(1),
+			},
+		},
 	},
 	{
-		Name:        commandPause,
-		Description: "Паузирај или настави репродукцију.",
+		Name:                     commandPermissions,
+		DefaultMemberPermissions: int64Ptr(discordgo.PermissionManageServer),
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "show",
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "dj-role",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionRole,
+						Name:     "role",
+						Required: false,
+					},
+				},
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "allow",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionString,
+						Name:     "command",
+						Required: true,
+						Choices:  commandChoices(configurableCommands...),
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionRole,
+						Name:     "role",
+						Required: true,
+					},
+				},
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "deny",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionString,
+						Name:     "command",
+						Required: true,
+						Choices:  commandChoices(configurableCommands...),
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionRole,
+						Name:     "role",
+						Required: true,
+					},
+				},
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "reset",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionString,
+						Name:     "command",
+						Required: true,
+						Choices:  commandChoices(configurableCommands...),
+					},
+				},
+			},
+		},
 	},
 	{
-		Name:        commandStop,
-		Description: "Заустави репродукцију и испразни ред.",
+		Name:                     commandLimits,
+		DefaultMemberPermissions: int64Ptr(discordgo.PermissionManageServer),
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "show",
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "set",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionInteger,
+						Name:     "max_queue",
+						MinValue: floatPtr(0),
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionInteger,
+						Name:     "max_per_user",
+						MinValue: floatPtr(0),
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionInteger,
+						Name:     "max_duration",
+						MinValue: floatPtr(0),
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionInteger,
+						Name:     "cooldown",
+						MinValue: floatPtr(0),
+					},
+					{
+						Type: discordgo.ApplicationCommandOptionBoolean,
+						Name: "reject_live",
+					},
+				},
+			},
+		},
 	},
 	{
-		Name:        commandSkip,
-		Description: "Прескочи тренутну песму.",
+		Name:                     commandLanguage,
+		DefaultMemberPermissions: int64Ptr(discordgo.PermissionManageServer),
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:     discordgo.ApplicationCommandOptionString,
+				Name:     "locale",
+				Required: true,
+				Choices:  languageChoices(),
+			},
+		},
 	},
 	{
-		Name:        commandLoop,
-		Description: "Промени понављање реда.",
+		Name:                     commandSettings,
+		DefaultMemberPermissions: int64Ptr(discordgo.PermissionManageServer),
 		Options: []*discordgo.ApplicationCommandOption{
 			{
-				Type:        discordgo.ApplicationCommandOptionBoolean,
-				Name:        "enabled",
-				Description: "Експлицитно постави понављање реда (изостави за промену).",
-				Required:    false,
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "show",
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "set",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "announce_channel",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews},
+					},
+					{
+						Type:    discordgo.ApplicationCommandOptionString,
+						Name:    "announce_mode",
+						Choices: announceModeChoices(),
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionInteger,
+						Name:     "volume",
+						MinValue: floatPtr(1),
+						MaxValue: 200,
+					},
+					{
+						Type: discordgo.ApplicationCommandOptionBoolean,
+						Name: "normalize",
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionNumber,
+						Name:     "loudness",
+						MinValue: floatPtr(-40),
+						MaxValue: -5,
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionInteger,
+						Name:     "idle_timeout",
+						MinValue: floatPtr(1),
+						MaxValue: 1440,
+					},
+					{
+						Type: discordgo.ApplicationCommandOptionRole,
+						Name: "dj_role",
+					},
+					{
+						Type:     discordgo.ApplicationCommandOptionInteger,
+						Name:     "max_queue",
+						MinValue: floatPtr(0),
+					},
+					{
+						Type:    discordgo.ApplicationCommandOptionString,
+						Name:    "locale",
+						Choices: languageChoices(),
+					},
+					{
+						Type: discordgo.ApplicationCommandOptionBoolean,
+						Name: "autoplay",
+					},
+				},
+			},
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "reset",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:     discordgo.ApplicationCommandOptionString,
+						Name:     "setting",
+						Required: true,
+						Choices:  settingChoices(),
+					},
+				},
 			},
 		},
 	},
+})
+
+// applicationCommands returns globalCommands with the /play source choices
+// filled in from the registered providers. /library is left out unless a
+// library is configured.
+func (k *Kvazar) applicationCommands() []*discordgo.ApplicationCommand {
+	commands := make([]*discordgo.ApplicationCommand, 0, len(globalCommands))
+	for _, cmd := range globalCommands {
+		if cmd.Name == commandLibrary && k.library == nil {
+			continue
+		}
+		if cmd.Name == commandPlay {
+			play := *cmd
+			play.Options = make([]*discordgo.ApplicationCommandOption, len(cmd.Options))
+			for j, opt := range cmd.Options {
+				if opt.Name == "source" {
+					source := *opt
+					source.Choices = k.sourceChoices()
+					opt = &source
+				}
+				play.Options[j] = opt
+			}
+			cmd = &play
+		}
+		commands = append(commands, cmd)
+	}
+	return commands
+}
+
+// sourceChoices offers every provider that can search, the default first,
+// under its translated name when the catalogs have one.
+func (k *Kvazar) sourceChoices() []*discordgo.ApplicationCommandOptionChoice {
+	providers := k.sources.Searchable()
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(providers))
+	for _, provider := range providers {
+		choice := &discordgo.ApplicationCommandOptionChoice{Name: provider.Name(), Value: provider.Name()}
+		if key := i18n.SourceKey(provider.Name()); i18n.Default.Has(key) {
+			choice.Name = i18n.Default.T(key)
+			choice.NameLocalizations = localizations(key)
+		}
+		choices = append(choices, choice)
+	}
+	return choices
+}
+
+func commandChoices(names ...string) []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(names))
+	for _, name := range names {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: "/" + name, Value: name})
+	}
+	return choices
+}
+
+func settingChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(settingNames))
+	for _, name := range settingNames {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+	}
+	return choices
+}
+
+func announceModeChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(announceModes))
+	for _, mode := range announceModes {
+		key := announceModeKeys[mode]
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:              i18n.Default.T(key),
+			NameLocalizations: localizations(key),
+			Value:             mode,
+		})
+	}
+	return choices
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
 }
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// syncCommands brings the registered commands in line with applicationCommands. A
+// single bulk overwrite replaces the whole set, so commands never disappear in
+// between, and the call is skipped entirely when nothing changed.
+func (k *Kvazar) syncCommands(appID string) error {
+	scope := describeCommandScope(k.commandGuildID)
+
+	existing, err := k.session.ApplicationCommands(appID, k.commandGuildID)
+	if err != nil {
+		return fmt.Errorf("list %s commands: %w", scope, err)
+	}
+
+	desired := k.applicationCommands()
+	added, changed, removed := diffCommands(existing, desired)
+	if len(added) == 0 && len(changed) == 0 && len(removed) == 0 {
+		log.Printf("%s commands up to date (%d registered)", scope, len(existing))
+		k.commands = existing
+		return nil
+	}
+
+	log.Printf("syncing %s commands: added %v, changed %v, removed %v", scope, added, changed, removed)
+	synced, err := k.session.ApplicationCommandBulkOverwrite(appID, k.commandGuildID, desired)
+	if err != nil {
+		return fmt.Errorf("overwrite %s commands: %w", scope, err)
+	}
+	k.commands = synced
+	return nil
+}
+
+// clearCommands removes every command registered in the configured scope.
+func (k *Kvazar) clearCommands(appID string) error {
+	if _, err := k.session.ApplicationCommandBulkOverwrite(appID, k.commandGuildID, []*discordgo.ApplicationCommand{}); err != nil {
+		return fmt.Errorf("clear %s commands: %w", describeCommandScope(k.commandGuildID), err)
+	}
+	k.commands = nil
+	return nil
+}
+
+// diffCommands compares the registered commands with the desired ones by name
+// and reports which were added, changed or removed.
+func diffCommands(existing, desired []*discordgo.ApplicationCommand) (added, changed, removed []string) {
+	registered := make(map[string]string, len(existing))
+	for _, cmd := range existing {
+		registered[cmd.Name] = commandSignature(cmd)
+	}
+
+	wanted := make(map[string]bool, len(desired))
+	for _, cmd := range desired {
+		wanted[cmd.Name] = true
+		signature, ok := registered[cmd.Name]
+		switch {
+		case !ok:
+			added = append(added, cmd.Name)
+		case signature != commandSignature(cmd):
+			changed = append(changed, cmd.Name)
+		}
+	}
+	for name := range registered {
+		if !wanted[name] {
+			removed = append(removed, name)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return added, changed, removed
+}
+
+// syncedCommand and syncedOption hold the fields Kvazar controls. Everything
+// Discord fills in by itself (IDs, versions, defaults) is left out so a freshly
+// fetched command compares equal to its local definition.
+type syncedCommand struct {
+	Name                     string                      `json:"name"`
+	Description              string                      `json:"description"`
+	NameLocalizations        map[discordgo.Locale]string `json:"name_localizations,omitempty"`
+	DescriptionLocalizations map[discordgo.Locale]string `json:"description_localizations,omitempty"`
+	DefaultMemberPermissions int64                       `json:"default_member_permissions,omitempty"`
+	Options                  []syncedOption              `json:"options,omitempty"`
+}
+
+type syncedOption struct {
+	Type                     discordgo.ApplicationCommandOptionType      `json:"type"`
+	Name                     string                                      `json:"name"`
+	Description              string                                      `json:"description"`
+	DescriptionLocalizations map[discordgo.Locale]string                 `json:"description_localizations,omitempty"`
+	Required                 bool                                        `json:"required,omitempty"`
+	ChannelTypes             []discordgo.ChannelType                     `json:"channel_types,omitempty"`
+	MinValue                 *float64                                    `json:"min_value,omitempty"`
+	MaxValue                 float64                                     `json:"max_value,omitempty"`
+	Choices                  []*discordgo.ApplicationCommandOptionChoice `json:"choices,omitempty"`
+	Options                  []syncedOption                              `json:"options,omitempty"`
+}
+
+func commandSignature(cmd *discordgo.ApplicationCommand) string {
+	normalized := syncedCommand{
+		Name:        cmd.Name,
+		Description: cmd.Description,
+		Options:     normalizeOptions(cmd.Options),
+	}
+	if cmd.NameLocalizations != nil {
+		normalized.NameLocalizations = *cmd.NameLocalizations
+	}
+	if cmd.DescriptionLocalizations != nil {
+		normalized.DescriptionLocalizations = *cmd.DescriptionLocalizations
+	}
+	if cmd.DefaultMemberPermissions != nil {
+		normalized.DefaultMemberPermissions = *cmd.DefaultMemberPermissions
+	}
+
+	// Maps marshal with sorted keys, so equal commands produce equal output.
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func normalizeOptions(options []*discordgo.ApplicationCommandOption) []syncedOption {
+	if len(options) == 0 {
+		return nil
+	}
+	out := make([]syncedOption, 0, len(options))
+	for _, opt := range options {
+		out = append(out, syncedOption{
+			Type:                     opt.Type,
+			Name:                     opt.Name,
+			Description:              opt.Description,
+			DescriptionLocalizations: opt.DescriptionLocalizations,
+			Required:                 opt.Required,
+			ChannelTypes:             opt.ChannelTypes,
+			MinValue:                 opt.MinValue,
+			MaxValue:                 opt.MaxValue,
+			Choices:                  opt.Choices,
+			Options:                  normalizeOptions(opt.Options),
+		})
+	}
+	return out
+}
+
+func describeCommandScope(guildID string) string {
+	if guildID == "" {
+		return "global"
+	}
+	return "guild " + guildID
+}
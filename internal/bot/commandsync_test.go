@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// roundTrip returns commands as Discord lists them after registering them:
+// decoded from JSON, with IDs, versions and defaults filled in.
+func roundTrip(t *testing.T, commands []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+	t.Helper()
+	encoded, err := json.Marshal(commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw []map[string]any
+	if err := json.Unmarshal(encoded, &raw); err != nil {
+		t.Fatal(err)
+	}
+	for i, cmd := range raw {
+		cmd["id"] = fmt.Sprint(1000 + i)
+		cmd["application_id"] = "app"
+		cmd["version"] = "1234567890"
+		cmd["type"] = 1
+		cmd["dm_permission"] = true
+		cmd["nsfw"] = false
+	}
+	if encoded, err = json.Marshal(raw); err != nil {
+		t.Fatal(err)
+	}
+	var fetched []*discordgo.ApplicationCommand
+	if err := json.Unmarshal(encoded, &fetched); err != nil {
+		t.Fatal(err)
+	}
+	return fetched
+}
+
+func TestCommandsMatchTheirRoundTrip(t *testing.T) {
+	k, _ := newTestBot(t)
+	desired := append(k.applicationCommands(), &discordgo.ApplicationCommand{
+		Name:        "numbers",
+		Description: "Choices with numeric values.",
+		Options: []*discordgo.ApplicationCommandOption{{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "count",
+			Description: "How many.",
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "five", Value: 5},
+				{Name: "half", Value: 0.5},
+			},
+		}},
+	})
+
+	added, changed, removed := diffCommands(roundTrip(t, desired), desired)
+	if len(added)+len(changed)+len(removed) > 0 {
+		t.Errorf("round trip differs: added %v, changed %v, removed %v", added, changed, removed)
+	}
+}
+
+func TestDiffCommands(t *testing.T) {
+	k, _ := newTestBot(t)
+	desired := k.applicationCommands()
+	existing := roundTrip(t, desired)
+
+	var kept []*discordgo.ApplicationCommand
+	for _, cmd := range existing {
+		switch cmd.Name {
+		case commandStop:
+			continue
+		case commandSkip:
+			cmd.Description = "An older description."
+		case commandLimits:
+			// A nested option's bound changed.
+			cmd.Options[1].Options[0].MinValue = floatPtr(5)
+		}
+		kept = append(kept, cmd)
+	}
+	kept = append(kept, &discordgo.ApplicationCommand{ID: "99", Name: "retired", Description: "No longer offered."})
+
+	added, changed, removed := diffCommands(kept, desired)
+	if want := []string{commandStop}; !reflect.DeepEqual(added, want) {
+		t.Errorf("added = %v, want %v", added, want)
+	}
+	if want := []string{commandLimits, commandSkip}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if want := []string{"retired"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+}
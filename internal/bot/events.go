@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType names what happened to a player.
+type EventType string
+
+// Player events published on the event bus.
+const (
+	EventTrackStarted      EventType = "track_started"
+	EventTrackEnded        EventType = "track_ended"
+	EventTrackSkipped      EventType = "track_skipped"
+	EventQueueChanged      EventType = "queue_changed"
+	EventPaused            EventType = "paused"
+	EventResumed           EventType = "resumed"
+	EventLoopChanged       EventType = "loop_changed"
+	EventVoiceConnected    EventType = "voice_connected"
+	EventVoiceDisconnected EventType = "voice_disconnected"
+	EventError             EventType = "error"
+	EventStreamTitle       EventType = "stream_title"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before it
+// is dropped.
+const subscriberBuffer = 64
+
+// Event is a single player event. Data holds the payload for the type: a
+// TrackEvent, QueueEvent, LoopEvent, VoiceEvent, ErrorEvent or
+// StreamTitleEvent, or nothing for paused and resumed.
+type Event struct {
+	ID      uint64    `json:"id"`
+	Type    EventType `json:"type"`
+	GuildID string    `json:"guild_id"`
+	Time    time.Time `json:"time"`
+	Data    any       `json:"data,omitempty"`
+}
+
+// TrackEvent is the payload of the track events.
+type TrackEvent struct {
+	Track TrackInfo `json:"track"`
+}
+
+// QueueEvent carries the whole upcoming queue after a change.
+type QueueEvent struct {
+	Queue []TrackInfo `json:"queue"`
+}
+
+// LoopEvent reports the new loop state.
+type LoopEvent struct {
+	Enabled bool `json:"enabled"`
+}
+
+// VoiceEvent names the voice channel that was joined or left.
+type VoiceEvent struct {
+	ChannelID string `json:"channel_id"`
+}
+
+// StreamTitleEvent names the song a radio station has started playing.
+type StreamTitleEvent struct {
+	Title string `json:"title"`
+}
+
+// ErrorEvent describes a playback failure.
+type ErrorEvent struct {
+	Message string     `json:"message"`
+	Track   *TrackInfo `json:"track,omitempty"`
+}
+
+// EventBus fans player events out to subscribers. Publishing never blocks:
+// a subscriber whose buffer is full is dropped and its channel closed, so it
+// can reconnect and fetch the current state.
+type EventBus struct {
+	mu     sync.Mutex
+	nextID uint64
+	subs   map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	ch     chan Event
+	guilds map[string]bool
+}
+
+// NewEventBus returns an empty bus.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[*subscriber]struct{})}
+}
+
+// Subscribe returns a channel receiving the events of the given guilds, or of
+// every guild when none are given. The returned function unsubscribes.
+func (b *EventBus) Subscribe(guildIDs ...string) (<-chan Event, func()) {
+	sub := &subscriber{ch: make(chan Event, subscriberBuffer)}
+	if len(guildIDs) > 0 {
+		sub.guilds = make(map[string]bool, len(guildIDs))
+		for _, id := range guildIDs {
+			sub.guilds[id] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub.ch, func() { b.drop(sub) }
+}
+
+// Publish stamps the event with an ID and time and delivers it.
+func (b *EventBus) Publish(guildID string, typ EventType, data any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event := Event{ID: b.nextID, Type: typ, GuildID: guildID, Time: time.Now(), Data: data}
+	for sub := range b.subs {
+		if sub.guilds != nil && !sub.guilds[guildID] {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+func (b *EventBus) drop(sub *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Subscribe streams player events; see EventBus.Subscribe.
+func (k *Kvazar) Subscribe(guildIDs ...string) (<-chan Event, func()) {
+	return k.events.Subscribe(guildIDs...)
+}
+
+func (p *Player) emit(typ EventType, data any) {
+	p.bot.events.Publish(p.guild, typ, data)
+}
+
+// emitQueueLocked publishes the queue as it is now; p.mu must be held.
+func (p *Player) emitQueueLocked() {
+	queue := make([]TrackInfo, 0, len(p.queue))
+	for _, track := range p.queue {
+		queue = append(queue, trackInfo(track))
+	}
+	p.emit(EventQueueChanged, QueueEvent{Queue: queue})
+}
@@ -0,0 +1,340 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+)
+
+const (
+	favoritesBucket   = "favorites"
+	maxFavorites      = 200
+	favoritesPageSize = 15
+)
+
+// favoriteTrack is the persisted form of a liked track.
+type favoriteTrack struct {
+	Title     string        `json:"title"`
+	Author    string        `json:"author,omitempty"`
+	URL       string        `json:"url"`
+	Thumbnail string        `json:"thumbnail,omitempty"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	Source    media.Source  `json:"source,omitempty"`
+	AddedAt   time.Time     `json:"added_at"`
+}
+
+type favoriteList struct {
+	Tracks []favoriteTrack `json:"tracks"`
+}
+
+func (k *Kvazar) loadFavorites(userID string) (favoriteList, error) {
+	var list favoriteList
+	if _, err := k.store.Load(favoritesBucket, userID, &list); err != nil {
+		return favoriteList{}, err
+	}
+	return list, nil
+}
+
+// addFavorite stores the track for the user. It reports false if the track was already saved.
+func (k *Kvazar) addFavorite(userID string, track *media.Track) (bool, error) {
+	k.favoritesMu.Lock()
+	defer k.favoritesMu.Unlock()
+
+	list, err := k.loadFavorites(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, fav := range list.Tracks {
+		if fav.URL == track.WebURL {
+			return false, nil
+		}
+	}
+	if len(list.Tracks) >= maxFavorites {
+		return false, fmt.Errorf("favorites limit of %d reached", maxFavorites)
+	}
+
+	list.Tracks = append(list.Tracks, favoriteTrack{
+		Title:     track.Title,
+		Author:    track.Author,
+		URL:       track.WebURL,
+		Thumbnail: track.Thumbnail,
+		Duration:  track.Duration,
+		Source:    track.Source,
+		AddedAt:   time.Now().UTC(),
+	})
+	return true, k.store.Save(favoritesBucket, userID, list)
+}
+
+func (k *Kvazar) removeFavorite(userID string, index int) (favoriteTrack, bool, error) {
+	k.favoritesMu.Lock()
+	defer k.favoritesMu.Unlock()
+
+	list, err := k.loadFavorites(userID)
+	if err != nil {
+		return favoriteTrack{}, false, err
+	}
+	if index < 0 || index >= len(list.Tracks) {
+		return favoriteTrack{}, false, nil
+	}
+	removed := list.Tracks[index]
+	list.Tracks = append(list.Tracks[:index], list.Tracks[index+1:]...)
+	return removed, true, k.store.Save(favoritesBucket, userID, list)
+}
+
+func (k *Kvazar) handleFavorites(ic *discordgo.InteractionCreate) {
+	data := ic.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		k.respondError(ic, k.lang(ic).T(i18n.ErrUnknownSubcommand))
+		return
+	}
+
+	sub := data.Options[0]
+	switch sub.Name {
+	case "list":
+		k.handleFavoritesList(ic, sub.Options)
+	case "play":
+		k.handleFavoritesPlay(ic, sub.Options)
+	case "remove":
+		k.handleFavoritesRemove(ic, sub.Options)
+	default:
+		k.respondError(ic, k.lang(ic).T(i18n.ErrUnknownSubcommand))
+	}
+}
+
+func (k *Kvazar) handleFavoritesList(ic *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	lang := k.lang(ic)
+	userID := interactionUserID(ic)
+	list, err := k.loadFavorites(userID)
+	if err != nil {
+		log.Printf("failed to load favorites for %s: %v", userID, err)
+		k.respondError(ic, lang.T(i18n.FavLoadFailed))
+		return
+	}
+	if len(list.Tracks) == 0 {
+		k.respondError(ic, lang.T(i18n.FavEmptyHint))
+		return
+	}
+
+	pages := (len(list.Tracks) + favoritesPageSize - 1) / favoritesPageSize
+	page := 1
+	if opt := findOption(options, "page"); opt != nil {
+		page = int(opt.IntValue())
+	}
+	if page < 1 || page > pages {
+		page = pages
+	}
+
+	start := (page - 1) * favoritesPageSize
+	end := start + favoritesPageSize
+	if end > len(list.Tracks) {
+		end = len(list.Tracks)
+	}
+
+	var lines []string
+	for i, fav := range list.Tracks[start:end] {
+		duration := media.Track{Duration: fav.Duration}.HumanDuration()
+		title := fav.Title
+		if link := webLink(fav.URL); link != "" {
+			title = fmt.Sprintf("[%s](%s)", fav.Title, link)
+		}
+		lines = append(lines, fmt.Sprintf("`%d.` %s • %s", start+i+1, title, duration))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       lang.T(i18n.FavTitle),
+		Description: strings.Join(lines, "\n"),
+		Color:       0xE91E63,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: lang.T(i18n.FavPage, page, pages, len(list.Tracks)),
+		},
+	}
+
+	_ = k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func (k *Kvazar) handleFavoritesPlay(ic *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	lang := k.lang(ic)
+	guildID := ic.GuildID
+	if guildID == "" {
+		k.respondError(ic, lang.T(i18n.ErrGuildOnly))
+		return
+	}
+
+	userID := interactionUserID(ic)
+	list, err := k.loadFavorites(userID)
+	if err != nil {
+		log.Printf("failed to load favorites for %s: %v", userID, err)
+		k.respondError(ic, lang.T(i18n.FavLoadFailed))
+		return
+	}
+	if len(list.Tracks) == 0 {
+		k.respondError(ic, lang.T(i18n.FavEmpty))
+		return
+	}
+
+	voiceChannel, err := locateVoiceChannel(k.session, guildID, userID)
+	if err != nil {
+		k.respondError(ic, lang.T(i18n.FavNeedVoice))
+		return
+	}
+
+	tracks := append([]favoriteTrack(nil), list.Tracks...)
+	count := 0
+	if opt := findOption(options, "count"); opt != nil {
+		count = int(opt.IntValue())
+	}
+	shuffle := count > 0
+	if opt := findOption(options, "shuffle"); opt != nil {
+		shuffle = opt.BoolValue()
+	}
+	if count > 0 && count < len(tracks) {
+		rand.Shuffle(len(tracks), func(i, j int) { tracks[i], tracks[j] = tracks[j], tracks[i] })
+		tracks = tracks[:count]
+	} else if shuffle {
+		rand.Shuffle(len(tracks), func(i, j int) { tracks[i], tracks[j] = tracks[j], tracks[i] })
+	}
+
+	if err := k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}); err != nil {
+		log.Printf("failed to acknowledge interaction: %v", err)
+		return
+	}
+
+	k.runDeferred(ic, func() {
+		player := k.getPlayer(guildID)
+		if err := player.EnsureConnected(voiceChannel); err != nil {
+			k.editInteractionError(ic, lang.T(i18n.ErrVoiceConnect, err))
+			return
+		}
+
+		queries := make([]string, 0, len(tracks))
+		for _, fav := range tracks {
+			queries = append(queries, fav.URL)
+		}
+		added, failed, limitErr := k.resolveAndEnqueue(player, queries, fmt.Sprintf("<@%s>", userID), ic.ChannelID)
+
+		message := lang.T(i18n.FavAddedMany, added)
+		if failed > 0 {
+			message += " " + lang.T(i18n.FailedCount, failed)
+		}
+		if limitErr != nil {
+			message += " " + limitErr.userMessage(lang)
+		}
+		k.editInteractionContent(ic, message)
+	})
+}
+
+func (k *Kvazar) handleFavoritesRemove(ic *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	lang := k.lang(ic)
+	opt := findOption(options, "position")
+	if opt == nil {
+		k.respondError(ic, lang.T(i18n.FavMissingPosition))
+		return
+	}
+
+	userID := interactionUserID(ic)
+	removed, ok, err := k.removeFavorite(userID, int(opt.IntValue())-1)
+	if err != nil {
+		log.Printf("failed to update favorites for %s: %v", userID, err)
+		k.respondError(ic, lang.T(i18n.FavSaveFailed))
+		return
+	}
+	if !ok {
+		k.respondError(ic, lang.T(i18n.FavNoSuch))
+		return
+	}
+
+	k.respondEphemeral(ic, lang.T(i18n.FavRemoved, removed.Title))
+}
+
+func (k *Kvazar) handleLikeButton(ic *discordgo.InteractionCreate) {
+	_ = k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	message := k.likeFromMessage(ic)
+	_, _ = k.session.FollowupMessageCreate(ic.Interaction, true, &discordgo.WebhookParams{
+		Content: message,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+}
+
+func (k *Kvazar) likeFromMessage(ic *discordgo.InteractionCreate) string {
+	lang := k.lang(ic)
+	track := k.trackForMessage(ic)
+	if track == nil || strings.TrimSpace(track.WebURL) == "" {
+		return lang.T(i18n.FavCardUnknown)
+	}
+
+	added, err := k.addFavorite(interactionUserID(ic), track)
+	if err != nil {
+		log.Printf("failed to save favorite: %v", err)
+		return lang.T(i18n.FavLikeFailed)
+	}
+	if !added {
+		return lang.T(i18n.FavAlready, track.Title)
+	}
+	return lang.T(i18n.FavLiked, track.Title)
+}
+
+// trackForMessage figures out which track a now-playing card refers to. The
+// player's own metadata is preferred; the embed is used for stale cards.
+func (k *Kvazar) trackForMessage(ic *discordgo.InteractionCreate) *media.Track {
+	if ic.Message == nil || len(ic.Message.Embeds) == 0 {
+		return nil
+	}
+	embed := ic.Message.Embeds[0]
+
+	if player := k.findPlayer(ic.GuildID); player != nil {
+		current, _, history := player.QueueSnapshot()
+		if current != nil && webLink(current.WebURL) == embed.URL {
+			return current
+		}
+		for i := len(history) - 1; i >= 0; i-- {
+			if webLink(history[i].WebURL) == embed.URL {
+				return history[i]
+			}
+		}
+	}
+
+	if embed.URL == "" {
+		return nil
+	}
+	title := embed.Title
+	if _, rest, found := strings.Cut(title, " • "); found {
+		title = rest
+	}
+	track := &media.Track{Title: title, WebURL: embed.URL}
+	if embed.Thumbnail != nil {
+		track.Thumbnail = embed.Thumbnail.URL
+	}
+	return track
+}
+
+// interactionUserID returns the invoking user for both guild and DM interactions.
+func interactionUserID(ic *discordgo.InteractionCreate) string {
+	if ic.Member != nil && ic.Member.User != nil {
+		return ic.Member.User.ID
+	}
+	if ic.User != nil {
+		return ic.User.ID
+	}
+	return ""
+}
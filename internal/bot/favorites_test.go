@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+)
+
+// favoritesSlash builds a /favorites subcommand with an optional position,
+// page or count option.
+func favoritesSlash(userID, sub string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return newInteraction(discordgo.InteractionApplicationCommand, userID, discordgo.ApplicationCommandInteractionData{
+		Name: commandFavorites,
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+			Name: sub, Type: discordgo.ApplicationCommandOptionSubCommand, Options: options,
+		}},
+	})
+}
+
+func intOption(name string, value int) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: discordgo.ApplicationCommandOptionInteger, Value: float64(value)}
+}
+
+// like presses the like button on a card showing embed.
+func like(k *Kvazar, s *fakeSession, userID string, embed *discordgo.MessageEmbed) []reply {
+	ic := button(userID, "like_button")
+	ic.Message = &discordgo.Message{ID: "card", ChannelID: testText, Embeds: []*discordgo.MessageEmbed{embed}}
+	return dispatch(k, s, ic)
+}
+
+func TestFavoriteStorage(t *testing.T) {
+	k, _ := newTestBot(t)
+	orbit := &media.Track{Title: "Orbit", WebURL: "https://example.com/orbit"}
+
+	if added, err := k.addFavorite("alice", orbit); !added || err != nil {
+		t.Fatalf("first like = %t, %v", added, err)
+	}
+	if added, err := k.addFavorite("alice", orbit); added || err != nil {
+		t.Errorf("second like = %t, %v, want it ignored", added, err)
+	}
+
+	full := favoriteList{}
+	for i := 0; i < maxFavorites; i++ {
+		full.Tracks = append(full.Tracks, favoriteTrack{Title: "song", URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+	if err := k.store.Save(favoritesBucket, "bob", full); err != nil {
+		t.Fatal(err)
+	}
+	if added, err := k.addFavorite("bob", orbit); added || err == nil {
+		t.Errorf("like past the limit = %t, %v", added, err)
+	}
+
+	for _, index := range []int{-1, 1} {
+		if _, ok, err := k.removeFavorite("alice", index); ok || err != nil {
+			t.Errorf("removeFavorite(%d) = %t, %v", index, ok, err)
+		}
+	}
+	if removed, ok, err := k.removeFavorite("alice", 0); !ok || err != nil || removed.URL != orbit.WebURL {
+		t.Errorf("removeFavorite(0) = %+v, %t, %v", removed, ok, err)
+	}
+	if list, _ := k.loadFavorites("alice"); len(list.Tracks) != 0 {
+		t.Errorf("favorites left after removing the only one: %+v", list.Tracks)
+	}
+}
+
+func TestFavoritesCommands(t *testing.T) {
+	k, s := newTestBot(t, "alice")
+	lang := k.defaultLang()
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	expectReply(t, dispatch(k, s, favoritesSlash("alice", "list")), "respond", lang.T(i18n.FavEmptyHint))
+	expectReply(t, dispatch(k, s, favoritesSlash("alice", "play")), "respond", lang.T(i18n.FavEmpty))
+
+	play(t, k, s, "alice", "forever-fav")
+	nextEvent(t, events, EventTrackStarted)
+	s.mu.Lock()
+	card := s.messages[testText][0].Embeds[0]
+	s.mu.Unlock()
+	expectReply(t, like(k, s, "alice", card), "followup", lang.T(i18n.FavLiked, "forever-fav"))
+	expectReply(t, like(k, s, "alice", card), "followup", lang.T(i18n.FavAlready, "forever-fav"))
+
+	// A card for a track the player no longer knows is read from its embed.
+	stale := &discordgo.MessageEmbed{Title: lang.T(i18n.EmbedNow, "older"), URL: "https://example.com/older"}
+	expectReply(t, like(k, s, "alice", stale), "followup", lang.T(i18n.FavLiked, "older"))
+	expectReply(t, like(k, s, "alice", &discordgo.MessageEmbed{Title: "?"}), "followup", lang.T(i18n.FavCardUnknown))
+
+	replies := dispatch(k, s, favoritesSlash("alice", "list"))
+	if len(replies) != 1 || len(replies[0].embeds) != 1 {
+		t.Fatalf("list replies = %+v", replies)
+	}
+	list := replies[0].embeds[0]
+	if lines := strings.Split(list.Description, "\n"); len(lines) != 2 || !strings.Contains(lines[0], "forever-fav") || !strings.Contains(lines[1], "older") {
+		t.Errorf("list = %q", list.Description)
+	}
+	if list.Footer == nil || list.Footer.Text != lang.T(i18n.FavPage, 1, 1, 2) {
+		t.Errorf("list footer = %+v", list.Footer)
+	}
+
+	expectReply(t, dispatch(k, s, favoritesSlash("alice", "remove", intOption("position", 3))), "respond", lang.T(i18n.FavNoSuch))
+	expectReply(t, dispatch(k, s, favoritesSlash("alice", "remove", intOption("position", 2))), "respond", lang.T(i18n.FavRemoved, "older"))
+
+	expectReply(t, dispatch(k, s, asDJ(slash("alice", commandStop))), "respond", lang.T(i18n.StopDone))
+	ic := favoritesSlash("alice", "play")
+	k.onInteractionCreate(nil, ic)
+	waitFor(t, "the favorites to be queued", func() bool {
+		replies = s.repliesTo(ic.ID)
+		return len(replies) == 2
+	})
+	expectReply(t, replies, "edit", lang.T(i18n.FavAddedMany, 1))
+	if title := trackTitle(nextEvent(t, events, EventTrackStarted)); title != "forever-fav" {
+		t.Errorf("started %q, want the liked track", title)
+	}
+}
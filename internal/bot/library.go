@@ -0,0 +1,274 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+)
+
+const (
+	libraryBucket = "library"
+	libraryKey    = "index"
+	// libraryResults is how many matches /library search lists.
+	libraryResults = 15
+)
+
+// LibraryCoverPath is where the HTTP server serves library cover art, as
+// LibraryCoverPath + entry ID.
+const LibraryCoverPath = "/library/covers/"
+
+// libraryIndex is the persisted library index. It is discarded when the
+// library directory changes.
+type libraryIndex struct {
+	Root    string               `json:"root"`
+	Entries []media.LibraryEntry `json:"entries"`
+}
+
+// newLibrary builds the configured library, or returns nil without one.
+func newLibrary(cfg Config) *media.Library {
+	dir := strings.TrimSpace(cfg.LibraryDir)
+	if dir == "" {
+		return nil
+	}
+	library := media.NewLibrary(dir)
+	library.FFProbePath = pickOrDefault(cfg.FFProbePath, "ffprobe")
+	library.FFMpegPath = pickOrDefault(cfg.FFMpegPath, "ffmpeg")
+	if public := strings.TrimSpace(cfg.PublicURL); public != "" {
+		library.CoverURL = strings.TrimSuffix(public, "/") + LibraryCoverPath
+	}
+	return library
+}
+
+// restoreLibrary loads the index saved by the last scan, so the library can
+// be played before the startup rescan finishes.
+func (k *Kvazar) restoreLibrary() {
+	if k.library == nil {
+		return
+	}
+	var index libraryIndex
+	if _, err := k.store.Load(libraryBucket, libraryKey, &index); err != nil {
+		log.Printf("failed to load library index: %v", err)
+		return
+	}
+	if index.Root == k.library.Root() {
+		k.library.Restore(index.Entries)
+	}
+}
+
+// rescanLibrary brings the library index up to date and saves it.
+func (k *Kvazar) rescanLibrary(ctx context.Context) (media.ScanResult, error) {
+	result, err := k.library.Scan(ctx)
+	if err != nil {
+		log.Printf("failed to scan library: %v", err)
+		return result, err
+	}
+	log.Printf("library scanned: %d tracks (%d added, %d updated, %d removed, %d unreadable, %d skipped)",
+		result.Tracks, result.Added, result.Updated, result.Removed, result.Unreadable, result.Skipped)
+
+	index := libraryIndex{Root: k.library.Root(), Entries: k.library.Entries()}
+	if err := k.store.Save(libraryBucket, libraryKey, index); err != nil {
+		log.Printf("failed to save library index: %v", err)
+	}
+	return result, nil
+}
+
+// LibraryCover returns the cover art of a library entry for the HTTP server.
+func (k *Kvazar) LibraryCover(ctx context.Context, id string) ([]byte, error) {
+	if k.library == nil {
+		return nil, media.ErrNotInLibrary
+	}
+	return k.library.Cover(ctx, id)
+}
+
+func (k *Kvazar) handleLibrary(ic *discordgo.InteractionCreate) {
+	data := ic.ApplicationCommandData()
+	lang := k.lang(ic)
+	if k.library == nil {
+		k.respondError(ic, lang.T(i18n.LibraryDisabled))
+		return
+	}
+	if len(data.Options) == 0 {
+		k.respondError(ic, lang.T(i18n.ErrUnknownSubcommand))
+		return
+	}
+
+	sub := data.Options[0]
+	switch sub.Name {
+	case "search":
+		k.handleLibrarySearch(ic, sub.Options)
+	case "play":
+		k.handleLibraryPlay(ic, sub.Options)
+	case "album":
+		k.handleLibraryGroup(ic, sub.Options, k.library.Album, i18n.LibraryQueuedAlbum)
+	case "artist":
+		k.handleLibraryGroup(ic, sub.Options, k.library.Artist, i18n.LibraryQueuedArtist)
+	case "rescan":
+		k.handleLibraryRescan(ic)
+	default:
+		k.respondError(ic, lang.T(i18n.ErrUnknownSubcommand))
+	}
+}
+
+func (k *Kvazar) handleLibrarySearch(ic *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	lang := k.lang(ic)
+	query := ""
+	if opt := findOption(options, "query"); opt != nil {
+		query = strings.TrimSpace(opt.StringValue())
+	}
+	matches := k.library.Search(query, 0)
+	if len(matches) == 0 {
+		k.respondError(ic, lang.T(i18n.LibraryNoMatch, query))
+		return
+	}
+
+	shown := matches
+	if len(shown) > libraryResults {
+		shown = shown[:libraryResults]
+	}
+	lines := make([]string, 0, len(shown))
+	for i, entry := range shown {
+		line := fmt.Sprintf("`%d.` **%s**", i+1, entry.Title)
+		if entry.Artist != "" {
+			line += " — " + entry.Artist
+		}
+		if entry.Album != "" {
+			line += " • " + entry.Album
+		}
+		line += " • " + media.Track{Duration: entry.Duration}.HumanDuration()
+		lines = append(lines, line)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       lang.T(i18n.LibraryResultsTitle, query),
+		Description: strings.Join(lines, "\n"),
+		Color:       0x1ABC9C,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: lang.T(i18n.LibraryResultsFooter, len(shown), len(matches)),
+		},
+	}
+	_ = k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func (k *Kvazar) handleLibraryPlay(ic *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	query := ""
+	if opt := findOption(options, "query"); opt != nil {
+		query = strings.TrimSpace(opt.StringValue())
+	}
+	if query == "" {
+		k.respondError(ic, k.lang(ic).T(i18n.PlayEmptyQuery))
+		return
+	}
+	k.startPlay(ic, media.ProviderLocal, query)
+}
+
+// handleLibraryGroup queues every track of the album or artist that find
+// picks for the query option.
+func (k *Kvazar) handleLibraryGroup(ic *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption, find func(string) []media.LibraryEntry, queued i18n.Key) {
+	lang := k.lang(ic)
+	guildID := ic.GuildID
+	if guildID == "" {
+		k.respondError(ic, lang.T(i18n.ErrGuildOnly))
+		return
+	}
+
+	query := ""
+	if opt := findOption(options, "query"); opt != nil {
+		query = strings.TrimSpace(opt.StringValue())
+	}
+	entries := find(query)
+	if len(entries) == 0 {
+		k.respondError(ic, lang.T(i18n.LibraryNoMatch, query))
+		return
+	}
+	if opt := findOption(options, "shuffle"); opt != nil && opt.BoolValue() {
+		rand.Shuffle(len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] })
+	}
+
+	userID := interactionUserID(ic)
+	voiceChannel, err := locateVoiceChannel(k.session, guildID, userID)
+	if err != nil {
+		k.respondError(ic, lang.T(i18n.LibraryNeedVoice))
+		return
+	}
+
+	if err := k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}); err != nil {
+		log.Printf("failed to acknowledge interaction: %v", err)
+		return
+	}
+
+	// Albums are named after their album, artists after the first track's.
+	title := entries[0].Album
+	if queued == i18n.LibraryQueuedArtist {
+		title = pickOrDefault(entries[0].AlbumArtist, entries[0].Artist)
+	}
+
+	k.runDeferred(ic, func() {
+		player := k.getPlayer(guildID)
+		if err := player.EnsureConnected(voiceChannel); err != nil {
+			k.editInteractionError(ic, lang.T(i18n.ErrVoiceConnect, err))
+			return
+		}
+
+		queries := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			queries = append(queries, media.ProviderLocal+":"+entry.Path)
+		}
+		added, failed, limitErr := k.resolveAndEnqueue(player, queries, fmt.Sprintf("<@%s>", userID), ic.ChannelID)
+
+		message := lang.T(queued, added, title)
+		if failed > 0 {
+			message += " " + lang.T(i18n.FailedCount, failed)
+		}
+		if limitErr != nil {
+			message += " " + limitErr.userMessage(lang)
+		}
+		k.editInteractionContent(ic, message)
+	})
+}
+
+func (k *Kvazar) handleLibraryRescan(ic *discordgo.InteractionCreate) {
+	lang := k.lang(ic)
+	if err := k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}); err != nil {
+		log.Printf("failed to acknowledge interaction: %v", err)
+		return
+	}
+
+	k.runDeferred(ic, func() {
+		result, err := k.rescanLibrary(context.Background())
+		if err != nil {
+			k.editInteractionError(ic, lang.T(i18n.LibraryRescanFailed))
+			return
+		}
+		message := lang.T(i18n.LibraryRescanned, result.Tracks, result.Added, result.Updated, result.Removed)
+		if result.Unreadable > 0 {
+			message += " " + lang.T(i18n.LibraryUnreadable, result.Unreadable)
+		}
+		if result.Skipped > 0 {
+			message += " " + lang.T(i18n.LibrarySkipped, result.Skipped)
+		}
+		k.editInteractionContent(ic, message)
+	})
+}
@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+)
+
+// librarySlash builds a /library subcommand with string options given as
+// name/value pairs.
+func librarySlash(userID, sub string, options ...string) *discordgo.InteractionCreate {
+	option := &discordgo.ApplicationCommandInteractionDataOption{Name: sub, Type: discordgo.ApplicationCommandOptionSubCommand}
+	for i := 0; i+1 < len(options); i += 2 {
+		option.Options = append(option.Options, &discordgo.ApplicationCommandInteractionDataOption{
+			Name: options[i], Type: discordgo.ApplicationCommandOptionString, Value: options[i+1],
+		})
+	}
+	return newInteraction(discordgo.InteractionApplicationCommand, userID, discordgo.ApplicationCommandInteractionData{
+		Name:    commandLibrary,
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{option},
+	})
+}
+
+// deferred dispatches an interaction that answers later and waits for the
+// answer.
+func deferred(t *testing.T, k *Kvazar, s *fakeSession, ic *discordgo.InteractionCreate) []reply {
+	t.Helper()
+	k.onInteractionCreate(nil, ic)
+	var replies []reply
+	waitFor(t, "the deferred response", func() bool {
+		replies = s.repliesTo(ic.ID)
+		return len(replies) == 2
+	})
+	return replies
+}
+
+func TestLibraryCommands(t *testing.T) {
+	k, s := newTestBot(t, "alice", "bob")
+	lang := k.defaultLang()
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	expectReply(t, dispatch(k, s, librarySlash("bob", "rescan")), "respond", lang.T(i18n.PermDJOnly))
+	expectReply(t, deferred(t, k, s, asDJ(librarySlash("bob", "rescan"))), "edit", lang.T(i18n.LibraryRescanned, 3, 3, 0, 0))
+	// Nothing changed, so nothing is read again.
+	expectReply(t, deferred(t, k, s, asDJ(librarySlash("bob", "rescan"))), "edit", lang.T(i18n.LibraryRescanned, 3, 0, 0, 0))
+
+	replies := dispatch(k, s, librarySlash("alice", "search", "query", "kosmos"))
+	if len(replies) != 1 || len(replies[0].embeds) != 1 || !replies[0].ephemeral {
+		t.Fatalf("search replies = %+v", replies)
+	}
+	if results := replies[0].embeds[0].Description; !strings.Contains(results, "gravity") || !strings.Contains(results, "slow-orbit") || strings.Contains(results, "tail") {
+		t.Errorf("search results = %q", results)
+	}
+	expectReply(t, dispatch(k, s, librarySlash("alice", "search", "query", "jazz")), "respond", lang.T(i18n.LibraryNoMatch, "jazz"))
+
+	expectReply(t, deferred(t, k, s, librarySlash("alice", "album", "query", "nebula")), "edit", lang.T(i18n.LibraryQueuedAlbum, 2, "Nebula"))
+	for _, want := range []string{"gravity", "slow-orbit"} {
+		if title := trackTitle(nextEvent(t, events, EventTrackStarted)); title != want {
+			t.Fatalf("started %q, want %q", title, want)
+		}
+	}
+	if got, want := play(t, k, s, "alice", "local:tail"), lang.T(i18n.PlayQueued, "tail", 1); got != want {
+		t.Errorf("/play local:tail = %q, want %q", got, want)
+	}
+	if title := trackTitle(nextEvent(t, events, EventTrackStarted)); title != "tail" {
+		t.Fatalf("started %q, want the library track", title)
+	}
+}
+
+func TestLibraryPermissions(t *testing.T) {
+	k, s := newTestBot(t, "alice", "bob")
+	lang := k.defaultLang()
+
+	deny := func(command string) []reply {
+		ic := newInteraction(discordgo.InteractionApplicationCommand, "alice", discordgo.ApplicationCommandInteractionData{
+			Name: commandPermissions,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+				Name: "deny", Type: discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: "command", Type: discordgo.ApplicationCommandOptionString, Value: command},
+					{Name: "role", Type: discordgo.ApplicationCommandOptionRole, Value: "muted"},
+				},
+			}},
+		})
+		ic.Member.Permissions = discordgo.PermissionManageServer
+		return dispatch(k, s, ic)
+	}
+	expectReply(t, deny(commandLibrary), "respond", lang.T(i18n.PermDeniedRole, commandLibrary, "muted"))
+	expectReply(t, deny(commandSettings), "respond", lang.T(i18n.PermUnknownCommandRole))
+
+	ic := librarySlash("bob", "search", "query", "kosmos")
+	ic.Member.Roles = []string{"muted"}
+	expectReply(t, dispatch(k, s, ic), "respond", lang.T(i18n.PermDenied, commandLibrary))
+	// The library has not been scanned yet, so an allowed search finds nothing.
+	expectReply(t, dispatch(k, s, librarySlash("alice", "search", "query", "kosmos")), "respond", lang.T(i18n.LibraryNoMatch, "kosmos"))
+}
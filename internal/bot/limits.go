@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+)
+
+// QueueLimits are the guild-configurable fairness rules applied when queueing.
+// A zero value disables the corresponding rule.
+type QueueLimits struct {
+	MaxPerUser   int           `json:"max_per_user,omitempty"`
+	MaxDuration  time.Duration `json:"max_duration,omitempty"`
+	RejectLive   bool          `json:"reject_live,omitempty"`
+	MaxQueue     int           `json:"max_queue,omitempty"`
+	PlayCooldown time.Duration `json:"play_cooldown,omitempty"`
+}
+
+type limitKind int
+
+const (
+	limitQueueFull limitKind = iota
+	limitPerUser
+	limitDuration
+	limitLive
+	limitCooldown
+)
+
+// limitError reports which queue limit rejected a request.
+type limitError struct {
+	kind        limitKind
+	limit       int
+	maxDuration time.Duration
+	wait        time.Duration
+	track       *media.Track
+}
+
+func (e *limitError) Error() string {
+	switch e.kind {
+	case limitQueueFull:
+		return fmt.Sprintf("queue limit of %d tracks reached", e.limit)
+	case limitPerUser:
+		return fmt.Sprintf("per-user limit of %d queued tracks reached", e.limit)
+	case limitDuration:
+		return fmt.Sprintf("track exceeds maximum duration of %s", e.maxDuration)
+	case limitLive:
+		return "livestreams are not allowed"
+	case limitCooldown:
+		return fmt.Sprintf("play cooldown active for another %s", e.wait)
+	}
+	return "queue limit reached"
+}
+
+// Is lets callers outside the package match any limit with ErrQueueLimit.
+func (e *limitError) Is(target error) bool {
+	return target == ErrQueueLimit
+}
+
+// blocksFurther reports whether subsequent tracks from the same requester would be rejected too.
+func (e *limitError) blocksFurther() bool {
+	return e.kind == limitQueueFull || e.kind == limitPerUser
+}
+
+// userMessage explains the limit in terms a guild member understands.
+func (e *limitError) userMessage(lang i18n.Lang) string {
+	switch e.kind {
+	case limitQueueFull:
+		return lang.T(i18n.LimitQueueFull, e.limit)
+	case limitPerUser:
+		return lang.T(i18n.LimitPerUser, e.limit)
+	case limitDuration:
+		track := e.track
+		if track == nil {
+			track = &media.Track{}
+		}
+		return lang.T(i18n.LimitDuration, track.Title, track.HumanDuration(), media.Track{Duration: e.maxDuration}.HumanDuration())
+	case limitLive:
+		return lang.T(i18n.LimitLive)
+	case limitCooldown:
+		return lang.T(i18n.LimitCooldown, int(e.wait.Round(time.Second)/time.Second))
+	}
+	return lang.T(i18n.LimitGeneric)
+}
+
+// limitMessage returns the user-facing explanation for err when it is a limit violation.
+func limitMessage(lang i18n.Lang, err error) (string, bool) {
+	var limitErr *limitError
+	if errors.As(err, &limitErr) {
+		return limitErr.userMessage(lang), true
+	}
+	return "", false
+}
+
+// checkLimitsLocked validates a new request against the limits. The track may
+// be nil to pre-check the capacity rules before resolving a query.
+func (p *Player) checkLimitsLocked(limits QueueLimits, requestedBy string, track *media.Track) error {
+	if limits.MaxQueue > 0 && len(p.queue) >= limits.MaxQueue {
+		return &limitError{kind: limitQueueFull, limit: limits.MaxQueue}
+	}
+	if limits.MaxPerUser > 0 && requestedBy != "" {
+		count := 0
+		for _, queued := range p.queue {
+			if queued.RequestedBy == requestedBy {
+				count++
+			}
+		}
+		if count >= limits.MaxPerUser {
+			return &limitError{kind: limitPerUser, limit: limits.MaxPerUser}
+		}
+	}
+	if track == nil {
+		return nil
+	}
+	if track.Duration == 0 && limits.RejectLive {
+		return &limitError{kind: limitLive, track: track}
+	}
+	if limits.MaxDuration > 0 && track.Duration > limits.MaxDuration {
+		return &limitError{kind: limitDuration, maxDuration: limits.MaxDuration, track: track}
+	}
+	return nil
+}
+
+// CheckCapacity reports whether a request from requestedBy could currently be queued.
+func (p *Player) CheckCapacity(requestedBy string) error {
+	limits := p.bot.queueLimits(p.guild)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.checkLimitsLocked(limits, requestedBy, nil)
+}
+
+// checkPlayCooldown enforces the per-user delay between /play calls that
+// queued something.
+func (k *Kvazar) checkPlayCooldown(guildID, userID string) error {
+	if k.queueLimits(guildID).PlayCooldown <= 0 {
+		return nil
+	}
+	k.cooldownMu.Lock()
+	defer k.cooldownMu.Unlock()
+	if wait := time.Until(k.cooldowns[guildID+":"+userID]); wait > 0 {
+		return &limitError{kind: limitCooldown, wait: wait}
+	}
+	return nil
+}
+
+// startPlayCooldown starts a user's cooldown once their /play queued a track,
+// so a typo or a lookup that found nothing can be retried at once. Cooldowns
+// that have ended are dropped.
+func (k *Kvazar) startPlayCooldown(guildID, userID string) {
+	cooldown := k.queueLimits(guildID).PlayCooldown
+	now := time.Now()
+
+	k.cooldownMu.Lock()
+	defer k.cooldownMu.Unlock()
+	for key, ends := range k.cooldowns {
+		if !ends.After(now) {
+			delete(k.cooldowns, key)
+		}
+	}
+	if cooldown > 0 {
+		k.cooldowns[guildID+":"+userID] = now.Add(cooldown)
+	}
+}
+
+func (k *Kvazar) handleLimits(ic *discordgo.InteractionCreate) {
+	data := ic.ApplicationCommandData()
+	lang := k.lang(ic)
+	if ic.GuildID == "" || len(data.Options) == 0 {
+		k.respondError(ic, lang.T(i18n.ErrGuildOnly))
+		return
+	}
+
+	sub := data.Options[0]
+	switch sub.Name {
+	case "show":
+		k.respondEphemeral(ic, describeLimits(lang, k.queueLimits(ic.GuildID)))
+	case "set":
+		// The first /limits set copies the bot-wide defaults into the guild.
+		limits := k.queueLimits(ic.GuildID)
+		for _, opt := range sub.Options {
+			switch opt.Name {
+			case "max_per_user":
+				limits.MaxPerUser = int(opt.IntValue())
+			case "max_queue":
+				limits.MaxQueue = int(opt.IntValue())
+			case "max_duration":
+				limits.MaxDuration = time.Duration(opt.IntValue()) * time.Minute
+			case "cooldown":
+				limits.PlayCooldown = time.Duration(opt.IntValue()) * time.Second
+			case "reject_live":
+				limits.RejectLive = opt.BoolValue()
+			}
+		}
+		err := k.updateGuildSettings(ic.GuildID, func(s *guildSettings) { s.Limits = &limits })
+		if err != nil {
+			log.Printf("failed to save limits for guild %s: %v", ic.GuildID, err)
+			k.respondError(ic, lang.T(i18n.ErrSaveSettings))
+			return
+		}
+		k.respondEphemeral(ic, describeLimits(lang, k.queueLimits(ic.GuildID)))
+	default:
+		k.respondError(ic, lang.T(i18n.ErrUnknownSubcommand))
+	}
+}
+
+func describeLimits(lang i18n.Lang, limits QueueLimits) string {
+	orOff := func(value int, format string) string {
+		if value <= 0 {
+			return lang.T(i18n.LimitsOff)
+		}
+		return fmt.Sprintf(format, value)
+	}
+
+	live := lang.T(i18n.LimitsLiveAllowed)
+	if limits.RejectLive {
+		live = lang.T(i18n.LimitsLiveRejected)
+	}
+
+	lines := []string{
+		lang.T(i18n.LimitsTitle),
+		lang.T(i18n.LimitsMaxQueue, orOff(limits.MaxQueue, "%d")),
+		lang.T(i18n.LimitsMaxPerUser, orOff(limits.MaxPerUser, "%d")),
+		lang.T(i18n.LimitsMaxDuration, orOff(int(limits.MaxDuration/time.Minute), "%d min")),
+		lang.T(i18n.LimitsCooldown, orOff(int(limits.PlayCooldown/time.Second), "%d s")),
+		lang.T(i18n.LimitsLive, live),
+	}
+	return strings.Join(lines, "\n")
+}
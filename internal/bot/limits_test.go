@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+)
+
+// setLimits gives the test guild its own queue limits.
+func setLimits(t *testing.T, k *Kvazar, limits QueueLimits) {
+	t.Helper()
+	if err := k.updateGuildSettings(testGuild, func(s *guildSettings) { s.Limits = &limits }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPlayLimits checks that /play turns away what the queue limits forbid,
+// before the lookup for the capacity rules and after it for the track rules.
+func TestPlayLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits QueueLimits
+		// queued are played first and must be accepted.
+		queued []string
+		query  string
+		// rejectedAtOnce is true when the limit applies before the lookup.
+		rejectedAtOnce bool
+		want           func(i18n.Lang) string
+	}{
+		{
+			name: "total length", limits: QueueLimits{MaxQueue: 1},
+			queued: []string{"forever-a", "forever-b"}, query: "forever-c", rejectedAtOnce: true,
+			want: func(lang i18n.Lang) string { return lang.T(i18n.LimitQueueFull, 1) },
+		},
+		{
+			name: "per-user cap", limits: QueueLimits{MaxPerUser: 1},
+			queued: []string{"forever-a", "forever-b"}, query: "forever-c", rejectedAtOnce: true,
+			want: func(lang i18n.Lang) string { return lang.T(i18n.LimitPerUser, 1) },
+		},
+		{
+			name: "max duration", limits: QueueLimits{MaxDuration: 500 * time.Millisecond},
+			query: "long-song",
+			want: func(lang i18n.Lang) string {
+				return lang.T(i18n.LimitDuration, "long-song", media.Track{Duration: time.Second}.HumanDuration(),
+					media.Track{Duration: 500 * time.Millisecond}.HumanDuration())
+			},
+		},
+		{
+			name: "livestream", limits: QueueLimits{RejectLive: true},
+			query: "live-radio",
+			want:  func(lang i18n.Lang) string { return lang.T(i18n.LimitLive) },
+		},
+		{
+			name: "cooldown", limits: QueueLimits{PlayCooldown: time.Minute},
+			queued: []string{"forever-a"}, query: "forever-b", rejectedAtOnce: true,
+			want: func(lang i18n.Lang) string { return lang.T(i18n.LimitCooldown, 60) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, s := newTestBot(t, "alice")
+			lang := k.defaultLang()
+			events, cancel := k.Subscribe(testGuild)
+			defer cancel()
+			setLimits(t, k, tt.limits)
+
+			for i, query := range tt.queued {
+				if got, want := play(t, k, s, "alice", query), lang.T(i18n.PlayQueued, query, max(i, 1)); got != want {
+					t.Fatalf("/play %s = %q, want %q", query, got, want)
+				}
+				if i == 0 {
+					nextEvent(t, events, EventTrackStarted)
+				}
+			}
+			if tt.rejectedAtOnce {
+				replies := dispatch(k, s, slash("alice", commandPlay, tt.query))
+				expectReply(t, replies, "respond", tt.want(lang))
+				if !replies[0].ephemeral {
+					t.Error("rejection is visible to everyone")
+				}
+			} else if got := play(t, k, s, "alice", tt.query); got != tt.want(lang) {
+				t.Errorf("/play %s = %q, want %q", tt.query, got, tt.want(lang))
+			}
+
+			if player := k.findPlayer(testGuild); player != nil {
+				current, queue, _ := player.QueueSnapshot()
+				for _, track := range append(queue, current) {
+					if track != nil && track.Title == tt.query {
+						t.Errorf("queued %q despite the limit", tt.query)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestCooldownStartsAfterQueueing checks that a /play that queued nothing
+// can be retried at once, and one that queued a track cannot.
+func TestCooldownStartsAfterQueueing(t *testing.T) {
+	k, s := newTestBot(t, "alice", "bob")
+	lang := k.defaultLang()
+	setLimits(t, k, QueueLimits{PlayCooldown: time.Minute})
+
+	if got := play(t, k, s, "alice", "broken song"); got != lang.T(i18n.ResolveFailed) {
+		t.Fatalf("/play broken song = %q", got)
+	}
+	if got, want := play(t, k, s, "alice", "forever-a"), lang.T(i18n.PlayQueued, "forever-a", 1); got != want {
+		t.Fatalf("retry after a failed lookup = %q, want %q", got, want)
+	}
+	expectReply(t, dispatch(k, s, slash("alice", commandPlay, "forever-b")), "respond", lang.T(i18n.LimitCooldown, 60))
+	// The cooldown is per user.
+	play(t, k, s, "bob", "forever-b")
+
+	// Cooldowns that have ended are dropped.
+	k.cooldownMu.Lock()
+	k.cooldowns[testGuild+":alice"] = time.Now().Add(-time.Second)
+	k.cooldownMu.Unlock()
+	play(t, k, s, "alice", "forever-c")
+	k.cooldownMu.Lock()
+	defer k.cooldownMu.Unlock()
+	if len(k.cooldowns) != 2 {
+		t.Errorf("cooldowns = %v, want alice's and bob's", k.cooldowns)
+	}
+}
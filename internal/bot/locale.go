@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+)
+
+const localeAuto = "auto"
+
+// lang picks the catalog for an interaction: the guild override wins, then the
+// user's client locale, then the guild's locale.
+func (k *Kvazar) lang(ic *discordgo.InteractionCreate) i18n.Lang {
+	if ic.GuildID != "" {
+		if lang, ok := i18n.Parse(k.guildSettings(ic.GuildID).Locale); ok {
+			return lang
+		}
+	}
+	if lang, ok := i18n.FromDiscord(string(ic.Locale)); ok {
+		return lang
+	}
+	if ic.GuildLocale != nil {
+		if lang, ok := i18n.FromDiscord(string(*ic.GuildLocale)); ok {
+			return lang
+		}
+	}
+	return k.defaultLang()
+}
+
+// guildLang picks the catalog for messages that are not replies to an
+// interaction, such as now-playing cards.
+func (k *Kvazar) guildLang(guildID string) i18n.Lang {
+	if lang, ok := i18n.Parse(k.guildSettings(guildID).Locale); ok {
+		return lang
+	}
+	if guild, err := k.session.CachedGuild(guildID); err == nil {
+		if lang, ok := i18n.FromDiscord(guild.PreferredLocale); ok {
+			return lang
+		}
+	}
+	return k.defaultLang()
+}
+
+// defaultLang is the configured fallback language.
+func (k *Kvazar) defaultLang() i18n.Lang {
+	if lang, ok := i18n.Parse(k.currentSettings().Locale); ok {
+		return lang
+	}
+	return i18n.Default
+}
+
+func (k *Kvazar) handleLanguage(ic *discordgo.InteractionCreate) {
+	if ic.GuildID == "" {
+		k.respondError(ic, k.lang(ic).T(i18n.ErrGuildOnly))
+		return
+	}
+
+	value := optionString(ic.ApplicationCommandData().Options, "locale")
+	target, ok := i18n.Parse(value)
+	if !ok {
+		value = ""
+	} else {
+		value = string(target)
+	}
+
+	if err := k.updateGuildSettings(ic.GuildID, func(s *guildSettings) { s.Locale = value }); err != nil {
+		log.Printf("failed to save locale for guild %s: %v", ic.GuildID, err)
+		k.respondError(ic, k.lang(ic).T(i18n.ErrSaveSettings))
+		return
+	}
+
+	if !ok {
+		k.respondEphemeral(ic, k.lang(ic).T(i18n.LangAuto))
+		return
+	}
+	k.respondEphemeral(ic, target.T(i18n.LangSet, target.T(i18n.LangName)))
+}
+
+// languageChoices offers automatic detection followed by every catalog under its own name.
+func languageChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := []*discordgo.ApplicationCommandOptionChoice{{
+		Name:              i18n.Default.T(i18n.LangAutoChoice),
+		NameLocalizations: localizations(i18n.LangAutoChoice),
+		Value:             localeAuto,
+	}}
+	for _, lang := range i18n.Supported() {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  lang.T(i18n.LangName),
+			Value: string(lang),
+		})
+	}
+	return choices
+}
+
+// localizeCommands fills in command descriptions from the default catalog and
+// attaches the translations for every Discord locale a catalog serves.
+func localizeCommands(commands []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+	for _, cmd := range commands {
+		names := localizations(i18n.CommandNameKey(cmd.Name))
+		descriptions := localizations(i18n.CommandKey(cmd.Name))
+
+		cmd.Description = i18n.Default.T(i18n.CommandKey(cmd.Name))
+		cmd.NameLocalizations = &names
+		cmd.DescriptionLocalizations = &descriptions
+		localizeOptions(cmd.Options, []string{cmd.Name})
+	}
+	return commands
+}
+
+func localizeOptions(options []*discordgo.ApplicationCommandOption, path []string) {
+	for _, opt := range options {
+		optPath := append(append([]string(nil), path...), opt.Name)
+		key := i18n.CommandKey(optPath...)
+
+		opt.Description = i18n.Default.T(key)
+		opt.DescriptionLocalizations = localizations(key)
+		localizeOptions(opt.Options, optPath)
+	}
+}
+
+func localizations(key i18n.Key) map[discordgo.Locale]string {
+	out := make(map[discordgo.Locale]string)
+	for _, lang := range i18n.Supported() {
+		if !lang.Has(key) {
+			continue
+		}
+		for _, locale := range lang.DiscordLocales() {
+			out[discordgo.Locale(locale)] = lang.T(key)
+		}
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"kvazar/internal/metrics"
+)
+
+const (
+	outcomeOK     = "ok"
+	outcomeError  = "error"
+	outcomeDenied = "denied"
+)
+
+// commandCall follows an interaction until every handler working on it,
+// including deferred work in the background, has finished.
+type commandCall struct {
+	command string
+	started time.Time
+	pending int
+	outcome string
+}
+
+// beginCommand starts tracking an interaction; the caller must finishCommand it.
+func (k *Kvazar) beginCommand(ic *discordgo.InteractionCreate) {
+	k.callsMu.Lock()
+	defer k.callsMu.Unlock()
+	k.calls[ic.ID] = &commandCall{
+		command: commandLabel(ic),
+		started: time.Now(),
+		pending: 1,
+		outcome: outcomeOK,
+	}
+}
+
+// runDeferred finishes a deferred interaction in the background. The command
+// counts as done once fn returns.
+func (k *Kvazar) runDeferred(ic *discordgo.InteractionCreate, fn func()) {
+	k.callsMu.Lock()
+	if call, ok := k.calls[ic.ID]; ok {
+		call.pending++
+	}
+	k.callsMu.Unlock()
+
+	go func() {
+		defer k.finishCommand(ic)
+		fn()
+	}()
+}
+
+// markCommand sets the outcome reported for the interaction.
+func (k *Kvazar) markCommand(ic *discordgo.InteractionCreate, outcome string) {
+	k.callsMu.Lock()
+	defer k.callsMu.Unlock()
+	if call, ok := k.calls[ic.ID]; ok {
+		call.outcome = outcome
+	}
+}
+
+func (k *Kvazar) finishCommand(ic *discordgo.InteractionCreate) {
+	k.callsMu.Lock()
+	call, ok := k.calls[ic.ID]
+	if ok {
+		call.pending--
+		if call.pending > 0 {
+			ok = false
+		} else {
+			delete(k.calls, ic.ID)
+		}
+	}
+	k.callsMu.Unlock()
+
+	if ok {
+		metrics.ObserveCommand(call.command, call.outcome, time.Since(call.started))
+	}
+}
+
+// commandLabel names an interaction for the metrics. Unknown component IDs are
+// grouped so they cannot inflate the number of series.
+func commandLabel(ic *discordgo.InteractionCreate) string {
+	switch ic.Type {
+	case discordgo.InteractionApplicationCommand:
+		return ic.ApplicationCommandData().Name
+	case discordgo.InteractionMessageComponent:
+		if id := ic.MessageComponentData().CustomID; buttonCommands[id] != "" {
+			return id
+		}
+		return "component"
+	}
+	return "other"
+}
+
+var (
+	playersDesc = prometheus.NewDesc("kvazar_players_active",
+		"Guild players currently alive.", nil, nil)
+	voiceConnectionsDesc = prometheus.NewDesc("kvazar_voice_connections",
+		"Voice connections held by players.", nil, nil)
+	queueLengthDesc = prometheus.NewDesc("kvazar_queue_length",
+		"Tracks waiting in a guild's queue.", []string{"guild"}, nil)
+)
+
+// playerCollector reports the players' state at scrape time.
+type playerCollector struct {
+	bot *Kvazar
+}
+
+// Collector exposes the active players, voice connections and queue lengths.
+func (k *Kvazar) Collector() prometheus.Collector {
+	return playerCollector{bot: k}
+}
+
+func (c playerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- playersDesc
+	ch <- voiceConnectionsDesc
+	ch <- queueLengthDesc
+}
+
+func (c playerCollector) Collect(ch chan<- prometheus.Metric) {
+	players := c.bot.snapshotPlayers()
+	connections := 0
+	for _, player := range players {
+		player.mu.Lock()
+		queued := len(player.queue)
+		if player.voice != nil {
+			connections++
+		}
+		player.mu.Unlock()
+		ch <- prometheus.MustNewConstMetric(queueLengthDesc, prometheus.GaugeValue, float64(queued), player.guild)
+	}
+	ch <- prometheus.MustNewConstMetric(playersDesc, prometheus.GaugeValue, float64(len(players)))
+	ch <- prometheus.MustNewConstMetric(voiceConnectionsDesc, prometheus.GaugeValue, float64(connections))
+}
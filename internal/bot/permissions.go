@@ -0,0 +1,364 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+)
+
+// requirement describes the baseline access rule of a command before the
+// guild's allow/deny table is applied.
+type requirement int
+
+const (
+	requireNone requirement = iota
+	requireSameChannel
+	requireDJ
+	requireAdmin
+)
+
+const (
+	// adminPermissions always pass every check.
+	adminPermissions = discordgo.PermissionAdministrator | discordgo.PermissionManageServer
+	// djPermissions make a member a DJ even without the configured DJ role.
+	djPermissions = adminPermissions | discordgo.PermissionVoiceMoveMembers
+)
+
+// commandRequirements maps "command" or "command subcommand" to its baseline rule.
+var commandRequirements = map[string]requirement{
+	commandPlay:        requireSameChannel,
+	commandPlayer:      requireNone,
+	commandPause:       requireSameChannel,
+	commandStop:        requireDJ,
+	commandSkip:        requireSameChannel,
+	commandLoop:        requireSameChannel,
+	commandQueue:       requireNone,
+	"queue import":     requireSameChannel,
+	commandFavorites:   requireNone,
+	"favorites play":   requireSameChannel,
+	commandRemove:      requireSameChannel,
+	commandPermissions: requireAdmin,
+	commandLimits:      requireAdmin,
+	commandLanguage:    requireAdmin,
+	commandSettings:    requireAdmin,
+	commandLibrary:     requireNone,
+	"library play":     requireSameChannel,
+	"library album":    requireSameChannel,
+	"library artist":   requireSameChannel,
+	"library rescan":   requireDJ,
+}
+
+// buttonCommands maps player buttons onto the command whose rules they follow.
+var buttonCommands = map[string]string{
+	"pause_button": commandPause,
+	"stop_button":  commandStop,
+	"skip_button":  commandSkip,
+	"loop_button":  commandLoop,
+	"like_button":  commandFavorites,
+}
+
+// configurableCommands lists the commands that can appear in the allow/deny
+// table. Rules apply to a whole command, subcommands included. The
+// requireAdmin commands are left out: only managers may run them, and
+// managers pass every check before the table is read.
+var configurableCommands = []string{
+	commandPlay, commandPlayer, commandPause, commandStop, commandSkip,
+	commandLoop, commandQueue, commandFavorites, commandRemove, commandLibrary,
+}
+
+// authorize checks whether the invoking member may run the command and
+// responds with an explanation when they may not.
+func (k *Kvazar) authorize(ic *discordgo.InteractionCreate, command, subcommand string) bool {
+	if message, ok := k.checkPermission(ic, command, subcommand); !ok {
+		k.respondError(ic, message)
+		k.markCommand(ic, outcomeDenied)
+		return false
+	}
+	return true
+}
+
+func (k *Kvazar) checkPermission(ic *discordgo.InteractionCreate, command, subcommand string) (string, bool) {
+	if ic.GuildID == "" || ic.Member == nil {
+		// Guild-only handlers reject direct messages themselves.
+		return "", true
+	}
+	if isAdmin(ic.Member) {
+		return "", true
+	}
+
+	lang := k.lang(ic)
+	req, ok := commandRequirements[strings.TrimSpace(command+" "+subcommand)]
+	if !ok {
+		req = commandRequirements[command]
+	}
+	if req == requireAdmin {
+		return lang.T(i18n.PermAdminOnly), false
+	}
+
+	settings := k.guildSettings(ic.GuildID)
+	policy := settings.Commands[command]
+	if hasAnyRole(ic.Member, policy.Deny) {
+		return lang.T(i18n.PermDenied, command), false
+	}
+
+	dj := k.isDJ(ic)
+	if len(policy.Allow) > 0 && !dj && !hasAnyRole(ic.Member, policy.Allow) {
+		return lang.T(i18n.PermDenied, command), false
+	}
+
+	switch req {
+	case requireDJ:
+		if !dj && !k.isAloneWithBot(ic) {
+			return lang.T(i18n.PermDJOnly), false
+		}
+	case requireSameChannel:
+		if dj {
+			break
+		}
+		player := k.findPlayer(ic.GuildID)
+		if player == nil {
+			break
+		}
+		botChannel := player.VoiceChannelID()
+		if botChannel == "" {
+			break
+		}
+		userChannel, err := locateVoiceChannel(k.session, ic.GuildID, ic.Member.User.ID)
+		if err != nil || userChannel != botChannel {
+			return lang.T(i18n.PermSameChannel, botChannel), false
+		}
+	}
+	return "", true
+}
+
+// isDJ reports whether the invoking member may control playback without voting.
+func (k *Kvazar) isDJ(ic *discordgo.InteractionCreate) bool {
+	if ic.Member == nil {
+		return false
+	}
+	if ic.Member.Permissions&djPermissions != 0 {
+		return true
+	}
+	roleID := k.guildSettings(ic.GuildID).DJRoleID
+	return roleID != "" && hasAnyRole(ic.Member, []string{roleID})
+}
+
+// IsDJ reports whether a guild member may control playback without voting,
+// for callers outside an interaction such as the web dashboard. The member is
+// fetched from Discord, since the bot does not cache members.
+func (k *Kvazar) IsDJ(guildID, userID string) bool {
+	member, err := k.session.GuildMember(guildID, userID)
+	if err != nil {
+		return false
+	}
+	guild, err := k.session.CachedGuild(guildID)
+	if err != nil {
+		return false
+	}
+	if guild.OwnerID == userID {
+		return true
+	}
+
+	var permissions int64
+	for _, role := range guild.Roles {
+		// The @everyone role shares the guild's ID.
+		if role.ID == guildID || hasAnyRole(member, []string{role.ID}) {
+			permissions |= role.Permissions
+		}
+	}
+	if permissions&djPermissions != 0 {
+		return true
+	}
+	roleID := k.guildSettings(guildID).DJRoleID
+	return roleID != "" && hasAnyRole(member, []string{roleID})
+}
+
+// isAloneWithBot reports whether the member is the only listener in the bot's voice channel.
+func (k *Kvazar) isAloneWithBot(ic *discordgo.InteractionCreate) bool {
+	player := k.findPlayer(ic.GuildID)
+	if player == nil || ic.Member == nil || ic.Member.User == nil {
+		return false
+	}
+	botChannel := player.VoiceChannelID()
+	if botChannel == "" {
+		return false
+	}
+	userChannel, err := locateVoiceChannel(k.session, ic.GuildID, ic.Member.User.ID)
+	return err == nil && userChannel == botChannel && k.countListeners(ic.GuildID, botChannel) == 1
+}
+
+func isAdmin(member *discordgo.Member) bool {
+	return member != nil && member.Permissions&adminPermissions != 0
+}
+
+func hasAnyRole(member *discordgo.Member, roles []string) bool {
+	if member == nil {
+		return false
+	}
+	for _, want := range roles {
+		for _, have := range member.Roles {
+			if want == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// subcommandName returns the name of the invoked subcommand, if any.
+func subcommandName(data discordgo.ApplicationCommandInteractionData) string {
+	if len(data.Options) > 0 && data.Options[0].Type == discordgo.ApplicationCommandOptionSubCommand {
+		return data.Options[0].Name
+	}
+	return ""
+}
+
+func (k *Kvazar) handlePermissions(ic *discordgo.InteractionCreate) {
+	data := ic.ApplicationCommandData()
+	lang := k.lang(ic)
+	if ic.GuildID == "" || len(data.Options) == 0 {
+		k.respondError(ic, lang.T(i18n.ErrGuildOnly))
+		return
+	}
+
+	sub := data.Options[0]
+	var err error
+	switch sub.Name {
+	case "show":
+		k.respondEphemeral(ic, k.describePermissions(lang, ic.GuildID))
+		return
+	case "dj-role":
+		roleID := ""
+		if opt := findOption(sub.Options, "role"); opt != nil {
+			roleID = fmt.Sprint(opt.Value)
+		}
+		err = k.updateGuildSettings(ic.GuildID, func(s *guildSettings) { s.DJRoleID = roleID })
+		if err == nil {
+			if roleID == "" {
+				k.respondEphemeral(ic, lang.T(i18n.PermDJCleared))
+			} else {
+				k.respondEphemeral(ic, lang.T(i18n.PermDJSet, roleID))
+			}
+			return
+		}
+	case "allow", "deny":
+		command := strings.ToLower(optionString(sub.Options, "command"))
+		roleID := optionString(sub.Options, "role")
+		if !isConfigurableCommand(command) || roleID == "" {
+			k.respondError(ic, lang.T(i18n.PermUnknownCommandRole))
+			return
+		}
+		deny := sub.Name == "deny"
+		err = k.updateGuildSettings(ic.GuildID, func(s *guildSettings) {
+			if s.Commands == nil {
+				s.Commands = make(map[string]commandPolicy)
+			}
+			policy := s.Commands[command]
+			policy.Allow = removeString(policy.Allow, roleID)
+			policy.Deny = removeString(policy.Deny, roleID)
+			if deny {
+				policy.Deny = append(policy.Deny, roleID)
+			} else {
+				policy.Allow = append(policy.Allow, roleID)
+			}
+			s.Commands[command] = policy
+		})
+		if err == nil {
+			key := i18n.PermAllowed
+			if deny {
+				key = i18n.PermDeniedRole
+			}
+			k.respondEphemeral(ic, lang.T(key, command, roleID))
+			return
+		}
+	case "reset":
+		command := strings.ToLower(optionString(sub.Options, "command"))
+		if !isConfigurableCommand(command) {
+			k.respondError(ic, lang.T(i18n.PermUnknownCommand))
+			return
+		}
+		err = k.updateGuildSettings(ic.GuildID, func(s *guildSettings) { delete(s.Commands, command) })
+		if err == nil {
+			k.respondEphemeral(ic, lang.T(i18n.PermReset, command))
+			return
+		}
+	default:
+		k.respondError(ic, lang.T(i18n.ErrUnknownSubcommand))
+		return
+	}
+
+	log.Printf("failed to save permissions for guild %s: %v", ic.GuildID, err)
+	k.respondError(ic, lang.T(i18n.ErrSaveSettings))
+}
+
+func (k *Kvazar) describePermissions(lang i18n.Lang, guildID string) string {
+	settings := k.guildSettings(guildID)
+
+	djRole := lang.T(i18n.PermDJUnset)
+	if settings.DJRoleID != "" {
+		djRole = fmt.Sprintf("<@&%s>", settings.DJRoleID)
+	}
+
+	var b strings.Builder
+	b.WriteString(lang.T(i18n.PermDJRole, djRole) + "\n")
+
+	names := make([]string, 0, len(settings.Commands))
+	for name := range settings.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		b.WriteString(lang.T(i18n.PermDefaults))
+		return b.String()
+	}
+	for _, name := range names {
+		policy := settings.Commands[name]
+		b.WriteString(lang.T(i18n.PermRule, name, formatRoles(policy.Allow), formatRoles(policy.Deny)) + "\n")
+	}
+	return b.String()
+}
+
+func formatRoles(roles []string) string {
+	if len(roles) == 0 {
+		return "—"
+	}
+	mentions := make([]string, 0, len(roles))
+	for _, role := range roles {
+		mentions = append(mentions, fmt.Sprintf("<@&%s>", role))
+	}
+	return strings.Join(mentions, ", ")
+}
+
+func isConfigurableCommand(name string) bool {
+	for _, candidate := range configurableCommands {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
+
+func optionString(options []*discordgo.ApplicationCommandInteractionDataOption, name string) string {
+	if opt := findOption(options, name); opt != nil && opt.Value != nil {
+		return strings.TrimSpace(fmt.Sprint(opt.Value))
+	}
+	return ""
+}
+
+func removeString(values []string, target string) []string {
+	out := values[:0]
+	for _, value := range values {
+		if value != target {
+			out = append(out, value)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -18,15 +18,24 @@ import (
 	"layeh.com/gopus"
 
 	"kvazar/internal/media"
+	"kvazar/internal/metrics"
 )
 
 const (
 	pcmFrameSize      = 960 // 20ms at 48kHz
+	frameDuration     = 20 * time.Millisecond
 	pcmChannelCount   = 2
 	sampleRate        = 48000
-	opusBitrate       = 128000 // 128 kbps for high quality
+	defaultBitrate    = 128000 // 128 kbps for high quality
 	opusFrameCapacity = 4096
-	disconnectDelay   = 90 * time.Second
+	defaultIdleDelay  = 90 * time.Second
+	historyLimit      = 50
+)
+
+var (
+	errTrackNotFound = errors.New("track not found in queue")
+	errNotPermitted  = errors.New("not permitted")
+	errStopped       = errors.New("player stopped")
 )
 
 // Player manages playback for a single guild.
@@ -36,15 +45,25 @@ type Player struct {
 
 	mu             sync.Mutex
 	queue          []*media.Track
+	history        []*media.Track
 	current        *media.Track
+	elapsed        time.Duration
 	loop           bool
 	playing        bool
 	paused         bool
 	skipRequested  bool
+	stopped        bool
+	// halt is closed by Stop to end the bulk imports started before it.
+	halt           chan struct{}
 	cancelPlayback context.CancelFunc
 	pauseChan      chan bool
+	skipVotes      map[string]struct{}
+	nowPlaying     *discordgo.Message
+	threadID       string
+	// streamTitle is the song a radio station reports for current.
+	streamTitle    string
 
-	voice           *discordgo.VoiceConnection
+	voice           VoiceConnection
 	disconnectTimer *time.Timer
 }
 
@@ -59,15 +78,17 @@ func (p *Player) EnsureConnected(channelID string) error {
 	vc := p.voice
 	p.mu.Unlock()
 
-	if vc != nil && vc.ChannelID == channelID {
+	if vc != nil && vc.ChannelID() == channelID {
 		return nil
 	}
 
 	if vc != nil {
 		vc.Disconnect()
+		metrics.VoiceReconnected()
+		p.emit(EventVoiceDisconnected, VoiceEvent{ChannelID: vc.ChannelID()})
 	}
 
-	conn, err := p.bot.session.ChannelVoiceJoin(p.guild, channelID, false, true)
+	conn, err := p.bot.session.JoinVoice(p.guild, channelID)
 	if err != nil {
 		return fmt.Errorf("voice join: %w", err)
 	}
@@ -76,40 +97,124 @@ func (p *Player) EnsureConnected(channelID string) error {
 	p.mu.Lock()
 	p.voice = conn
 	p.mu.Unlock()
+	p.emit(EventVoiceConnected, VoiceEvent{ChannelID: channelID})
 	return nil
 }
 
 // Enqueue adds the track to the playback queue and starts playback if idle.
-func (p *Player) Enqueue(track *media.Track) int {
+// It returns a *limitError when the guild's queue limits reject the track.
+func (p *Player) Enqueue(track *media.Track) (int, error) {
+	limits := p.bot.queueLimits(p.guild)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.enqueueLocked(limits, track)
+}
+
+// stopSignal returns a channel that is closed the next time Stop is called.
+func (p *Player) stopSignal() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.halt == nil {
+		p.halt = make(chan struct{})
+	}
+	return p.halt
+}
+
+// enqueueUntil is Enqueue for bulk imports: once halt is closed it refuses
+// the track with errStopped, so a /stop is not undone by the next entry.
+func (p *Player) enqueueUntil(halt <-chan struct{}, track *media.Track) (int, error) {
+	limits := p.bot.queueLimits(p.guild)
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	select {
+	case <-halt:
+		return 0, errStopped
+	default:
+	}
+	return p.enqueueLocked(limits, track)
+}
+
+func (p *Player) enqueueLocked(limits QueueLimits, track *media.Track) (int, error) {
+	if err := p.checkLimitsLocked(limits, track.RequestedBy, track); err != nil {
+		return 0, err
+	}
 
 	p.queue = append(p.queue, track)
 	position := len(p.queue)
+	p.stopped = false
 	p.cancelDisconnectTimerLocked()
+	p.emitQueueLocked()
 
 	if !p.playing {
 		p.playing = true
 		go p.playLoop()
 	}
 
-	return position
+	return position, nil
 }
 
 // Skip stops the current playback and advances to the next track. Returns false if nothing is playing.
 func (p *Player) Skip() bool {
 	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.skipLocked()
+}
+
+func (p *Player) skipLocked() bool {
 	cancel := p.cancelPlayback
 	active := p.current != nil
 	if cancel != nil {
 		p.skipRequested = true
-		p.loop = false
+		if p.loop {
+			p.loop = false
+			p.emit(EventLoopChanged, LoopEvent{Enabled: false})
+		}
 		cancel()
 	}
-	p.mu.Unlock()
 	return active
 }
 
+// VoteSkip registers a skip vote from userID for the current track and skips once
+// the number of votes reaches required. It returns the vote count after voting,
+// whether the track was skipped, and false if nothing is playing.
+func (p *Player) VoteSkip(userID string, required int) (int, bool, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.current == nil {
+		return 0, false, false
+	}
+	if p.skipVotes == nil {
+		p.skipVotes = make(map[string]struct{})
+	}
+	p.skipVotes[userID] = struct{}{}
+	votes := len(p.skipVotes)
+	if votes >= required {
+		p.skipLocked()
+		return votes, true, true
+	}
+	return votes, false, true
+}
+
+// SkipVotes returns the number of skip votes registered for the current track.
+func (p *Player) SkipVotes() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.skipVotes)
+}
+
+// VoiceChannelID returns the voice channel the player is connected to, if any.
+func (p *Player) VoiceChannelID() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.voice == nil {
+		return ""
+	}
+	return p.voice.ChannelID()
+}
+
 // Pause toggles the pause state. Returns true if now paused, false if resumed.
 func (p *Player) Pause() bool {
 	p.mu.Lock()
@@ -121,7 +226,18 @@ func (p *Player) Pause() bool {
 	
 	p.paused = !p.paused
 	if p.pauseChan != nil {
-		p.pauseChan <- p.paused
+		// Never block with the lock held: streamTrack rereads the pause
+		// state on its own shortly, so a toggle that finds the buffer full
+		// is not lost.
+		select {
+		case p.pauseChan <- p.paused:
+		default:
+		}
+	}
+	if p.paused {
+		p.emit(EventPaused, nil)
+	} else {
+		p.emit(EventResumed, nil)
 	}
 	return p.paused
 }
@@ -131,10 +247,21 @@ func (p *Player) Stop() bool {
 	p.mu.Lock()
 	cancel := p.cancelPlayback
 	hadContent := p.current != nil || len(p.queue) > 0
-	p.queue = nil
+	if len(p.queue) > 0 {
+		p.queue = nil
+		p.emitQueueLocked()
+	}
+	if p.loop {
+		p.loop = false
+		p.emit(EventLoopChanged, LoopEvent{Enabled: false})
+	}
 	p.current = nil
-	p.loop = false
 	p.paused = false
+	p.stopped = true
+	if p.halt != nil {
+		close(p.halt)
+		p.halt = nil
+	}
 	if cancel != nil {
 		p.skipRequested = true
 		cancel()
@@ -148,14 +275,60 @@ func (p *Player) ToggleLoop(explicit *bool) bool {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	previous := p.loop
 	if explicit != nil {
 		p.loop = *explicit && p.current != nil
 	} else if p.current != nil {
 		p.loop = !p.loop
 	}
+	if p.loop != previous {
+		p.emit(EventLoopChanged, LoopEvent{Enabled: p.loop})
+	}
 	return p.loop
 }
 
+// Remove deletes the queued track at the zero-based index when allowed approves it.
+func (p *Player) Remove(index int, allowed func(*media.Track) bool) (*media.Track, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if index < 0 || index >= len(p.queue) {
+		return nil, errTrackNotFound
+	}
+	track := p.queue[index]
+	if allowed != nil && !allowed(track) {
+		return track, errNotPermitted
+	}
+	p.queue = append(p.queue[:index], p.queue[index+1:]...)
+	p.emitQueueLocked()
+	return track, nil
+}
+
+// Move relocates the queued track at index from to index to, both zero-based.
+func (p *Player) Move(from, to int) (*media.Track, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if from < 0 || from >= len(p.queue) || to < 0 || to >= len(p.queue) {
+		return nil, errTrackNotFound
+	}
+	track := p.queue[from]
+	p.queue = append(p.queue[:from], p.queue[from+1:]...)
+	p.queue = append(p.queue[:to], append([]*media.Track{track}, p.queue[to:]...)...)
+	p.emitQueueLocked()
+	return track, nil
+}
+
+// QueueSnapshot returns the current track together with copies of the upcoming queue and play history (oldest first).
+func (p *Player) QueueSnapshot() (*media.Track, []*media.Track, []*media.Track) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	queue := append([]*media.Track(nil), p.queue...)
+	history := append([]*media.Track(nil), p.history...)
+	return p.current, queue, history
+}
+
 // Shutdown terminates playback and disconnects the voice connection.
 func (p *Player) Shutdown() {
 	p.mu.Lock()
@@ -168,12 +341,17 @@ func (p *Player) Shutdown() {
 
 	if vc != nil {
 		vc.Disconnect()
+		p.emit(EventVoiceDisconnected, VoiceEvent{ChannelID: vc.ChannelID()})
 	}
+	p.bot.endAnnouncements(p)
 }
 
 func (p *Player) playLoop() {
 	for {
 		track, repeat := p.nextTrack()
+		if track == nil && p.autoplayNext() {
+			continue
+		}
 		if track == nil {
 			p.mu.Lock()
 			p.playing = false
@@ -183,31 +361,67 @@ func (p *Player) playLoop() {
 			return
 		}
 
-		if !repeat {
-			p.bot.announceNowPlaying(track, p.loop)
-		}
-
+		// Make the track skippable before anyone hears it has started.
 		ctx, cancel := context.WithCancel(context.Background())
 		p.mu.Lock()
 		p.cancelPlayback = cancel
 		p.pauseChan = make(chan bool, 1)
+		p.streamTitle = ""
 		p.mu.Unlock()
 
+		if !repeat {
+			p.bot.announceNowPlaying(p, track, p.loop)
+		}
+		p.emit(EventTrackStarted, TrackEvent{Track: trackInfo(track)})
+		go p.watchStreamTitles(ctx, track)
+
 		err := p.streamTrack(ctx, track)
 
 		p.mu.Lock()
 		// Clear the cancel function after playback
 		p.cancelPlayback = nil
 		p.pauseChan = nil
+		// Tracks that never got a frame out did not play, so they stay out
+		// of the history that autoplay and /queue export read.
+		if p.elapsed > 0 {
+			p.recordHistoryLocked(track)
+		}
 		p.mu.Unlock()
 
 		if errors.Is(err, context.Canceled) {
+			p.bot.playback.record(false)
+			p.emit(EventTrackSkipped, TrackEvent{Track: trackInfo(track)})
 			continue
 		}
 
+		p.bot.playback.record(err != nil)
 		if err != nil {
 			log.Printf("playback error: %v", err)
+			info := trackInfo(track)
+			p.emit(EventError, ErrorEvent{Message: err.Error(), Track: &info})
+		}
+		p.emit(EventTrackEnded, TrackEvent{Track: trackInfo(track)})
+	}
+}
+
+// watchStreamTitles follows the songs a radio station announces while the
+// track plays and shows them on the now-playing card.
+func (p *Player) watchStreamTitles(ctx context.Context, track *media.Track) {
+	err := p.bot.sources.WatchTitles(ctx, track, func(title string) {
+		p.mu.Lock()
+		if p.current != track || ctx.Err() != nil {
+			p.mu.Unlock()
+			return
 		}
+		p.streamTitle = title
+		p.mu.Unlock()
+
+		p.emit(EventStreamTitle, StreamTitleEvent{Title: title})
+		required := requiredSkipVotes(p.bot.countListeners(p.guild, p.VoiceChannelID()), p.bot.currentSettings().VoteSkipRatio)
+		p.bot.refreshNowPlaying(p, required)
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Printf("failed to read stream titles in guild %s: %v", p.guild, err)
 	}
 }
 
@@ -216,6 +430,7 @@ func (p *Player) nextTrack() (*media.Track, bool) {
 	defer p.mu.Unlock()
 
 	p.skipRequested = false
+	p.skipVotes = nil
 
 	// If loop is enabled and we have a current track, add it back to the queue
 	if p.loop && p.current != nil {
@@ -230,12 +445,71 @@ func (p *Player) nextTrack() (*media.Track, bool) {
 	track := p.queue[0]
 	p.queue = p.queue[1:]
 	p.current = track
+	p.emitQueueLocked()
 	return track, false
 }
 
+// autoplayNext queues a track related to the last one played when the guild
+// has autoplay enabled. It reports whether a track was queued.
+func (p *Player) autoplayNext() bool {
+	if !p.bot.guildSettings(p.guild).Autoplay {
+		return false
+	}
+
+	p.mu.Lock()
+	if p.stopped || len(p.history) == 0 {
+		p.mu.Unlock()
+		return false
+	}
+	seed := p.history[len(p.history)-1]
+	played := make(map[string]bool, len(p.history))
+	for _, track := range p.history {
+		played[track.WebURL] = true
+	}
+	p.mu.Unlock()
+
+	seen := func(webURL string) bool { return played[webURL] }
+	// Autoplay keeps to the guild's limits, passing over livestreams and
+	// long tracks it forbids.
+	limits := p.bot.queueLimits(p.guild)
+	accept := func(track *media.Track) bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.checkLimitsLocked(limits, track.RequestedBy, track) == nil
+	}
+	ctx := media.WithPriority(context.Background(), media.PriorityBackground)
+	track, err := p.bot.resolver.Related(ctx, seed, seen, accept, seed.RequestedBy, seed.RequestChannelID)
+	if err != nil {
+		log.Printf("autoplay failed for guild %s: %v", p.guild, err)
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Stop may have been pressed while the track was resolving.
+	if p.stopped {
+		return false
+	}
+	if err := p.checkLimitsLocked(limits, track.RequestedBy, track); err != nil {
+		log.Printf("autoplay failed for guild %s: %v", p.guild, err)
+		return false
+	}
+	p.queue = append(p.queue, track)
+	p.emitQueueLocked()
+	return true
+}
+
+func (p *Player) recordHistoryLocked(track *media.Track) {
+	p.history = append(p.history, track)
+	if overflow := len(p.history) - historyLimit; overflow > 0 {
+		p.history = append([]*media.Track(nil), p.history[overflow:]...)
+	}
+}
+
 func (p *Player) streamTrack(ctx context.Context, track *media.Track) error {
 	p.mu.Lock()
 	vc := p.voice
+	p.elapsed = 0
 	p.mu.Unlock()
 
 	if vc == nil {
@@ -248,10 +522,26 @@ func (p *Player) streamTrack(ctx context.Context, track *media.Track) error {
 	}
 	
 	// Set high quality bitrate
-	opusEncoder.SetBitrate(opusBitrate)
+	opusEncoder.SetBitrate(p.bot.currentSettings().Bitrate)
+
+	input, err := p.bot.sources.Open(ctx, track)
+	if err != nil {
+		return fmt.Errorf("open stream: %w", err)
+	}
 
-	cmdArgs := buildFFMpegArgs(track)
+	fetchCtx, stopFetch := context.WithCancel(ctx)
+	defer stopFetch()
+	input, body, err := pipedInput(fetchCtx, input)
+	if err != nil {
+		return fmt.Errorf("open stream: %w", err)
+	}
+
+	cmdArgs := buildFFMpegArgs(input, audioFilters(p.bot.guildSettings(p.guild)))
 	cmd := exec.CommandContext(ctx, p.bot.ffmpegPath, cmdArgs...)
+	if body != nil {
+		defer body.Close()
+		cmd.Stdin = body
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -264,10 +554,16 @@ func (p *Player) streamTrack(ctx context.Context, track *media.Track) error {
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("ffmpeg start: %w", err)
 	}
+	started := time.Now()
 
 	defer func() {
 		_ = cmd.Process.Kill()
+		// A stalled fetch would otherwise keep Wait copying to stdin.
+		stopFetch()
 		_ = cmd.Wait()
+		if cmd.ProcessState != nil {
+			metrics.ObserveFFMpegExit(cmd.ProcessState.ExitCode())
+		}
 	}()
 
 	reader := bufio.NewReader(stdout)
@@ -283,6 +579,8 @@ func (p *Player) streamTrack(ctx context.Context, track *media.Track) error {
 		}
 	}()
 
+	// lastSent paces the frame lag metric; it restarts after a pause.
+	var lastSent time.Time
 	for {
 		if ctx.Err() != nil {
 			return context.Canceled
@@ -295,6 +593,7 @@ func (p *Player) streamTrack(ctx context.Context, track *media.Track) error {
 		p.mu.Unlock()
 
 		if isPaused {
+			lastSent = time.Time{}
 			select {
 			case <-ctx.Done():
 				return context.Canceled
@@ -309,11 +608,19 @@ func (p *Player) streamTrack(ctx context.Context, track *media.Track) error {
 		}
 
 		if _, err := io.ReadFull(reader, byteBuf); err != nil {
+			if ctx.Err() != nil {
+				// Cancelling kills ffmpeg, which also ends the stream.
+				return context.Canceled
+			}
 			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 				return nil
 			}
 			return fmt.Errorf("pcm read: %w", err)
 		}
+		if !started.IsZero() {
+			metrics.ObserveFFMpegStart(time.Since(started))
+			started = time.Time{}
+		}
 
 		for i := 0; i < len(pcmBuf); i++ {
 			pcmBuf[i] = int16(binary.LittleEndian.Uint16(byteBuf[i*2 : i*2+2]))
@@ -327,8 +634,18 @@ func (p *Player) streamTrack(ctx context.Context, track *media.Track) error {
 		select {
 		case <-ctx.Done():
 			return context.Canceled
-		case vc.OpusSend <- packet:
+		case vc.OpusSend() <- packet:
+		}
+		p.mu.Lock()
+		p.elapsed += frameDuration
+		p.mu.Unlock()
+
+		var lag time.Duration
+		if !lastSent.IsZero() {
+			lag = time.Since(lastSent) - frameDuration
 		}
+		lastSent = time.Now()
+		metrics.ObserveFrameSent(lag, frameDuration)
 	}
 }
 
@@ -336,7 +653,7 @@ func (p *Player) scheduleDisconnectLocked() {
 	if p.disconnectTimer != nil {
 		p.disconnectTimer.Stop()
 	}
-	p.disconnectTimer = time.AfterFunc(disconnectDelay, func() {
+	p.disconnectTimer = time.AfterFunc(p.bot.idleTimeout(p.guild), func() {
 		p.mu.Lock()
 		vc := p.voice
 		p.voice = nil
@@ -345,8 +662,10 @@ func (p *Player) scheduleDisconnectLocked() {
 
 		if vc != nil {
 			vc.Disconnect()
+			p.emit(EventVoiceDisconnected, VoiceEvent{ChannelID: vc.ChannelID()})
 		}
 
+		p.bot.endAnnouncements(p)
 		p.bot.releasePlayer(p.guild)
 	})
 }
@@ -358,22 +677,61 @@ func (p *Player) cancelDisconnectTimerLocked() {
 	}
 }
 
-func buildFFMpegArgs(track *media.Track) []string {
-	args := []string{
-		"-reconnect", "1",
-		"-reconnect_streamed", "1",
-		"-reconnect_delay_max", "5",
+// audioFilters builds the ffmpeg filter chain for a guild's volume and
+// loudness settings.
+func audioFilters(settings guildSettings) string {
+	var filters []string
+	if settings.normalize() {
+		filters = append(filters, fmt.Sprintf("loudnorm=I=%g:LRA=11:TP=-1.5", settings.loudnessTarget()))
+	}
+	if volume := settings.volume(); volume != defaultVolume {
+		filters = append(filters, fmt.Sprintf("volume=%.2f", float64(volume)/100))
+	}
+	return strings.Join(filters, ",")
+}
+
+// pipedInput fetches a credentialed input itself and returns the body for
+// ffmpeg to read on stdin, so that the cookies stay off ffmpeg's command
+// line, where any local user could read them. Other inputs are returned as
+// they are, with a nil body.
+func pipedInput(ctx context.Context, input media.Input) (media.Input, io.ReadCloser, error) {
+	if !input.Credentialed() {
+		return input, nil, nil
+	}
+	body, err := input.Fetch(ctx)
+	if err != nil {
+		return media.Input{}, nil, err
+	}
+	return media.Input{URL: "pipe:0"}, body, nil
+}
+
+func buildFFMpegArgs(input media.Input, filters string) []string {
+	var args []string
+	// Reconnecting is an HTTP option; ffmpeg refuses it for local files.
+	if scheme, _, ok := strings.Cut(input.URL, "://"); ok && strings.HasPrefix(strings.ToLower(scheme), "http") {
+		args = append(args,
+			"-reconnect", "1",
+			"-reconnect_streamed", "1",
+			"-reconnect_delay_max", "5",
+		)
+		if input.Proxy != "" {
+			args = append(args, "-http_proxy", input.Proxy)
+		}
 	}
 
-	headerLines := headersToLines(track.HTTPHeaders)
+	headerLines := headersToLines(input.Headers)
 	if headerLines != "" {
 		args = append(args, "-headers", headerLines)
 	}
 
 	args = append(args,
-		"-i", track.StreamURL,
+		"-i", input.URL,
 		"-vn",
-		"-af", "loudnorm=I=-16:LRA=11:TP=-1.5", // Audio normalization for better quality
+	)
+	if filters != "" {
+		args = append(args, "-af", filters)
+	}
+	args = append(args,
 		"-f", "s16le",
 		"-ac", fmt.Sprintf("%d", pcmChannelCount),
 		"-ar", fmt.Sprintf("%d", sampleRate),
@@ -396,17 +754,15 @@ func headersToLines(headers map[string]string) string {
 }
 
 // locateVoiceChannel ensures we can find the member's voice channel.
-func locateVoiceChannel(session *discordgo.Session, guildID, userID string) (string, error) {
+func locateVoiceChannel(state StateLookup, guildID, userID string) (string, error) {
 	if guildID == "" || userID == "" {
 		return "", errors.New("missing guild or user identifier")
 	}
 
-	guild, err := session.State.Guild(guildID)
+	// Only the gateway state carries voice states; the REST guild never does.
+	guild, err := state.CachedGuild(guildID)
 	if err != nil {
-		guild, err = session.Guild(guildID)
-		if err != nil {
-			return "", fmt.Errorf("guild fetch: %w", err)
-		}
+		return "", fmt.Errorf("guild lookup: %w", err)
 	}
 
 	for _, vs := range guild.VoiceStates {
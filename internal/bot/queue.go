@@ -0,0 +1,373 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+	"kvazar/internal/playlist"
+)
+
+const (
+	maxImportFileSize  = 1 << 20 // 1 MiB is plenty for a few hundred entries
+	maxImportEntries   = 100
+	importFetchTimeout = 15 * time.Second
+	// importWorkers is how many entries of a bulk import are looked up at once.
+	importWorkers = 4
+	// importDeadline ends a bulk import in time to report back before
+	// Discord's 15-minute interaction token expires.
+	importDeadline = 14 * time.Minute
+)
+
+func (k *Kvazar) handleQueue(ic *discordgo.InteractionCreate) {
+	data := ic.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		k.respondError(ic, k.lang(ic).T(i18n.ErrUnknownSubcommand))
+		return
+	}
+
+	sub := data.Options[0]
+	switch sub.Name {
+	case "export":
+		k.handleQueueExport(ic, sub.Options)
+	case "import":
+		k.handleQueueImport(ic, sub.Options)
+	default:
+		k.respondError(ic, k.lang(ic).T(i18n.ErrUnknownSubcommand))
+	}
+}
+
+func (k *Kvazar) handleQueueExport(ic *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	lang := k.lang(ic)
+	format := playlist.FormatJSON
+	if opt := findOption(options, "format"); opt != nil {
+		parsed, err := playlist.ParseFormat(opt.StringValue())
+		if err != nil {
+			k.respondError(ic, lang.T(i18n.QueueBadFormat))
+			return
+		}
+		format = parsed
+	}
+
+	player := k.findPlayer(ic.GuildID)
+	if player == nil {
+		k.respondError(ic, lang.T(i18n.ErrQueueEmpty))
+		return
+	}
+
+	current, queue, history := player.QueueSnapshot()
+	if current != nil {
+		queue = append([]*media.Track{current}, queue...)
+	}
+	if len(queue) == 0 && len(history) == 0 {
+		k.respondError(ic, lang.T(i18n.ErrQueueEmpty))
+		return
+	}
+
+	var buf bytes.Buffer
+	doc := playlist.Document{
+		Queue:   playlist.FromTracks(queue),
+		History: playlist.FromTracks(history),
+	}
+	if err := playlist.Encode(&buf, format, doc); err != nil {
+		log.Printf("failed to encode queue export: %v", err)
+		k.respondError(ic, lang.T(i18n.QueueExportFailed))
+		return
+	}
+
+	name := fmt.Sprintf("kvazar-queue-%s.%s", time.Now().UTC().Format("20060102-150405"), format.Extension())
+	if err := k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: lang.T(i18n.QueueExportSummary, len(doc.Queue), len(doc.History)),
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Files: []*discordgo.File{
+				{Name: name, ContentType: format.ContentType(), Reader: &buf},
+			},
+		},
+	}); err != nil {
+		log.Printf("failed to send queue export: %v", err)
+	}
+}
+
+func (k *Kvazar) handleQueueImport(ic *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	lang := k.lang(ic)
+	guildID := ic.GuildID
+	if guildID == "" {
+		k.respondError(ic, lang.T(i18n.ErrGuildOnly))
+		return
+	}
+
+	fileOpt := findOption(options, "file")
+	resolved := ic.ApplicationCommandData().Resolved
+	if fileOpt == nil || resolved == nil || resolved.Attachments[fmt.Sprint(fileOpt.Value)] == nil {
+		k.respondError(ic, lang.T(i18n.QueueNeedFile))
+		return
+	}
+	attachment := resolved.Attachments[fmt.Sprint(fileOpt.Value)]
+	if attachment.Size > maxImportFileSize {
+		k.respondError(ic, lang.T(i18n.QueueFileTooLarge))
+		return
+	}
+
+	includeHistory := false
+	if opt := findOption(options, "history"); opt != nil {
+		includeHistory = opt.BoolValue()
+	}
+
+	userID := ic.Member.User.ID
+	voiceChannel, err := locateVoiceChannel(k.session, guildID, userID)
+	if err != nil {
+		k.respondError(ic, lang.T(i18n.QueueNeedVoice))
+		return
+	}
+
+	if err := k.session.InteractionRespond(ic.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}); err != nil {
+		log.Printf("failed to acknowledge interaction: %v", err)
+		return
+	}
+
+	requestedBy := fmt.Sprintf("<@%s>", userID)
+	k.runDeferred(ic, func() { k.fulfilImport(ic, attachment, includeHistory, voiceChannel, requestedBy) })
+}
+
+func (k *Kvazar) fulfilImport(ic *discordgo.InteractionCreate, attachment *discordgo.MessageAttachment, includeHistory bool, voiceChannel, requestedBy string) {
+	lang := k.lang(ic)
+	content, err := downloadAttachment(attachment.URL)
+	if err != nil {
+		log.Printf("failed to download import %s: %v", attachment.Filename, err)
+		k.editInteractionError(ic, lang.T(i18n.QueueDownloadFailed))
+		return
+	}
+
+	format, err := playlist.DetectFormat(attachment.Filename, content)
+	if err != nil {
+		k.editInteractionError(ic, lang.T(i18n.QueueUnsupported))
+		return
+	}
+
+	doc, err := playlist.Decode(bytes.NewReader(content), format)
+	if err != nil {
+		k.editInteractionError(ic, lang.T(i18n.QueueReadFailed, err))
+		return
+	}
+
+	entries := doc.Queue
+	if includeHistory {
+		entries = append(append([]playlist.Entry(nil), doc.History...), entries...)
+	}
+	if len(entries) == 0 {
+		k.editInteractionError(ic, lang.T(i18n.QueueNoEntries))
+		return
+	}
+	truncated := 0
+	if len(entries) > maxImportEntries {
+		truncated = len(entries) - maxImportEntries
+		entries = entries[:maxImportEntries]
+	}
+
+	player := k.getPlayer(ic.GuildID)
+	if err := player.EnsureConnected(voiceChannel); err != nil {
+		k.editInteractionError(ic, lang.T(i18n.ErrVoiceConnect, err))
+		return
+	}
+
+	queries := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		queries = append(queries, entry.Query())
+	}
+	added, failed, limitErr := k.resolveAndEnqueue(player, queries, requestedBy, ic.ChannelID)
+
+	message := lang.T(i18n.QueueImported, added)
+	if failed > 0 {
+		message += " " + lang.T(i18n.FailedCount, failed)
+	}
+	if truncated > 0 {
+		message += " " + lang.T(i18n.QueueTruncated, truncated)
+	}
+	if limitErr != nil {
+		message += " " + limitErr.userMessage(lang)
+	}
+	k.editInteractionContent(ic, message)
+}
+
+func (k *Kvazar) handleRemove(ic *discordgo.InteractionCreate) {
+	lang := k.lang(ic)
+	player := k.findPlayer(ic.GuildID)
+	if player == nil {
+		k.respondError(ic, lang.T(i18n.ErrQueueEmpty))
+		return
+	}
+
+	opt := findOption(ic.ApplicationCommandData().Options, "position")
+	if opt == nil {
+		k.respondError(ic, lang.T(i18n.RemoveMissingPosition))
+		return
+	}
+
+	userID := interactionUserID(ic)
+	dj := k.isDJ(ic)
+	track, err := player.Remove(int(opt.IntValue())-1, func(t *media.Track) bool {
+		return dj || isRequester(t, userID)
+	})
+	switch {
+	case errors.Is(err, errTrackNotFound):
+		k.respondError(ic, lang.T(i18n.RemoveNoSuch))
+	case errors.Is(err, errNotPermitted):
+		k.respondError(ic, lang.T(i18n.RemoveNotYours, track.Title, track.RequestedBy))
+	case err != nil:
+		k.respondError(ic, lang.T(i18n.RemoveFailed))
+	default:
+		k.respondSuccess(ic, lang.T(i18n.RemoveDone, track.Title))
+	}
+}
+
+// resolveAndEnqueue resolves the queries a few at a time and appends the
+// results to the player's queue in order. It stops early when a queue limit
+// would reject every remaining track, returning the last limit that was hit,
+// when the player is stopped, and when the import runs out of time, in which
+// case the entries left over count as failed.
+func (k *Kvazar) resolveAndEnqueue(player *Player, queries []string, requestedBy, channelID string) (added, failed int, lastLimit *limitError) {
+	halt := player.stopSignal()
+	ctx, cancel := context.WithTimeout(media.WithPriority(context.Background(), media.PriorityBackground), importDeadline)
+	defer cancel()
+	go func() {
+		select {
+		case <-halt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	type lookup struct {
+		track *media.Track
+		err   error
+	}
+	results := make([]chan lookup, len(queries))
+	for i := range results {
+		results[i] = make(chan lookup, 1)
+	}
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i, query := range queries {
+			if strings.TrimSpace(query) == "" {
+				continue
+			}
+			select {
+			case next <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for w := 0; w < importWorkers; w++ {
+		go func() {
+			for i := range next {
+				entryCtx, cancel := context.WithTimeout(ctx, 45*time.Second)
+				track, err := k.sources.Resolve(entryCtx, "", queries[i], requestedBy, channelID)
+				cancel()
+				results[i] <- lookup{track, err}
+			}
+		}()
+	}
+
+	for i, query := range queries {
+		if strings.TrimSpace(query) == "" {
+			failed++
+			continue
+		}
+
+		var result lookup
+		select {
+		case result = <-results[i]:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				log.Printf("import for guild %s ran out of time", player.guild)
+				failed += countNonEmpty(queries[i:])
+			}
+			return added, failed, lastLimit
+		}
+		if result.err != nil {
+			log.Printf("failed to resolve %q: %v", query, result.err)
+			failed++
+			continue
+		}
+
+		if _, err := player.enqueueUntil(halt, result.track); err != nil {
+			if errors.Is(err, errStopped) {
+				return added, failed, lastLimit
+			}
+			failed++
+			var limitErr *limitError
+			if errors.As(err, &limitErr) {
+				if limitErr.blocksFurther() {
+					return added, failed + countNonEmpty(queries[i+1:]), limitErr
+				}
+				lastLimit = limitErr
+			}
+			continue
+		}
+		added++
+	}
+	return added, failed, lastLimit
+}
+
+func countNonEmpty(values []string) int {
+	count := 0
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+func downloadAttachment(url string) ([]byte, error) {
+	client := &http.Client{Timeout: importFetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxImportFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxImportFileSize {
+		return nil, fmt.Errorf("file exceeds %d bytes", maxImportFileSize)
+	}
+	return content, nil
+}
+
+// findOption returns the named option, or nil if it was not supplied.
+func findOption(options []*discordgo.ApplicationCommandInteractionDataOption, name string) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range options {
+		if opt != nil && strings.EqualFold(opt.Name, name) {
+			return opt
+		}
+	}
+	return nil
+}
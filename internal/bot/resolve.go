@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+)
+
+// resolveMessages explain each kind of failed lookup.
+var resolveMessages = map[media.ResolveErrorKind]i18n.Key{
+	media.ResolveFailed:        i18n.ResolveFailed,
+	media.ResolveNotFound:      i18n.ResolveNotFound,
+	media.ResolvePrivate:       i18n.ResolvePrivate,
+	media.ResolveAgeRestricted: i18n.ResolveAgeRestricted,
+	media.ResolveGeoBlocked:    i18n.ResolveGeoBlocked,
+	media.ResolveCopyright:     i18n.ResolveCopyright,
+	media.ResolveNotStarted:    i18n.ResolveNotStarted,
+	media.ResolveRateLimited:   i18n.ResolveRateLimited,
+	media.ResolveTimeout:       i18n.ResolveTimeout,
+	media.ResolveMissingBinary: i18n.ResolveMissingBinary,
+}
+
+// resolveMessage explains why a query could not be played. The error itself,
+// with what yt-dlp printed or a site answered, is left to the log.
+func resolveMessage(lang i18n.Lang, err error) string {
+	var resolveErr *media.ResolveError
+	switch {
+	case errors.As(err, &resolveErr):
+		if key, ok := resolveMessages[resolveErr.Kind]; ok {
+			return lang.T(key)
+		}
+		return lang.T(i18n.ResolveFailed)
+	case errors.Is(err, media.ErrResolverBusy):
+		return lang.T(i18n.ResolveBusy)
+	case errors.Is(err, media.ErrNoMatch):
+		return lang.T(i18n.ResolveNoMatch)
+	case errors.Is(err, media.ErrUnknownSource):
+		return lang.T(i18n.ResolveUnknownSource)
+	case errors.Is(err, media.ErrNotInLibrary):
+		return lang.T(i18n.ResolveNotInLibrary)
+	case errors.Is(err, context.DeadlineExceeded):
+		return lang.T(i18n.ResolveTimeout)
+	}
+	return lang.T(i18n.PlayNotFound)
+}
@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"time"
+)
+
+// Settings are the parts of the configuration that can change while the bot
+// is running.
+type Settings struct {
+	Status string
+
+	// VoteSkipRatio is the share of listeners that must vote before a track is skipped.
+	VoteSkipRatio float64
+	// Bitrate is the Opus bitrate in bits per second.
+	Bitrate int
+	// DisconnectDelay is how long an idle player stays in the voice channel.
+	DisconnectDelay time.Duration
+	// Locale is the language used when neither the guild nor the member picked one.
+	Locale string
+	// Limits apply to guilds that have not configured their own with /limits.
+	Limits QueueLimits
+}
+
+func (s Settings) normalized() Settings {
+	s.VoteSkipRatio = pickRatio(s.VoteSkipRatio, defaultVoteSkipRatio)
+	if s.Bitrate <= 0 {
+		s.Bitrate = defaultBitrate
+	}
+	if s.DisconnectDelay <= 0 {
+		s.DisconnectDelay = defaultIdleDelay
+	}
+	return s
+}
+
+// Reload replaces the runtime settings. Players pick up the new bitrate with
+// their next track and the new disconnect delay the next time they go idle.
+func (k *Kvazar) Reload(settings Settings) {
+	settings = settings.normalized()
+
+	k.runtimeMu.Lock()
+	previous := k.runtime
+	k.runtime = settings
+	k.runtimeMu.Unlock()
+
+	if previous.Status != settings.Status {
+		k.updatePresence()
+	}
+}
+
+func (k *Kvazar) currentSettings() Settings {
+	k.runtimeMu.RLock()
+	defer k.runtimeMu.RUnlock()
+	return k.runtime
+}
+
+// queueLimits returns the guild's own limits, or the bot-wide defaults when it has none.
+func (k *Kvazar) queueLimits(guildID string) QueueLimits {
+	if limits := k.guildSettings(guildID).Limits; limits != nil {
+		return *limits
+	}
+	return k.currentSettings().Limits
+}
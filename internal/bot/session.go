@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Session is everything the bot asks of Discord. discordSession adapts a
+// *discordgo.Session to it; the tests use an in-memory fake.
+type Session interface {
+	Gateway
+	Interactions
+	Messages
+	Members
+	StateLookup
+	VoiceJoiner
+}
+
+// Gateway covers the connection itself and command registration.
+type Gateway interface {
+	Open() error
+	Close() error
+	UpdateStatusComplex(usd discordgo.UpdateStatusData) error
+	// Heartbeat reports when the gateway last acknowledged a heartbeat and
+	// the latency of that round trip.
+	Heartbeat() (lastAck time.Time, latency time.Duration)
+	ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+	ApplicationCommandBulkOverwrite(appID, guildID string, commands []*discordgo.ApplicationCommand, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+}
+
+// Interactions answers slash commands and button clicks.
+type Interactions interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	InteractionResponseEdit(interaction *discordgo.Interaction, edit *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// Messages posts and maintains the now-playing cards and their threads.
+type Messages interface {
+	ChannelMessageSend(channelID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEditComplex(edit *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageDelete(channelID, messageID string, options ...discordgo.RequestOption) error
+	ThreadStart(channelID, name string, typ discordgo.ChannelType, archiveDuration int, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	ChannelEdit(channelID string, data *discordgo.ChannelEdit, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+}
+
+// Members fetches guild members the state does not cache.
+type Members interface {
+	GuildMember(guildID, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error)
+}
+
+// StateLookup reads the gateway's cache of guilds, channels, voice states
+// and members. It never calls the REST API.
+type StateLookup interface {
+	// BotUserID is empty until the session is open.
+	BotUserID() string
+	CachedGuild(guildID string) (*discordgo.Guild, error)
+	// CachedGuilds returns copies that are safe to read without the state's
+	// lock.
+	CachedGuilds() []*discordgo.Guild
+	CachedMember(guildID, userID string) (*discordgo.Member, error)
+}
+
+// VoiceJoiner connects to voice channels.
+type VoiceJoiner interface {
+	// JoinVoice joins the channel self-deafened and returns once the
+	// connection is ready.
+	JoinVoice(guildID, channelID string) (VoiceConnection, error)
+}
+
+// VoiceConnection is the part of a voice connection playback uses.
+type VoiceConnection interface {
+	ChannelID() string
+	Speaking(speaking bool) error
+	// OpusSend receives the encoded 20ms frames to play.
+	OpusSend() chan<- []byte
+	Disconnect() error
+}
+
+// discordSession adapts *discordgo.Session to Session.
+type discordSession struct {
+	*discordgo.Session
+}
+
+func (s discordSession) Heartbeat() (time.Time, time.Duration) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.LastHeartbeatAck, s.HeartbeatLatency()
+}
+
+func (s discordSession) BotUserID() string {
+	if s.State.User == nil {
+		return ""
+	}
+	return s.State.User.ID
+}
+
+func (s discordSession) CachedGuild(guildID string) (*discordgo.Guild, error) {
+	return s.State.Guild(guildID)
+}
+
+func (s discordSession) CachedGuilds() []*discordgo.Guild {
+	s.State.RLock()
+	defer s.State.RUnlock()
+	guilds := make([]*discordgo.Guild, 0, len(s.State.Guilds))
+	for _, guild := range s.State.Guilds {
+		copied := *guild
+		copied.Channels = append([]*discordgo.Channel(nil), guild.Channels...)
+		guilds = append(guilds, &copied)
+	}
+	return guilds
+}
+
+func (s discordSession) CachedMember(guildID, userID string) (*discordgo.Member, error) {
+	return s.State.Member(guildID, userID)
+}
+
+func (s discordSession) JoinVoice(guildID, channelID string) (VoiceConnection, error) {
+	conn, err := s.ChannelVoiceJoin(guildID, channelID, false, true)
+	if err != nil {
+		return nil, err
+	}
+	return discordVoice{conn}, nil
+}
+
+// discordVoice adapts *discordgo.VoiceConnection to VoiceConnection.
+type discordVoice struct {
+	conn *discordgo.VoiceConnection
+}
+
+func (v discordVoice) ChannelID() string {
+	v.conn.RLock()
+	defer v.conn.RUnlock()
+	return v.conn.ChannelID
+}
+
+func (v discordVoice) Speaking(speaking bool) error {
+	return v.conn.Speaking(speaking)
+}
+
+func (v discordVoice) OpusSend() chan<- []byte {
+	return v.conn.OpusSend
+}
+
+func (v discordVoice) Disconnect() error {
+	return v.conn.Disconnect()
+}
@@ -0,0 +1,470 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	testGuild   = "guild"
+	testText    = "text"
+	testVoice   = "voice"
+	testBotUser = "kvazar"
+)
+
+// reply is an interaction response, edit or followup recorded by fakeSession.
+type reply struct {
+	interaction string
+	kind        string // "respond", "edit" or "followup"
+	typ         discordgo.InteractionResponseType
+	content     string
+	embeds      []*discordgo.MessageEmbed
+	ephemeral   bool
+}
+
+// fakeSession is an in-memory Session holding one guild and its voice states.
+type fakeSession struct {
+	mu       sync.Mutex
+	guild    *discordgo.Guild
+	replies  []reply
+	messages map[string][]*discordgo.MessageSend
+	voices   []*fakeVoice
+	nextID   int
+	// lastAck is the last heartbeat acknowledgement, now if zero.
+	lastAck time.Time
+	// actions lists the message edits and deletions and the thread changes,
+	// such as "delete text/message-1".
+	actions []string
+	// deleted holds the IDs of messages deleted by hand, which cannot be
+	// edited.
+	deleted map[string]bool
+}
+
+func newFakeSession(listeners ...string) *fakeSession {
+	guild := &discordgo.Guild{ID: testGuild, Name: "Test", OwnerID: "owner"}
+	for _, userID := range listeners {
+		guild.VoiceStates = append(guild.VoiceStates, &discordgo.VoiceState{GuildID: testGuild, ChannelID: testVoice, UserID: userID})
+	}
+	return &fakeSession{guild: guild, messages: make(map[string][]*discordgo.MessageSend)}
+}
+
+func (s *fakeSession) Open() error                                          { return nil }
+func (s *fakeSession) Close() error                                         { return nil }
+func (s *fakeSession) UpdateStatusComplex(discordgo.UpdateStatusData) error { return nil }
+
+func (s *fakeSession) Heartbeat() (time.Time, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastAck.IsZero() {
+		return time.Now(), time.Millisecond
+	}
+	return s.lastAck, time.Millisecond
+}
+
+func (s *fakeSession) ApplicationCommands(string, string, ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	return nil, nil
+}
+
+func (s *fakeSession) ApplicationCommandBulkOverwrite(_, _ string, commands []*discordgo.ApplicationCommand, _ ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	return commands, nil
+}
+
+func (s *fakeSession) record(r reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, r)
+}
+
+func (s *fakeSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	r := reply{interaction: interaction.ID, kind: "respond", typ: resp.Type}
+	if resp.Data != nil {
+		r.content = resp.Data.Content
+		r.embeds = resp.Data.Embeds
+		r.ephemeral = resp.Data.Flags&discordgo.MessageFlagsEphemeral != 0
+	}
+	s.record(r)
+	return nil
+}
+
+func (s *fakeSession) InteractionResponseEdit(interaction *discordgo.Interaction, edit *discordgo.WebhookEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	r := reply{interaction: interaction.ID, kind: "edit"}
+	if edit.Content != nil {
+		r.content = *edit.Content
+	}
+	s.record(r)
+	return &discordgo.Message{ID: interaction.ID}, nil
+}
+
+func (s *fakeSession) FollowupMessageCreate(interaction *discordgo.Interaction, _ bool, data *discordgo.WebhookParams, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.record(reply{
+		interaction: interaction.ID,
+		kind:        "followup",
+		content:     data.Content,
+		ephemeral:   data.Flags&discordgo.MessageFlagsEphemeral != 0,
+	})
+	return &discordgo.Message{ID: interaction.ID + "-followup"}, nil
+}
+
+func (s *fakeSession) ChannelMessageSend(channelID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{Content: content}, options...)
+}
+
+func (s *fakeSession) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[channelID] = append(s.messages[channelID], data)
+	s.nextID++
+	return &discordgo.Message{ID: fmt.Sprintf("message-%d", s.nextID), ChannelID: channelID}, nil
+}
+
+func (s *fakeSession) act(action string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actions = append(s.actions, action)
+}
+
+func (s *fakeSession) ChannelMessageEditComplex(edit *discordgo.MessageEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	deleted := s.deleted[edit.ID]
+	s.mu.Unlock()
+	if deleted {
+		return nil, errors.New("HTTP 404 Not Found, {\"message\": \"Unknown Message\", \"code\": 10008}")
+	}
+	action := "edit "
+	if edit.Embeds == nil && edit.Components != nil && len(edit.Components) == 0 {
+		action = "clear buttons "
+	}
+	s.act(action + edit.Channel + "/" + edit.ID)
+	return &discordgo.Message{ID: edit.ID, ChannelID: edit.Channel}, nil
+}
+
+func (s *fakeSession) ChannelMessageDelete(channelID, messageID string, _ ...discordgo.RequestOption) error {
+	s.act("delete " + channelID + "/" + messageID)
+	return nil
+}
+
+func (s *fakeSession) ThreadStart(channelID, name string, _ discordgo.ChannelType, _ int, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	s.act("start thread " + channelID)
+	return &discordgo.Channel{ID: channelID + "-thread", Name: name}, nil
+}
+
+func (s *fakeSession) ChannelEdit(channelID string, edit *discordgo.ChannelEdit, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	if edit.Archived != nil && *edit.Archived {
+		s.act("archive " + channelID)
+	}
+	return &discordgo.Channel{ID: channelID}, nil
+}
+
+// deleteByHand deletes a message the way a moderator would.
+func (s *fakeSession) deleteByHand(messageID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deleted == nil {
+		s.deleted = make(map[string]bool)
+	}
+	s.deleted[messageID] = true
+}
+
+// takeActions returns the actions since the last call.
+func (s *fakeSession) takeActions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	actions := s.actions
+	s.actions = nil
+	return actions
+}
+
+func (s *fakeSession) GuildMember(guildID, userID string, _ ...discordgo.RequestOption) (*discordgo.Member, error) {
+	return s.CachedMember(guildID, userID)
+}
+
+func (s *fakeSession) BotUserID() string { return testBotUser }
+
+func (s *fakeSession) CachedGuild(guildID string) (*discordgo.Guild, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if guildID != s.guild.ID {
+		return nil, discordgo.ErrStateNotFound
+	}
+	copied := *s.guild
+	copied.VoiceStates = append([]*discordgo.VoiceState(nil), s.guild.VoiceStates...)
+	return &copied, nil
+}
+
+func (s *fakeSession) CachedGuilds() []*discordgo.Guild {
+	guild, _ := s.CachedGuild(testGuild)
+	return []*discordgo.Guild{guild}
+}
+
+func (s *fakeSession) CachedMember(guildID, userID string) (*discordgo.Member, error) {
+	if guildID != testGuild {
+		return nil, discordgo.ErrStateNotFound
+	}
+	return &discordgo.Member{GuildID: guildID, User: &discordgo.User{ID: userID, Bot: userID == testBotUser}}, nil
+}
+
+// JoinVoice connects a fakeVoice and moves the bot's voice state with it.
+func (s *fakeSession) JoinVoice(guildID, channelID string) (VoiceConnection, error) {
+	if guildID != testGuild {
+		return nil, errors.New("unknown guild")
+	}
+	voice := newFakeVoice(channelID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.voices = append(s.voices, voice)
+	states := s.guild.VoiceStates[:0:0]
+	for _, vs := range s.guild.VoiceStates {
+		if vs.UserID != testBotUser {
+			states = append(states, vs)
+		}
+	}
+	s.guild.VoiceStates = append(states, &discordgo.VoiceState{GuildID: guildID, ChannelID: channelID, UserID: testBotUser})
+	return voice, nil
+}
+
+// repliesTo returns what the bot has answered to an interaction so far.
+func (s *fakeSession) repliesTo(interactionID string) []reply {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []reply
+	for _, r := range s.replies {
+		if r.interaction == interactionID {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (s *fakeSession) sent(channelID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.messages[channelID])
+}
+
+func (s *fakeSession) voice() *fakeVoice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.voices) == 0 {
+		return nil
+	}
+	return s.voices[len(s.voices)-1]
+}
+
+// fakeVoice is a voice connection that accepts and counts every frame.
+type fakeVoice struct {
+	channelID string
+	send      chan []byte
+	done      chan struct{}
+
+	mu           sync.Mutex
+	frames       int
+	speaking     bool
+	disconnected bool
+}
+
+func newFakeVoice(channelID string) *fakeVoice {
+	v := &fakeVoice{channelID: channelID, send: make(chan []byte), done: make(chan struct{})}
+	go func() {
+		for {
+			select {
+			case <-v.send:
+				v.mu.Lock()
+				v.frames++
+				v.mu.Unlock()
+			case <-v.done:
+				return
+			}
+		}
+	}()
+	return v
+}
+
+func (v *fakeVoice) ChannelID() string { return v.channelID }
+
+func (v *fakeVoice) Speaking(speaking bool) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.speaking = speaking
+	return nil
+}
+
+func (v *fakeVoice) OpusSend() chan<- []byte { return v.send }
+
+func (v *fakeVoice) Disconnect() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.disconnected {
+		v.disconnected = true
+		close(v.done)
+	}
+	return nil
+}
+
+func (v *fakeVoice) sentFrames() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.frames
+}
+
+// fakeYTDLP answers every lookup with a one-second track titled after the
+// query, a ten-minute one for queries starting with "marathon" or a
+// livestream for queries starting with "live", but fails the way YouTube does
+// for private and age-restricted videos and never answers for queries
+// starting with "stuck". Mix playlists list live-mix, marathon-mix and mix,
+// in that order.
+const fakeYTDLP = `#!/bin/sh
+for query; do :; done
+title=${query#ytsearch:}
+title=${title#https://example.com/}
+case "$title" in
+*list=RD*) for title in live-mix marathon-mix mix; do printf '{"id":"%s","title":"%s","webpage_url":"https://example.com/%s","url":"https://example.com/%s","extractor_key":"Youtube"}\n' "$title" "$title" "$title" "$title"; done; exit 0 ;;
+private*) echo "ERROR: [youtube] $title: Private video. Sign in if you've been granted access to this video" >&2; exit 1 ;;
+restricted*) echo "ERROR: [youtube] $title: Sign in to confirm your age. This video may be inappropriate for some users." >&2; exit 1 ;;
+broken*) echo "ERROR: [youtube] $title: Unexpected response from the player" >&2; exit 1 ;;
+stuck*) exec sleep 30 ;;
+live*) printf '{"id":"%s","title":"%s","webpage_url":"https://example.com/%s","url":"https://media.example.com/%s","is_live":true,"extractor_key":"Youtube"}\n' "$title" "$title" "$title" "$title"; exit 0 ;;
+marathon*) printf '{"id":"%s","title":"%s","webpage_url":"https://example.com/%s","url":"https://media.example.com/%s","duration":600,"extractor_key":"Youtube"}\n' "$title" "$title" "$title" "$title"; exit 0 ;;
+esac
+printf '{"id":"%s","title":"%s","webpage_url":"https://example.com/%s","url":"https://media.example.com/%s","duration":1,"extractor_key":"Youtube"}\n' "$title" "$title" "$title" "$title"
+`
+
+// fakeFFMpeg decodes silence in real time: forever for streams named
+// "forever", for a second for "slow", not at all for "dead" and ten frames
+// for anything else.
+// Unlike ffmpeg it forks, so stderr is closed to keep the children from
+// holding the pipe open after the script is killed.
+const fakeFFMpeg = `#!/bin/sh
+exec 2>/dev/null
+frame() { head -c 3840 /dev/zero || exit 0; sleep 0.02; }
+case "$*" in
+*forever*) while :; do frame; done ;;
+*slow*) i=0; while [ $i -lt 50 ]; do frame; i=$((i+1)); done ;;
+*dead*) exit 1 ;;
+*) head -c 38400 /dev/zero ;;
+esac
+`
+
+// fakeFFProbe tags library files as Artist/Album/Title.ext.
+const fakeFFProbe = `#!/bin/sh
+for file; do :; done
+name=$(basename "$file")
+album=$(dirname "$file")
+artist=$(dirname "$album")
+printf '{"format":{"duration":"1","tags":{"title":"%s","album":"%s","artist":"%s"}}}\n' "${name%.*}" "$(basename "$album")" "$(basename "$artist")"
+`
+
+// testLibrary lists the files in the test bot's library.
+var testLibrary = []string{"Kosmos/Nebula/slow-orbit.flac", "Kosmos/Nebula/gravity.flac", "Zora/Comet/tail.mp3"}
+
+// newTestBot returns a bot wired to a fakeSession whose listeners sit in the
+// test voice channel, with stand-ins for yt-dlp, ffmpeg and ffprobe and a
+// small library that has not been scanned yet.
+func newTestBot(t *testing.T, listeners ...string) (*Kvazar, *fakeSession) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake yt-dlp and ffmpeg are shell scripts")
+	}
+
+	dir := t.TempDir()
+	script := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	music := filepath.Join(dir, "music")
+	for _, file := range testLibrary {
+		file = filepath.Join(music, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	session := newFakeSession(listeners...)
+	k, err := newKvazar(Config{
+		FFMpegPath:  script("ffmpeg", fakeFFMpeg),
+		FFProbePath: script("ffprobe", fakeFFProbe),
+		YTDLPPath:   script("yt-dlp", fakeYTDLP),
+		DataDir:     filepath.Join(dir, "data"),
+		LibraryDir:  music,
+	}, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, player := range k.snapshotPlayers() {
+			player.Shutdown()
+		}
+	})
+	return k, session
+}
+
+var interactionIDs struct {
+	sync.Mutex
+	next int
+}
+
+func newInteraction(typ discordgo.InteractionType, userID string, data discordgo.InteractionData) *discordgo.InteractionCreate {
+	interactionIDs.Lock()
+	interactionIDs.next++
+	id := fmt.Sprintf("interaction-%d", interactionIDs.next)
+	interactionIDs.Unlock()
+
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:        id,
+		Type:      typ,
+		GuildID:   testGuild,
+		ChannelID: testText,
+		Member:    &discordgo.Member{User: &discordgo.User{ID: userID}},
+		Data:      data,
+	}}
+}
+
+// slash builds a slash command interaction with a single string option.
+func slash(userID, command string, value ...string) *discordgo.InteractionCreate {
+	data := discordgo.ApplicationCommandInteractionData{Name: command}
+	for _, v := range value {
+		data.Options = append(data.Options, &discordgo.ApplicationCommandInteractionDataOption{
+			Name: "query", Type: discordgo.ApplicationCommandOptionString, Value: v,
+		})
+	}
+	return newInteraction(discordgo.InteractionApplicationCommand, userID, data)
+}
+
+func button(userID, customID string) *discordgo.InteractionCreate {
+	return newInteraction(discordgo.InteractionMessageComponent, userID, discordgo.MessageComponentInteractionData{
+		CustomID: customID, ComponentType: discordgo.ButtonComponent,
+	})
+}
+
+// asDJ gives the interaction's member the permissions of a DJ.
+func asDJ(ic *discordgo.InteractionCreate) *discordgo.InteractionCreate {
+	ic.Member.Permissions = discordgo.PermissionVoiceMoveMembers
+	return ic
+}
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,285 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+)
+
+const (
+	guildsBucket = "guilds"
+
+	defaultVolume         = 100
+	defaultLoudnessTarget = -16.0
+)
+
+// guildSettings holds the per-guild configuration persisted in the store.
+// Zero values fall back to the bot-wide defaults.
+type guildSettings struct {
+	DJRoleID string                   `json:"dj_role_id,omitempty"`
+	Commands map[string]commandPolicy `json:"commands,omitempty"`
+	Limits   *QueueLimits             `json:"limits,omitempty"`
+	Locale   string                   `json:"locale,omitempty"`
+
+	AnnounceChannelID string        `json:"announce_channel_id,omitempty"`
+	AnnounceMode      string        `json:"announce_mode,omitempty"`
+	Volume            int           `json:"volume,omitempty"`
+	Normalize         *bool         `json:"normalize,omitempty"`
+	LoudnessTarget    float64       `json:"loudness_target,omitempty"`
+	IdleTimeout       time.Duration `json:"idle_timeout,omitempty"`
+	Autoplay          bool          `json:"autoplay,omitempty"`
+}
+
+// settingNames lists what /settings reset accepts.
+var settingNames = []string{
+	"announce_channel", "announce_mode", "volume", "normalize", "loudness", "idle_timeout",
+	"dj_role", "max_queue", "locale", "autoplay",
+}
+
+// commandPolicy restricts a command to, or excludes it from, a set of roles.
+type commandPolicy struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+func (g guildSettings) clone() guildSettings {
+	out := g
+	if g.Limits != nil {
+		limits := *g.Limits
+		out.Limits = &limits
+	}
+	if g.Normalize != nil {
+		normalize := *g.Normalize
+		out.Normalize = &normalize
+	}
+	if g.Commands != nil {
+		out.Commands = make(map[string]commandPolicy, len(g.Commands))
+		for name, policy := range g.Commands {
+			out.Commands[name] = commandPolicy{
+				Allow: append([]string(nil), policy.Allow...),
+				Deny:  append([]string(nil), policy.Deny...),
+			}
+		}
+	}
+	return out
+}
+
+// guildSettings returns a copy of the guild's settings, loading them from the store on first use.
+func (k *Kvazar) guildSettings(guildID string) guildSettings {
+	k.settingsMu.Lock()
+	defer k.settingsMu.Unlock()
+	return k.guildSettingsLocked(guildID).clone()
+}
+
+func (k *Kvazar) guildSettingsLocked(guildID string) *guildSettings {
+	if cached, ok := k.settings[guildID]; ok {
+		return cached
+	}
+
+	settings := &guildSettings{}
+	if guildID != "" {
+		if _, err := k.store.Load(guildsBucket, guildID, settings); err != nil {
+			log.Printf("failed to load settings for guild %s: %v", guildID, err)
+			settings = &guildSettings{}
+		}
+	}
+	k.settings[guildID] = settings
+	return settings
+}
+
+// updateGuildSettings applies mutate to the guild's settings and persists the result.
+func (k *Kvazar) updateGuildSettings(guildID string, mutate func(*guildSettings)) error {
+	k.settingsMu.Lock()
+	defer k.settingsMu.Unlock()
+
+	updated := k.guildSettingsLocked(guildID).clone()
+	mutate(&updated)
+	if err := k.store.Save(guildsBucket, guildID, updated); err != nil {
+		return err
+	}
+	k.settings[guildID] = &updated
+	return nil
+}
+
+func (g guildSettings) volume() int {
+	if g.Volume <= 0 {
+		return defaultVolume
+	}
+	return g.Volume
+}
+
+func (g guildSettings) normalize() bool {
+	return g.Normalize == nil || *g.Normalize
+}
+
+func (g guildSettings) loudnessTarget() float64 {
+	if g.LoudnessTarget == 0 {
+		return defaultLoudnessTarget
+	}
+	return g.LoudnessTarget
+}
+
+// idleTimeout returns how long the guild's player may sit idle in a voice channel.
+func (k *Kvazar) idleTimeout(guildID string) time.Duration {
+	if timeout := k.guildSettings(guildID).IdleTimeout; timeout > 0 {
+		return timeout
+	}
+	return k.currentSettings().DisconnectDelay
+}
+
+func (k *Kvazar) handleSettings(ic *discordgo.InteractionCreate) {
+	data := ic.ApplicationCommandData()
+	lang := k.lang(ic)
+	if ic.GuildID == "" || len(data.Options) == 0 {
+		k.respondError(ic, lang.T(i18n.ErrGuildOnly))
+		return
+	}
+
+	sub := data.Options[0]
+	var err error
+	switch sub.Name {
+	case "show":
+	case "set":
+		// Changing max_queue copies the bot-wide limit defaults into the guild.
+		limits := k.queueLimits(ic.GuildID)
+		err = k.updateGuildSettings(ic.GuildID, func(s *guildSettings) {
+			for _, opt := range sub.Options {
+				switch opt.Name {
+				case "announce_channel":
+					s.AnnounceChannelID = fmt.Sprint(opt.Value)
+				case "announce_mode":
+					s.AnnounceMode = opt.StringValue()
+				case "volume":
+					s.Volume = int(opt.IntValue())
+				case "normalize":
+					normalize := opt.BoolValue()
+					s.Normalize = &normalize
+				case "loudness":
+					s.LoudnessTarget = opt.FloatValue()
+				case "idle_timeout":
+					s.IdleTimeout = time.Duration(opt.IntValue()) * time.Minute
+				case "dj_role":
+					s.DJRoleID = fmt.Sprint(opt.Value)
+				case "max_queue":
+					limits.MaxQueue = int(opt.IntValue())
+					s.Limits = &limits
+				case "locale":
+					s.Locale = ""
+					if lang, ok := i18n.Parse(opt.StringValue()); ok {
+						s.Locale = string(lang)
+					}
+				case "autoplay":
+					s.Autoplay = opt.BoolValue()
+				}
+			}
+		})
+	case "reset":
+		name := optionString(sub.Options, "setting")
+		defaults := k.currentSettings().Limits
+		err = k.updateGuildSettings(ic.GuildID, func(s *guildSettings) {
+			switch name {
+			case "announce_channel":
+				s.AnnounceChannelID = ""
+			case "announce_mode":
+				s.AnnounceMode = ""
+			case "volume":
+				s.Volume = 0
+			case "normalize":
+				s.Normalize = nil
+			case "loudness":
+				s.LoudnessTarget = 0
+			case "idle_timeout":
+				s.IdleTimeout = 0
+			case "dj_role":
+				s.DJRoleID = ""
+			case "max_queue":
+				if s.Limits != nil {
+					s.Limits.MaxQueue = defaults.MaxQueue
+				}
+			case "locale":
+				s.Locale = ""
+			case "autoplay":
+				s.Autoplay = false
+			}
+		})
+		if err == nil {
+			lang = k.lang(ic)
+			k.respondEphemeral(ic, lang.T(i18n.SettingsReset, name)+"\n\n"+k.describeSettings(lang, ic.GuildID))
+			return
+		}
+	default:
+		k.respondError(ic, lang.T(i18n.ErrUnknownSubcommand))
+		return
+	}
+
+	if err != nil {
+		log.Printf("failed to save settings for guild %s: %v", ic.GuildID, err)
+		k.respondError(ic, lang.T(i18n.ErrSaveSettings))
+		return
+	}
+	// The locale may have just changed.
+	k.respondEphemeral(ic, k.describeSettings(k.lang(ic), ic.GuildID))
+}
+
+func (k *Kvazar) describeSettings(lang i18n.Lang, guildID string) string {
+	settings := k.guildSettings(guildID)
+	orDefault := func(value string, isDefault bool) string {
+		if isDefault {
+			return lang.T(i18n.SettingsDefaultSuffix, value)
+		}
+		return value
+	}
+	onOff := func(enabled bool) string {
+		if enabled {
+			return lang.T(i18n.SettingsOn)
+		}
+		return lang.T(i18n.SettingsOff)
+	}
+
+	announce := lang.T(i18n.SettingsAnnounceDefault)
+	if settings.AnnounceChannelID != "" {
+		announce = fmt.Sprintf("<#%s>", settings.AnnounceChannelID)
+	}
+
+	normalize := lang.T(i18n.SettingsNormalizeOff)
+	if settings.normalize() {
+		normalize = lang.T(i18n.SettingsNormalizeOn, settings.loudnessTarget())
+	}
+
+	idle := media.Track{Duration: k.idleTimeout(guildID)}.HumanDuration()
+
+	djRole := lang.T(i18n.PermDJUnset)
+	if settings.DJRoleID != "" {
+		djRole = fmt.Sprintf("<@&%s>", settings.DJRoleID)
+	}
+
+	maxQueue := lang.T(i18n.LimitsOff)
+	if limit := k.queueLimits(guildID).MaxQueue; limit > 0 {
+		maxQueue = fmt.Sprintf("%d", limit)
+	}
+
+	locale := lang.T(i18n.LangAutoChoice)
+	if chosen, ok := i18n.Parse(settings.Locale); ok {
+		locale = chosen.T(i18n.LangName)
+	}
+
+	lines := []string{
+		lang.T(i18n.SettingsTitle),
+		lang.T(i18n.SettingsAnnounceChannel, announce),
+		lang.T(i18n.SettingsAnnounceMode, orDefault(lang.T(announceModeKeys[settings.announceMode()]), settings.AnnounceMode == "")),
+		lang.T(i18n.SettingsVolume, settings.volume()),
+		lang.T(i18n.SettingsNormalize, orDefault(normalize, settings.Normalize == nil && settings.LoudnessTarget == 0)),
+		lang.T(i18n.SettingsIdleTimeout, orDefault(idle, settings.IdleTimeout == 0)),
+		lang.T(i18n.PermDJRole, djRole),
+		lang.T(i18n.LimitsMaxQueue, orDefault(maxQueue, settings.Limits == nil)),
+		lang.T(i18n.SettingsLocale, locale),
+		lang.T(i18n.SettingsAutoplay, onOff(settings.Autoplay)),
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAudioFilters(t *testing.T) {
+	off := false
+	tests := []struct {
+		settings guildSettings
+		want     string
+	}{
+		{guildSettings{}, "loudnorm=I=-16:LRA=11:TP=-1.5"},
+		{guildSettings{LoudnessTarget: -23, Volume: 50}, "loudnorm=I=-23:LRA=11:TP=-1.5,volume=0.50"},
+		{guildSettings{Normalize: &off, Volume: 150}, "volume=1.50"},
+		{guildSettings{Normalize: &off, Volume: defaultVolume}, ""},
+	}
+	for _, tt := range tests {
+		if got := audioFilters(tt.settings); got != tt.want {
+			t.Errorf("audioFilters(%+v) = %q, want %q", tt.settings, got, tt.want)
+		}
+	}
+}
+
+func TestIdleTimeoutDisconnects(t *testing.T) {
+	k, s := newTestBot(t, "alice")
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	if got := k.idleTimeout(testGuild); got != defaultIdleDelay {
+		t.Errorf("idleTimeout = %s before it was set, want %s", got, defaultIdleDelay)
+	}
+	if err := k.updateGuildSettings(testGuild, func(s *guildSettings) { s.IdleTimeout = 100 * time.Millisecond }); err != nil {
+		t.Fatal(err)
+	}
+
+	play(t, k, s, "alice", "intro")
+	nextEvent(t, events, EventTrackEnded)
+	if event := nextEvent(t, events, EventVoiceDisconnected); event.Data.(VoiceEvent).ChannelID != testVoice {
+		t.Errorf("left %+v, want the voice channel", event.Data)
+	}
+}
+
+// TestAutoplayKeepsToLimits checks that autoplay follows the last track with
+// one from its mix, passing over those the guild's limits forbid.
+func TestAutoplayKeepsToLimits(t *testing.T) {
+	k, s := newTestBot(t, "alice")
+	events, cancel := k.Subscribe(testGuild)
+	defer cancel()
+
+	err := k.updateGuildSettings(testGuild, func(s *guildSettings) {
+		s.Autoplay = true
+		s.Limits = &QueueLimits{RejectLive: true, MaxDuration: 5 * time.Minute}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	play(t, k, s, "alice", "seed")
+	for _, want := range []string{"seed", "mix"} {
+		if title := trackTitle(nextEvent(t, events, EventTrackStarted)); title != want {
+			t.Fatalf("started %q, want %q", title, want)
+		}
+	}
+
+	// Every other entry of the mix is either played or forbidden.
+	player := k.findPlayer(testGuild)
+	waitFor(t, "the player to go idle", func() bool {
+		player.mu.Lock()
+		defer player.mu.Unlock()
+		return !player.playing
+	})
+	if _, _, history := player.QueueSnapshot(); len(history) != 2 {
+		t.Errorf("history = %v, want seed and mix", history)
+	}
+}
@@ -0,0 +1,319 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// gatewayGrace is how long the gateway may stay disconnected while
+	// discordgo reconnects before the bot counts as unhealthy.
+	gatewayGrace = 2 * time.Minute
+	// heartbeatStale is how old the last heartbeat acknowledgement may be.
+	heartbeatStale = 3 * time.Minute
+
+	toolProbeInterval = 5 * time.Minute
+	toolProbeTimeout  = 5 * time.Second
+
+	playbackWindow = 15 * time.Minute
+	// A sustained error rate above maxErrorRate, over at least
+	// minErrorSamples tracks, marks the bot as degraded.
+	maxErrorRate    = 0.5
+	minErrorSamples = 4
+)
+
+// Status is a point-in-time view of the bot's health.
+type Status struct {
+	// Healthy is false when the bot cannot recover by itself and should be
+	// restarted. Ready is false when it cannot serve requests right now.
+	Healthy  bool     `json:"healthy"`
+	Ready    bool     `json:"ready"`
+	Problems []string `json:"problems,omitempty"`
+
+	StartedAt        time.Time      `json:"started_at"`
+	Gateway          GatewayStatus  `json:"gateway"`
+	Tools            []ToolStatus   `json:"tools"`
+	Players          int            `json:"players"`
+	VoiceConnections int            `json:"voice_connections"`
+	Playback         PlaybackStatus `json:"playback"`
+}
+
+// GatewayStatus describes the Discord gateway connection.
+type GatewayStatus struct {
+	Connected bool `json:"connected"`
+	// Since is when the connection last went up or down.
+	Since              time.Time `json:"since"`
+	HeartbeatLatencyMS int64     `json:"heartbeat_latency_ms"`
+	LastHeartbeatAck   time.Time `json:"last_heartbeat_ack"`
+}
+
+// ToolStatus describes an external binary Kvazar depends on.
+type ToolStatus struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Found   bool      `json:"found"`
+	Version string    `json:"version,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Checked time.Time `json:"checked"`
+}
+
+// PlaybackStatus summarises how recent tracks ended.
+type PlaybackStatus struct {
+	WindowSeconds int     `json:"window_seconds"`
+	Tracks        int     `json:"tracks"`
+	Errors        int     `json:"errors"`
+	ErrorRate     float64 `json:"error_rate"`
+}
+
+// gatewayState follows the gateway connection through discordgo's events.
+type gatewayState struct {
+	mu        sync.Mutex
+	connected bool
+	since     time.Time
+}
+
+func (g *gatewayState) set(connected bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.connected != connected || g.since.IsZero() {
+		g.connected = connected
+		g.since = time.Now()
+	}
+}
+
+func (g *gatewayState) get() (bool, time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.connected, g.since
+}
+
+func (k *Kvazar) onConnect(_ *discordgo.Session, _ *discordgo.Connect) {
+	k.gateway.set(true)
+}
+
+func (k *Kvazar) onDisconnect(_ *discordgo.Session, _ *discordgo.Disconnect) {
+	k.gateway.set(false)
+}
+
+// playbackLog keeps the outcome of the tracks that finished within playbackWindow.
+type playbackLog struct {
+	mu      sync.Mutex
+	entries []playbackEntry
+}
+
+type playbackEntry struct {
+	at     time.Time
+	failed bool
+}
+
+func (l *playbackLog) record(failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pruneLocked(time.Now())
+	l.entries = append(l.entries, playbackEntry{at: time.Now(), failed: failed})
+}
+
+func (l *playbackLog) snapshot() PlaybackStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pruneLocked(time.Now())
+
+	status := PlaybackStatus{WindowSeconds: int(playbackWindow / time.Second), Tracks: len(l.entries)}
+	for _, entry := range l.entries {
+		if entry.failed {
+			status.Errors++
+		}
+	}
+	if status.Tracks > 0 {
+		status.ErrorRate = float64(status.Errors) / float64(status.Tracks)
+	}
+	return status
+}
+
+func (l *playbackLog) pruneLocked(now time.Time) {
+	cutoff := now.Add(-playbackWindow)
+	keep := 0
+	for keep < len(l.entries) && l.entries[keep].at.Before(cutoff) {
+		keep++
+	}
+	l.entries = l.entries[keep:]
+}
+
+// toolProbe caches the result of checking the external binaries, so health
+// checks do not spawn processes on every request. Only one check runs at a
+// time, detached from the request that started it: a health check that gives
+// up early gets the previous results, and the check still completes for the
+// next one.
+type toolProbe struct {
+	mu      sync.Mutex
+	results []ToolStatus
+	expires time.Time
+	running chan struct{}
+}
+
+func (t *toolProbe) get(ctx context.Context, tools map[string]string) []ToolStatus {
+	t.mu.Lock()
+	if len(t.results) > 0 && time.Now().Before(t.expires) {
+		defer t.mu.Unlock()
+		return append([]ToolStatus(nil), t.results...)
+	}
+	if t.running == nil {
+		t.running = make(chan struct{})
+		go t.probe(context.WithoutCancel(ctx), tools, t.running)
+	}
+	running := t.running
+	t.mu.Unlock()
+
+	select {
+	case <-running:
+	case <-ctx.Done():
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.results) == 0 {
+		// The first check has not finished in time.
+		results := make([]ToolStatus, 0, len(tools))
+		for _, name := range toolNames {
+			results = append(results, ToolStatus{Name: name, Path: tools[name], Error: "still being checked", Checked: time.Now()})
+		}
+		return results
+	}
+	return append([]ToolStatus(nil), t.results...)
+}
+
+// probe checks the tools and stores the results. Results of a check that
+// timed out are kept only until the next health check, which tries again.
+func (t *toolProbe) probe(ctx context.Context, tools map[string]string, done chan struct{}) {
+	results := make([]ToolStatus, 0, len(tools))
+	expires := time.Now().Add(toolProbeInterval)
+	for _, name := range toolNames {
+		status, timedOut := probeTool(ctx, name, tools[name])
+		if timedOut {
+			expires = time.Time{}
+		}
+		results = append(results, status)
+	}
+
+	t.mu.Lock()
+	t.results, t.expires, t.running = results, expires, nil
+	t.mu.Unlock()
+	close(done)
+}
+
+// toolNames are the external binaries, in the order they are reported.
+var toolNames = []string{"ffmpeg", "yt-dlp"}
+
+// probeTool checks one binary and reports whether the check ran out of time.
+func probeTool(ctx context.Context, name, path string) (ToolStatus, bool) {
+	status := ToolStatus{Name: name, Path: path, Checked: time.Now()}
+
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		status.Error = err.Error()
+		return status, false
+	}
+	status.Found = true
+
+	// ffmpeg only understands -version; yt-dlp accepts both spellings.
+	ctx, cancel := context.WithTimeout(ctx, toolProbeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, resolved, "-version").Output()
+	if err != nil && name == "yt-dlp" && ctx.Err() == nil {
+		out, err = exec.CommandContext(ctx, resolved, "--version").Output()
+	}
+	if err != nil {
+		status.Error = fmt.Sprintf("version check: %v", err)
+		return status, ctx.Err() != nil
+	}
+	status.Version = parseToolVersion(string(out))
+	return status, false
+}
+
+// parseToolVersion extracts the version from "ffmpeg version 6.0 Copyright ..."
+// or from yt-dlp's bare "2024.03.10".
+func parseToolVersion(output string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	fields := strings.Fields(line)
+	for i, field := range fields {
+		if field == "version" && i+1 < len(fields) {
+			return fields[i+1]
+		}
+	}
+	return strings.TrimSpace(line)
+}
+
+// checkTools logs missing binaries at startup instead of at the first /play.
+func (k *Kvazar) checkTools(ctx context.Context) {
+	for _, tool := range k.Status(ctx).Tools {
+		if tool.Error != "" {
+			log.Printf("warning: %s is unavailable: %s", tool.Name, tool.Error)
+			continue
+		}
+		log.Printf("found %s %s at %s", tool.Name, tool.Version, tool.Path)
+	}
+}
+
+// Status reports the current state of the gateway, the external tools and
+// playback.
+func (k *Kvazar) Status(ctx context.Context) Status {
+	status := Status{
+		StartedAt: k.startedAt,
+		Tools: k.tools.get(ctx, map[string]string{
+			"ffmpeg": k.ffmpegPath,
+			"yt-dlp": k.resolver.Executable,
+		}),
+		Playback: k.playback.snapshot(),
+	}
+
+	connected, since := k.gateway.get()
+	lastAck, latency := k.session.Heartbeat()
+	status.Gateway = GatewayStatus{
+		Connected:          connected,
+		Since:              since,
+		HeartbeatLatencyMS: latency.Milliseconds(),
+		LastHeartbeatAck:   lastAck,
+	}
+
+	for _, player := range k.snapshotPlayers() {
+		status.Players++
+		player.mu.Lock()
+		if player.voice != nil {
+			status.VoiceConnections++
+		}
+		player.mu.Unlock()
+	}
+
+	status.Healthy, status.Ready = true, true
+	problem := func(fatal bool, format string, args ...any) {
+		status.Problems = append(status.Problems, fmt.Sprintf(format, args...))
+		status.Ready = false
+		if fatal {
+			status.Healthy = false
+		}
+	}
+
+	switch {
+	case !connected && since.IsZero():
+		problem(false, "gateway has not connected yet")
+	case !connected:
+		problem(time.Since(since) > gatewayGrace, "gateway disconnected since %s", since.Format(time.RFC3339))
+	case time.Since(lastAck) > heartbeatStale:
+		problem(true, "no heartbeat acknowledged since %s", lastAck.Format(time.RFC3339))
+	}
+	for _, tool := range status.Tools {
+		if !tool.Found || tool.Error != "" {
+			problem(false, "%s unavailable: %s", tool.Name, tool.Error)
+		}
+	}
+	if status.Playback.Tracks >= minErrorSamples && status.Playback.ErrorRate > maxErrorRate {
+		problem(false, "%d of the last %d tracks failed", status.Playback.Errors, status.Playback.Tracks)
+	}
+	return status
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseToolVersion(t *testing.T) {
+	tests := map[string]string{
+		"ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers\nbuilt with gcc 13": "6.1.1-3ubuntu5",
+		"ffmpeg version n7.0 Copyright (c) 2000-2024":                                                    "n7.0",
+		"2024.03.10\n":                     "2024.03.10",
+		"  2025.01.26  ":                   "2025.01.26",
+		"":                                 "",
+		"usage: tool [options]\nversion 1": "usage: tool [options]",
+	}
+	for output, want := range tests {
+		if got := parseToolVersion(output); got != want {
+			t.Errorf("parseToolVersion(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+func TestPlaybackLog(t *testing.T) {
+	var log playbackLog
+	now := time.Now()
+	log.entries = []playbackEntry{
+		{at: now.Add(-playbackWindow - time.Minute), failed: true},
+		{at: now.Add(-playbackWindow - time.Second), failed: true},
+		{at: now.Add(-time.Minute), failed: true},
+	}
+	log.record(false)
+	log.record(false)
+	log.record(true)
+
+	got := log.snapshot()
+	want := PlaybackStatus{WindowSeconds: int(playbackWindow / time.Second), Tracks: 4, Errors: 2, ErrorRate: 0.5}
+	if got != want {
+		t.Errorf("snapshot = %+v, want %+v", got, want)
+	}
+	if len(log.entries) != 4 {
+		t.Errorf("kept %d entries, want the 4 within the window", len(log.entries))
+	}
+}
+
+func TestStatus(t *testing.T) {
+	k, s := newTestBot(t)
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		connected bool
+		since     time.Time
+		lastAck   time.Time
+		failures  int
+		healthy   bool
+		ready     bool
+		problem   string
+	}{
+		{"connected", true, now.Add(-time.Hour), now, 0, true, true, ""},
+		{"never connected", false, time.Time{}, now, 0, true, false, "gateway has not connected yet"},
+		{"reconnecting", false, now.Add(-gatewayGrace / 2), now, 0, true, false, "gateway disconnected since"},
+		{"disconnected too long", false, now.Add(-gatewayGrace - time.Second), now, 0, false, false, "gateway disconnected since"},
+		{"stale heartbeat", true, now.Add(-time.Hour), now.Add(-heartbeatStale - time.Second), 0, false, false, "no heartbeat acknowledged"},
+		{"failing playback", true, now.Add(-time.Hour), now, minErrorSamples, true, false, "4 of the last 4 tracks failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k.gateway = gatewayState{connected: tt.connected, since: tt.since}
+			s.mu.Lock()
+			s.lastAck = tt.lastAck
+			s.mu.Unlock()
+			k.playback = playbackLog{}
+			for i := 0; i < tt.failures; i++ {
+				k.playback.record(true)
+			}
+
+			status := k.Status(context.Background())
+			if status.Healthy != tt.healthy || status.Ready != tt.ready {
+				t.Errorf("healthy, ready = %v, %v; want %v, %v (problems %q)", status.Healthy, status.Ready, tt.healthy, tt.ready, status.Problems)
+			}
+			if tt.problem == "" && len(status.Problems) > 0 {
+				t.Errorf("problems = %q, want none", status.Problems)
+			}
+			if tt.problem != "" && (len(status.Problems) != 1 || !strings.HasPrefix(status.Problems[0], tt.problem)) {
+				t.Errorf("problems = %q, want %q", status.Problems, tt.problem)
+			}
+		})
+	}
+}
+
+// TestToolProbeOutlivesRequest checks that a health check giving up early
+// does not cut the tool check short or have a failure cached.
+func TestToolProbeOutlivesRequest(t *testing.T) {
+	k, _ := newTestBot(t)
+	tools := map[string]string{"ffmpeg": k.ffmpegPath, "yt-dlp": k.resolver.Executable}
+	var probe toolProbe
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, tool := range probe.get(ctx, tools) {
+		if tool.Found && tool.Error != "" {
+			t.Errorf("%s failed the check cut short: %s", tool.Name, tool.Error)
+		}
+	}
+	for _, tool := range probe.get(context.Background(), tools) {
+		if !tool.Found || tool.Error != "" {
+			t.Errorf("%s = %+v after the check finished", tool.Name, tool)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+)
+
+const defaultVoteSkipRatio = 0.5
+
+// requestSkip skips the current track for its requester and DJs, and registers a
+// vote for everybody else. It returns the user-facing outcome and whether the
+// request was accepted.
+func (k *Kvazar) requestSkip(ic *discordgo.InteractionCreate, player *Player) (string, bool) {
+	lang := k.lang(ic)
+	current, _, _ := player.QueueSnapshot()
+	if current == nil {
+		return lang.T(i18n.SkipNothing), false
+	}
+
+	userID := interactionUserID(ic)
+	if isRequester(current, userID) || k.isDJ(ic) {
+		if !player.Skip() {
+			return lang.T(i18n.SkipNothing), false
+		}
+		return lang.T(i18n.SkipDone), true
+	}
+
+	channelID := player.VoiceChannelID()
+	userChannel, err := locateVoiceChannel(k.session, ic.GuildID, userID)
+	if err != nil || channelID == "" || userChannel != channelID {
+		return lang.T(i18n.SkipSameChannel), false
+	}
+
+	required := requiredSkipVotes(k.countListeners(ic.GuildID, channelID), k.currentSettings().VoteSkipRatio)
+	votes, skipped, ok := player.VoteSkip(userID, required)
+	if !ok {
+		return lang.T(i18n.SkipNothing), false
+	}
+	if skipped {
+		return lang.T(i18n.SkipVotePassed, votes, required), true
+	}
+
+	k.refreshNowPlaying(player, required)
+	return lang.T(i18n.SkipVoteRecorded, votes, required), true
+}
+
+// countListeners returns the number of non-bot users in the given voice channel.
+func (k *Kvazar) countListeners(guildID, channelID string) int {
+	guild, err := k.session.CachedGuild(guildID)
+	if err != nil {
+		return 0
+	}
+
+	selfID := k.session.BotUserID()
+
+	listeners := 0
+	for _, vs := range guild.VoiceStates {
+		if vs.ChannelID != channelID || vs.UserID == selfID {
+			continue
+		}
+		if vs.Member != nil && vs.Member.User != nil && vs.Member.User.Bot {
+			continue
+		}
+		if member, err := k.session.CachedMember(guildID, vs.UserID); err == nil && member.User != nil && member.User.Bot {
+			continue
+		}
+		listeners++
+	}
+	return listeners
+}
+
+// refreshNowPlaying re-renders the current now-playing card with the vote
+// tally and the radio station's current song.
+func (k *Kvazar) refreshNowPlaying(player *Player, required int) {
+	player.mu.Lock()
+	msg := player.nowPlaying
+	current := player.current
+	loop := player.loop
+	votes := len(player.skipVotes)
+	streamTitle := player.streamTitle
+	player.mu.Unlock()
+
+	if msg == nil || current == nil {
+		return
+	}
+
+	lang := k.guildLang(player.guild)
+	embed := buildNowPlayingEmbed(lang, current, loop)
+	appendStreamTitleField(lang, embed, streamTitle)
+	appendSkipVotesField(lang, embed, votes, required)
+
+	loopLabel := lang.T(i18n.ButtonRepeat)
+	if loop {
+		loopLabel = lang.T(i18n.ButtonLoopOff)
+	}
+	embeds := []*discordgo.MessageEmbed{embed}
+	if _, err := k.session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         msg.ID,
+		Channel:    msg.ChannelID,
+		Embeds:     embeds,
+		Components: buildPlayerComponents(lang, loopLabel, loop),
+	}); err != nil {
+		log.Printf("failed to update now playing message: %v", err)
+	}
+}
+
+func appendSkipVotesField(lang i18n.Lang, embed *discordgo.MessageEmbed, votes, required int) {
+	if votes == 0 {
+		return
+	}
+	value := fmt.Sprintf("%d", votes)
+	if required > 0 {
+		value = fmt.Sprintf("%d/%d", votes, required)
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:   lang.T(i18n.EmbedSkipVotes),
+		Value:  value,
+		Inline: true,
+	})
+}
+
+func appendStreamTitleField(lang i18n.Lang, embed *discordgo.MessageEmbed, title string) {
+	if title == "" {
+		return
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:  lang.T(i18n.EmbedOnAir),
+		Value: title,
+	})
+}
+
+// requiredSkipVotes converts the configured ratio into a vote count, never less than one.
+func requiredSkipVotes(listeners int, ratio float64) int {
+	required := int(math.Ceil(float64(listeners) * ratio))
+	if required < 1 {
+		required = 1
+	}
+	return required
+}
+
+func isRequester(track *media.Track, userID string) bool {
+	return userID != "" && track.RequestedBy == fmt.Sprintf("<@%s>", userID)
+}
+
+func pickRatio(value, fallback float64) float64 {
+	if value <= 0 || value > 1 {
+		return fallback
+	}
+	return value
+}
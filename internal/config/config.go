@@ -0,0 +1,429 @@
+// Package config loads Kvazar's settings from an optional YAML file and the
+// environment. Environment variables always win over values from the file.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"kvazar/internal/i18n"
+	"kvazar/internal/media"
+)
+
+// minAPITokenLength keeps the REST API from being opened with a guessable token.
+const minAPITokenLength = 16
+
+// proxySchemes are the proxy URL schemes yt-dlp supports.
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks4": true, "socks4a": true, "socks5": true, "socks5h": true}
+
+// Config is the complete bot configuration.
+type Config struct {
+	Discord  Discord  `yaml:"discord"`
+	Media    Media    `yaml:"media"`
+	Audio    Audio    `yaml:"audio"`
+	Playback Playback `yaml:"playback"`
+	Limits   Limits   `yaml:"limits"`
+	Storage  Storage  `yaml:"storage"`
+	HTTP     HTTP     `yaml:"http"`
+}
+
+// Discord configures the gateway connection and command registration.
+type Discord struct {
+	Token           string `yaml:"token"`
+	Status          string `yaml:"status"`
+	CommandGuildID  string `yaml:"command_guild_id"`
+	CleanupCommands bool   `yaml:"cleanup_commands"`
+}
+
+// Media configures the external binaries used to resolve and decode audio.
+type Media struct {
+	FFMpegPath     string        `yaml:"ffmpeg_path"`
+	FFProbePath    string        `yaml:"ffprobe_path"`
+	YTDLPPath      string        `yaml:"ytdlp_path"`
+	ResolveTimeout time.Duration `yaml:"resolve_timeout"`
+	// ResolveWorkers caps how many yt-dlp processes run at once; 0 means no
+	// cap.
+	ResolveWorkers int `yaml:"resolve_workers"`
+	// ResolverBackend is "exec", which starts yt-dlp for every lookup, or
+	// "helper", which keeps one Python process with yt_dlp loaded and falls
+	// back to exec while it is unavailable.
+	ResolverBackend string `yaml:"resolver_backend"`
+	// PythonPath runs the helper; it must be able to import yt_dlp.
+	PythonPath string `yaml:"python_path"`
+	// LibraryDir enables the local music library with the audio files found
+	// in this directory.
+	LibraryDir string `yaml:"library_dir"`
+	// Spotify reads Spotify links through the Web API. Without it the public
+	// embed player pages are read instead.
+	Spotify Spotify `yaml:"spotify"`
+	Cache   Cache   `yaml:"cache"`
+	YTDLP   YTDLP   `yaml:"ytdlp"`
+}
+
+// YTDLP lets yt-dlp reach age-restricted and region-locked videos. The proxy
+// and the cookies also apply when ffmpeg fetches the stream.
+type YTDLP struct {
+	// CookiesFile is a Netscape cookies.txt file exported from a signed-in
+	// browser. yt-dlp writes refreshed cookies back to it.
+	CookiesFile string `yaml:"cookies_file"`
+	// Proxy is an http, https, socks4, socks4a, socks5 or socks5h URL.
+	// ffmpeg can only use http proxies.
+	Proxy string `yaml:"proxy"`
+	// SourceAddress is the local IP address to connect from.
+	SourceAddress string `yaml:"source_address"`
+	// ExtractorArgs maps an extractor to its arguments as --extractor-args
+	// takes them, such as youtube: "player_client=android,web".
+	ExtractorArgs map[string]string `yaml:"extractor_args"`
+}
+
+// Cache configures the cache of yt-dlp lookups.
+type Cache struct {
+	// MaxEntries is how many tracks are kept; 0 turns the cache off.
+	MaxEntries int `yaml:"max_entries"`
+	// TTL is how long a track's metadata is trusted. Stream URLs are kept
+	// only until they expire.
+	TTL time.Duration `yaml:"ttl"`
+	// Persist keeps the cache in the data directory across restarts.
+	Persist bool `yaml:"persist"`
+}
+
+// Spotify holds the credentials of an application registered in the Spotify
+// developer dashboard.
+type Spotify struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+}
+
+// Audio configures the voice stream.
+type Audio struct {
+	// BitrateKbps is the Opus bitrate in kilobits per second.
+	BitrateKbps     int           `yaml:"bitrate_kbps"`
+	DisconnectDelay time.Duration `yaml:"disconnect_delay"`
+}
+
+// Playback holds defaults for how guilds interact with the player.
+type Playback struct {
+	VoteSkipRatio float64 `yaml:"vote_skip_ratio"`
+	Locale        string  `yaml:"locale"`
+}
+
+// Limits are the queue limits for guilds that have not configured their own.
+type Limits struct {
+	MaxQueue     int           `yaml:"max_queue"`
+	MaxPerUser   int           `yaml:"max_per_user"`
+	MaxDuration  time.Duration `yaml:"max_duration"`
+	PlayCooldown time.Duration `yaml:"play_cooldown"`
+	RejectLive   bool          `yaml:"reject_live"`
+}
+
+// Storage configures persistence.
+type Storage struct {
+	DataDir string `yaml:"data_dir"`
+}
+
+// HTTP configures the health check server, the REST API and the dashboard.
+type HTTP struct {
+	Listen string `yaml:"listen"`
+	// APIToken enables the REST API; requests must send it as a bearer token.
+	// It is also the shared password of the dashboard.
+	APIToken string `yaml:"api_token"`
+	OAuth    OAuth  `yaml:"oauth"`
+	// PublicURL is the address Discord can reach the server under, such as
+	// https://kvazar.example.com. Library cover art is linked from there.
+	PublicURL string `yaml:"public_url"`
+}
+
+// OAuth enables signing in to the dashboard with Discord. The application is
+// the bot's own, found in the Discord developer portal.
+type OAuth struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	// RedirectURL is the dashboard's public callback address, for example
+	// https://kvazar.example.com/dashboard/auth/callback. It must also be
+	// registered with the application.
+	RedirectURL string `yaml:"redirect_url"`
+}
+
+// Enabled reports whether Discord sign-in is configured.
+func (o OAuth) Enabled() bool {
+	return o.ClientID != ""
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() Config {
+	return Config{
+		Media: Media{
+			FFMpegPath:      "ffmpeg",
+			FFProbePath:     "ffprobe",
+			YTDLPPath:       "yt-dlp",
+			ResolveTimeout:  20 * time.Second,
+			ResolveWorkers:  4,
+			ResolverBackend: media.BackendExec,
+			PythonPath:      "python3",
+			Cache:           Cache{MaxEntries: 1000, TTL: 72 * time.Hour},
+		},
+		Audio: Audio{
+			BitrateKbps:     128,
+			DisconnectDelay: 90 * time.Second,
+		},
+		Playback: Playback{
+			VoteSkipRatio: 0.5,
+			Locale:        string(i18n.Default),
+		},
+		Storage: Storage{DataDir: "data"},
+		HTTP:    HTTP{Listen: ":8080"},
+	}
+}
+
+// Load reads the file at path, when given, on top of the defaults, applies the
+// environment and validates the result.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) readFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides file values with the KVZ_* environment variables.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	str := func(name string, dst *string) {
+		if value, ok := lookup(name); ok && value != "" {
+			*dst = value
+		}
+	}
+	parse := func(name string, fn func(string) error) {
+		value, ok := lookup(name)
+		if !ok || value == "" {
+			return
+		}
+		if err := fn(strings.TrimSpace(value)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	integer := func(name string, dst *int) {
+		parse(name, func(value string) (err error) {
+			*dst, err = strconv.Atoi(value)
+			return err
+		})
+	}
+	duration := func(name string, dst *time.Duration) {
+		parse(name, func(value string) (err error) {
+			*dst, err = time.ParseDuration(value)
+			return err
+		})
+	}
+	boolean := func(name string, dst *bool) {
+		parse(name, func(value string) (err error) {
+			*dst, err = strconv.ParseBool(value)
+			return err
+		})
+	}
+
+	str("DISCORD_TOKEN", &c.Discord.Token)
+	str("KVZ_DISCORD_TOKEN", &c.Discord.Token)
+	str("KVZ_STATUS", &c.Discord.Status)
+	str("KVZ_COMMAND_GUILD_ID", &c.Discord.CommandGuildID)
+	boolean("KVZ_CLEANUP_COMMANDS", &c.Discord.CleanupCommands)
+
+	str("KVZ_FFMPEG_PATH", &c.Media.FFMpegPath)
+	str("KVZ_FFPROBE_PATH", &c.Media.FFProbePath)
+	str("KVZ_YTDLP_PATH", &c.Media.YTDLPPath)
+	duration("KVZ_RESOLVE_TIMEOUT", &c.Media.ResolveTimeout)
+	integer("KVZ_RESOLVE_WORKERS", &c.Media.ResolveWorkers)
+	str("KVZ_RESOLVER_BACKEND", &c.Media.ResolverBackend)
+	str("KVZ_PYTHON_PATH", &c.Media.PythonPath)
+	str("KVZ_YTDLP_COOKIES_FILE", &c.Media.YTDLP.CookiesFile)
+	str("KVZ_YTDLP_PROXY", &c.Media.YTDLP.Proxy)
+	str("KVZ_YTDLP_SOURCE_ADDRESS", &c.Media.YTDLP.SourceAddress)
+	parse("KVZ_YTDLP_EXTRACTOR_ARGS", func(value string) error {
+		// Extractors are separated by spaces, as in
+		// "youtube:player_client=android,web youtubetab:skip=webpage".
+		c.Media.YTDLP.ExtractorArgs = make(map[string]string)
+		for _, entry := range strings.Fields(value) {
+			extractor, args, ok := strings.Cut(entry, ":")
+			if !ok {
+				return fmt.Errorf("%q is not extractor:args", entry)
+			}
+			c.Media.YTDLP.ExtractorArgs[extractor] = args
+		}
+		return nil
+	})
+	str("KVZ_LIBRARY_DIR", &c.Media.LibraryDir)
+	str("KVZ_SPOTIFY_CLIENT_ID", &c.Media.Spotify.ClientID)
+	str("KVZ_SPOTIFY_CLIENT_SECRET", &c.Media.Spotify.ClientSecret)
+	integer("KVZ_CACHE_MAX_ENTRIES", &c.Media.Cache.MaxEntries)
+	duration("KVZ_CACHE_TTL", &c.Media.Cache.TTL)
+	boolean("KVZ_CACHE_PERSIST", &c.Media.Cache.Persist)
+
+	integer("KVZ_BITRATE_KBPS", &c.Audio.BitrateKbps)
+	duration("KVZ_DISCONNECT_DELAY", &c.Audio.DisconnectDelay)
+
+	parse("KVZ_VOTE_SKIP_RATIO", func(value string) (err error) {
+		c.Playback.VoteSkipRatio, err = strconv.ParseFloat(value, 64)
+		return err
+	})
+	str("KVZ_LOCALE", &c.Playback.Locale)
+
+	integer("KVZ_MAX_QUEUE", &c.Limits.MaxQueue)
+	integer("KVZ_MAX_PER_USER", &c.Limits.MaxPerUser)
+	duration("KVZ_MAX_DURATION", &c.Limits.MaxDuration)
+	duration("KVZ_PLAY_COOLDOWN", &c.Limits.PlayCooldown)
+	boolean("KVZ_REJECT_LIVE", &c.Limits.RejectLive)
+
+	str("KVZ_DATA_DIR", &c.Storage.DataDir)
+
+	// KVZ_HEALTH_PORT predates the listen address and only sets the port.
+	if port, ok := lookup("KVZ_HEALTH_PORT"); ok && port != "" {
+		c.HTTP.Listen = ":" + port
+	}
+	str("KVZ_HTTP_LISTEN", &c.HTTP.Listen)
+	str("KVZ_API_TOKEN", &c.HTTP.APIToken)
+	str("KVZ_OAUTH_CLIENT_ID", &c.HTTP.OAuth.ClientID)
+	str("KVZ_OAUTH_CLIENT_SECRET", &c.HTTP.OAuth.ClientSecret)
+	str("KVZ_OAUTH_REDIRECT_URL", &c.HTTP.OAuth.RedirectURL)
+	str("KVZ_PUBLIC_URL", &c.HTTP.PublicURL)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config: invalid environment: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(strings.TrimSpace(c.Discord.Token) != "", "discord.token is required (or set KVZ_DISCORD_TOKEN)")
+	check(strings.TrimSpace(c.Media.FFMpegPath) != "", "media.ffmpeg_path must not be empty")
+	check(strings.TrimSpace(c.Media.FFProbePath) != "", "media.ffprobe_path must not be empty")
+	check(strings.TrimSpace(c.Media.YTDLPPath) != "", "media.ytdlp_path must not be empty")
+	check(c.Media.ResolveTimeout > 0, "media.resolve_timeout must be positive, got %s", c.Media.ResolveTimeout)
+	check(c.Media.ResolveWorkers >= 0, "media.resolve_workers must not be negative")
+	check(c.Media.ResolverBackend == media.BackendExec || c.Media.ResolverBackend == media.BackendHelper,
+		"media.resolver_backend must be %q or %q, got %q", media.BackendExec, media.BackendHelper, c.Media.ResolverBackend)
+	check(c.Media.ResolverBackend != media.BackendHelper || strings.TrimSpace(c.Media.PythonPath) != "",
+		"media.python_path must not be empty with the helper backend")
+	if ytdlp := c.Media.YTDLP; ytdlp.CookiesFile != "" {
+		_, err := os.Stat(ytdlp.CookiesFile)
+		check(err == nil, "media.ytdlp.cookies_file: %v", err)
+	}
+	if proxy := c.Media.YTDLP.Proxy; proxy != "" {
+		parsed, err := url.Parse(proxy)
+		check(err == nil && proxySchemes[strings.ToLower(parsed.Scheme)] && parsed.Host != "",
+			"media.ytdlp.proxy must be an http, https or socks URL, got %q", proxy)
+	}
+	check(c.Media.YTDLP.SourceAddress == "" || net.ParseIP(c.Media.YTDLP.SourceAddress) != nil,
+		"media.ytdlp.source_address must be an IP address, got %q", c.Media.YTDLP.SourceAddress)
+	for extractor, args := range c.Media.YTDLP.ExtractorArgs {
+		check(extractor != "" && !strings.ContainsAny(extractor, ": "),
+			"media.ytdlp.extractor_args: %q is not an extractor name", extractor)
+		check(strings.Contains(args, "="),
+			"media.ytdlp.extractor_args.%s must be key=value pairs, got %q", extractor, args)
+	}
+	check((c.Media.Spotify.ClientID == "") == (c.Media.Spotify.ClientSecret == ""),
+		"media.spotify.client_id and media.spotify.client_secret must be set together")
+	check(c.Media.Cache.MaxEntries >= 0, "media.cache.max_entries must not be negative")
+	check(c.Media.Cache.MaxEntries == 0 || c.Media.Cache.TTL > 0, "media.cache.ttl must be positive, got %s", c.Media.Cache.TTL)
+	check(c.Audio.BitrateKbps >= 6 && c.Audio.BitrateKbps <= 510, "audio.bitrate_kbps must be between 6 and 510, got %d", c.Audio.BitrateKbps)
+	check(c.Audio.DisconnectDelay > 0, "audio.disconnect_delay must be positive, got %s", c.Audio.DisconnectDelay)
+	check(c.Playback.VoteSkipRatio > 0 && c.Playback.VoteSkipRatio <= 1, "playback.vote_skip_ratio must be in (0, 1], got %g", c.Playback.VoteSkipRatio)
+	if _, ok := i18n.Parse(c.Playback.Locale); !ok {
+		check(false, "playback.locale %q is not supported", c.Playback.Locale)
+	}
+	check(c.Limits.MaxQueue >= 0, "limits.max_queue must not be negative")
+	check(c.Limits.MaxPerUser >= 0, "limits.max_per_user must not be negative")
+	check(c.Limits.MaxDuration >= 0, "limits.max_duration must not be negative")
+	check(c.Limits.PlayCooldown >= 0, "limits.play_cooldown must not be negative")
+	if _, _, err := net.SplitHostPort(c.HTTP.Listen); err != nil {
+		check(false, "http.listen %q is not a valid address: %v", c.HTTP.Listen, err)
+	}
+	check(c.HTTP.APIToken == "" || len(c.HTTP.APIToken) >= minAPITokenLength,
+		"http.api_token must be at least %d characters long", minAPITokenLength)
+	if oauth := c.HTTP.OAuth; oauth.Enabled() {
+		check(oauth.ClientSecret != "", "http.oauth.client_secret is required with http.oauth.client_id")
+		redirect, err := url.Parse(oauth.RedirectURL)
+		check(err == nil && (redirect.Scheme == "http" || redirect.Scheme == "https") && redirect.Host != "",
+			"http.oauth.redirect_url must be an absolute http(s) URL, got %q", oauth.RedirectURL)
+	}
+	if c.HTTP.PublicURL != "" {
+		public, err := url.Parse(c.HTTP.PublicURL)
+		check(err == nil && (public.Scheme == "http" || public.Scheme == "https") && public.Host != "",
+			"http.public_url must be an absolute http(s) URL, got %q", c.HTTP.PublicURL)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// RestartRequired lists the settings that differ between c and next but only
+// take effect after a restart.
+func (c Config) RestartRequired(next Config) []string {
+	var changed []string
+	add := func(differs bool, name string) {
+		if differs {
+			changed = append(changed, name)
+		}
+	}
+	add(c.Discord.Token != next.Discord.Token, "discord.token")
+	add(c.Discord.CommandGuildID != next.Discord.CommandGuildID, "discord.command_guild_id")
+	add(c.Discord.CleanupCommands != next.Discord.CleanupCommands, "discord.cleanup_commands")
+	add(!reflect.DeepEqual(c.Media, next.Media), "media")
+	add(c.Storage != next.Storage, "storage")
+	add(c.HTTP != next.HTTP, "http")
+	return changed
+}
+
+// Reloaded returns what runs after next is loaded on top of c: next's
+// settings, except those RestartRequired lists, which keep c's values until
+// a restart.
+func (c Config) Reloaded(next Config) Config {
+	next.Discord.Token = c.Discord.Token
+	next.Discord.CommandGuildID = c.Discord.CommandGuildID
+	next.Discord.CleanupCommands = c.Discord.CleanupCommands
+	next.Media = c.Media
+	next.Storage = c.Storage
+	next.HTTP = c.HTTP
+	return next
+}
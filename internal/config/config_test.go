@@ -0,0 +1,244 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// valid returns the defaults with the one required setting filled in.
+func valid() Config {
+	cfg := Default()
+	cfg.Discord.Token = "token"
+	return cfg
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kvazar.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// env is a lookup over a fixed environment.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("defaults with a token: %v", err)
+	}
+	cookies := writeFile(t, "# Netscape HTTP Cookie File\n")
+
+	tests := []struct {
+		name   string
+		change func(*Config)
+		want   string // in the error, or "" for none
+	}{
+		{"missing token", func(c *Config) { c.Discord.Token = " " }, "discord.token is required"},
+		{"empty ffmpeg", func(c *Config) { c.Media.FFMpegPath = "" }, "media.ffmpeg_path"},
+		{"empty ffprobe", func(c *Config) { c.Media.FFProbePath = "" }, "media.ffprobe_path"},
+		{"empty yt-dlp", func(c *Config) { c.Media.YTDLPPath = "" }, "media.ytdlp_path"},
+		{"zero resolve timeout", func(c *Config) { c.Media.ResolveTimeout = 0 }, "media.resolve_timeout"},
+		{"negative workers", func(c *Config) { c.Media.ResolveWorkers = -1 }, "media.resolve_workers"},
+		{"no worker cap", func(c *Config) { c.Media.ResolveWorkers = 0 }, ""},
+		{"unknown backend", func(c *Config) { c.Media.ResolverBackend = "daemon" }, "media.resolver_backend"},
+		{"helper without python", func(c *Config) { c.Media.ResolverBackend, c.Media.PythonPath = "helper", "" }, "media.python_path"},
+		{"exec without python", func(c *Config) { c.Media.PythonPath = "" }, ""},
+		{"missing cookies file", func(c *Config) { c.Media.YTDLP.CookiesFile = cookies + ".missing" }, "media.ytdlp.cookies_file"},
+		{"cookies file", func(c *Config) { c.Media.YTDLP.CookiesFile = cookies }, ""},
+		{"proxy without scheme", func(c *Config) { c.Media.YTDLP.Proxy = "proxy:3128" }, "media.ytdlp.proxy"},
+		{"ftp proxy", func(c *Config) { c.Media.YTDLP.Proxy = "ftp://proxy:21" }, "media.ytdlp.proxy"},
+		{"socks proxy", func(c *Config) { c.Media.YTDLP.Proxy = "socks5h://proxy:1080" }, ""},
+		{"source hostname", func(c *Config) { c.Media.YTDLP.SourceAddress = "localhost" }, "media.ytdlp.source_address"},
+		{"source IPv6", func(c *Config) { c.Media.YTDLP.SourceAddress = "2001:db8::7" }, ""},
+		{"extractor with colon", func(c *Config) { c.Media.YTDLP.ExtractorArgs = map[string]string{"youtube:": "a=b"} }, "is not an extractor name"},
+		{"extractor args without value", func(c *Config) { c.Media.YTDLP.ExtractorArgs = map[string]string{"youtube": "android"} }, "key=value"},
+		{"spotify id alone", func(c *Config) { c.Media.Spotify.ClientID = "id" }, "media.spotify.client_id"},
+		{"spotify secret alone", func(c *Config) { c.Media.Spotify.ClientSecret = "secret" }, "media.spotify.client_id"},
+		{"negative cache", func(c *Config) { c.Media.Cache.MaxEntries = -1 }, "media.cache.max_entries"},
+		{"cache without ttl", func(c *Config) { c.Media.Cache.TTL = 0 }, "media.cache.ttl"},
+		{"no cache, no ttl", func(c *Config) { c.Media.Cache = Cache{} }, ""},
+		{"bitrate too low", func(c *Config) { c.Audio.BitrateKbps = 5 }, "audio.bitrate_kbps"},
+		{"bitrate too high", func(c *Config) { c.Audio.BitrateKbps = 511 }, "audio.bitrate_kbps"},
+		{"zero disconnect delay", func(c *Config) { c.Audio.DisconnectDelay = 0 }, "audio.disconnect_delay"},
+		{"zero vote ratio", func(c *Config) { c.Playback.VoteSkipRatio = 0 }, "playback.vote_skip_ratio"},
+		{"vote ratio above one", func(c *Config) { c.Playback.VoteSkipRatio = 1.5 }, "playback.vote_skip_ratio"},
+		{"unknown locale", func(c *Config) { c.Playback.Locale = "xx" }, "playback.locale"},
+		{"negative max queue", func(c *Config) { c.Limits.MaxQueue = -1 }, "limits.max_queue"},
+		{"negative per-user cap", func(c *Config) { c.Limits.MaxPerUser = -1 }, "limits.max_per_user"},
+		{"negative max duration", func(c *Config) { c.Limits.MaxDuration = -time.Minute }, "limits.max_duration"},
+		{"negative cooldown", func(c *Config) { c.Limits.PlayCooldown = -time.Second }, "limits.play_cooldown"},
+		{"listen without port", func(c *Config) { c.HTTP.Listen = "localhost" }, "http.listen"},
+		{"short api token", func(c *Config) { c.HTTP.APIToken = "short" }, "http.api_token"},
+		{"oauth without secret", func(c *Config) {
+			c.HTTP.OAuth = OAuth{ClientID: "id", RedirectURL: "https://kvazar.example.com/dashboard/auth/callback"}
+		}, "http.oauth.client_secret"},
+		{"oauth relative redirect", func(c *Config) {
+			c.HTTP.OAuth = OAuth{ClientID: "id", ClientSecret: "secret", RedirectURL: "/dashboard/auth/callback"}
+		}, "http.oauth.redirect_url"},
+		{"relative public url", func(c *Config) { c.HTTP.PublicURL = "kvazar.example.com" }, "http.public_url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.change(&cfg)
+			err := cfg.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Validate() = %v, want no error", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Validate() = %v, want an error about %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := valid()
+	cfg.Discord.Token = ""
+	cfg.Audio.BitrateKbps = 0
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "discord.token") || !strings.Contains(err.Error(), "audio.bitrate_kbps") {
+		t.Errorf("Validate() = %v, want both problems", err)
+	}
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	path := writeFile(t, `
+discord:
+  token: from-file
+  status: from file
+media:
+  resolve_timeout: 30s
+  cache:
+    max_entries: 50
+http:
+  listen: ":7000"
+`)
+	tests := []struct {
+		name  string
+		env   map[string]string
+		check func(Config) bool
+	}{
+		{"file only", nil, func(c Config) bool {
+			return c.Discord.Token == "from-file" && c.Media.ResolveTimeout == 30*time.Second && c.HTTP.Listen == ":7000" &&
+				c.Audio.BitrateKbps == Default().Audio.BitrateKbps
+		}},
+		{"env over file", map[string]string{"KVZ_DISCORD_TOKEN": "from-env", "KVZ_RESOLVE_TIMEOUT": "5s", "KVZ_CACHE_MAX_ENTRIES": "0"}, func(c Config) bool {
+			return c.Discord.Token == "from-env" && c.Media.ResolveTimeout == 5*time.Second && c.Media.Cache.MaxEntries == 0 &&
+				c.Discord.Status == "from file"
+		}},
+		{"empty env keeps file", map[string]string{"KVZ_STATUS": ""}, func(c Config) bool { return c.Discord.Status == "from file" }},
+		{"legacy token", map[string]string{"DISCORD_TOKEN": "legacy"}, func(c Config) bool { return c.Discord.Token == "legacy" }},
+		{"token over legacy token", map[string]string{"DISCORD_TOKEN": "legacy", "KVZ_DISCORD_TOKEN": "current"}, func(c Config) bool {
+			return c.Discord.Token == "current"
+		}},
+		{"health port", map[string]string{"KVZ_HEALTH_PORT": "9000"}, func(c Config) bool { return c.HTTP.Listen == ":9000" }},
+		{"listen over health port", map[string]string{"KVZ_HEALTH_PORT": "9000", "KVZ_HTTP_LISTEN": "127.0.0.1:9100"}, func(c Config) bool {
+			return c.HTTP.Listen == "127.0.0.1:9100"
+		}},
+		{"extractor args", map[string]string{"KVZ_YTDLP_EXTRACTOR_ARGS": "youtube:player_client=android,web youtubetab:skip=webpage"}, func(c Config) bool {
+			return reflect.DeepEqual(c.Media.YTDLP.ExtractorArgs, map[string]string{"youtube": "player_client=android,web", "youtubetab": "skip=webpage"})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			if err := cfg.readFile(path); err != nil {
+				t.Fatal(err)
+			}
+			if err := cfg.applyEnv(env(tt.env)); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("config = %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestInvalidEnv(t *testing.T) {
+	cfg := Default()
+	err := cfg.applyEnv(env(map[string]string{
+		"KVZ_RESOLVE_WORKERS":      "many",
+		"KVZ_CACHE_TTL":            "3 days",
+		"KVZ_CACHE_PERSIST":        "sure",
+		"KVZ_YTDLP_EXTRACTOR_ARGS": "player_client=android",
+	}))
+	if err == nil {
+		t.Fatal("applyEnv accepted invalid values")
+	}
+	for _, name := range []string{"KVZ_RESOLVE_WORKERS", "KVZ_CACHE_TTL", "KVZ_CACHE_PERSIST", "KVZ_YTDLP_EXTRACTOR_ARGS"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not name %s", err, name)
+		}
+	}
+}
+
+func TestUnknownFieldsRejected(t *testing.T) {
+	for _, content := range []string{
+		"discord:\n  tokn: abc\n",
+		"media:\n  cache:\n    size: 10\n",
+		"volume: 50\n",
+	} {
+		cfg := Default()
+		if err := cfg.readFile(writeFile(t, content)); err == nil {
+			t.Errorf("readFile accepted %q", content)
+		}
+	}
+	cfg := Default()
+	if err := cfg.readFile(writeFile(t, "")); err != nil {
+		t.Errorf("empty file: %v", err)
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Config)
+		want   []string
+	}{
+		{"nothing", func(*Config) {}, nil},
+		{"reloadable settings", func(c *Config) {
+			c.Discord.Status = "new"
+			c.Audio.BitrateKbps = 96
+			c.Playback.Locale = "sr-Latn"
+			c.Limits.MaxQueue = 10
+		}, nil},
+		{"token", func(c *Config) { c.Discord.Token = "other" }, []string{"discord.token"}},
+		{"command guild", func(c *Config) { c.Discord.CommandGuildID = "1" }, []string{"discord.command_guild_id"}},
+		{"cleanup", func(c *Config) { c.Discord.CleanupCommands = true }, []string{"discord.cleanup_commands"}},
+		{"extractor args", func(c *Config) { c.Media.YTDLP.ExtractorArgs = map[string]string{"youtube": "a=b"} }, []string{"media"}},
+		{"storage and http", func(c *Config) { c.Storage.DataDir = "/var/lib/kvazar"; c.HTTP.APIToken = "x" }, []string{"storage", "http"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running := valid()
+			next := valid()
+			tt.change(&next)
+			if got := running.RestartRequired(next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RestartRequired = %v, want %v", got, tt.want)
+			}
+
+			// After the reload, the same file needs the same restart.
+			reloaded := running.Reloaded(next)
+			if got := reloaded.RestartRequired(next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("after the reload, RestartRequired = %v, want %v", got, tt.want)
+			}
+			if reloaded.Audio != next.Audio || reloaded.Playback != next.Playback || reloaded.Limits != next.Limits ||
+				reloaded.Discord.Status != next.Discord.Status {
+				t.Errorf("Reloaded = %+v, lost reloadable settings of %+v", reloaded, next)
+			}
+		})
+	}
+}
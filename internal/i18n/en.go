@@ -0,0 +1,292 @@
+package i18n
+
+// english is the English catalog.
+var english = map[Key]string{
+	"err.guild_only":         "This command can only be used in a server.",
+	"err.unknown_subcommand": "Unknown subcommand.",
+	"err.nothing_playing":    "Nothing is playing right now.",
+	"err.save_settings":      "Could not save the settings.",
+	"err.voice_connect":      "Could not connect to the voice channel: %v",
+	"err.queue_empty":        "The queue is empty.",
+
+	"common.failed_count": "Failed: %d.",
+
+	"play.missing_query":     "Please enter a search query or URL.",
+	"play.empty_query":       "Please enter a search query.",
+	"play.need_voice":        "You need to be in a voice channel to use /play.",
+	"play.preparing":         "Preparing the track…",
+	"play.not_found":         "Could not find or play that track.",
+	"play.enqueue_failed":    "Could not add the track to the queue: %v",
+	"play.queued":            "Queued **%s** — position #%d.",
+	"play.queued_collection": "💿 Added **%d** tracks from **%s** to the queue.",
+
+	"player.field_queue":  "Up next",
+	"player.queue_count":  "%d tracks",
+	"player.field_status": "Status",
+	"player.paused":       "⏸️ Paused",
+
+	"button.pause":    "Pause",
+	"button.stop":     "Stop",
+	"button.skip":     "Skip",
+	"button.repeat":   "Repeat",
+	"button.loop_on":  "Enable loop",
+	"button.loop_off": "Disable loop",
+
+	"pause.paused":  "⏸️ Playback paused.",
+	"pause.resumed": "▶️ Playback resumed.",
+
+	"stop.nothing": "There is nothing to stop.",
+	"stop.done":    "⏹️ Playback stopped and the queue cleared.",
+
+	"loop.nothing":  "Nothing is playing that could be looped.",
+	"loop.enabled":  "Loop is enabled.",
+	"loop.disabled": "Loop is disabled.",
+
+	"embed.queued_title": "Queued • %s",
+	"embed.next_title":   "Up next • %s",
+	"embed.now":          "Now • %s",
+	"embed.repeating":    "Looping • %s",
+	"embed.duration":     "Duration",
+	"embed.source":       "Source",
+	"embed.requested_by": "Requested by",
+	"embed.position":     "Position",
+	"embed.skip_votes":   "Skip votes",
+	"embed.on_air":       "On air",
+
+	"skip.nothing":       "There is no active track to skip.",
+	"skip.done":          "⏭️ Skipped the current track.",
+	"skip.same_channel":  "You need to be in the bot's voice channel to vote for a skip.",
+	"skip.vote_passed":   "⏭️ Vote passed (%d/%d) — the track was skipped.",
+	"skip.vote_recorded": "🗳️ Vote recorded: %d/%d to skip.",
+
+	"fav.load_failed":      "Could not load your favourites.",
+	"fav.empty_hint":       "You have no favourites yet. Press ❤️ on the now-playing card.",
+	"fav.empty":            "You have no favourites yet.",
+	"fav.title":            "❤️ Favourites",
+	"fav.page":             "Page %d/%d • %d in total",
+	"fav.need_voice":       "You need to be in a voice channel to play your favourites.",
+	"fav.added_many":       "❤️ Added **%d** favourites to the queue.",
+	"fav.missing_position": "Please enter the track number.",
+	"fav.save_failed":      "Could not save your favourites.",
+	"fav.no_such":          "There is no track with that number.",
+	"fav.removed":          "Removed from favourites: **%s**",
+	"fav.card_unknown":     "Could not find the track on this card.",
+	"fav.like_failed":      "Could not save the track to your favourites.",
+	"fav.already":          "**%s** is already in your favourites.",
+	"fav.liked":            "❤️ Added to favourites: **%s**",
+
+	"limit.queue_full": "The queue is full — at most %d tracks may be queued.",
+	"limit.per_user":   "You already have %d tracks queued, which is the per-user maximum.",
+	"limit.duration":   "**%s** (%s) is too long — the maximum is %s.",
+	"limit.live":       "Livestreams are not allowed in this server.",
+	"limit.cooldown":   "Please wait another %d s before the next /play.",
+	"limit.generic":    "A queue limit was reached.",
+
+	"resolve.not_found":      "Nothing was found for that search or link.",
+	"resolve.private":        "That video is private or for channel members only.",
+	"resolve.age_restricted": "That video is age-restricted and cannot be played without a signed-in account.",
+	"resolve.geo_blocked":    "That video is not available in the country the bot connects from.",
+	"resolve.copyright":      "That video was taken down over a copyright claim.",
+	"resolve.not_started":    "That livestream or premiere has not started yet.",
+	"resolve.rate_limited":   "The site is limiting the bot's requests right now. Try again in a few minutes.",
+	"resolve.timeout":        "Looking up the track took too long. Try again.",
+	"resolve.missing_binary": "The bot cannot look up tracks because yt-dlp is not installed. Let an administrator know.",
+	"resolve.busy":           "The bot is handling too many requests right now. Try again in a moment.",
+	"resolve.no_match":       "That track could not be found on YouTube or SoundCloud.",
+	"resolve.unknown_source": "That source is not available on this bot.",
+	"resolve.not_in_library": "That track is not in the music library.",
+	"resolve.failed":         "That track cannot be played; the site reported an error.",
+
+	"limits.title":         "**Queue limits**",
+	"limits.off":           "off",
+	"limits.live_allowed":  "allowed",
+	"limits.live_rejected": "rejected",
+	"limits.max_queue":     "Maximum queued tracks: %s",
+	"limits.max_per_user":  "Maximum tracks per user: %s",
+	"limits.max_duration":  "Maximum track duration: %s",
+	"limits.cooldown":      "Cooldown between /play: %s",
+	"limits.live":          "Livestreams: %s",
+
+	"perm.admin_only":           "Only server administrators can use this command.",
+	"perm.denied":               "You are not allowed to use /%s.",
+	"perm.dj_only":              "This command is only available to DJs.",
+	"perm.same_channel":         "You need to be in <#%s> to use this command.",
+	"perm.dj_cleared":           "The DJ role was cleared.",
+	"perm.dj_set":               "The DJ role is now <@&%s>.",
+	"perm.unknown_command_role": "Unknown command or role.",
+	"perm.allowed":              "/%s is now allowed for <@&%s>.",
+	"perm.denied_role":          "/%s is now denied for <@&%s>.",
+	"perm.unknown_command":      "Unknown command.",
+	"perm.reset":                "The rules for /%s were reset to the defaults.",
+	"perm.dj_role":              "**DJ role:** %s",
+	"perm.dj_unset":             "not set",
+	"perm.defaults":             "All command rules are at their defaults.",
+	"perm.rule":                 "`/%s` — allowed: %s; denied: %s",
+
+	"queue.bad_format":      "Unknown file format.",
+	"queue.export_failed":   "Exporting the queue failed.",
+	"queue.export_summary":  "Queue: %d tracks, history: %d tracks.",
+	"queue.need_file":       "Please attach a playlist file.",
+	"queue.file_too_large":  "The file is too large.",
+	"queue.need_voice":      "You need to be in a voice channel to import a queue.",
+	"queue.download_failed": "Could not download the file.",
+	"queue.unsupported":     "Unknown file format. M3U8, XSPF and JSON are supported.",
+	"queue.read_failed":     "Could not read the file: %v",
+	"queue.no_entries":      "The file does not contain any tracks.",
+	"queue.imported":        "Imported **%d** tracks into the queue.",
+	"queue.truncated":       "Skipped due to the import limit: %d.",
+
+	"remove.missing_position": "Please enter the track position.",
+	"remove.no_such":          "There is no track at that position.",
+	"remove.not_yours":        "You can only remove your own tracks. **%s** was added by %s.",
+	"remove.failed":           "Removing the track failed.",
+	"remove.done":             "🗑️ Removed from the queue: **%s**",
+
+	"settings.title":            "**Server settings**",
+	"settings.announce_channel": "Announcement channel: %s",
+	"settings.announce_default": "the channel the track was requested in",
+	"settings.announce_mode":    "Announcements: %s",
+	"settings.mode_off":         "off",
+	"settings.mode_single":      "one player message, edited in place",
+	"settings.mode_track":       "a new message per track",
+	"settings.mode_thread":      "a thread per session",
+	"settings.volume":           "Volume: %d%%",
+	"settings.normalize":        "Loudness normalization: %s",
+	"settings.normalize_on":     "on, targeting %g LUFS",
+	"settings.normalize_off":    "off",
+	"settings.idle_timeout":     "Leave the channel after being idle for: %s",
+	"settings.autoplay":         "Autoplay related tracks: %s",
+	"settings.on":               "on",
+	"settings.off":              "off",
+	"settings.locale":           "Language: %s",
+	"settings.default_suffix":   "%s (default)",
+	"settings.reset":            "`%s` was reset to its default.",
+
+	"announce.thread_name":   "Music %s",
+	"announce.session_ended": "The session has ended.",
+
+	"lang.name":        "English",
+	"lang.set":         "The bot language is now: %s.",
+	"lang.auto_choice": "Automatic (Discord)",
+	"lang.auto":        "The bot language now follows your Discord settings.",
+
+	"source.youtube":    "YouTube",
+	"source.soundcloud": "SoundCloud",
+	"source.direct":     "Direct stream or radio",
+	"source.local":      "Local library",
+
+	"library.disabled":       "The local library is not configured.",
+	"library.no_match":       "Nothing in the library matches “%s”.",
+	"library.results_title":  "📚 Library: %s",
+	"library.results_footer": "Showing %d of %d • play one with /library play",
+	"library.need_voice":     "You need to be in a voice channel to play from the library.",
+	"library.queued_album":   "📀 Added **%d** tracks from **%s** to the queue.",
+	"library.queued_artist":  "🎤 Added **%d** tracks by **%s** to the queue.",
+	"library.rescanned":      "Library rescanned: %d tracks (%d new, %d changed, %d removed).",
+	"library.rescan_failed":  "Rescanning the library failed.",
+	"library.unreadable":     "Could not read the tags of %d files.",
+	"library.skipped":        "Could not open %d files or folders; they were skipped.",
+
+	// Slash command names and descriptions.
+	"cmd.play.name":   "play",
+	"cmd.play":        "Play music from YouTube or SoundCloud, or search.",
+	"cmd.play.query":  "URL or search query (prefix 'sc' for SoundCloud)",
+	"cmd.play.source": "Where to look it up (picked from the query by default).",
+
+	"cmd.player.name": "player",
+	"cmd.player":      "Show the current player state.",
+
+	"cmd.pause.name": "pause",
+	"cmd.pause":      "Pause or resume playback.",
+
+	"cmd.stop.name": "stop",
+	"cmd.stop":      "Stop playback and clear the queue.",
+
+	"cmd.skip.name": "skip",
+	"cmd.skip":      "Skip the current track.",
+
+	"cmd.loop.name":    "loop",
+	"cmd.loop":         "Toggle queue looping.",
+	"cmd.loop.enabled": "Explicitly set queue looping (omit to toggle).",
+
+	"cmd.queue.name":           "queue",
+	"cmd.queue":                "Export or import the queue.",
+	"cmd.queue.export":         "Download the current queue and history as a file.",
+	"cmd.queue.export.format":  "File format (JSON by default).",
+	"cmd.queue.import":         "Queue the tracks from an M3U8, XSPF or JSON file.",
+	"cmd.queue.import.file":    "Playlist file.",
+	"cmd.queue.import.history": "Also queue the history entries (off by default).",
+
+	"cmd.favorites.name":            "favorites",
+	"cmd.favorites":                 "Your favourite tracks.",
+	"cmd.favorites.list":            "List your saved favourites.",
+	"cmd.favorites.list.page":       "Page of the list.",
+	"cmd.favorites.play":            "Queue your favourites.",
+	"cmd.favorites.play.count":      "Only queue a random selection of this many tracks.",
+	"cmd.favorites.play.shuffle":    "Shuffle the order.",
+	"cmd.favorites.remove":          "Remove a track from your favourites.",
+	"cmd.favorites.remove.position": "Track number from /favorites list.",
+
+	"cmd.library.name":           "library",
+	"cmd.library":                "The local music library.",
+	"cmd.library.search":         "Search the library.",
+	"cmd.library.search.query":   "Title, artist or album.",
+	"cmd.library.play":           "Play the best match from the library.",
+	"cmd.library.play.query":     "Title, artist or album.",
+	"cmd.library.album":          "Queue a whole album.",
+	"cmd.library.album.query":    "Album name, optionally with the artist.",
+	"cmd.library.album.shuffle":  "Shuffle the order.",
+	"cmd.library.artist":         "Queue every track by an artist.",
+	"cmd.library.artist.query":   "Artist name.",
+	"cmd.library.artist.shuffle": "Shuffle the order.",
+	"cmd.library.rescan":         "Read the library files again.",
+
+	"cmd.remove.name":     "remove",
+	"cmd.remove":          "Remove a track from the queue (your own, or any if you are a DJ).",
+	"cmd.remove.position": "Position of the track in the queue.",
+
+	"cmd.permissions.name":          "permissions",
+	"cmd.permissions":               "Configure the DJ role and command permissions.",
+	"cmd.permissions.show":          "Show the current rules.",
+	"cmd.permissions.dj-role":       "Set the DJ role (omit to clear).",
+	"cmd.permissions.dj-role.role":  "Role with full control over the player.",
+	"cmd.permissions.allow":         "Restrict a command to this role (and DJs).",
+	"cmd.permissions.allow.command": "Command.",
+	"cmd.permissions.allow.role":    "Role.",
+	"cmd.permissions.deny":          "Deny a command to this role.",
+	"cmd.permissions.deny.command":  "Command.",
+	"cmd.permissions.deny.role":     "Role.",
+	"cmd.permissions.reset":         "Reset a command to the default rules.",
+	"cmd.permissions.reset.command": "Command.",
+
+	"cmd.limits.name":             "limits",
+	"cmd.limits":                  "Configure the queue limits for this server.",
+	"cmd.limits.show":             "Show the current limits.",
+	"cmd.limits.set":              "Change the limits (0 turns a limit off).",
+	"cmd.limits.set.max_queue":    "Maximum number of queued tracks.",
+	"cmd.limits.set.max_per_user": "Maximum queued tracks per user.",
+	"cmd.limits.set.max_duration": "Maximum track duration in minutes.",
+	"cmd.limits.set.cooldown":     "Per-user cooldown between /play calls, in seconds.",
+	"cmd.limits.set.reject_live":  "Reject livestreams.",
+
+	"cmd.settings.name":                 "settings",
+	"cmd.settings":                      "Player settings for this server.",
+	"cmd.settings.show":                 "Show the current settings.",
+	"cmd.settings.set":                  "Change one or more settings.",
+	"cmd.settings.set.announce_channel": "Channel that receives the now-playing cards.",
+	"cmd.settings.set.announce_mode":    "How the playing track is announced.",
+	"cmd.settings.set.volume":           "Volume in percent (100 leaves it unchanged).",
+	"cmd.settings.set.normalize":        "Even out the loudness of tracks.",
+	"cmd.settings.set.loudness":         "Normalization target in LUFS (default -16).",
+	"cmd.settings.set.idle_timeout":     "Idle minutes before leaving the voice channel.",
+	"cmd.settings.set.dj_role":          "Role with full control over the player.",
+	"cmd.settings.set.max_queue":        "Maximum number of queued tracks (0 turns the limit off).",
+	"cmd.settings.set.locale":           "Bot language in this server.",
+	"cmd.settings.set.autoplay":         "Play related tracks when the queue runs dry.",
+	"cmd.settings.reset":                "Reset a setting to its default.",
+	"cmd.settings.reset.setting":        "Setting.",
+
+	"cmd.language.name":   "language",
+	"cmd.language":        "Choose the bot language for this server.",
+	"cmd.language.locale": "Language (automatic follows Discord settings).",
+}
@@ -0,0 +1,110 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lang identifies a message catalog.
+type Lang string
+
+const (
+	SerbianCyrillic Lang = "sr-Cyrl"
+	SerbianLatin    Lang = "sr-Latn"
+	English         Lang = "en"
+)
+
+// Default is the language used when nothing better is known.
+const Default = SerbianCyrillic
+
+// Key identifies a translatable message.
+type Key string
+
+var catalogs = map[Lang]map[Key]string{
+	SerbianCyrillic: serbianCyrillic,
+	SerbianLatin:    serbianLatin,
+	English:         english,
+}
+
+// discordLocales lists the Discord client locales each catalog serves. Discord
+// has no Serbian locale: the Cyrillic catalog provides the base command strings
+// and Croatian clients get the Latin script.
+var discordLocales = map[Lang][]string{
+	SerbianCyrillic: nil,
+	SerbianLatin:    {"hr"},
+	English:         {"en-US", "en-GB"},
+}
+
+// Supported returns the available languages in display order.
+func Supported() []Lang {
+	return []Lang{SerbianCyrillic, SerbianLatin, English}
+}
+
+// T renders the message for key, formatting it with args when given. Missing
+// translations fall back to the default catalog and finally to the key itself.
+func (l Lang) T(key Key, args ...any) string {
+	text, ok := catalogs[l][key]
+	if !ok {
+		text, ok = catalogs[Default][key]
+	}
+	if !ok {
+		text = string(key)
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// Has reports whether the catalog for l defines key.
+func (l Lang) Has(key Key) bool {
+	_, ok := catalogs[l][key]
+	return ok
+}
+
+// Parse accepts a catalog identifier ("sr-Latn") or a Discord locale ("en-US").
+func Parse(value string) (Lang, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", false
+	}
+	for lang := range catalogs {
+		if strings.EqualFold(string(lang), value) {
+			return lang, true
+		}
+	}
+	return FromDiscord(value)
+}
+
+// FromDiscord maps a Discord client locale onto a catalog.
+func FromDiscord(locale string) (Lang, bool) {
+	for lang, locales := range discordLocales {
+		for _, candidate := range locales {
+			if strings.EqualFold(candidate, locale) {
+				return lang, true
+			}
+		}
+	}
+	return "", false
+}
+
+// DiscordLocales returns the Discord client locales served by l.
+func (l Lang) DiscordLocales() []string {
+	return discordLocales[l]
+}
+
+// CommandKey builds the key describing a slash command, option or subcommand,
+// e.g. CommandKey("queue", "export", "format").
+func CommandKey(path ...string) Key {
+	return Key("cmd." + strings.Join(path, "."))
+}
+
+// CommandNameKey builds the key holding a command's localized name.
+func CommandNameKey(command string) Key {
+	return Key("cmd." + command + ".name")
+}
+
+// SourceKey builds the key holding the display name of a media provider.
+func SourceKey(provider string) Key {
+	return Key("source." + provider)
+}
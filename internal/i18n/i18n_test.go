@@ -0,0 +1,146 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+var verbPattern = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z%]`)
+
+// declaredKeys parses keys.go so a constant added without translations fails the test.
+func declaredKeys(t *testing.T) []Key {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), "keys.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parse keys.go: %v", err)
+	}
+
+	var keys []Key
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		for _, value := range spec.Values {
+			lit, ok := value.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				continue
+			}
+			text, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Fatalf("unquote %s: %v", lit.Value, err)
+			}
+			keys = append(keys, Key(text))
+		}
+		return true
+	})
+	if len(keys) == 0 {
+		t.Fatal("no keys found in keys.go")
+	}
+	return keys
+}
+
+func TestCatalogsDefineEveryKey(t *testing.T) {
+	for _, key := range declaredKeys(t) {
+		for _, lang := range Supported() {
+			if !lang.Has(key) {
+				t.Errorf("%s: missing %q", lang, key)
+			}
+		}
+	}
+}
+
+func TestCatalogsShareKeys(t *testing.T) {
+	reference := catalogs[Default]
+	for _, lang := range Supported() {
+		catalog := catalogs[lang]
+		for key := range reference {
+			if _, ok := catalog[key]; !ok {
+				t.Errorf("%s: missing %q", lang, key)
+			}
+		}
+		for key := range catalog {
+			if _, ok := reference[key]; !ok {
+				t.Errorf("%s: %q is not in the %s catalog", lang, key, Default)
+			}
+		}
+	}
+}
+
+func TestCatalogsAgreeOnFormatVerbs(t *testing.T) {
+	for key, text := range catalogs[Default] {
+		want := verbs(text)
+		for _, lang := range Supported() {
+			got := verbs(catalogs[lang][key])
+			if !equalStrings(got, want) {
+				t.Errorf("%s: %q uses %v, %s uses %v", lang, key, got, Default, want)
+			}
+		}
+	}
+}
+
+func TestCommandNamesAreValid(t *testing.T) {
+	// Discord only accepts lowercase names without spaces.
+	valid := regexp.MustCompile(`^[-_\p{Ll}\p{Lo}\p{N}]{1,32}$`)
+	for _, lang := range Supported() {
+		for key, text := range catalogs[lang] {
+			if len(key) > 5 && key[:4] == "cmd." && key[len(key)-5:] == ".name" && !valid.MatchString(text) {
+				t.Errorf("%s: invalid command name %q for %q", lang, text, key)
+			}
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := English.T(PlayQueued, "Song", 2); got != "Queued **Song** — position #2." {
+		t.Errorf("English.T = %q", got)
+	}
+	if got := Lang("xx").T(ErrQueueEmpty); got != SerbianCyrillic.T(ErrQueueEmpty) {
+		t.Errorf("unknown language should fall back to %s, got %q", Default, got)
+	}
+	if got := English.T("does.not.exist"); got != "does.not.exist" {
+		t.Errorf("missing key should render as itself, got %q", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := map[string]Lang{
+		"sr-Cyrl": SerbianCyrillic,
+		"sr-latn": SerbianLatin,
+		"en":      English,
+		"en-US":   English,
+		"en-GB":   English,
+		"hr":      SerbianLatin,
+	}
+	for input, want := range cases {
+		if got, ok := Parse(input); !ok || got != want {
+			t.Errorf("Parse(%q) = %q, %v; want %q", input, got, ok, want)
+		}
+	}
+	for _, input := range []string{"", "de", "sr", "auto"} {
+		if got, ok := Parse(input); ok {
+			t.Errorf("Parse(%q) = %q, want no match", input, got)
+		}
+	}
+}
+
+func verbs(text string) []string {
+	return verbPattern.FindAllString(text, -1)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+package i18n
+
+// Message keys. Command descriptions use dynamic "cmd.*" keys built by
+// CommandKey, and source names "source.*" keys built by SourceKey.
+const (
+	ErrGuildOnly         Key = "err.guild_only"
+	ErrUnknownSubcommand Key = "err.unknown_subcommand"
+	ErrNothingPlaying    Key = "err.nothing_playing"
+	ErrSaveSettings      Key = "err.save_settings"
+	ErrVoiceConnect      Key = "err.voice_connect"
+	ErrQueueEmpty        Key = "err.queue_empty"
+	FailedCount          Key = "common.failed_count"
+
+	PlayMissingQuery  Key = "play.missing_query"
+	PlayEmptyQuery    Key = "play.empty_query"
+	PlayNeedVoice     Key = "play.need_voice"
+	PlayPreparing     Key = "play.preparing"
+	PlayNotFound      Key = "play.not_found"
+	PlayEnqueueFailed Key = "play.enqueue_failed"
+	PlayQueued        Key = "play.queued"
+
+	PlayerFieldQueue  Key = "player.field_queue"
+	PlayerQueueCount  Key = "player.queue_count"
+	PlayerFieldStatus Key = "player.field_status"
+	PlayerPaused      Key = "player.paused"
+
+	ButtonPause   Key = "button.pause"
+	ButtonStop    Key = "button.stop"
+	ButtonSkip    Key = "button.skip"
+	ButtonRepeat  Key = "button.repeat"
+	ButtonLoopOn  Key = "button.loop_on"
+	ButtonLoopOff Key = "button.loop_off"
+
+	PausePaused  Key = "pause.paused"
+	PauseResumed Key = "pause.resumed"
+
+	StopNothing Key = "stop.nothing"
+	StopDone    Key = "stop.done"
+
+	LoopNothing  Key = "loop.nothing"
+	LoopEnabled  Key = "loop.enabled"
+	LoopDisabled Key = "loop.disabled"
+
+	EmbedQueuedTitle Key = "embed.queued_title"
+	EmbedNextTitle   Key = "embed.next_title"
+	EmbedNow         Key = "embed.now"
+	EmbedRepeating   Key = "embed.repeating"
+	EmbedDuration    Key = "embed.duration"
+	EmbedSource      Key = "embed.source"
+	EmbedRequestedBy Key = "embed.requested_by"
+	EmbedPosition    Key = "embed.position"
+	EmbedSkipVotes   Key = "embed.skip_votes"
+	EmbedOnAir       Key = "embed.on_air"
+
+	PlayQueuedCollection Key = "play.queued_collection"
+	LibraryDisabled      Key = "library.disabled"
+	LibraryNoMatch       Key = "library.no_match"
+	LibraryResultsTitle  Key = "library.results_title"
+	LibraryResultsFooter Key = "library.results_footer"
+	LibraryNeedVoice     Key = "library.need_voice"
+	LibraryQueuedAlbum   Key = "library.queued_album"
+	LibraryQueuedArtist  Key = "library.queued_artist"
+	LibraryRescanned     Key = "library.rescanned"
+	LibraryRescanFailed  Key = "library.rescan_failed"
+	LibraryUnreadable    Key = "library.unreadable"
+	LibrarySkipped       Key = "library.skipped"
+
+	SkipNothing      Key = "skip.nothing"
+	SkipDone         Key = "skip.done"
+	SkipSameChannel  Key = "skip.same_channel"
+	SkipVotePassed   Key = "skip.vote_passed"
+	SkipVoteRecorded Key = "skip.vote_recorded"
+
+	FavLoadFailed      Key = "fav.load_failed"
+	FavEmptyHint       Key = "fav.empty_hint"
+	FavEmpty           Key = "fav.empty"
+	FavTitle           Key = "fav.title"
+	FavPage            Key = "fav.page"
+	FavNeedVoice       Key = "fav.need_voice"
+	FavAddedMany       Key = "fav.added_many"
+	FavMissingPosition Key = "fav.missing_position"
+	FavSaveFailed      Key = "fav.save_failed"
+	FavNoSuch          Key = "fav.no_such"
+	FavRemoved         Key = "fav.removed"
+	FavCardUnknown     Key = "fav.card_unknown"
+	FavLikeFailed      Key = "fav.like_failed"
+	FavAlready         Key = "fav.already"
+	FavLiked           Key = "fav.liked"
+
+	LimitQueueFull Key = "limit.queue_full"
+	LimitPerUser   Key = "limit.per_user"
+	LimitDuration  Key = "limit.duration"
+	LimitLive      Key = "limit.live"
+	LimitCooldown  Key = "limit.cooldown"
+	LimitGeneric   Key = "limit.generic"
+
+	ResolveNotFound      Key = "resolve.not_found"
+	ResolvePrivate       Key = "resolve.private"
+	ResolveAgeRestricted Key = "resolve.age_restricted"
+	ResolveGeoBlocked    Key = "resolve.geo_blocked"
+	ResolveCopyright     Key = "resolve.copyright"
+	ResolveNotStarted    Key = "resolve.not_started"
+	ResolveRateLimited   Key = "resolve.rate_limited"
+	ResolveTimeout       Key = "resolve.timeout"
+	ResolveMissingBinary Key = "resolve.missing_binary"
+	ResolveBusy          Key = "resolve.busy"
+	ResolveNoMatch       Key = "resolve.no_match"
+	ResolveUnknownSource Key = "resolve.unknown_source"
+	ResolveNotInLibrary  Key = "resolve.not_in_library"
+	ResolveFailed        Key = "resolve.failed"
+
+	LimitsTitle        Key = "limits.title"
+	LimitsOff          Key = "limits.off"
+	LimitsLiveAllowed  Key = "limits.live_allowed"
+	LimitsLiveRejected Key = "limits.live_rejected"
+	LimitsMaxQueue     Key = "limits.max_queue"
+	LimitsMaxPerUser   Key = "limits.max_per_user"
+	LimitsMaxDuration  Key = "limits.max_duration"
+	LimitsCooldown     Key = "limits.cooldown"
+	LimitsLive         Key = "limits.live"
+
+	PermAdminOnly          Key = "perm.admin_only"
+	PermDenied             Key = "perm.denied"
+	PermDJOnly             Key = "perm.dj_only"
+	PermSameChannel        Key = "perm.same_channel"
+	PermDJCleared          Key = "perm.dj_cleared"
+	PermDJSet              Key = "perm.dj_set"
+	PermUnknownCommandRole Key = "perm.unknown_command_role"
+	PermAllowed            Key = "perm.allowed"
+	PermDeniedRole         Key = "perm.denied_role"
+	PermUnknownCommand     Key = "perm.unknown_command"
+	PermReset              Key = "perm.reset"
+	PermDJRole             Key = "perm.dj_role"
+	PermDJUnset            Key = "perm.dj_unset"
+	PermDefaults           Key = "perm.defaults"
+	PermRule               Key = "perm.rule"
+
+	QueueBadFormat      Key = "queue.bad_format"
+	QueueExportFailed   Key = "queue.export_failed"
+	QueueExportSummary  Key = "queue.export_summary"
+	QueueNeedFile       Key = "queue.need_file"
+	QueueFileTooLarge   Key = "queue.file_too_large"
+	QueueNeedVoice      Key = "queue.need_voice"
+	QueueDownloadFailed Key = "queue.download_failed"
+	QueueUnsupported    Key = "queue.unsupported"
+	QueueReadFailed     Key = "queue.read_failed"
+	QueueNoEntries      Key = "queue.no_entries"
+	QueueImported       Key = "queue.imported"
+	QueueTruncated      Key = "queue.truncated"
+
+	RemoveMissingPosition Key = "remove.missing_position"
+	RemoveNoSuch          Key = "remove.no_such"
+	RemoveNotYours        Key = "remove.not_yours"
+	RemoveFailed          Key = "remove.failed"
+	RemoveDone            Key = "remove.done"
+
+	SettingsTitle           Key = "settings.title"
+	SettingsAnnounceChannel Key = "settings.announce_channel"
+	SettingsAnnounceDefault Key = "settings.announce_default"
+	SettingsAnnounceMode    Key = "settings.announce_mode"
+	SettingsModeOff         Key = "settings.mode_off"
+	SettingsModeSingle      Key = "settings.mode_single"
+	SettingsModeTrack       Key = "settings.mode_track"
+	SettingsModeThread      Key = "settings.mode_thread"
+	SettingsVolume          Key = "settings.volume"
+	SettingsNormalize       Key = "settings.normalize"
+	SettingsNormalizeOn     Key = "settings.normalize_on"
+	SettingsNormalizeOff    Key = "settings.normalize_off"
+	SettingsIdleTimeout     Key = "settings.idle_timeout"
+	SettingsAutoplay        Key = "settings.autoplay"
+	SettingsOn              Key = "settings.on"
+	SettingsOff             Key = "settings.off"
+	SettingsLocale          Key = "settings.locale"
+	SettingsDefaultSuffix   Key = "settings.default_suffix"
+	SettingsReset           Key = "settings.reset"
+
+	AnnounceThreadName   Key = "announce.thread_name"
+	AnnounceSessionEnded Key = "announce.session_ended"
+
+	LangName       Key = "lang.name"
+	LangSet        Key = "lang.set"
+	LangAuto       Key = "lang.auto"
+	LangAutoChoice Key = "lang.auto_choice"
+)
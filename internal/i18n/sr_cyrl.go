@@ -0,0 +1,292 @@
+package i18n
+
+// serbianCyrillic is the reference catalog; every other catalog mirrors its keys.
+var serbianCyrillic = map[Key]string{
+	"err.guild_only":         "Ова команда се може користити само на серверу.",
+	"err.unknown_subcommand": "Непозната подкоманда.",
+	"err.nothing_playing":    "Ништа тренутно не свира.",
+	"err.save_settings":      "Не могу да сачувам подешавања.",
+	"err.voice_connect":      "Неуспело повезивање на гласовни канал: %v",
+	"err.queue_empty":        "Ред је празан.",
+
+	"common.failed_count": "Неуспело: %d.",
+
+	"play.missing_query":     "Молим те унеси упит или URL адресу.",
+	"play.empty_query":       "Молим те унеси упит.",
+	"play.need_voice":        "Мораш бити повезан на гласовни канал да би користио /play.",
+	"play.preparing":         "Припремам песму…",
+	"play.not_found":         "Не могу да пронађем ни да пустим ту песму.",
+	"play.enqueue_failed":    "Не могу да додам песму у ред: %v",
+	"play.queued":            "У реду **%s** — позиција #%d.",
+	"play.queued_collection": "💿 Додато **%d** песама из **%s** у ред.",
+
+	"player.field_queue":  "У реду",
+	"player.queue_count":  "%d песама",
+	"player.field_status": "Статус",
+	"player.paused":       "⏸️ Паузирано",
+
+	"button.pause":    "Пауза",
+	"button.stop":     "Заустави",
+	"button.skip":     "Прескочи",
+	"button.repeat":   "Понови",
+	"button.loop_on":  "Укључи понављање",
+	"button.loop_off": "Искључи понављање",
+
+	"pause.paused":  "⏸️ Репродукција паузирана.",
+	"pause.resumed": "▶️ Репродукција настављена.",
+
+	"stop.nothing": "Нема ничега за заустављање.",
+	"stop.done":    "⏹️ Репродукција заустављена и ред испражњен.",
+
+	"loop.nothing":  "Ништа не свира да би се понављало.",
+	"loop.enabled":  "Понављање је укључено.",
+	"loop.disabled": "Понављање је искључено.",
+
+	"embed.queued_title": "У реду • %s",
+	"embed.next_title":   "Следеће • %s",
+	"embed.now":          "Сада • %s",
+	"embed.repeating":    "Понавља • %s",
+	"embed.duration":     "Трајање",
+	"embed.source":       "Извор",
+	"embed.requested_by": "Захтевао",
+	"embed.position":     "Позиција",
+	"embed.skip_votes":   "Гласови за прескакање",
+	"embed.on_air":       "Сада на станици",
+
+	"skip.nothing":       "Нема активне песме за прескакање.",
+	"skip.done":          "⏭️ Прескочена је тренутна песма.",
+	"skip.same_channel":  "Мораш бити у истом гласовном каналу као бот да би гласао за прескакање.",
+	"skip.vote_passed":   "⏭️ Гласање успело (%d/%d) — песма је прескочена.",
+	"skip.vote_recorded": "🗳️ Глас је забележен: %d/%d за прескакање.",
+
+	"fav.load_failed":      "Не могу да учитам омиљене песме.",
+	"fav.empty_hint":       "Још немаш омиљених песама. Притисни ❤️ на картици песме која свира.",
+	"fav.empty":            "Још немаш омиљених песама.",
+	"fav.title":            "❤️ Омиљене песме",
+	"fav.page":             "Страница %d/%d • укупно %d",
+	"fav.need_voice":       "Мораш бити повезан на гласовни канал да би пустио омиљене песме.",
+	"fav.added_many":       "❤️ Додато **%d** омиљених песама у ред.",
+	"fav.missing_position": "Молим те унеси редни број песме.",
+	"fav.save_failed":      "Не могу да сачувам омиљене песме.",
+	"fav.no_such":          "Не постоји песма са тим редним бројем.",
+	"fav.removed":          "Уклоњено из омиљених: **%s**",
+	"fav.card_unknown":     "Не могу да пронађем песму са ове картице.",
+	"fav.like_failed":      "Не могу да сачувам песму у омиљене.",
+	"fav.already":          "**%s** је већ у твојим омиљеним.",
+	"fav.liked":            "❤️ Додато у омиљене: **%s**",
+
+	"limit.queue_full": "Ред је пун — највише %d песама може бити у реду.",
+	"limit.per_user":   "Већ имаш %d песама у реду, што је максимум по кориснику.",
+	"limit.duration":   "Песма **%s** (%s) је предугачка — дозвољено је највише %s.",
+	"limit.live":       "Преноси уживо нису дозвољени на овом серверу.",
+	"limit.cooldown":   "Сачекај још %d s пре следећег /play.",
+	"limit.generic":    "Достигнуто је ограничење реда.",
+
+	"resolve.not_found":      "Ништа није пронађено за тај упит или линк.",
+	"resolve.private":        "Тај снимак је приватан или доступан само члановима канала.",
+	"resolve.age_restricted": "Тај снимак је ограничен по узрасту и не може да се пусти без пријављеног налога.",
+	"resolve.geo_blocked":    "Тај снимак није доступан у земљи из које се бот повезује.",
+	"resolve.copyright":      "Тај снимак је уклоњен због ауторских права.",
+	"resolve.not_started":    "Тај пренос или премијера још није почела.",
+	"resolve.rate_limited":   "Сајт тренутно ограничава захтеве бота. Покушај поново за неколико минута.",
+	"resolve.timeout":        "Тражење песме је трајало предуго. Покушај поново.",
+	"resolve.missing_binary": "Бот не може да тражи песме јер yt-dlp није инсталиран. Обавести администратора.",
+	"resolve.busy":           "Бот тренутно обрађује превише захтева. Покушај поново за тренутак.",
+	"resolve.no_match":       "Та песма није пронађена ни на YouTube-у ни на SoundCloud-у.",
+	"resolve.unknown_source": "Тај извор није доступан на овом боту.",
+	"resolve.not_in_library": "Та песма није у музичкој библиотеци.",
+	"resolve.failed":         "Та песма не може да се пусти; сајт је пријавио грешку.",
+
+	"limits.title":         "**Ограничења реда**",
+	"limits.off":           "искључено",
+	"limits.live_allowed":  "дозвољени",
+	"limits.live_rejected": "забрањени",
+	"limits.max_queue":     "Највише песама у реду: %s",
+	"limits.max_per_user":  "Највише песама по кориснику: %s",
+	"limits.max_duration":  "Најдуже трајање песме: %s",
+	"limits.cooldown":      "Пауза између /play: %s",
+	"limits.live":          "Преноси уживо: %s",
+
+	"perm.admin_only":           "Ову команду могу користити само администратори сервера.",
+	"perm.denied":               "Немаш дозволу да користиш /%s.",
+	"perm.dj_only":              "Ова команда је доступна само DJ-евима.",
+	"perm.same_channel":         "Мораш бити у гласовном каналу <#%s> да би користио ову команду.",
+	"perm.dj_cleared":           "DJ улога је уклоњена.",
+	"perm.dj_set":               "DJ улога је сада <@&%s>.",
+	"perm.unknown_command_role": "Непозната команда или улога.",
+	"perm.allowed":              "Команда /%s је дозвољена улози <@&%s>.",
+	"perm.denied_role":          "Команда /%s је забрањена улози <@&%s>.",
+	"perm.unknown_command":      "Непозната команда.",
+	"perm.reset":                "Правила за /%s су враћена на подразумевана.",
+	"perm.dj_role":              "**DJ улога:** %s",
+	"perm.dj_unset":             "није подешена",
+	"perm.defaults":             "Сва правила команди су подразумевана.",
+	"perm.rule":                 "`/%s` — дозвољено: %s; забрањено: %s",
+
+	"queue.bad_format":      "Непознат формат датотеке.",
+	"queue.export_failed":   "Извоз реда није успео.",
+	"queue.export_summary":  "Ред: %d песама, историја: %d песама.",
+	"queue.need_file":       "Молим те приложи датотеку са листом песама.",
+	"queue.file_too_large":  "Датотека је превелика.",
+	"queue.need_voice":      "Мораш бити повезан на гласовни канал да би увезао ред.",
+	"queue.download_failed": "Не могу да преузмем датотеку.",
+	"queue.unsupported":     "Непознат формат датотеке. Подржани су M3U8, XSPF и JSON.",
+	"queue.read_failed":     "Не могу да прочитам датотеку: %v",
+	"queue.no_entries":      "Датотека не садржи ниједну песму.",
+	"queue.imported":        "Увезено **%d** песама у ред.",
+	"queue.truncated":       "Прескочено због ограничења: %d.",
+
+	"remove.missing_position": "Молим те унеси позицију песме.",
+	"remove.no_such":          "Не постоји песма на тој позицији.",
+	"remove.not_yours":        "Можеш уклонити само своје песме. **%s** је додао %s.",
+	"remove.failed":           "Уклањање није успело.",
+	"remove.done":             "🗑️ Уклоњено из реда: **%s**",
+
+	"settings.title":            "**Подешавања сервера**",
+	"settings.announce_channel": "Канал за најаве: %s",
+	"settings.announce_default": "канал у ком је песма затражена",
+	"settings.announce_mode":    "Начин најављивања: %s",
+	"settings.mode_off":         "искључено",
+	"settings.mode_single":      "једна порука плејера која се ажурира",
+	"settings.mode_track":       "нова порука за сваку песму",
+	"settings.mode_thread":      "нит за сваку сесију",
+	"settings.volume":           "Јачина звука: %d%%",
+	"settings.normalize":        "Нормализација гласноће: %s",
+	"settings.normalize_on":     "укључена, циљ %g LUFS",
+	"settings.normalize_off":    "искључена",
+	"settings.idle_timeout":     "Излазак из канала после неактивности: %s",
+	"settings.autoplay":         "Аутоматско пуштање сличних песама: %s",
+	"settings.on":               "укључено",
+	"settings.off":              "искључено",
+	"settings.locale":           "Језик: %s",
+	"settings.default_suffix":   "%s (подразумевано)",
+	"settings.reset":            "Подешавање `%s` је враћено на подразумевано.",
+
+	"announce.thread_name":   "Музика %s",
+	"announce.session_ended": "Сесија је завршена.",
+
+	"lang.name":        "Српски (ћирилица)",
+	"lang.set":         "Језик бота је сада: %s.",
+	"lang.auto_choice": "Аутоматски (Discord)",
+	"lang.auto":        "Језик бота се сада бира аутоматски према Discord подешавањима.",
+
+	"source.youtube":    "YouTube",
+	"source.soundcloud": "SoundCloud",
+	"source.direct":     "Директан стрим или радио",
+	"source.local":      "Локална библиотека",
+
+	"library.disabled":       "Локална библиотека није подешена.",
+	"library.no_match":       "Ништа у библиотеци не одговара упиту „%s”.",
+	"library.results_title":  "📚 Библиотека: %s",
+	"library.results_footer": "Приказано %d од %d • пусти песму са /library play",
+	"library.need_voice":     "Мораш бити повезан на гласовни канал да би пуштао из библиотеке.",
+	"library.queued_album":   "📀 Додато **%d** песама са албума **%s** у ред.",
+	"library.queued_artist":  "🎤 Додато **%d** песама извођача **%s** у ред.",
+	"library.rescanned":      "Библиотека је освежена: %d песама (%d нових, %d измењених, %d уклоњених).",
+	"library.rescan_failed":  "Освежавање библиотеке није успело.",
+	"library.unreadable":     "Ознаке %d фајлова нису могле да се прочитају.",
+	"library.skipped":        "%d фајлова или фасцикли није могло да се отвори; прескочени су.",
+
+	// Slash command names and descriptions.
+	"cmd.play.name":   "пусти",
+	"cmd.play":        "Пусти музику са YouTube-а или SoundCloud-а, или претражи.",
+	"cmd.play.query":  "URL адреса или упит за претрагу (префикс 'sc' за SoundCloud)",
+	"cmd.play.source": "Где да се тражи (подразумевано се бира према упиту).",
+
+	"cmd.player.name": "плејер",
+	"cmd.player":      "Прикажи тренутно стање плејера.",
+
+	"cmd.pause.name": "пауза",
+	"cmd.pause":      "Паузирај или настави репродукцију.",
+
+	"cmd.stop.name": "заустави",
+	"cmd.stop":      "Заустави репродукцију и испразни ред.",
+
+	"cmd.skip.name": "прескочи",
+	"cmd.skip":      "Прескочи тренутну песму.",
+
+	"cmd.loop.name":    "понављање",
+	"cmd.loop":         "Промени понављање реда.",
+	"cmd.loop.enabled": "Експлицитно постави понављање реда (изостави за промену).",
+
+	"cmd.queue.name":           "ред",
+	"cmd.queue":                "Извези или увези ред песама.",
+	"cmd.queue.export":         "Преузми тренутни ред и историју као датотеку.",
+	"cmd.queue.export.format":  "Формат датотеке (подразумевано JSON).",
+	"cmd.queue.import":         "Додај песме из M3U8, XSPF или JSON датотеке у ред.",
+	"cmd.queue.import.file":    "Датотека са листом песама.",
+	"cmd.queue.import.history": "Додај и песме из историје (подразумевано не).",
+
+	"cmd.favorites.name":            "омиљене",
+	"cmd.favorites":                 "Твоје омиљене песме.",
+	"cmd.favorites.list":            "Прикажи сачуване омиљене песме.",
+	"cmd.favorites.list.page":       "Страница листе.",
+	"cmd.favorites.play":            "Додај омиљене песме у ред.",
+	"cmd.favorites.play.count":      "Додај само насумичан избор од оволико песама.",
+	"cmd.favorites.play.shuffle":    "Измешај редослед песама.",
+	"cmd.favorites.remove":          "Уклони песму из омиљених.",
+	"cmd.favorites.remove.position": "Редни број песме из /favorites list.",
+
+	"cmd.library.name":           "библиотека",
+	"cmd.library":                "Локална музичка библиотека.",
+	"cmd.library.search":         "Претражи библиотеку.",
+	"cmd.library.search.query":   "Наслов, извођач или албум.",
+	"cmd.library.play":           "Пусти најбољи погодак из библиотеке.",
+	"cmd.library.play.query":     "Наслов, извођач или албум.",
+	"cmd.library.album":          "Додај цео албум у ред.",
+	"cmd.library.album.query":    "Назив албума, уз извођача по жељи.",
+	"cmd.library.album.shuffle":  "Измешај редослед песама.",
+	"cmd.library.artist":         "Додај све песме извођача у ред.",
+	"cmd.library.artist.query":   "Име извођача.",
+	"cmd.library.artist.shuffle": "Измешај редослед песама.",
+	"cmd.library.rescan":         "Поново прочитај фајлове у библиотеци.",
+
+	"cmd.remove.name":     "уклони",
+	"cmd.remove":          "Уклони песму из реда (своју, или било коју ако си DJ).",
+	"cmd.remove.position": "Позиција песме у реду.",
+
+	"cmd.permissions.name":          "дозволе",
+	"cmd.permissions":               "Подеси DJ улогу и дозволе за команде.",
+	"cmd.permissions.show":          "Прикажи тренутна правила.",
+	"cmd.permissions.dj-role":       "Постави DJ улогу (изостави за уклањање).",
+	"cmd.permissions.dj-role.role":  "Улога која има пуну контролу над плејером.",
+	"cmd.permissions.allow":         "Ограничи команду на ову улогу (и DJ-еве).",
+	"cmd.permissions.allow.command": "Команда.",
+	"cmd.permissions.allow.role":    "Улога.",
+	"cmd.permissions.deny":          "Забрани команду овој улози.",
+	"cmd.permissions.deny.command":  "Команда.",
+	"cmd.permissions.deny.role":     "Улога.",
+	"cmd.permissions.reset":         "Врати подразумевана правила за команду.",
+	"cmd.permissions.reset.command": "Команда.",
+
+	"cmd.limits.name":             "ограничења",
+	"cmd.limits":                  "Подеси ограничења реда за овај сервер.",
+	"cmd.limits.show":             "Прикажи тренутна ограничења.",
+	"cmd.limits.set":              "Промени ограничења (0 искључује ограничење).",
+	"cmd.limits.set.max_queue":    "Највише песама у реду.",
+	"cmd.limits.set.max_per_user": "Највише песама у реду по кориснику.",
+	"cmd.limits.set.max_duration": "Најдуже трајање песме у минутима.",
+	"cmd.limits.set.cooldown":     "Пауза између /play позива по кориснику, у секундама.",
+	"cmd.limits.set.reject_live":  "Одбиј преносе уживо.",
+
+	"cmd.settings.name":                 "подешавања",
+	"cmd.settings":                      "Подешавања плејера за овај сервер.",
+	"cmd.settings.show":                 "Прикажи тренутна подешавања.",
+	"cmd.settings.set":                  "Промени једно или више подешавања.",
+	"cmd.settings.set.announce_channel": "Канал у који се шаљу картице „Сада свира”.",
+	"cmd.settings.set.announce_mode":    "Како се најављују песме које свирају.",
+	"cmd.settings.set.volume":           "Јачина звука у процентима (100 је непромењено).",
+	"cmd.settings.set.normalize":        "Уједначи гласноћу песама.",
+	"cmd.settings.set.loudness":         "Циљна гласноћа нормализације у LUFS (подразумевано -16).",
+	"cmd.settings.set.idle_timeout":     "Минути неактивности пре изласка из гласовног канала.",
+	"cmd.settings.set.dj_role":          "Улога која има пуну контролу над плејером.",
+	"cmd.settings.set.max_queue":        "Највише песама у реду (0 искључује ограничење).",
+	"cmd.settings.set.locale":           "Језик бота на овом серверу.",
+	"cmd.settings.set.autoplay":         "Пуштај сличне песме када се ред испразни.",
+	"cmd.settings.reset":                "Врати подешавање на подразумевану вредност.",
+	"cmd.settings.reset.setting":        "Подешавање.",
+
+	"cmd.language.name":   "језик",
+	"cmd.language":        "Изабери језик бота за овај сервер.",
+	"cmd.language.locale": "Језик (аутоматски прати Discord подешавања).",
+}
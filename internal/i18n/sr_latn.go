@@ -0,0 +1,292 @@
+package i18n
+
+// serbianLatin is the Latin-script transliteration of serbianCyrillic.
+var serbianLatin = map[Key]string{
+	"err.guild_only":         "Ova komanda se može koristiti samo na serveru.",
+	"err.unknown_subcommand": "Nepoznata podkomanda.",
+	"err.nothing_playing":    "Ništa trenutno ne svira.",
+	"err.save_settings":      "Ne mogu da sačuvam podešavanja.",
+	"err.voice_connect":      "Neuspelo povezivanje na glasovni kanal: %v",
+	"err.queue_empty":        "Red je prazan.",
+
+	"common.failed_count": "Neuspelo: %d.",
+
+	"play.missing_query":     "Molim te unesi upit ili URL adresu.",
+	"play.empty_query":       "Molim te unesi upit.",
+	"play.need_voice":        "Moraš biti povezan na glasovni kanal da bi koristio /play.",
+	"play.preparing":         "Pripremam pesmu…",
+	"play.not_found":         "Ne mogu da pronađem ni da pustim tu pesmu.",
+	"play.enqueue_failed":    "Ne mogu da dodam pesmu u red: %v",
+	"play.queued":            "U redu **%s** — pozicija #%d.",
+	"play.queued_collection": "💿 Dodato **%d** pesama iz **%s** u red.",
+
+	"player.field_queue":  "U redu",
+	"player.queue_count":  "%d pesama",
+	"player.field_status": "Status",
+	"player.paused":       "⏸️ Pauzirano",
+
+	"button.pause":    "Pauza",
+	"button.stop":     "Zaustavi",
+	"button.skip":     "Preskoči",
+	"button.repeat":   "Ponovi",
+	"button.loop_on":  "Uključi ponavljanje",
+	"button.loop_off": "Isključi ponavljanje",
+
+	"pause.paused":  "⏸️ Reprodukcija pauzirana.",
+	"pause.resumed": "▶️ Reprodukcija nastavljena.",
+
+	"stop.nothing": "Nema ničega za zaustavljanje.",
+	"stop.done":    "⏹️ Reprodukcija zaustavljena i red ispražnjen.",
+
+	"loop.nothing":  "Ništa ne svira da bi se ponavljalo.",
+	"loop.enabled":  "Ponavljanje je uključeno.",
+	"loop.disabled": "Ponavljanje je isključeno.",
+
+	"embed.queued_title": "U redu • %s",
+	"embed.next_title":   "Sledeće • %s",
+	"embed.now":          "Sada • %s",
+	"embed.repeating":    "Ponavlja • %s",
+	"embed.duration":     "Trajanje",
+	"embed.source":       "Izvor",
+	"embed.requested_by": "Zahtevao",
+	"embed.position":     "Pozicija",
+	"embed.skip_votes":   "Glasovi za preskakanje",
+	"embed.on_air":       "Sada na stanici",
+
+	"skip.nothing":       "Nema aktivne pesme za preskakanje.",
+	"skip.done":          "⏭️ Preskočena je trenutna pesma.",
+	"skip.same_channel":  "Moraš biti u istom glasovnom kanalu kao bot da bi glasao za preskakanje.",
+	"skip.vote_passed":   "⏭️ Glasanje uspelo (%d/%d) — pesma je preskočena.",
+	"skip.vote_recorded": "🗳️ Glas je zabeležen: %d/%d za preskakanje.",
+
+	"fav.load_failed":      "Ne mogu da učitam omiljene pesme.",
+	"fav.empty_hint":       "Još nemaš omiljenih pesama. Pritisni ❤️ na kartici pesme koja svira.",
+	"fav.empty":            "Još nemaš omiljenih pesama.",
+	"fav.title":            "❤️ Omiljene pesme",
+	"fav.page":             "Stranica %d/%d • ukupno %d",
+	"fav.need_voice":       "Moraš biti povezan na glasovni kanal da bi pustio omiljene pesme.",
+	"fav.added_many":       "❤️ Dodato **%d** omiljenih pesama u red.",
+	"fav.missing_position": "Molim te unesi redni broj pesme.",
+	"fav.save_failed":      "Ne mogu da sačuvam omiljene pesme.",
+	"fav.no_such":          "Ne postoji pesma sa tim rednim brojem.",
+	"fav.removed":          "Uklonjeno iz omiljenih: **%s**",
+	"fav.card_unknown":     "Ne mogu da pronađem pesmu sa ove kartice.",
+	"fav.like_failed":      "Ne mogu da sačuvam pesmu u omiljene.",
+	"fav.already":          "**%s** je već u tvojim omiljenim.",
+	"fav.liked":            "❤️ Dodato u omiljene: **%s**",
+
+	"limit.queue_full": "Red je pun — najviše %d pesama može biti u redu.",
+	"limit.per_user":   "Već imaš %d pesama u redu, što je maksimum po korisniku.",
+	"limit.duration":   "Pesma **%s** (%s) je predugačka — dozvoljeno je najviše %s.",
+	"limit.live":       "Prenosi uživo nisu dozvoljeni na ovom serveru.",
+	"limit.cooldown":   "Sačekaj još %d s pre sledećeg /play.",
+	"limit.generic":    "Dostignuto je ograničenje reda.",
+
+	"resolve.not_found":      "Ništa nije pronađeno za taj upit ili link.",
+	"resolve.private":        "Taj snimak je privatan ili dostupan samo članovima kanala.",
+	"resolve.age_restricted": "Taj snimak je ograničen po uzrastu i ne može da se pusti bez prijavljenog naloga.",
+	"resolve.geo_blocked":    "Taj snimak nije dostupan u zemlji iz koje se bot povezuje.",
+	"resolve.copyright":      "Taj snimak je uklonjen zbog autorskih prava.",
+	"resolve.not_started":    "Taj prenos ili premijera još nije počela.",
+	"resolve.rate_limited":   "Sajt trenutno ograničava zahteve bota. Pokušaj ponovo za nekoliko minuta.",
+	"resolve.timeout":        "Traženje pesme je trajalo predugo. Pokušaj ponovo.",
+	"resolve.missing_binary": "Bot ne može da traži pesme jer yt-dlp nije instaliran. Obavesti administratora.",
+	"resolve.busy":           "Bot trenutno obrađuje previše zahteva. Pokušaj ponovo za trenutak.",
+	"resolve.no_match":       "Ta pesma nije pronađena ni na YouTube-u ni na SoundCloud-u.",
+	"resolve.unknown_source": "Taj izvor nije dostupan na ovom botu.",
+	"resolve.not_in_library": "Ta pesma nije u muzičkoj biblioteci.",
+	"resolve.failed":         "Ta pesma ne može da se pusti; sajt je prijavio grešku.",
+
+	"limits.title":         "**Ograničenja reda**",
+	"limits.off":           "isključeno",
+	"limits.live_allowed":  "dozvoljeni",
+	"limits.live_rejected": "zabranjeni",
+	"limits.max_queue":     "Najviše pesama u redu: %s",
+	"limits.max_per_user":  "Najviše pesama po korisniku: %s",
+	"limits.max_duration":  "Najduže trajanje pesme: %s",
+	"limits.cooldown":      "Pauza između /play: %s",
+	"limits.live":          "Prenosi uživo: %s",
+
+	"perm.admin_only":           "Ovu komandu mogu koristiti samo administratori servera.",
+	"perm.denied":               "Nemaš dozvolu da koristiš /%s.",
+	"perm.dj_only":              "Ova komanda je dostupna samo DJ-evima.",
+	"perm.same_channel":         "Moraš biti u glasovnom kanalu <#%s> da bi koristio ovu komandu.",
+	"perm.dj_cleared":           "DJ uloga je uklonjena.",
+	"perm.dj_set":               "DJ uloga je sada <@&%s>.",
+	"perm.unknown_command_role": "Nepoznata komanda ili uloga.",
+	"perm.allowed":              "Komanda /%s je dozvoljena ulozi <@&%s>.",
+	"perm.denied_role":          "Komanda /%s je zabranjena ulozi <@&%s>.",
+	"perm.unknown_command":      "Nepoznata komanda.",
+	"perm.reset":                "Pravila za /%s su vraćena na podrazumevana.",
+	"perm.dj_role":              "**DJ uloga:** %s",
+	"perm.dj_unset":             "nije podešena",
+	"perm.defaults":             "Sva pravila komandi su podrazumevana.",
+	"perm.rule":                 "`/%s` — dozvoljeno: %s; zabranjeno: %s",
+
+	"queue.bad_format":      "Nepoznat format datoteke.",
+	"queue.export_failed":   "Izvoz reda nije uspeo.",
+	"queue.export_summary":  "Red: %d pesama, istorija: %d pesama.",
+	"queue.need_file":       "Molim te priloži datoteku sa listom pesama.",
+	"queue.file_too_large":  "Datoteka je prevelika.",
+	"queue.need_voice":      "Moraš biti povezan na glasovni kanal da bi uvezao red.",
+	"queue.download_failed": "Ne mogu da preuzmem datoteku.",
+	"queue.unsupported":     "Nepoznat format datoteke. Podržani su M3U8, XSPF i JSON.",
+	"queue.read_failed":     "Ne mogu da pročitam datoteku: %v",
+	"queue.no_entries":      "Datoteka ne sadrži nijednu pesmu.",
+	"queue.imported":        "Uvezeno **%d** pesama u red.",
+	"queue.truncated":       "Preskočeno zbog ograničenja: %d.",
+
+	"remove.missing_position": "Molim te unesi poziciju pesme.",
+	"remove.no_such":          "Ne postoji pesma na toj poziciji.",
+	"remove.not_yours":        "Možeš ukloniti samo svoje pesme. **%s** je dodao %s.",
+	"remove.failed":           "Uklanjanje nije uspelo.",
+	"remove.done":             "🗑️ Uklonjeno iz reda: **%s**",
+
+	"settings.title":            "**Podešavanja servera**",
+	"settings.announce_channel": "Kanal za najave: %s",
+	"settings.announce_default": "kanal u kom je pesma zatražena",
+	"settings.announce_mode":    "Način najavljivanja: %s",
+	"settings.mode_off":         "isključeno",
+	"settings.mode_single":      "jedna poruka plejera koja se ažurira",
+	"settings.mode_track":       "nova poruka za svaku pesmu",
+	"settings.mode_thread":      "nit za svaku sesiju",
+	"settings.volume":           "Jačina zvuka: %d%%",
+	"settings.normalize":        "Normalizacija glasnoće: %s",
+	"settings.normalize_on":     "uključena, cilj %g LUFS",
+	"settings.normalize_off":    "isključena",
+	"settings.idle_timeout":     "Izlazak iz kanala posle neaktivnosti: %s",
+	"settings.autoplay":         "Automatsko puštanje sličnih pesama: %s",
+	"settings.on":               "uključeno",
+	"settings.off":              "isključeno",
+	"settings.locale":           "Jezik: %s",
+	"settings.default_suffix":   "%s (podrazumevano)",
+	"settings.reset":            "Podešavanje `%s` je vraćeno na podrazumevano.",
+
+	"announce.thread_name":   "Muzika %s",
+	"announce.session_ended": "Sesija je završena.",
+
+	"lang.name":        "Srpski (latinica)",
+	"lang.set":         "Jezik bota je sada: %s.",
+	"lang.auto_choice": "Automatski (Discord)",
+	"lang.auto":        "Jezik bota se sada bira automatski prema Discord podešavanjima.",
+
+	"source.youtube":    "YouTube",
+	"source.soundcloud": "SoundCloud",
+	"source.direct":     "Direktan strim ili radio",
+	"source.local":      "Lokalna biblioteka",
+
+	"library.disabled":       "Lokalna biblioteka nije podešena.",
+	"library.no_match":       "Ništa u biblioteci ne odgovara upitu „%s”.",
+	"library.results_title":  "📚 Biblioteka: %s",
+	"library.results_footer": "Prikazano %d od %d • pusti pesmu sa /library play",
+	"library.need_voice":     "Moraš biti povezan na glasovni kanal da bi puštao iz biblioteke.",
+	"library.queued_album":   "📀 Dodato **%d** pesama sa albuma **%s** u red.",
+	"library.queued_artist":  "🎤 Dodato **%d** pesama izvođača **%s** u red.",
+	"library.rescanned":      "Biblioteka je osvežena: %d pesama (%d novih, %d izmenjenih, %d uklonjenih).",
+	"library.rescan_failed":  "Osvežavanje biblioteke nije uspelo.",
+	"library.unreadable":     "Oznake %d fajlova nisu mogle da se pročitaju.",
+	"library.skipped":        "%d fajlova ili fascikli nije moglo da se otvori; preskočeni su.",
+
+	// Slash command names and descriptions.
+	"cmd.play.name":   "pusti",
+	"cmd.play":        "Pusti muziku sa YouTube-a ili SoundCloud-a, ili pretraži.",
+	"cmd.play.query":  "URL adresa ili upit za pretragu (prefiks 'sc' za SoundCloud)",
+	"cmd.play.source": "Gde da se traži (podrazumevano se bira prema upitu).",
+
+	"cmd.player.name": "plejer",
+	"cmd.player":      "Prikaži trenutno stanje plejera.",
+
+	"cmd.pause.name": "pauza",
+	"cmd.pause":      "Pauziraj ili nastavi reprodukciju.",
+
+	"cmd.stop.name": "zaustavi",
+	"cmd.stop":      "Zaustavi reprodukciju i isprazni red.",
+
+	"cmd.skip.name": "preskoči",
+	"cmd.skip":      "Preskoči trenutnu pesmu.",
+
+	"cmd.loop.name":    "ponavljanje",
+	"cmd.loop":         "Promeni ponavljanje reda.",
+	"cmd.loop.enabled": "Eksplicitno postavi ponavljanje reda (izostavi za promenu).",
+
+	"cmd.queue.name":           "red",
+	"cmd.queue":                "Izvezi ili uvezi red pesama.",
+	"cmd.queue.export":         "Preuzmi trenutni red i istoriju kao datoteku.",
+	"cmd.queue.export.format":  "Format datoteke (podrazumevano JSON).",
+	"cmd.queue.import":         "Dodaj pesme iz M3U8, XSPF ili JSON datoteke u red.",
+	"cmd.queue.import.file":    "Datoteka sa listom pesama.",
+	"cmd.queue.import.history": "Dodaj i pesme iz istorije (podrazumevano ne).",
+
+	"cmd.favorites.name":            "omiljene",
+	"cmd.favorites":                 "Tvoje omiljene pesme.",
+	"cmd.favorites.list":            "Prikaži sačuvane omiljene pesme.",
+	"cmd.favorites.list.page":       "Stranica liste.",
+	"cmd.favorites.play":            "Dodaj omiljene pesme u red.",
+	"cmd.favorites.play.count":      "Dodaj samo nasumičan izbor od ovoliko pesama.",
+	"cmd.favorites.play.shuffle":    "Izmešaj redosled pesama.",
+	"cmd.favorites.remove":          "Ukloni pesmu iz omiljenih.",
+	"cmd.favorites.remove.position": "Redni broj pesme iz /favorites list.",
+
+	"cmd.library.name":           "biblioteka",
+	"cmd.library":                "Lokalna muzička biblioteka.",
+	"cmd.library.search":         "Pretraži biblioteku.",
+	"cmd.library.search.query":   "Naslov, izvođač ili album.",
+	"cmd.library.play":           "Pusti najbolji pogodak iz biblioteke.",
+	"cmd.library.play.query":     "Naslov, izvođač ili album.",
+	"cmd.library.album":          "Dodaj ceo album u red.",
+	"cmd.library.album.query":    "Naziv albuma, uz izvođača po želji.",
+	"cmd.library.album.shuffle":  "Izmešaj redosled pesama.",
+	"cmd.library.artist":         "Dodaj sve pesme izvođača u red.",
+	"cmd.library.artist.query":   "Ime izvođača.",
+	"cmd.library.artist.shuffle": "Izmešaj redosled pesama.",
+	"cmd.library.rescan":         "Ponovo pročitaj fajlove u biblioteci.",
+
+	"cmd.remove.name":     "ukloni",
+	"cmd.remove":          "Ukloni pesmu iz reda (svoju, ili bilo koju ako si DJ).",
+	"cmd.remove.position": "Pozicija pesme u redu.",
+
+	"cmd.permissions.name":          "dozvole",
+	"cmd.permissions":               "Podesi DJ ulogu i dozvole za komande.",
+	"cmd.permissions.show":          "Prikaži trenutna pravila.",
+	"cmd.permissions.dj-role":       "Postavi DJ ulogu (izostavi za uklanjanje).",
+	"cmd.permissions.dj-role.role":  "Uloga koja ima punu kontrolu nad plejerom.",
+	"cmd.permissions.allow":         "Ograniči komandu na ovu ulogu (i DJ-eve).",
+	"cmd.permissions.allow.command": "Komanda.",
+	"cmd.permissions.allow.role":    "Uloga.",
+	"cmd.permissions.deny":          "Zabrani komandu ovoj ulozi.",
+	"cmd.permissions.deny.command":  "Komanda.",
+	"cmd.permissions.deny.role":     "Uloga.",
+	"cmd.permissions.reset":         "Vrati podrazumevana pravila za komandu.",
+	"cmd.permissions.reset.command": "Komanda.",
+
+	"cmd.limits.name":             "ograničenja",
+	"cmd.limits":                  "Podesi ograničenja reda za ovaj server.",
+	"cmd.limits.show":             "Prikaži trenutna ograničenja.",
+	"cmd.limits.set":              "Promeni ograničenja (0 isključuje ograničenje).",
+	"cmd.limits.set.max_queue":    "Najviše pesama u redu.",
+	"cmd.limits.set.max_per_user": "Najviše pesama u redu po korisniku.",
+	"cmd.limits.set.max_duration": "Najduže trajanje pesme u minutima.",
+	"cmd.limits.set.cooldown":     "Pauza između /play poziva po korisniku, u sekundama.",
+	"cmd.limits.set.reject_live":  "Odbij prenose uživo.",
+
+	"cmd.settings.name":                 "podešavanja",
+	"cmd.settings":                      "Podešavanja plejera za ovaj server.",
+	"cmd.settings.show":                 "Prikaži trenutna podešavanja.",
+	"cmd.settings.set":                  "Promeni jedno ili više podešavanja.",
+	"cmd.settings.set.announce_channel": "Kanal u koji se šalju kartice „Sada svira”.",
+	"cmd.settings.set.announce_mode":    "Kako se najavljuju pesme koje sviraju.",
+	"cmd.settings.set.volume":           "Jačina zvuka u procentima (100 je nepromenjeno).",
+	"cmd.settings.set.normalize":        "Ujednači glasnoću pesama.",
+	"cmd.settings.set.loudness":         "Ciljna glasnoća normalizacije u LUFS (podrazumevano -16).",
+	"cmd.settings.set.idle_timeout":     "Minuti neaktivnosti pre izlaska iz glasovnog kanala.",
+	"cmd.settings.set.dj_role":          "Uloga koja ima punu kontrolu nad plejerom.",
+	"cmd.settings.set.max_queue":        "Najviše pesama u redu (0 isključuje ograničenje).",
+	"cmd.settings.set.locale":           "Jezik bota na ovom serveru.",
+	"cmd.settings.set.autoplay":         "Puštaj slične pesme kada se red isprazni.",
+	"cmd.settings.reset":                "Vrati podešavanje na podrazumevanu vrednost.",
+	"cmd.settings.reset.setting":        "Podešavanje.",
+
+	"cmd.language.name":   "jezik",
+	"cmd.language":        "Izaberi jezik bota za ovaj server.",
+	"cmd.language.locale": "Jezik (automatski prati Discord podešavanja).",
+}
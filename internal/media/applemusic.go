@@ -0,0 +1,129 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var appleMusicPatterns = Patterns{
+	Hosts: []string{"music.apple.com", "itunes.apple.com"},
+}
+
+const (
+	appleLookupURL = "https://itunes.apple.com/lookup"
+	// appleSongURL links to a song as appleSongURL + country + "/song/" + ID.
+	appleSongURL = "https://music.apple.com/"
+)
+
+// appleMusicCatalog reads Apple Music through the public iTunes lookup API,
+// which needs no credentials but knows nothing of playlists.
+type appleMusicCatalog struct {
+	client    *http.Client
+	lookupURL string
+}
+
+// NewAppleMusicProvider returns the provider for Apple Music song and album
+// links, which it plays from YouTube or SoundCloud. A nil client uses
+// http.DefaultClient.
+func NewAppleMusicProvider(resolver *Resolver, client *http.Client) Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return newLinkProvider(ProviderAppleMusic, appleMusicPatterns, &appleMusicCatalog{client: client, lookupURL: appleLookupURL}, resolver)
+}
+
+// parse reads links such as https://music.apple.com/us/album/<name>/<id>,
+// which point at one of the album's songs with ?i=<song id>.
+func (c *appleMusicCatalog) parse(link *url.URL) (linkRef, bool) {
+	segments := pathSegments(link)
+	ref := linkRef{country: "us"}
+	if len(segments) > 0 && len(segments[0]) == 2 {
+		ref.country = strings.ToLower(segments[0])
+		segments = segments[1:]
+	}
+	if len(segments) < 2 {
+		return linkRef{}, false
+	}
+	// iTunes links prefix the ID with "id".
+	id := strings.TrimPrefix(segments[len(segments)-1], "id")
+
+	switch segments[0] {
+	case "song":
+		ref.kind, ref.id = linkTrack, id
+	case linkAlbum:
+		ref.kind, ref.id = linkAlbum, id
+		if song := link.Query().Get("i"); song != "" {
+			ref.kind, ref.id = linkTrack, song
+		}
+	case linkPlaylist:
+		ref.kind, ref.id = linkPlaylist, id
+	default:
+		return linkRef{}, false
+	}
+	return ref, ref.id != ""
+}
+
+func (c *appleMusicCatalog) lookup(ctx context.Context, ref linkRef) (*Collection, error) {
+	if ref.kind == linkPlaylist {
+		return nil, errors.New("media: apple music playlists cannot be read, only songs and albums")
+	}
+	if _, err := strconv.ParseInt(ref.id, 10, 64); err != nil {
+		return nil, fmt.Errorf("media: %q is not an apple music ID", ref.id)
+	}
+
+	query := url.Values{
+		"id":      {ref.id},
+		"entity":  {"song"},
+		"country": {ref.country},
+		"limit":   {strconv.Itoa(maxLinkTracks)},
+	}
+	var found struct {
+		Results []appleResult `json:"results"`
+	}
+	if err := getJSON(ctx, c.client, c.lookupURL+"?"+query.Encode(), "", &found); err != nil {
+		return nil, err
+	}
+
+	collection := &Collection{}
+	for _, result := range found.Results {
+		switch {
+		case result.WrapperType == "collection" && ref.kind == linkAlbum:
+			collection.Title = result.CollectionName
+		case result.WrapperType == "track" && result.Kind == "song":
+			if ref.kind == linkTrack && strconv.FormatInt(result.TrackID, 10) != ref.id {
+				continue
+			}
+			collection.Tracks = append(collection.Tracks, result.linkTrack(ref.country))
+		}
+	}
+	if len(collection.Tracks) == 0 {
+		return nil, fmt.Errorf("media: apple music has no song or album %s", ref.id)
+	}
+	return collection, nil
+}
+
+type appleResult struct {
+	WrapperType     string `json:"wrapperType"`
+	Kind            string `json:"kind"`
+	TrackID         int64  `json:"trackId"`
+	TrackName       string `json:"trackName"`
+	ArtistName      string `json:"artistName"`
+	CollectionName  string `json:"collectionName"`
+	TrackTimeMillis int64  `json:"trackTimeMillis"`
+}
+
+func (r appleResult) linkTrack(country string) LinkTrack {
+	return LinkTrack{
+		URL:      appleSongURL + country + "/song/" + strconv.FormatInt(r.TrackID, 10),
+		Title:    r.TrackName,
+		Artists:  splitArtists(strings.ReplaceAll(r.ArtistName, " & ", ", ")),
+		Album:    r.CollectionName,
+		Duration: time.Duration(r.TrackTimeMillis) * time.Millisecond,
+	}
+}
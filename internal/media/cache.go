@@ -0,0 +1,384 @@
+package media
+
+import (
+	"container/list"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"kvazar/internal/metrics"
+)
+
+const (
+	// searchAliasTTL is how long a search keeps leading to the video it
+	// found, which newer uploads may displace.
+	searchAliasTTL = 24 * time.Hour
+	// defaultStreamTTL is how long stream URLs without an expire parameter
+	// are trusted.
+	defaultStreamTTL = 30 * time.Minute
+	// streamMargin is how much longer than the track itself a cached stream
+	// URL must stay valid to be played.
+	streamMargin = 2 * time.Minute
+	// aliasesPerEntry bounds the aliases as a multiple of MaxEntries.
+	aliasesPerEntry = 4
+)
+
+// Results of a cache lookup, as counted in the metrics.
+const (
+	cacheHit   = "hit"
+	cacheStale = "stale"
+	cacheMiss  = "miss"
+)
+
+// trackingParams are query parameters that do not change what a URL plays.
+var trackingParams = []string{"si", "feature", "pp", "utm_source", "utm_medium", "utm_campaign", "utm_content", "utm_term"}
+
+// ResolveCache remembers what yt-dlp found for recent lookups, so a song
+// played again does not need yt-dlp until it is opened. Entries are stored
+// under the video's canonical ID; URLs and searches that led there are
+// aliases, so different spellings of a search share one entry. The metadata
+// is kept for MetadataTTL and the stream URL until it expires. The least
+// recently used entries are dropped beyond MaxEntries.
+//
+// A ResolveCache encodes to and from JSON to be kept across restarts. A nil
+// cache caches nothing.
+type ResolveCache struct {
+	MaxEntries  int
+	MetadataTTL time.Duration
+
+	mu      sync.Mutex
+	entries lru[cacheEntry]
+	aliases lru[cacheAlias]
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	Item    ytdlpItem `json:"item"`
+	Fetched time.Time `json:"fetched"`
+	// StreamExpires is when Item.URL stops working.
+	StreamExpires time.Time `json:"stream_expires"`
+}
+
+type cacheAlias struct {
+	Key     string    `json:"key"`
+	Expires time.Time `json:"expires"`
+}
+
+// NewResolveCache returns a cache holding up to maxEntries tracks, whose
+// metadata is looked up again after metadataTTL.
+func NewResolveCache(maxEntries int, metadataTTL time.Duration) *ResolveCache {
+	return &ResolveCache{
+		MaxEntries:  maxEntries,
+		MetadataTTL: metadataTTL,
+		entries:     newLRU[cacheEntry](),
+		aliases:     newLRU[cacheAlias](),
+		now:         time.Now,
+	}
+}
+
+// Len reports how many tracks the cache holds.
+func (c *ResolveCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries.len()
+}
+
+// get returns the cached item for a prepared query. With stream set, the
+// item's stream URL must also be valid for the whole track; otherwise the
+// stream URL is left out, to be looked up when the track is opened.
+func (c *ResolveCache) get(query string, stream bool) (ytdlpItem, bool) {
+	if c == nil {
+		return ytdlpItem{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+
+	key := queryKey(query)
+	if alias, ok := c.aliases.get(key); ok {
+		if now.After(alias.Expires) {
+			c.aliases.remove(key)
+		} else {
+			key = alias.Key
+		}
+	}
+	entry, ok := c.entries.get(key)
+	if ok && now.Sub(entry.Fetched) > c.MetadataTTL {
+		c.entries.remove(key)
+		metrics.SetResolveCacheEntries(c.entries.len())
+		ok = false
+	}
+	if !ok {
+		metrics.ObserveResolveCache(cacheMiss)
+		return ytdlpItem{}, false
+	}
+
+	item := entry.Item
+	if stream {
+		if item.URL == "" || entry.StreamExpires.Sub(now) < itemDuration(item)+streamMargin {
+			metrics.ObserveResolveCache(cacheStale)
+			return ytdlpItem{}, false
+		}
+	} else {
+		item.URL = ""
+		item.HTTPHeaders = nil
+		item.Cookies = ""
+	}
+	metrics.ObserveResolveCache(cacheHit)
+	return item, true
+}
+
+// put stores what yt-dlp reported for a prepared query.
+func (c *ResolveCache) put(query string, item ytdlpItem) {
+	if c == nil || item.WebpageURL == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+
+	key := itemKey(item)
+	c.entries.put(key, cacheEntry{Item: item, Fetched: now, StreamExpires: streamExpiry(item.URL, now)})
+	if alias := queryKey(query); alias != key {
+		ttl := c.MetadataTTL
+		if isSearch(query) {
+			ttl = min(ttl, searchAliasTTL)
+		}
+		c.aliases.put(alias, cacheAlias{Key: key, Expires: now.Add(ttl)})
+	}
+	if alias := queryKey(item.WebpageURL); alias != key {
+		c.aliases.put(alias, cacheAlias{Key: key, Expires: now.Add(c.MetadataTTL)})
+	}
+
+	if evicted := c.entries.trim(c.MaxEntries); evicted > 0 {
+		metrics.ResolveCacheEvicted(evicted)
+	}
+	c.aliases.trim(c.MaxEntries * aliasesPerEntry)
+	metrics.SetResolveCacheEntries(c.entries.len())
+}
+
+type cacheSnapshot struct {
+	Entries []keyed[cacheEntry] `json:"entries"`
+	Aliases []keyed[cacheAlias] `json:"aliases"`
+}
+
+type keyed[V any] struct {
+	Key   string `json:"key"`
+	Value V      `json:"value"`
+}
+
+// MarshalJSON encodes the cache, least recently used entries first. Streams
+// opened with cookies or credentials are left out, so the cache file holds
+// no secrets; they are looked up again when played.
+func (c *ResolveCache) MarshalJSON() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := c.entries.all()
+	for i := range entries {
+		if item := &entries[i].Value.Item; hasCredentials(*item) {
+			item.URL = ""
+			item.HTTPHeaders = nil
+			item.Cookies = ""
+			entries[i].Value.StreamExpires = time.Time{}
+		}
+	}
+	return json.Marshal(cacheSnapshot{Entries: entries, Aliases: c.aliases.all()})
+}
+
+// hasCredentials reports whether opening an item's stream takes cookies or
+// an Authorization header.
+func hasCredentials(item ytdlpItem) bool {
+	return item.Cookies != "" || sendsCredentials(item.HTTPHeaders)
+}
+
+// UnmarshalJSON adds the entries of an encoded cache that have not expired.
+func (c *ResolveCache) UnmarshalJSON(data []byte) error {
+	var snapshot cacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for _, entry := range snapshot.Entries {
+		if now.Sub(entry.Value.Fetched) <= c.MetadataTTL {
+			c.entries.put(entry.Key, entry.Value)
+		}
+	}
+	for _, alias := range snapshot.Aliases {
+		if now.Before(alias.Value.Expires) {
+			c.aliases.put(alias.Key, alias.Value)
+		}
+	}
+	c.entries.trim(c.MaxEntries)
+	c.aliases.trim(c.MaxEntries * aliasesPerEntry)
+	metrics.SetResolveCacheEntries(c.entries.len())
+	return nil
+}
+
+// queryKey normalizes a prepared query: YouTube links become the video's
+// key, other links lose tracking parameters, and searches are compared by
+// their words alone.
+func queryKey(query string) string {
+	query = strings.TrimSpace(query)
+	for _, prefix := range []string{"ytsearch:", "scsearch:"} {
+		if text, ok := strings.CutPrefix(query, prefix); ok {
+			normalized := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+			return prefix + strings.Join(normalized, " ")
+		}
+	}
+
+	parsed, err := url.Parse(query)
+	if err != nil || !looksLikeURL(query) {
+		return strings.ToLower(query)
+	}
+	if id := youTubeID(parsed); id != "" {
+		return "youtube:" + id
+	}
+	values := parsed.Query()
+	for _, param := range trackingParams {
+		values.Del(param)
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Host), "www.")
+	key := host + strings.TrimSuffix(parsed.EscapedPath(), "/")
+	if len(values) > 0 {
+		key += "?" + values.Encode()
+	}
+	return key
+}
+
+// itemKey is the canonical key of what yt-dlp found: the extractor and the
+// ID, such as youtube:dQw4w9WgXcQ.
+func itemKey(item ytdlpItem) string {
+	extractor := strings.ToLower(firstNonEmpty(item.Extractor, item.IEKey))
+	if extractor == "" || item.ID == "" {
+		return queryKey(item.WebpageURL)
+	}
+	return extractor + ":" + item.ID
+}
+
+// youTubeID returns the video ID of a YouTube video link.
+func youTubeID(link *url.URL) string {
+	host := strings.ToLower(link.Hostname())
+	segments := pathSegments(link)
+	switch {
+	case host == "youtu.be":
+		if len(segments) > 0 {
+			return segments[0]
+		}
+	case youTubePatterns.Match("https://" + host):
+		if id := link.Query().Get("v"); id != "" {
+			return id
+		}
+		if len(segments) == 2 {
+			switch segments[0] {
+			case "shorts", "embed", "live", "v":
+				return segments[1]
+			}
+		}
+	}
+	return ""
+}
+
+// streamExpiry reads when a stream URL expires from its expire parameter,
+// which YouTube puts in the query or, for manifests, the path.
+func streamExpiry(stream string, now time.Time) time.Time {
+	parsed, err := url.Parse(stream)
+	if err != nil || stream == "" {
+		return now
+	}
+	expire := parsed.Query().Get("expire")
+	if expire == "" {
+		segments := pathSegments(parsed)
+		for i := 0; i+1 < len(segments); i++ {
+			if segments[i] == "expire" {
+				expire = segments[i+1]
+				break
+			}
+		}
+	}
+	if seconds, err := strconv.ParseInt(expire, 10, 64); err == nil {
+		return time.Unix(seconds, 0)
+	}
+	return now.Add(defaultStreamTTL)
+}
+
+func itemDuration(item ytdlpItem) time.Duration {
+	seconds, err := item.Duration.Float64()
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func isSearch(query string) bool {
+	return strings.HasPrefix(query, "ytsearch") || strings.HasPrefix(query, "scsearch")
+}
+
+// lru is a map that remembers the order its keys were last used in.
+type lru[V any] struct {
+	order *list.List
+	items map[string]*list.Element
+}
+
+func newLRU[V any]() lru[V] {
+	return lru[V]{order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (l *lru[V]) get(key string) (V, bool) {
+	element, ok := l.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	l.order.MoveToFront(element)
+	return element.Value.(*keyed[V]).Value, true
+}
+
+func (l *lru[V]) put(key string, value V) {
+	if element, ok := l.items[key]; ok {
+		element.Value.(*keyed[V]).Value = value
+		l.order.MoveToFront(element)
+		return
+	}
+	l.items[key] = l.order.PushFront(&keyed[V]{Key: key, Value: value})
+}
+
+func (l *lru[V]) remove(key string) {
+	if element, ok := l.items[key]; ok {
+		l.order.Remove(element)
+		delete(l.items, key)
+	}
+}
+
+// trim drops the least recently used keys beyond max and reports how many.
+func (l *lru[V]) trim(max int) int {
+	dropped := 0
+	for l.order.Len() > max {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*keyed[V]).Key)
+		dropped++
+	}
+	return dropped
+}
+
+func (l *lru[V]) len() int { return l.order.Len() }
+
+// all lists the entries, least recently used first.
+func (l *lru[V]) all() []keyed[V] {
+	out := make([]keyed[V], 0, l.order.Len())
+	for element := l.order.Back(); element != nil; element = element.Prev() {
+		out = append(out, *element.Value.(*keyed[V]))
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClock is a settable clock for caches.
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func newTestCache(maxEntries int, clock *testClock) *ResolveCache {
+	cache := NewResolveCache(maxEntries, 48*time.Hour)
+	cache.now = clock.Now
+	return cache
+}
+
+// cachedVideo is what yt-dlp reports for a YouTube video whose stream URL
+// expires at expire.
+func cachedVideo(id string, expire time.Time) ytdlpItem {
+	return ytdlpItem{
+		ID:         id,
+		Title:      "Video " + id,
+		Extractor:  "Youtube",
+		WebpageURL: "https://www.youtube.com/watch?v=" + id,
+		URL:        "https://rr1.googlevideo.com/videoplayback?id=" + id + "&expire=" + strconv.FormatInt(expire.Unix(), 10),
+		Duration:   "180",
+	}
+}
+
+func TestResolveCache(t *testing.T) {
+	clock := &testClock{now: time.Unix(1_700_000_000, 0)}
+	cache := newTestCache(10, clock)
+	cache.put("ytsearch:Kosmos - Orbit", cachedVideo("orbit", clock.now.Add(6*time.Hour)))
+
+	tests := []struct {
+		name   string
+		after  time.Duration
+		query  string
+		stream bool
+		hit    bool
+	}{
+		{"same search, other spelling", 0, "ytsearch:kosmos   orbit!", false, true},
+		{"other search", 0, "ytsearch:kosmos gravity", false, false},
+		{"short link with tracking", 0, "https://youtu.be/orbit?si=share", true, true},
+		{"shorts link", 0, "https://m.youtube.com/shorts/orbit", false, true},
+		{"stream nearly expired", 6*time.Hour - 4*time.Minute, "https://youtube.com/watch?v=orbit", true, false},
+		{"metadata still fresh", 6 * time.Hour, "https://youtube.com/watch?v=orbit", false, true},
+		{"search alias expired", 25 * time.Hour, "ytsearch:kosmos orbit", false, false},
+		{"link after the alias expired", 25 * time.Hour, "https://youtube.com/watch?v=orbit", false, true},
+		{"metadata expired", 49 * time.Hour, "https://youtube.com/watch?v=orbit", false, false},
+	}
+	start := clock.now
+	for _, tt := range tests {
+		clock.now = start.Add(tt.after)
+		item, ok := cache.get(tt.query, tt.stream)
+		if ok != tt.hit {
+			t.Errorf("%s: hit = %v, want %v", tt.name, ok, tt.hit)
+			continue
+		}
+		if ok && item.ID != "orbit" {
+			t.Errorf("%s: got %q", tt.name, item.ID)
+		}
+		if ok && (item.URL != "") != tt.stream {
+			t.Errorf("%s: stream URL %q", tt.name, item.URL)
+		}
+	}
+}
+
+func TestResolveCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	clock := &testClock{now: time.Unix(1_700_000_000, 0)}
+	cache := newTestCache(2, clock)
+	expire := clock.now.Add(time.Hour)
+	cache.put("https://youtu.be/a", cachedVideo("a", expire))
+	cache.put("https://youtu.be/b", cachedVideo("b", expire))
+	cache.get("https://youtu.be/a", false)
+	cache.put("https://youtu.be/c", cachedVideo("c", expire))
+
+	for id, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := cache.get("https://youtu.be/"+id, false); ok != want {
+			t.Errorf("%s cached = %v, want %v", id, ok, want)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Len = %d", cache.Len())
+	}
+}
+
+func TestResolveCachePersists(t *testing.T) {
+	clock := &testClock{now: time.Unix(1_700_000_000, 0)}
+	cache := newTestCache(10, clock)
+	cache.put("ytsearch:orbit", cachedVideo("orbit", clock.now.Add(6*time.Hour)))
+	clock.now = clock.now.Add(47 * time.Hour)
+	cache.put("ytsearch:gravity", cachedVideo("gravity", clock.now.Add(6*time.Hour)))
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(2 * time.Hour)
+	restored := newTestCache(10, clock)
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != 1 {
+		t.Errorf("restored %d entries, want the one that has not expired", restored.Len())
+	}
+	if item, ok := restored.get("ytsearch:gravity", true); !ok || item.ID != "gravity" {
+		t.Errorf("restored search = %+v, %v", item, ok)
+	}
+}
+
+func TestResolveCachePersistsNoCookies(t *testing.T) {
+	clock := &testClock{now: time.Unix(1_700_000_000, 0)}
+	cache := newTestCache(10, clock)
+	signedIn := cachedVideo("orbit", clock.now.Add(6*time.Hour))
+	signedIn.Cookies = "SID=secret; Domain=.youtube.com; Path=/"
+	signedIn.HTTPHeaders = map[string]string{"User-Agent": "test", "Cookie": "SID=secret"}
+	cache.put("ytsearch:orbit", signedIn)
+	authorized := cachedVideo("gravity", clock.now.Add(6*time.Hour))
+	authorized.HTTPHeaders = map[string]string{"authorization": "Bearer secret"}
+	cache.put("ytsearch:gravity", authorized)
+	cache.put("ytsearch:nebula", cachedVideo("nebula", clock.now.Add(6*time.Hour)))
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("persisted cache holds credentials: %s", data)
+	}
+	if item, ok := cache.get("ytsearch:orbit", true); !ok || item.Cookies == "" {
+		t.Errorf("persisting changed the cached item: %+v, %v", item, ok)
+	}
+
+	restored := newTestCache(10, clock)
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := restored.get("ytsearch:orbit", true); ok {
+		t.Error("restored a stream without the cookies it needs")
+	}
+	if item, ok := restored.get("ytsearch:orbit", false); !ok || item.ID != "orbit" {
+		t.Errorf("restored metadata = %+v, %v", item, ok)
+	}
+	if _, ok := restored.get("ytsearch:nebula", true); !ok {
+		t.Error("lost a stream that needs no cookies")
+	}
+}
+
+func TestQueryKey(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{"ytsearch:  Kosmos – Orbit (Official)", "ytsearch:kosmos orbit official"},
+		{"scsearch:Kosmos Orbit", "scsearch:kosmos orbit"},
+		{"https://www.youtube.com/watch?v=abc&feature=share", "youtube:abc"},
+		{"https://music.youtube.com/watch?v=abc&list=RDabc", "youtube:abc"},
+		{"https://youtube.com/embed/abc", "youtube:abc"},
+		{"https://www.youtube.com/playlist?list=PL1", "youtube.com/playlist?list=PL1"},
+		{"https://soundcloud.com/kosmos/orbit/?si=x&utm_source=clipboard", "soundcloud.com/kosmos/orbit"},
+	}
+	for _, tt := range tests {
+		if got := queryKey(tt.query); got != tt.want {
+			t.Errorf("queryKey(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+// TestResolverCache checks that a cached search needs no yt-dlp run to be
+// queued or opened.
+func TestResolverCache(t *testing.T) {
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	expire := strconv.FormatInt(time.Now().Add(6*time.Hour).Unix(), 10)
+	ytdlp := filepath.Join(dir, "yt-dlp")
+	script := `#!/bin/sh
+echo run >> ` + runs + `
+printf '{"id":"orbit","title":"Orbit","extractor_key":"Youtube","webpage_url":"https://www.youtube.com/watch?v=orbit","url":"https://media.example.com/orbit?expire=` + expire + `","duration":180}\n'
+`
+	if err := os.WriteFile(ytdlp, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewResolver(ytdlp)
+	resolver.Cache = NewResolveCache(10, time.Hour)
+	provider := NewYouTubeProvider(resolver)
+
+	first, err := provider.Resolve(context.Background(), "Kosmos Orbit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := provider.Resolve(context.Background(), "kosmos - orbit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.StreamURL == "" || second.StreamURL != "" || second.Title != "Orbit" || second.Duration != 3*time.Minute {
+		t.Errorf("first = %+v, second = %+v", first, second)
+	}
+	input, err := provider.Open(context.Background(), second)
+	if err != nil || !strings.HasPrefix(input.URL, "https://media.example.com/orbit") {
+		t.Errorf("Open = %+v, %v", input, err)
+	}
+
+	output, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(output), "run"); n != 1 {
+		t.Errorf("yt-dlp ran %d times, want once", n)
+	}
+}
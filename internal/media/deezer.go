@@ -0,0 +1,151 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var deezerPatterns = Patterns{
+	Hosts: []string{"deezer.com"},
+}
+
+const (
+	deezerAPIURL = "https://api.deezer.com"
+	// deezerTrackURL links to a track by its ID.
+	deezerTrackURL = "https://www.deezer.com/track/"
+)
+
+// deezerCatalog reads Deezer through its public API, which needs no
+// credentials.
+type deezerCatalog struct {
+	client *http.Client
+	apiURL string
+}
+
+// NewDeezerProvider returns the provider for Deezer track, album and
+// playlist links, which it plays from YouTube or SoundCloud. A nil client
+// uses http.DefaultClient.
+func NewDeezerProvider(resolver *Resolver, client *http.Client) Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return newLinkProvider(ProviderDeezer, deezerPatterns, &deezerCatalog{client: client, apiURL: deezerAPIURL}, resolver)
+}
+
+// parse reads links such as https://www.deezer.com/en/album/<id>.
+func (c *deezerCatalog) parse(link *url.URL) (linkRef, bool) {
+	segments := pathSegments(link)
+	if len(segments) > 0 && len(segments[0]) == 2 {
+		segments = segments[1:]
+	}
+	if len(segments) < 2 {
+		return linkRef{}, false
+	}
+	if _, err := strconv.ParseInt(segments[1], 10, 64); err != nil {
+		return linkRef{}, false
+	}
+	switch segments[0] {
+	case linkTrack, linkAlbum, linkPlaylist:
+		return linkRef{kind: segments[0], id: segments[1]}, true
+	}
+	return linkRef{}, false
+}
+
+func (c *deezerCatalog) lookup(ctx context.Context, ref linkRef) (*Collection, error) {
+	if ref.kind == linkTrack {
+		var track deezerTrack
+		if err := c.get(ctx, c.apiURL+"/track/"+ref.id, &track, &track.deezerError); err != nil {
+			return nil, err
+		}
+		return &Collection{Tracks: []LinkTrack{track.linkTrack("")}}, nil
+	}
+
+	var listing struct {
+		deezerError
+		Title  string `json:"title"`
+		Tracks struct {
+			Data []deezerTrack `json:"data"`
+			Next string        `json:"next"`
+		} `json:"tracks"`
+	}
+	if err := c.get(ctx, c.apiURL+"/"+ref.kind+"/"+ref.id, &listing, &listing.deezerError); err != nil {
+		return nil, err
+	}
+	tracks := listing.Tracks.Data
+	for next := listing.Tracks.Next; next != "" && len(tracks) < maxLinkTracks; {
+		var page struct {
+			deezerError
+			Data []deezerTrack `json:"data"`
+			Next string        `json:"next"`
+		}
+		if err := c.get(ctx, next, &page, &page.deezerError); err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, page.Data...)
+		next = page.Next
+	}
+
+	collection := &Collection{Title: listing.Title}
+	album := ""
+	if ref.kind == linkAlbum {
+		album = listing.Title
+	}
+	for _, track := range tracks {
+		collection.Tracks = append(collection.Tracks, track.linkTrack(album))
+	}
+	return collection, nil
+}
+
+// get decodes an API answer into v. Deezer reports failures such as unknown
+// IDs in the body of a successful response, which land in apiErr.
+func (c *deezerCatalog) get(ctx context.Context, rawURL string, v any, apiErr *deezerError) error {
+	if err := getJSON(ctx, c.client, rawURL, "", v); err != nil {
+		return err
+	}
+	if apiErr.Error != nil {
+		return fmt.Errorf("media: deezer: %s", apiErr.Error.Message)
+	}
+	return nil
+}
+
+type deezerError struct {
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type deezerTrack struct {
+	deezerError
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Duration int64  `json:"duration"`
+	Artist   struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+	Contributors []struct {
+		Name string `json:"name"`
+	} `json:"contributors"`
+	Album struct {
+		Title string `json:"title"`
+	} `json:"album"`
+}
+
+func (t deezerTrack) linkTrack(album string) LinkTrack {
+	track := LinkTrack{
+		URL:      deezerTrackURL + strconv.FormatInt(t.ID, 10),
+		Title:    t.Title,
+		Album:    firstNonEmpty(album, t.Album.Title),
+		Duration: time.Duration(t.Duration) * time.Second,
+	}
+	for _, contributor := range t.Contributors {
+		track.Artists = append(track.Artists, contributor.Name)
+	}
+	if len(track.Artists) == 0 && t.Artist.Name != "" {
+		track.Artists = []string{t.Artist.Name}
+	}
+	return track
+}
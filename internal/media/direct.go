@@ -0,0 +1,267 @@
+package media
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ProviderDirect is the name of the provider that plays audio URLs and
+// internet radio straight through ffmpeg.
+const ProviderDirect = "direct"
+
+// audioExtensions are the file extensions the direct provider claims without
+// probing the URL first, and the files the local library indexes.
+var audioExtensions = []string{".mp3", ".aac", ".m4a", ".ogg", ".oga", ".opus", ".flac", ".wav"}
+
+// probeTimeout bounds how long a URL may take to answer a probe.
+const probeTimeout = 10 * time.Second
+
+// directProvider plays HTTP audio files and Icecast/Shoutcast streams
+// without yt-dlp. Streams are treated as live; stations that send ICY
+// metadata also report the song they are playing.
+type directProvider struct {
+	client *http.Client
+}
+
+// NewDirectProvider returns the provider for direct audio URLs. It claims
+// URLs ending in an audio file extension, and other URLs nobody else claims
+// once a probe shows they serve audio. A nil client uses
+// http.DefaultClient.
+func NewDirectProvider(client *http.Client) Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &directProvider{client: client}
+}
+
+func (p *directProvider) Name() string { return ProviderDirect }
+
+func (p *directProvider) Match(query string) bool {
+	parsed, ok := httpURL(query)
+	return ok && isAudioFile(parsed.Path)
+}
+
+// Probe reports whether the URL serves audio.
+func (p *directProvider) Probe(ctx context.Context, query string) bool {
+	if _, ok := httpURL(query); !ok {
+		return false
+	}
+	info, err := p.probe(ctx, strings.TrimSpace(query))
+	return err == nil && info.audio()
+}
+
+func (p *directProvider) Resolve(ctx context.Context, query string) (*Track, error) {
+	query = strings.TrimSpace(query)
+	parsed, ok := httpURL(query)
+	if !ok {
+		return nil, fmt.Errorf("media: direct streams need an http(s) URL, got %q", query)
+	}
+	info, err := p.probe(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if !info.audio() {
+		return nil, fmt.Errorf("media: %s is not an audio stream (%s)", query, info.contentType)
+	}
+
+	track := &Track{
+		Title:     info.name,
+		Author:    info.description,
+		WebURL:    query,
+		StreamURL: query,
+		Source:    SourceDirect,
+	}
+	if info.icy {
+		track.Source = SourceRadio
+	}
+	if track.Title == "" {
+		track.Title = fileTitle(parsed)
+	}
+	if track.Author == "" {
+		track.Author = parsed.Hostname()
+	}
+	return track, nil
+}
+
+func (p *directProvider) Open(_ context.Context, track *Track) (Input, error) {
+	return Input{URL: fallbackURL(track.StreamURL, track.WebURL)}, nil
+}
+
+// WatchTitles reads a radio station's ICY metadata and reports each new song
+// title. It returns once ctx is cancelled, the stream ends, or right away for
+// streams without metadata.
+func (p *directProvider) WatchTitles(ctx context.Context, track *Track, update func(title string)) error {
+	if track.Source != SourceRadio {
+		return nil
+	}
+	resp, err := p.get(ctx, fallbackURL(track.StreamURL, track.WebURL))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	interval, err := strconv.Atoi(resp.Header.Get("icy-metaint"))
+	if err != nil || interval <= 0 {
+		return nil
+	}
+	err = readICYTitles(resp.Body, interval, update)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// streamInfo is what a probe learns from a URL's response headers.
+type streamInfo struct {
+	contentType string
+	icy         bool
+	name        string
+	description string
+}
+
+func (s streamInfo) audio() bool {
+	switch {
+	case strings.HasPrefix(s.contentType, "audio/"), s.contentType == "application/ogg":
+		return true
+	case s.contentType == "application/octet-stream", s.contentType == "":
+		// Some stations send no useful type but do speak ICY.
+		return s.icy
+	}
+	return false
+}
+
+// probe requests the URL and reads its headers without downloading the body.
+func (p *directProvider) probe(ctx context.Context, rawURL string) (streamInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	resp, err := p.get(ctx, rawURL)
+	if err != nil {
+		return streamInfo{}, err
+	}
+	resp.Body.Close()
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	info := streamInfo{
+		contentType: strings.ToLower(contentType),
+		name:        strings.TrimSpace(resp.Header.Get("icy-name")),
+		description: strings.TrimSpace(resp.Header.Get("icy-description")),
+	}
+	for key := range resp.Header {
+		if strings.HasPrefix(strings.ToLower(key), "icy-") {
+			info.icy = true
+			break
+		}
+	}
+	return info, nil
+}
+
+// get opens the URL asking for ICY metadata. Shoutcast v1 servers, which
+// answer with a bare "ICY 200 OK" status line, are not supported.
+func (p *directProvider) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Icy-MetaData", "1")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("media: request %s: %w", rawURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("media: request %s: %s", rawURL, resp.Status)
+	}
+	return resp, nil
+}
+
+// readICYTitles skips the audio between metadata blocks, which come every
+// interval bytes, and calls update whenever StreamTitle changes.
+func readICYTitles(r io.Reader, interval int, update func(title string)) error {
+	reader := bufio.NewReader(r)
+	last := ""
+	for {
+		if _, err := io.CopyN(io.Discard, reader, int64(interval)); err != nil {
+			return err
+		}
+		size, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			continue
+		}
+		block := make([]byte, int(size)*16)
+		if _, err := io.ReadFull(reader, block); err != nil {
+			return err
+		}
+		title, ok := parseStreamTitle(block)
+		if ok && title != last {
+			last = title
+			update(title)
+		}
+	}
+}
+
+// parseStreamTitle extracts StreamTitle from a metadata block such as
+// "StreamTitle='Artist - Song';StreamUrl='http://example.com';". Titles
+// that are not valid UTF-8 are read as Latin-1, which older stations still
+// send.
+func parseStreamTitle(block []byte) (string, bool) {
+	meta := strings.TrimRight(string(block), "\x00")
+	const key = "StreamTitle='"
+	start := strings.Index(meta, key)
+	if start < 0 {
+		return "", false
+	}
+	value := meta[start+len(key):]
+	// Titles may contain quotes, so only a quote ending the field counts.
+	if end := strings.Index(value, "';"); end >= 0 {
+		value = value[:end]
+	} else {
+		value = strings.TrimSuffix(value, "'")
+	}
+	if !utf8.ValidString(value) {
+		runes := make([]rune, len(value))
+		for i := 0; i < len(value); i++ {
+			runes[i] = rune(value[i])
+		}
+		value = string(runes)
+	}
+	return strings.TrimSpace(value), true
+}
+
+// httpURL parses the query as an http or https URL.
+func httpURL(query string) (*url.URL, bool) {
+	query = strings.TrimSpace(query)
+	if !looksLikeURL(query) {
+		return nil, false
+	}
+	parsed, err := url.Parse(query)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, false
+	}
+	return parsed, true
+}
+
+// fileTitle names a track after the file in its URL, or its host.
+func fileTitle(u *url.URL) string {
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return u.Hostname()
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
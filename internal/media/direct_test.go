@@ -0,0 +1,166 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// icyBlock encodes a metadata block as a station sends it: a length byte
+// counting 16-byte units, then the padded text.
+func icyBlock(meta string) []byte {
+	size := (len(meta) + 15) / 16
+	block := make([]byte, 1+size*16)
+	block[0] = byte(size)
+	copy(block[1:], meta)
+	return block
+}
+
+// radioServer plays a station that sends metaint bytes of audio between
+// metadata blocks announcing each of the titles in turn.
+func radioServer(t *testing.T, metaint int, titles ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("icy-name", "Kosmos FM")
+		w.Header().Set("icy-description", "Music from orbit")
+		if r.Header.Get("Icy-MetaData") != "1" {
+			return
+		}
+		w.Header().Set("icy-metaint", strconv.Itoa(metaint))
+		audio := bytes.Repeat([]byte{0xff}, metaint)
+		for _, title := range titles {
+			w.Write(audio)
+			w.Write(icyBlock("StreamTitle='" + title + "';"))
+			// Stations repeat the title until the song changes.
+			w.Write(audio)
+			w.Write(icyBlock("StreamTitle='" + title + "';"))
+			w.Write(audio)
+			w.Write([]byte{0})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDirectProviderResolve(t *testing.T) {
+	radio := radioServer(t, 64)
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/music/Night%20Drive.ogg", "/music/Night Drive.ogg":
+			w.Header().Set("Content-Type", "application/ogg")
+		case "/stream":
+			w.Header().Set("Content-Type", "audio/aac; charset=binary")
+		default:
+			w.Header().Set("Content-Type", "text/html")
+		}
+	}))
+	defer files.Close()
+
+	provider := NewDirectProvider(nil)
+	tests := []struct {
+		query         string
+		title, author string
+		source        Source
+	}{
+		{radio.URL + "/live", "Kosmos FM", "Music from orbit", SourceRadio},
+		{files.URL + "/music/Night%20Drive.ogg", "Night Drive", "127.0.0.1", SourceDirect},
+		{files.URL + "/stream", "stream", "127.0.0.1", SourceDirect},
+	}
+	for _, tt := range tests {
+		track, err := provider.Resolve(context.Background(), tt.query)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", tt.query, err)
+		}
+		if track.Title != tt.title || track.Author != tt.author || track.Source != tt.source || track.Duration != 0 || track.StreamURL != tt.query {
+			t.Errorf("Resolve(%q) = %+v", tt.query, track)
+		}
+	}
+	if _, err := provider.Resolve(context.Background(), files.URL+"/page"); err == nil {
+		t.Error("resolved an HTML page")
+	}
+	if _, err := provider.Resolve(context.Background(), "some song"); err == nil {
+		t.Error("resolved a search query")
+	}
+}
+
+func TestRegistryProbesUnclaimedURLs(t *testing.T) {
+	radio := radioServer(t, 64)
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+	}))
+	defer page.Close()
+
+	registry, err := NewRegistry(stubProvider{name: "default"}, NewDirectProvider(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query, provider string
+	}{
+		{radio.URL + "/live", ProviderDirect},
+		{radio.URL + "/hits.mp3", ProviderDirect},
+		{page.URL + "/watch", "default"},
+		{"some song", "default"},
+	}
+	for _, tt := range tests {
+		track, err := registry.Resolve(context.Background(), "", tt.query, "<@1>", "")
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", tt.query, err)
+		}
+		if track.Provider != tt.provider {
+			t.Errorf("%q went to %s, want %s", tt.query, track.Provider, tt.provider)
+		}
+	}
+}
+
+func TestWatchTitles(t *testing.T) {
+	radio := radioServer(t, 100, "Artist - First", "Artist - Second", "Ünïcode – Third")
+	registry, err := NewRegistry(stubProvider{name: "default"}, NewDirectProvider(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, err := registry.Resolve(context.Background(), "", radio.URL, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var titles []string
+	if err := registry.WatchTitles(ctx, track, func(title string) { titles = append(titles, title) }); err == nil {
+		t.Error("the stream ended without an error")
+	}
+	want := []string{"Artist - First", "Artist - Second", "Ünïcode – Third"}
+	if len(titles) != len(want) {
+		t.Fatalf("titles = %q, want %q", titles, want)
+	}
+	for i := range want {
+		if titles[i] != want[i] {
+			t.Errorf("titles[%d] = %q, want %q", i, titles[i], want[i])
+		}
+	}
+}
+
+func TestParseStreamTitle(t *testing.T) {
+	tests := []struct {
+		block string
+		want  string
+		ok    bool
+	}{
+		{"StreamTitle='Artist - Song';StreamUrl='';\x00\x00", "Artist - Song", true},
+		{"StreamTitle='Don't Stop';", "Don't Stop", true},
+		{"StreamTitle='Caf\xe9 Tacvba';", "Café Tacvba", true},
+		{"StreamUrl='http://example.com';", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseStreamTitle([]byte(tt.block))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseStreamTitle(%q) = %q, %v; want %q, %v", tt.block, got, ok, tt.want, tt.ok)
+		}
+	}
+}
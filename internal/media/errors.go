@@ -0,0 +1,120 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// ResolveErrorKind is why yt-dlp could not resolve a query.
+type ResolveErrorKind int
+
+const (
+	// ResolveFailed is any failure not classified below.
+	ResolveFailed ResolveErrorKind = iota
+	// ResolveNotFound means the video or search result does not exist.
+	ResolveNotFound
+	// ResolvePrivate means the video is private or for members only.
+	ResolvePrivate
+	// ResolveAgeRestricted means the video needs a signed-in adult account.
+	ResolveAgeRestricted
+	// ResolveGeoBlocked means the video is not available where Kvazar is.
+	ResolveGeoBlocked
+	// ResolveCopyright means the video was taken down over a copyright claim.
+	ResolveCopyright
+	// ResolveNotStarted means the livestream or premiere has yet to begin.
+	ResolveNotStarted
+	// ResolveRateLimited means the site is refusing requests for a while.
+	ResolveRateLimited
+	// ResolveTimeout means yt-dlp did not answer within Resolver.Timeout.
+	ResolveTimeout
+	// ResolveMissingBinary means the yt-dlp executable was not found.
+	ResolveMissingBinary
+)
+
+var resolveErrorKindNames = [...]string{
+	ResolveFailed:        "failed",
+	ResolveNotFound:      "not found",
+	ResolvePrivate:       "private",
+	ResolveAgeRestricted: "age restricted",
+	ResolveGeoBlocked:    "geo-blocked",
+	ResolveCopyright:     "removed for copyright",
+	ResolveNotStarted:    "not started",
+	ResolveRateLimited:   "rate limited",
+	ResolveTimeout:       "timed out",
+	ResolveMissingBinary: "yt-dlp missing",
+}
+
+func (k ResolveErrorKind) String() string {
+	if k >= 0 && int(k) < len(resolveErrorKindNames) {
+		return resolveErrorKindNames[k]
+	}
+	return resolveErrorKindNames[ResolveFailed]
+}
+
+// resolveErrorPatterns map what yt-dlp prints to the kind of failure. The
+// first kind with a match wins, so the specific messages come before
+// "Video unavailable", which YouTube puts in front of most of them.
+var resolveErrorPatterns = []struct {
+	kind     ResolveErrorKind
+	patterns []string
+}{
+	{ResolveCopyright, []string{"copyright"}},
+	{ResolveAgeRestricted, []string{"confirm your age", "age-restricted", "age restricted", "inappropriate for some users"}},
+	{ResolveRateLimited, []string{"http error 429", "too many requests", "not a bot", "rate-limit", "rate limit"}},
+	{ResolvePrivate, []string{"private video", "video is private", "members-only", "members only", "join this channel"}},
+	{ResolveGeoBlocked, []string{"in your country", "geo restrict", "geo-restrict", "in your location", "not available from your location"}},
+	{ResolveNotStarted, []string{"live event will begin", "premieres in", "premiere will begin", "is upcoming", "will begin in"}},
+	{ResolveNotFound, []string{"video unavailable", "no longer available", "has been removed", "does not exist",
+		"http error 404", "not found", "no results", "unsupported url", "is not a valid url", "no video formats"}},
+}
+
+// classifyResolveError reads the kind of failure from what yt-dlp printed.
+func classifyResolveError(output string) ResolveErrorKind {
+	output = strings.ToLower(strings.ReplaceAll(output, "’", "'"))
+	for _, class := range resolveErrorPatterns {
+		for _, pattern := range class.patterns {
+			if strings.Contains(output, pattern) {
+				return class.kind
+			}
+		}
+	}
+	return ResolveFailed
+}
+
+// ResolveError is a lookup yt-dlp could not complete. Callers pick it out
+// with errors.As to explain the Kind; Detail is what yt-dlp printed, which is
+// meant for the log rather than for users.
+type ResolveError struct {
+	Kind   ResolveErrorKind
+	Detail string
+	// Err is the underlying error, such as the process's exit status.
+	Err error
+}
+
+func (e *ResolveError) Error() string {
+	if e.Detail == "" {
+		return "resolver: " + e.Kind.String()
+	}
+	return fmt.Sprintf("resolver: %s: %s", e.Kind, e.Detail)
+}
+
+func (e *ResolveError) Unwrap() error { return e.Err }
+
+// failure turns what went wrong with a yt-dlp run into a ResolveError.
+func (r *Resolver) failure(ctx context.Context, output string, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &ResolveError{Kind: ResolveTimeout, Detail: fmt.Sprintf("no answer after %s", r.Timeout), Err: ctx.Err()}
+	case errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist):
+		return &ResolveError{Kind: ResolveMissingBinary, Detail: err.Error(), Err: err}
+	}
+	detail := strings.TrimSpace(output)
+	if detail == "" && err != nil {
+		detail = err.Error()
+	}
+	return &ResolveError{Kind: classifyResolveError(detail), Detail: detail, Err: err}
+}
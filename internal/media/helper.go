@@ -0,0 +1,341 @@
+package media
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"kvazar/internal/metrics"
+)
+
+// Resolver backends.
+const (
+	// BackendExec starts yt-dlp for every lookup.
+	BackendExec = "exec"
+	// BackendHelper sends lookups to a long-running Python process that has
+	// yt_dlp imported.
+	BackendHelper = "helper"
+)
+
+const (
+	defaultPythonExecutable = "python3"
+	// helperStartTimeout bounds how long the helper may take to import
+	// yt_dlp and report ready.
+	helperStartTimeout = 15 * time.Second
+	// helperRetryDelay is how long lookups go through exec after the helper
+	// failed to start, before it is tried again.
+	helperRetryDelay = 30 * time.Second
+	// helperStopGrace is how long the helper gets to exit after its input is
+	// closed.
+	helperStopGrace = 2 * time.Second
+)
+
+//go:embed ytdlp_helper.py
+var helperScript string
+
+// errHelperUnavailable means a lookup could not be handed to the helper, or
+// the helper died before answering it; the lookup goes through exec instead.
+var errHelperUnavailable = errors.New("resolver: yt-dlp helper unavailable")
+
+// Helper runs ytdlp_helper.py, a small Python program that imports yt_dlp
+// once and answers lookups sent as JSON lines, so they do not each pay for
+// starting Python. Lookups run concurrently in the helper and are matched to
+// replies by ID. The helper is started on first use and again after it
+// exits; while it cannot be started, lookups fall back to running yt-dlp.
+type Helper struct {
+	// Python is the interpreter that runs the helper. It must be able to
+	// import yt_dlp.
+	Python string
+
+	mu       sync.Mutex
+	proc     *helperProcess
+	failedAt time.Time
+	closed   bool
+}
+
+// NewHelper returns a helper run by the given Python interpreter.
+func NewHelper(python string) *Helper {
+	if strings.TrimSpace(python) == "" {
+		python = defaultPythonExecutable
+	}
+	return &Helper{Python: python}
+}
+
+type helperRequest struct {
+	ID      uint64        `json:"id"`
+	Op      string        `json:"op"`
+	Query   string        `json:"query"`
+	Limit   int           `json:"limit,omitempty"`
+	Options helperOptions `json:"options"`
+}
+
+// helperOptions are the YTDLPOptions of a request.
+type helperOptions struct {
+	CookiesFile   string            `json:"cookies_file,omitempty"`
+	Proxy         string            `json:"proxy,omitempty"`
+	SourceAddress string            `json:"source_address,omitempty"`
+	ExtractorArgs map[string]string `json:"extractor_args,omitempty"`
+}
+
+type helperReply struct {
+	ID      uint64      `json:"id"`
+	Ready   bool        `json:"ready"`
+	Version string      `json:"version"`
+	Item    *ytdlpItem  `json:"item"`
+	Entries []ytdlpItem `json:"entries"`
+	Error   string      `json:"error"`
+}
+
+// helperProcess is one run of the helper.
+type helperProcess struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan helperReply
+
+	// exited is closed once the process is gone; err says why.
+	exited     chan struct{}
+	err        error
+	stderrDone chan struct{}
+}
+
+// lookup asks the helper what yt-dlp finds for a query.
+func (h *Helper) lookup(ctx context.Context, query string, options YTDLPOptions) (ytdlpItem, error) {
+	reply, err := h.do(ctx, helperRequest{Op: "lookup", Query: query, Options: helperOptions(options)})
+	if err != nil {
+		return ytdlpItem{}, err
+	}
+	if reply.Item == nil {
+		return ytdlpItem{}, errors.New("resolver: yt-dlp helper sent no item")
+	}
+	return *reply.Item, nil
+}
+
+// list asks the helper for the entries of a playlist or search.
+func (h *Helper) list(ctx context.Context, query string, limit int, options YTDLPOptions) ([]ytdlpItem, error) {
+	reply, err := h.do(ctx, helperRequest{Op: "list", Query: query, Limit: limit, Options: helperOptions(options)})
+	if err != nil {
+		return nil, err
+	}
+	return reply.Entries, nil
+}
+
+// Close stops the helper. Later lookups fall back to exec.
+func (h *Helper) Close() error {
+	h.mu.Lock()
+	h.closed = true
+	proc := h.proc
+	h.proc = nil
+	h.mu.Unlock()
+	if proc == nil {
+		return nil
+	}
+	proc.stop()
+	return nil
+}
+
+func (h *Helper) do(ctx context.Context, req helperRequest) (helperReply, error) {
+	proc, err := h.process()
+	if err != nil {
+		return helperReply{}, err
+	}
+	replies, err := proc.send(req)
+	if err != nil {
+		return helperReply{}, err
+	}
+
+	select {
+	case reply := <-replies:
+		return checkReply(reply)
+	case <-proc.exited:
+		select {
+		case reply := <-replies:
+			return checkReply(reply)
+		default:
+		}
+		return helperReply{}, fmt.Errorf("%w: %v", errHelperUnavailable, proc.err)
+	case <-ctx.Done():
+		// The helper finishes the lookup anyway; its reply is dropped.
+		proc.forget(req.ID)
+		return helperReply{}, ctx.Err()
+	}
+}
+
+// checkReply turns an error the helper reports into a ResolveError, as exec
+// would give.
+func checkReply(reply helperReply) (helperReply, error) {
+	if detail := strings.TrimSpace(reply.Error); detail != "" {
+		return helperReply{}, &ResolveError{Kind: classifyResolveError(detail), Detail: detail}
+	}
+	return reply, nil
+}
+
+// process returns the running helper, starting it if needed.
+func (h *Helper) process() (*helperProcess, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, errHelperUnavailable
+	}
+	if h.proc != nil {
+		select {
+		case <-h.proc.exited:
+			log.Printf("yt-dlp helper exited (%v); restarting it", h.proc.err)
+			metrics.ResolveHelperRestarted()
+			h.proc = nil
+		default:
+			return h.proc, nil
+		}
+	}
+	if !h.failedAt.IsZero() && time.Since(h.failedAt) < helperRetryDelay {
+		return nil, errHelperUnavailable
+	}
+
+	proc, err := startHelper(h.Python)
+	if err != nil {
+		h.failedAt = time.Now()
+		log.Printf("failed to start yt-dlp helper, running yt-dlp per lookup for %s: %v", helperRetryDelay, err)
+		return nil, fmt.Errorf("%w: %v", errHelperUnavailable, err)
+	}
+	h.failedAt = time.Time{}
+	h.proc = proc
+	return proc, nil
+}
+
+func startHelper(python string) (*helperProcess, error) {
+	cmd := exec.Command(python, "-u", "-c", helperScript)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	proc := &helperProcess{
+		cmd:        cmd,
+		stdin:      stdin,
+		pending:    make(map[uint64]chan helperReply),
+		exited:     make(chan struct{}),
+		stderrDone: make(chan struct{}),
+	}
+	ready := make(chan helperReply, 1)
+	go proc.logStderr(stderr)
+	go proc.read(stdout, ready)
+
+	timer := time.NewTimer(helperStartTimeout)
+	defer timer.Stop()
+	select {
+	case hello := <-ready:
+		log.Printf("started yt-dlp helper with yt-dlp %s", hello.Version)
+		return proc, nil
+	case <-proc.exited:
+		return nil, proc.err
+	case <-timer.C:
+		proc.stop()
+		return nil, fmt.Errorf("not ready after %s", helperStartTimeout)
+	}
+}
+
+// send writes a request and returns where its reply will arrive.
+func (p *helperProcess) send(req helperRequest) (<-chan helperReply, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextID++
+	req.ID = p.nextID
+	line, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	replies := make(chan helperReply, 1)
+	p.pending[req.ID] = replies
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		delete(p.pending, req.ID)
+		return nil, fmt.Errorf("%w: %v", errHelperUnavailable, err)
+	}
+	return replies, nil
+}
+
+func (p *helperProcess) forget(id uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, id)
+}
+
+// read hands each reply to its request until the helper exits.
+func (p *helperProcess) read(stdout io.Reader, ready chan<- helperReply) {
+	dec := json.NewDecoder(stdout)
+	for {
+		var reply helperReply
+		if err := dec.Decode(&reply); err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("yt-dlp helper sent a malformed reply, stopping it: %v", err)
+				_ = p.cmd.Process.Kill()
+			}
+			break
+		}
+		if reply.Ready {
+			select {
+			case ready <- reply:
+			default:
+			}
+			continue
+		}
+		p.mu.Lock()
+		replies, ok := p.pending[reply.ID]
+		delete(p.pending, reply.ID)
+		p.mu.Unlock()
+		if ok {
+			replies <- reply
+		}
+	}
+
+	<-p.stderrDone
+	err := p.cmd.Wait()
+	if err == nil {
+		err = errors.New("exited")
+	}
+	p.err = err
+	close(p.exited)
+}
+
+func (p *helperProcess) logStderr(stderr io.Reader) {
+	defer close(p.stderrDone)
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			log.Printf("yt-dlp helper: %s", line)
+		}
+	}
+}
+
+// stop closes the helper's input, which makes it exit, and kills it if it
+// does not.
+func (p *helperProcess) stop() {
+	_ = p.stdin.Close()
+	select {
+	case <-p.exited:
+	case <-time.After(helperStopGrace):
+		_ = p.cmd.Process.Kill()
+		<-p.exited
+	}
+}
@@ -0,0 +1,168 @@
+package media
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeYTDLP is a yt_dlp module for the helper to import. It notes each
+// import in $KVZ_TEST_STARTS, finds one video per query, and fails, exits or
+// reports its options for queries saying so.
+const fakeYTDLP = `import json
+import os
+
+with open(os.environ["KVZ_TEST_STARTS"], "a") as starts:
+    starts.write("start\n")
+
+
+class YoutubeDL:
+    def __init__(self, opts):
+        self.opts = opts
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        return False
+
+    @staticmethod
+    def sanitize_info(info):
+        return info
+
+    def extract_info(self, query, download=False):
+        if "unavailable" in query:
+            raise Exception("ERROR: [youtube] gone: Video unavailable")
+        if "crash" in query:
+            os._exit(3)
+        if "options" in query:
+            keys = ("cookiefile", "proxy", "source_address", "extractor_args")
+            return {"id": "options", "title": json.dumps({key: self.opts.get(key) for key in keys}, sort_keys=True),
+                    "webpage_url": "https://example.com/options"}
+        video = {"id": "orbit", "title": "Orbit", "extractor_key": "Youtube",
+                 "webpage_url": "https://www.youtube.com/watch?v=orbit",
+                 "url": "https://media.example.com/orbit", "duration": 180, "formats": [{}]}
+        if query.startswith("ytsearch"):
+            entries = [dict(video, id="orbit%d" % i) for i in range(self.opts.get("playlistend") or 1)]
+            return {"id": query, "entries": entries}
+        return video
+`
+
+// newTestHelper returns a resolver that uses the helper with the fake
+// yt_dlp module, and the file counting helper starts. Its yt-dlp executable
+// answers only when the helper cannot.
+func newTestHelper(t *testing.T) (*Resolver, string) {
+	t.Helper()
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 is not installed")
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "yt_dlp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "yt_dlp", "__init__.py"), []byte(fakeYTDLP), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "yt_dlp", "version.py"), []byte("__version__ = 'test'\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ytdlp := filepath.Join(dir, "yt-dlp")
+	script := `#!/bin/sh
+printf '{"id":"exec","title":"From exec","extractor_key":"Youtube","webpage_url":"https://www.youtube.com/watch?v=exec","url":"https://media.example.com/exec"}\n'
+`
+	if err := os.WriteFile(ytdlp, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	starts := filepath.Join(dir, "starts")
+	t.Setenv("PYTHONPATH", dir)
+	t.Setenv("KVZ_TEST_STARTS", starts)
+
+	resolver := NewResolver(ytdlp)
+	resolver.Helper = NewHelper(python)
+	t.Cleanup(func() { resolver.Helper.Close() })
+	return resolver, starts
+}
+
+func helperStarts(t *testing.T, starts string) int {
+	t.Helper()
+	data, err := os.ReadFile(starts)
+	if err != nil {
+		return 0
+	}
+	return strings.Count(string(data), "start")
+}
+
+func TestHelper(t *testing.T) {
+	resolver, starts := newTestHelper(t)
+	ctx := context.Background()
+
+	track, err := resolver.Resolve(ctx, "https://youtu.be/orbit", "tester", "channel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if track.Title != "Orbit" || track.StreamURL != "https://media.example.com/orbit" || track.Duration != 3*time.Minute {
+		t.Errorf("track = %+v", track)
+	}
+	if track, err := resolver.Resolve(ctx, "Kosmos Orbit", "tester", "channel"); err != nil || track.ID != "orbit0" {
+		t.Errorf("search = %+v, %v", track, err)
+	}
+	results, err := resolver.Search(ctx, "Kosmos Orbit", 3)
+	if err != nil || len(results) != 3 {
+		t.Errorf("Search = %d results, %v", len(results), err)
+	}
+
+	_, err = resolver.Resolve(ctx, "https://youtu.be/unavailable", "tester", "channel")
+	if err == nil || !strings.Contains(err.Error(), "Video unavailable") {
+		t.Errorf("err = %v, want the helper's error rather than exec's answer", err)
+	}
+	if n := helperStarts(t, starts); n != 1 {
+		t.Errorf("helper started %d times, want once", n)
+	}
+}
+
+func TestHelperOptions(t *testing.T) {
+	resolver, _ := newTestHelper(t)
+	resolver.Options = testOptions
+
+	track, err := resolver.Resolve(context.Background(), "https://example.com/options", "tester", "channel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"cookiefile": "/etc/kvazar/cookies.txt", "extractor_args": {"soundcloud": {"formats": ["http_mp3"]}, ` +
+		`"youtube": {"player_client": ["android", "web"]}}, "proxy": "socks5://proxy.example.com:1080", "source_address": "192.0.2.7"}`
+	if track.Title != want {
+		t.Errorf("helper options = %s\nwant %s", track.Title, want)
+	}
+}
+
+func TestHelperRestartsAfterCrash(t *testing.T) {
+	resolver, starts := newTestHelper(t)
+	ctx := context.Background()
+
+	track, err := resolver.Resolve(ctx, "https://youtu.be/crash", "tester", "channel")
+	if err != nil || track.ID != "exec" {
+		t.Errorf("lookup that crashed the helper = %+v, %v; want exec's answer", track, err)
+	}
+	track, err = resolver.Resolve(ctx, "https://youtu.be/orbit", "tester", "channel")
+	if err != nil || track.ID != "orbit" {
+		t.Errorf("lookup after the crash = %+v, %v", track, err)
+	}
+	if n := helperStarts(t, starts); n != 2 {
+		t.Errorf("helper started %d times, want twice", n)
+	}
+}
+
+func TestHelperFallsBackToExec(t *testing.T) {
+	resolver, _ := newTestHelper(t)
+	resolver.Helper = NewHelper(filepath.Join(t.TempDir(), "no-python"))
+
+	track, err := resolver.Resolve(context.Background(), "https://youtu.be/orbit", "tester", "channel")
+	if err != nil || track.ID != "exec" {
+		t.Errorf("track = %+v, %v; want exec's answer", track, err)
+	}
+}
@@ -0,0 +1,691 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderLocal is the name of the provider that plays the local library.
+const ProviderLocal = "local"
+
+// ErrNotInLibrary is returned when a search or lookup finds nothing in the
+// local library.
+var ErrNotInLibrary = errors.New("media: not found in the library")
+
+// libraryPatterns claims "local:" queries.
+var libraryPatterns = Patterns{Prefixes: []string{ProviderLocal + ":"}}
+
+// coverFiles are the folder images used as album art when a file has none
+// embedded, in order of preference.
+var coverFiles = []string{"cover.jpg", "cover.png", "folder.jpg", "folder.png", "front.jpg", "front.png"}
+
+const (
+	// libraryProbeWorkers is how many files are read with ffprobe at once.
+	libraryProbeWorkers = 4
+	// libraryProbeTimeout bounds reading the tags of a single file.
+	libraryProbeTimeout = 30 * time.Second
+	// embeddedCover marks entries whose cover art is inside the audio file.
+	embeddedCover = ":embedded"
+)
+
+// LibraryEntry is one audio file in the local library.
+type LibraryEntry struct {
+	// ID is derived from Path and stays the same across rescans.
+	ID string `json:"id"`
+	// Path is relative to the library root, with forward slashes.
+	Path        string        `json:"path"`
+	Title       string        `json:"title"`
+	Artist      string        `json:"artist,omitempty"`
+	AlbumArtist string        `json:"album_artist,omitempty"`
+	Album       string        `json:"album,omitempty"`
+	Disc        int           `json:"disc,omitempty"`
+	Number      int           `json:"number,omitempty"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	// Cover is the library path of a folder image, or ":embedded" when the
+	// file carries its own art.
+	Cover   string    `json:"cover,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// albumArtist is the artist an entry's album is filed under.
+func (e LibraryEntry) albumArtist() string {
+	return firstNonEmpty(e.AlbumArtist, e.Artist)
+}
+
+// ScanResult summarizes a library scan.
+type ScanResult struct {
+	Tracks  int
+	Added   int
+	Updated int
+	Removed int
+	// Unreadable counts files whose tags could not be read; they are still
+	// indexed under their file name.
+	Unreadable int
+	// Skipped counts files and directories that could not be listed or
+	// opened. Tracks indexed under them before are kept until they can be
+	// read again.
+	Skipped int
+}
+
+// Library indexes a directory of audio files and plays them from disk. Only
+// files whose size or modification time changed are read again on rescan.
+type Library struct {
+	// FFProbePath and FFMpegPath name the binaries that read tags and
+	// extract embedded cover art.
+	FFProbePath string
+	FFMpegPath  string
+	// CoverURL is the public address covers are served under, as
+	// CoverURL/<id>. Tracks have no thumbnail when it is empty.
+	CoverURL string
+
+	root     string
+	readTags func(ctx context.Context, file string) (fileTags, error)
+
+	scanMu  sync.Mutex
+	mu      sync.RWMutex
+	entries map[string]LibraryEntry
+	byID    map[string]string
+}
+
+// NewLibrary returns an empty library rooted at dir. Call Restore to load a
+// saved index and Scan to bring it up to date.
+func NewLibrary(dir string) *Library {
+	l := &Library{
+		FFProbePath: "ffprobe",
+		FFMpegPath:  "ffmpeg",
+		root:        dir,
+		entries:     make(map[string]LibraryEntry),
+		byID:        make(map[string]string),
+	}
+	l.readTags = l.ffprobe
+	return l
+}
+
+// Root returns the library directory.
+func (l *Library) Root() string { return l.root }
+
+// Len returns the number of indexed files.
+func (l *Library) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.entries)
+}
+
+// Entries returns the whole index, ordered by path, for saving.
+func (l *Library) Entries() []LibraryEntry {
+	l.mu.RLock()
+	entries := make([]LibraryEntry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		entries = append(entries, entry)
+	}
+	l.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// Restore replaces the index with a saved one.
+func (l *Library) Restore(entries []LibraryEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = make(map[string]LibraryEntry, len(entries))
+	l.byID = make(map[string]string, len(entries))
+	for _, entry := range entries {
+		entry.ID = libraryID(entry.Path)
+		l.entries[entry.Path] = entry
+		l.byID[entry.ID] = entry.Path
+	}
+}
+
+// Scan walks the library directory and updates the index.
+func (l *Library) Scan(ctx context.Context) (ScanResult, error) {
+	l.scanMu.Lock()
+	defer l.scanMu.Unlock()
+
+	l.mu.RLock()
+	previous := make(map[string]LibraryEntry, len(l.entries))
+	for key, entry := range l.entries {
+		previous[key] = entry
+	}
+	l.mu.RUnlock()
+
+	var (
+		result  ScanResult
+		found   = make(map[string]LibraryEntry)
+		changed []LibraryEntry
+		covers  = make(map[string]string)
+		skipped []string
+	)
+	// skip leaves out a file or directory that cannot be read, rather than
+	// losing the whole scan to one bad folder on a network share.
+	skip := func(file string, d fs.DirEntry, err error) error {
+		log.Printf("library: skipping %s: %v", file, err)
+		result.Skipped++
+		if rel, relErr := filepath.Rel(l.root, file); relErr == nil {
+			skipped = append(skipped, filepath.ToSlash(rel))
+		}
+		if d != nil && d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+	err := filepath.WalkDir(l.root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if file == l.root {
+				return err
+			}
+			return skip(file, d, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && file != l.root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isAudioFile(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return skip(file, d, err)
+		}
+		rel, err := filepath.Rel(l.root, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		dir := path.Dir(rel)
+		cover, ok := covers[dir]
+		if !ok {
+			cover = l.folderCover(dir)
+			covers[dir] = cover
+		}
+
+		entry, known := previous[rel]
+		if known && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+			if entry.Cover != embeddedCover {
+				entry.Cover = cover
+			}
+			found[rel] = entry
+			return nil
+		}
+		if known {
+			result.Updated++
+		} else {
+			result.Added++
+		}
+		changed = append(changed, LibraryEntry{
+			ID:      libraryID(rel),
+			Path:    rel,
+			Cover:   cover,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("media: scan %s: %w", l.root, err)
+	}
+
+	for i, unreadable := range l.readAll(ctx, changed) {
+		if unreadable {
+			result.Unreadable++
+		}
+		found[changed[i].Path] = changed[i]
+	}
+	if err := ctx.Err(); err != nil {
+		return ScanResult{}, err
+	}
+	for key, entry := range previous {
+		if _, ok := found[key]; ok {
+			continue
+		}
+		if underAny(key, skipped) {
+			found[key] = entry
+			continue
+		}
+		result.Removed++
+	}
+
+	byID := make(map[string]string, len(found))
+	for key, entry := range found {
+		byID[entry.ID] = key
+	}
+	l.mu.Lock()
+	l.entries = found
+	l.byID = byID
+	l.mu.Unlock()
+
+	result.Tracks = len(found)
+	return result, nil
+}
+
+// underAny reports whether a library path is one of dirs or inside one.
+func underAny(rel string, dirs []string) bool {
+	for _, dir := range dirs {
+		if rel == dir || strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// readAll fills in the tags of the entries in place, a few files at a time,
+// and reports which ones could not be read.
+func (l *Library) readAll(ctx context.Context, entries []LibraryEntry) []bool {
+	unreadable := make([]bool, len(entries))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < libraryProbeWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entry := &entries[i]
+				probeCtx, cancel := context.WithTimeout(ctx, libraryProbeTimeout)
+				tags, err := l.readTags(probeCtx, filepath.Join(l.root, filepath.FromSlash(entry.Path)))
+				cancel()
+				unreadable[i] = err != nil
+				tags.apply(entry)
+			}
+		}()
+	}
+	for i := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return unreadable
+}
+
+// folderCover returns the library path of the directory's cover image.
+func (l *Library) folderCover(dir string) string {
+	for _, name := range coverFiles {
+		rel := path.Join(dir, name)
+		if info, err := os.Stat(filepath.Join(l.root, filepath.FromSlash(rel))); err == nil && info.Mode().IsRegular() {
+			return rel
+		}
+	}
+	return ""
+}
+
+// Lookup returns the entry with the given ID.
+func (l *Library) Lookup(id string) (LibraryEntry, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entry, ok := l.entries[l.byID[id]]
+	return entry, ok
+}
+
+// Search returns up to limit entries whose title, artist, album or path
+// contain every word of the query, best matches first.
+func (l *Library) Search(query string, limit int) []LibraryEntry {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil
+	}
+	type scored struct {
+		entry LibraryEntry
+		score int
+	}
+	var matches []scored
+	l.mu.RLock()
+	for _, entry := range l.entries {
+		title := strings.ToLower(entry.Title)
+		artist := strings.ToLower(entry.Artist + " " + entry.AlbumArtist)
+		album := strings.ToLower(entry.Album)
+		haystack := strings.Join([]string{title, artist, album, strings.ToLower(entry.Path)}, " ")
+		score := 0
+		for _, word := range words {
+			if !strings.Contains(haystack, word) {
+				score = -1
+				break
+			}
+			switch {
+			case strings.Contains(title, word):
+				score += 3
+			case strings.Contains(artist, word):
+				score += 2
+			case strings.Contains(album, word):
+				score++
+			}
+		}
+		if score < 0 {
+			continue
+		}
+		if title == strings.Join(words, " ") {
+			score += 10
+		}
+		matches = append(matches, scored{entry, score})
+	}
+	l.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return albumOrder(matches[i].entry, matches[j].entry)
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	entries := make([]LibraryEntry, len(matches))
+	for i, match := range matches {
+		entries[i] = match.entry
+	}
+	return entries
+}
+
+// Album returns the tracks of the album that best matches the query, in
+// album order. The query may also name the album's artist.
+func (l *Library) Album(query string) []LibraryEntry {
+	return l.group(query, func(e LibraryEntry) (string, string) {
+		return e.Album, e.albumArtist()
+	})
+}
+
+// Artist returns every track of the artist that best matches the query,
+// album by album.
+func (l *Library) Artist(query string) []LibraryEntry {
+	return l.group(query, func(e LibraryEntry) (string, string) {
+		return e.albumArtist(), ""
+	})
+}
+
+// group buckets the entries by the name key returns, qualified by its extra
+// words, and returns the bucket that best matches the query: one whose name
+// equals it, then one whose name contains it, then one matching every word.
+func (l *Library) group(query string, key func(LibraryEntry) (name, extra string)) []LibraryEntry {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	if query == "" {
+		return nil
+	}
+	type bucket struct {
+		name    string
+		score   int
+		entries []LibraryEntry
+	}
+	buckets := make(map[string]*bucket)
+	l.mu.RLock()
+	for _, entry := range l.entries {
+		name, extra := key(entry)
+		if name == "" {
+			continue
+		}
+		id := strings.ToLower(name + "\x00" + extra)
+		b, ok := buckets[id]
+		if !ok {
+			b = &bucket{name: strings.ToLower(name + " " + extra), score: groupScore(strings.ToLower(name), strings.ToLower(extra), query)}
+			buckets[id] = b
+		}
+		b.entries = append(b.entries, entry)
+	}
+	l.mu.RUnlock()
+
+	var best *bucket
+	for _, b := range buckets {
+		if b.score == 0 {
+			continue
+		}
+		if best == nil || b.score > best.score || (b.score == best.score && b.name < best.name) {
+			best = b
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	sort.Slice(best.entries, func(i, j int) bool { return albumOrder(best.entries[i], best.entries[j]) })
+	return best.entries
+}
+
+func groupScore(name, extra, query string) int {
+	switch {
+	case name == query:
+		return 3
+	case strings.Contains(name, query):
+		return 2
+	}
+	haystack := name + " " + extra
+	for _, word := range strings.Fields(query) {
+		if !strings.Contains(haystack, word) {
+			return 0
+		}
+	}
+	return 1
+}
+
+// albumOrder sorts entries by artist, album, disc, track number and path.
+func albumOrder(a, b LibraryEntry) bool {
+	if x, y := strings.ToLower(a.albumArtist()), strings.ToLower(b.albumArtist()); x != y {
+		return x < y
+	}
+	if x, y := strings.ToLower(a.Album), strings.ToLower(b.Album); x != y {
+		return x < y
+	}
+	if a.Disc != b.Disc {
+		return a.Disc < b.Disc
+	}
+	if a.Number != b.Number {
+		return a.Number < b.Number
+	}
+	return a.Path < b.Path
+}
+
+// Track converts an entry into a playable track.
+func (l *Library) Track(entry LibraryEntry) *Track {
+	track := &Track{
+		ID:       entry.ID,
+		Title:    entry.Title,
+		Author:   entry.Artist,
+		WebURL:   ProviderLocal + ":" + entry.Path,
+		Duration: entry.Duration,
+		Source:   SourceLocal,
+	}
+	if entry.Cover != "" && l.CoverURL != "" {
+		track.Thumbnail = strings.TrimSuffix(l.CoverURL, "/") + "/" + entry.ID
+	}
+	return track
+}
+
+// Cover returns the cover art of the entry with the given ID.
+func (l *Library) Cover(ctx context.Context, id string) ([]byte, error) {
+	entry, ok := l.Lookup(id)
+	if !ok || entry.Cover == "" {
+		return nil, ErrNotInLibrary
+	}
+	if entry.Cover != embeddedCover {
+		return os.ReadFile(filepath.Join(l.root, filepath.FromSlash(entry.Cover)))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, l.FFMpegPath,
+		"-v", "error",
+		"-i", l.file(entry),
+		"-an", "-frames:v", "1", "-c:v", "copy",
+		"-f", "image2pipe", "pipe:1",
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("media: extract cover of %s: %w: %s", entry.Path, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func (l *Library) file(entry LibraryEntry) string {
+	return filepath.Join(l.root, filepath.FromSlash(entry.Path))
+}
+
+func (l *Library) Name() string { return ProviderLocal }
+
+func (l *Library) Match(query string) bool { return libraryPatterns.Match(query) }
+
+// Resolve plays the file at the given library path, or else the best search
+// match.
+func (l *Library) Resolve(_ context.Context, query string) (*Track, error) {
+	query = libraryPatterns.TrimPrefix(query)
+	l.mu.RLock()
+	entry, ok := l.entries[query]
+	l.mu.RUnlock()
+	if !ok {
+		matches := l.Search(query, 1)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%w: %q", ErrNotInLibrary, query)
+		}
+		entry = matches[0]
+	}
+	return l.Track(entry), nil
+}
+
+// Open reads the file from disk. Files removed since the track was queued
+// fail to open.
+func (l *Library) Open(_ context.Context, track *Track) (Input, error) {
+	entry, ok := l.Lookup(track.ID)
+	if !ok {
+		return Input{}, fmt.Errorf("%w: %s", ErrNotInLibrary, track.WebURL)
+	}
+	return Input{URL: l.file(entry)}, nil
+}
+
+// fileTags is what ffprobe reads from a file.
+type fileTags struct {
+	title, artist, albumArtist, album string
+	disc, number                      int
+	duration                          time.Duration
+	embeddedCover                     bool
+}
+
+// apply copies the tags onto the entry, naming it after its file when the
+// title tag is missing.
+func (t fileTags) apply(entry *LibraryEntry) {
+	entry.Title = t.title
+	if entry.Title == "" {
+		name := path.Base(entry.Path)
+		entry.Title = strings.TrimSuffix(name, path.Ext(name))
+	}
+	entry.Artist = t.artist
+	entry.AlbumArtist = t.albumArtist
+	entry.Album = t.album
+	entry.Disc = t.disc
+	entry.Number = t.number
+	entry.Duration = t.duration
+	if t.embeddedCover {
+		entry.Cover = embeddedCover
+	}
+}
+
+type ffprobeOutput struct {
+	Format struct {
+		Duration string            `json:"duration"`
+		Tags     map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType   string            `json:"codec_type"`
+		Tags        map[string]string `json:"tags"`
+		Disposition struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+	} `json:"streams"`
+}
+
+func (l *Library) ffprobe(ctx context.Context, file string) (fileTags, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, l.FFProbePath,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format", "-show_streams",
+		file,
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fileTags{}, fmt.Errorf("ffprobe %s: %w: %s", file, err, strings.TrimSpace(stderr.String()))
+	}
+	return parseFFProbe(stdout.Bytes())
+}
+
+// parseFFProbe reads tags from ffprobe's JSON. Container tags win over
+// stream tags, which is where Ogg files keep theirs; tag names are matched
+// without regard to case.
+func parseFFProbe(data []byte) (fileTags, error) {
+	var out ffprobeOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return fileTags{}, fmt.Errorf("decode ffprobe output: %w", err)
+	}
+
+	all := make(map[string]string)
+	merge := func(tags map[string]string) {
+		for key, value := range tags {
+			key = strings.ToLower(key)
+			if _, ok := all[key]; !ok && strings.TrimSpace(value) != "" {
+				all[key] = strings.TrimSpace(value)
+			}
+		}
+	}
+	merge(out.Format.Tags)
+	var tags fileTags
+	for _, stream := range out.Streams {
+		if stream.CodecType == "video" && stream.Disposition.AttachedPic == 1 {
+			tags.embeddedCover = true
+		}
+		if stream.CodecType == "audio" {
+			merge(stream.Tags)
+		}
+	}
+
+	tags.title = all["title"]
+	tags.artist = all["artist"]
+	tags.albumArtist = firstNonEmpty(all["album_artist"], all["albumartist"], all["album artist"])
+	tags.album = all["album"]
+	tags.disc = leadingNumber(firstNonEmpty(all["disc"], all["discnumber"]))
+	tags.number = leadingNumber(firstNonEmpty(all["track"], all["tracknumber"]))
+	if seconds, err := strconv.ParseFloat(out.Format.Duration, 64); err == nil && seconds > 0 {
+		tags.duration = time.Duration(seconds * float64(time.Second)).Round(time.Second)
+	}
+	return tags, nil
+}
+
+// leadingNumber parses tags such as "3" or "3/12".
+func leadingNumber(value string) int {
+	value, _, _ = strings.Cut(value, "/")
+	n, _ := strconv.Atoi(strings.TrimSpace(value))
+	return n
+}
+
+func isAudioFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, want := range audioExtensions {
+		if ext == want {
+			return true
+		}
+	}
+	return false
+}
+
+func libraryID(rel string) string {
+	sum := sha1.Sum([]byte(rel))
+	return hex.EncodeToString(sum[:6])
+}
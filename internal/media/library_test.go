@@ -0,0 +1,237 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testTags are the tags the fake tag reader reports, keyed by file name.
+var testTags = map[string]fileTags{
+	"01.flac":    {title: "Orbit", artist: "Kosmos", album: "Nebula", number: 1, duration: 3 * time.Minute},
+	"02.flac":    {title: "Gravity", artist: "Kosmos", album: "Nebula", number: 2, duration: 4 * time.Minute},
+	"10.flac":    {title: "Landing", artist: "Kosmos", album: "Nebula", disc: 2, number: 1},
+	"single.mp3": {title: "Comet Tail", artist: "Zora", album: "Comet", embeddedCover: true},
+}
+
+// newTestLibrary creates files under a temporary directory and a library
+// whose tag reader counts the files it reads.
+func newTestLibrary(t *testing.T, files ...string) (*Library, *[]string) {
+	t.Helper()
+	root := t.TempDir()
+	for _, file := range files {
+		writeFile(t, filepath.Join(root, file), "audio")
+	}
+	library := NewLibrary(root)
+	var (
+		mu   sync.Mutex
+		read []string
+	)
+	library.readTags = func(_ context.Context, file string) (fileTags, error) {
+		mu.Lock()
+		read = append(read, filepath.Base(file))
+		mu.Unlock()
+		tags, ok := testTags[filepath.Base(file)]
+		if !ok {
+			return fileTags{}, errors.New("no tags")
+		}
+		return tags, nil
+	}
+	return library, &read
+}
+
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLibraryScan(t *testing.T) {
+	library, read := newTestLibrary(t,
+		"Kosmos/Nebula/01.flac", "Kosmos/Nebula/02.flac", "Kosmos/Nebula/cover.jpg",
+		"Zora/single.mp3", "Zora/Unknown Song.ogg", "notes.txt", ".hidden/03.flac",
+	)
+	result, err := library.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ScanResult{Tracks: 4, Added: 4, Unreadable: 1}); result != want {
+		t.Fatalf("first scan = %+v, want %+v", result, want)
+	}
+
+	entries := library.Entries()
+	byPath := make(map[string]LibraryEntry)
+	for _, entry := range entries {
+		byPath[entry.Path] = entry
+	}
+	if entry := byPath["Kosmos/Nebula/01.flac"]; entry.Title != "Orbit" || entry.Cover != "Kosmos/Nebula/cover.jpg" || entry.Duration != 3*time.Minute {
+		t.Errorf("01.flac = %+v", entry)
+	}
+	if entry := byPath["Zora/single.mp3"]; entry.Cover != embeddedCover {
+		t.Errorf("single.mp3 cover = %q", entry.Cover)
+	}
+	if entry := byPath["Zora/Unknown Song.ogg"]; entry.Title != "Unknown Song" {
+		t.Errorf("untagged file is titled %q", entry.Title)
+	}
+
+	// Only new and changed files are read again.
+	*read = nil
+	root := library.Root()
+	writeFile(t, filepath.Join(root, "Kosmos/Nebula/10.flac"), "audio")
+	writeFile(t, filepath.Join(root, "Kosmos/Nebula/02.flac"), "remastered")
+	if err := os.Remove(filepath.Join(root, "Zora/Unknown Song.ogg")); err != nil {
+		t.Fatal(err)
+	}
+	result, err = library.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ScanResult{Tracks: 4, Added: 1, Updated: 1, Removed: 1}); result != want {
+		t.Errorf("rescan = %+v, want %+v", result, want)
+	}
+	if strings.Join(*read, ",") != "02.flac,10.flac" && strings.Join(*read, ",") != "10.flac,02.flac" {
+		t.Errorf("rescan read %v", *read)
+	}
+
+	// A restored index is not read again either.
+	restored, read := newTestLibrary(t)
+	restored.root = root
+	restored.Restore(library.Entries())
+	if _, err := restored.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(*read) != 0 {
+		t.Errorf("scan after Restore read %v", *read)
+	}
+}
+
+// TestLibraryScanSkipsUnreadable checks that a folder that cannot be read is
+// skipped, and its tracks kept, instead of failing the whole scan.
+func TestLibraryScanSkipsUnreadable(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("root can read every folder")
+	}
+	library, _ := newTestLibrary(t, "Kosmos/Nebula/01.flac", "Kosmos/Nebula/02.flac", "Zora/single.mp3")
+	if _, err := library.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	locked := filepath.Join(library.Root(), "Kosmos")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+	writeFile(t, filepath.Join(library.Root(), "Zora/Unknown Song.ogg"), "audio")
+
+	result, err := library.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ScanResult{Tracks: 4, Added: 1, Unreadable: 1, Skipped: 1}); result != want {
+		t.Errorf("scan = %+v, want %+v", result, want)
+	}
+	if found := library.Search("Orbit", 5); len(found) != 1 {
+		t.Errorf("Search(Orbit) = %v, want the skipped folder's track kept", found)
+	}
+}
+
+func TestLibrarySearchAndGroups(t *testing.T) {
+	library, _ := newTestLibrary(t, "Kosmos/Nebula/01.flac", "Kosmos/Nebula/02.flac", "Kosmos/Nebula/10.flac", "Zora/single.mp3")
+	if _, err := library.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	titles := func(entries []LibraryEntry) string {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Title)
+		}
+		return strings.Join(names, ", ")
+	}
+
+	tests := []struct {
+		name string
+		got  []LibraryEntry
+		want string
+	}{
+		{"search by title", library.Search("gravity", 0), "Gravity"},
+		{"search by artist and title", library.Search("kosmos orbit", 0), "Orbit"},
+		{"search by album", library.Search("comet", 0), "Comet Tail"},
+		{"search ranks titles first", library.Search("o", 2), "Orbit, Comet Tail"},
+		{"search without a match", library.Search("jazz", 0), ""},
+		{"album in order", library.Album("nebula"), "Orbit, Gravity, Landing"},
+		{"album with its artist", library.Album("kosmos nebula"), "Orbit, Gravity, Landing"},
+		{"artist", library.Artist("zora"), "Comet Tail"},
+		{"unknown artist", library.Artist("nobody"), ""},
+	}
+	for _, tt := range tests {
+		if got := titles(tt.got); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLibraryProvider(t *testing.T) {
+	library, _ := newTestLibrary(t, "Kosmos/Nebula/01.flac", "Kosmos/Nebula/cover.jpg")
+	library.CoverURL = "https://kvazar.example.com/library/covers/"
+	if _, err := library.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewRegistry(stubProvider{name: "default"}, library)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{"local: orbit", "local:Kosmos/Nebula/01.flac"} {
+		track, err := registry.Resolve(context.Background(), "", query, "", "")
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", query, err)
+		}
+		if track.Title != "Orbit" || track.Provider != ProviderLocal || track.WebURL != "local:Kosmos/Nebula/01.flac" ||
+			track.Thumbnail != "https://kvazar.example.com/library/covers/"+track.ID {
+			t.Errorf("Resolve(%q) = %+v", query, track)
+		}
+		input, err := registry.Open(context.Background(), track)
+		if err != nil || input.URL != filepath.Join(library.Root(), "Kosmos", "Nebula", "01.flac") {
+			t.Errorf("Open = %+v, %v", input, err)
+		}
+		cover, err := library.Cover(context.Background(), track.ID)
+		if err != nil || string(cover) != "audio" {
+			t.Errorf("Cover = %q, %v", cover, err)
+		}
+	}
+	if _, err := registry.Resolve(context.Background(), ProviderLocal, "jazz", "", ""); !errors.Is(err, ErrNotInLibrary) {
+		t.Errorf("missing track: err = %v", err)
+	}
+	if _, err := registry.Open(context.Background(), &Track{ID: "gone", Provider: ProviderLocal}); !errors.Is(err, ErrNotInLibrary) {
+		t.Errorf("Open of a removed file: err = %v", err)
+	}
+}
+
+func TestParseFFProbe(t *testing.T) {
+	output := `{
+		"streams": [
+			{"codec_type": "audio", "tags": {"TITLE": "From the stream", "ARTIST": "Ogg Artist"}},
+			{"codec_type": "video", "disposition": {"attached_pic": 1}}
+		],
+		"format": {"duration": "215.4", "tags": {"title": "Orbit", "album_artist": "Kosmos", "track": "3/12", "disc": "1/2"}}
+	}`
+	tags, err := parseFFProbe([]byte(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fileTags{title: "Orbit", artist: "Ogg Artist", albumArtist: "Kosmos", disc: 1, number: 3, duration: 215 * time.Second, embeddedCover: true}
+	if tags != want {
+		t.Errorf("tags = %+v, want %+v", tags, want)
+	}
+	if _, err := parseFFProbe([]byte("not json")); err == nil {
+		t.Error("parsed garbage")
+	}
+}
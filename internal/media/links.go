@@ -0,0 +1,351 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Names of the providers that play streaming service links by finding the
+// same tracks elsewhere.
+const (
+	ProviderSpotify    = "spotify"
+	ProviderAppleMusic = "applemusic"
+	ProviderDeezer     = "deezer"
+)
+
+// Kinds of item a streaming service link points at.
+const (
+	linkTrack    = "track"
+	linkAlbum    = "album"
+	linkPlaylist = "playlist"
+)
+
+const (
+	// maxLinkTracks caps how many tracks of an album or playlist are queued.
+	maxLinkTracks = 100
+	// linkCandidates is how many search results are scored for each track.
+	linkCandidates = 5
+	// minMatchScore is the score a search result needs to stand in for a
+	// linked track.
+	minMatchScore = 0.65
+	// linkMemoSize bounds how many listed tracks are remembered, so the
+	// tracks of an album need not be looked up again one by one.
+	linkMemoSize = 1000
+	// maxMetadataSize bounds the metadata responses read from a service.
+	maxMetadataSize = 4 << 20
+)
+
+// ErrNoMatch is returned when no search result is close enough to a linked
+// track.
+var ErrNoMatch = errors.New("media: no matching track found")
+
+// versionTags mark recordings other than the original. A search result
+// carrying one the linked title does not is marked down.
+var versionTags = []string{"live", "cover", "remix", "karaoke", "instrumental", "acoustic", "nightcore", "slowed", "sped", "reverb", "8d"}
+
+// LinkTrack is a track as a streaming service lists it.
+type LinkTrack struct {
+	// URL links to the track on the service.
+	URL      string
+	Title    string
+	Artists  []string
+	Album    string
+	Duration time.Duration
+}
+
+// Collection is an album or playlist.
+type Collection struct {
+	Title  string
+	Tracks []LinkTrack
+}
+
+// linkRef identifies an item on a streaming service.
+type linkRef struct {
+	kind string
+	id   string
+	// country is the storefront of an Apple Music link.
+	country string
+}
+
+// linkCatalog reads track metadata from a streaming service.
+type linkCatalog interface {
+	// parse picks the item out of a link to the service.
+	parse(link *url.URL) (linkRef, bool)
+	// lookup lists the tracks of the item. A track is a Collection of one
+	// without a title.
+	lookup(ctx context.Context, ref linkRef) (*Collection, error)
+}
+
+// trackFinder searches for tracks and resolves them; Resolver is one.
+type trackFinder interface {
+	Search(ctx context.Context, query string, limit int) ([]*Track, error)
+	lookup(ctx context.Context, query string) (*Track, error)
+	open(ctx context.Context, track *Track) (Input, error)
+}
+
+// linkProvider plays links to a streaming service Kvazar cannot stream
+// from. It reads each track's title, artists and duration from the service
+// and plays the best YouTube match, or SoundCloud when YouTube has none.
+type linkProvider struct {
+	Patterns
+	name    string
+	catalog linkCatalog
+	finder  trackFinder
+
+	mu   sync.Mutex
+	memo map[string]LinkTrack
+}
+
+func newLinkProvider(name string, patterns Patterns, catalog linkCatalog, finder trackFinder) *linkProvider {
+	return &linkProvider{Patterns: patterns, name: name, catalog: catalog, finder: finder, memo: make(map[string]LinkTrack)}
+}
+
+func (p *linkProvider) Name() string { return p.name }
+
+// LinksOnly keeps the provider out of the /play source choices.
+func (p *linkProvider) LinksOnly() bool { return true }
+
+// Expand lists the tracks of an album or playlist link, and returns nil for
+// a link to a single track.
+func (p *linkProvider) Expand(ctx context.Context, query string) (*Collection, error) {
+	ref, err := p.ref(query)
+	if err != nil {
+		return nil, err
+	}
+	if ref.kind == linkTrack {
+		return nil, nil
+	}
+	collection, err := p.catalog.lookup(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(collection.Tracks) == 0 {
+		return nil, fmt.Errorf("media: the %s %s has no playable tracks", p.name, ref.kind)
+	}
+	if len(collection.Tracks) > maxLinkTracks {
+		collection.Tracks = collection.Tracks[:maxLinkTracks]
+	}
+	p.remember(collection.Tracks)
+	return collection, nil
+}
+
+// Resolve plays the linked track, or the first track of an album or
+// playlist.
+func (p *linkProvider) Resolve(ctx context.Context, query string) (*Track, error) {
+	query = strings.TrimSpace(query)
+	want, ok := p.recall(query)
+	if !ok {
+		ref, err := p.ref(query)
+		if err != nil {
+			return nil, err
+		}
+		collection, err := p.catalog.lookup(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if len(collection.Tracks) == 0 {
+			return nil, fmt.Errorf("media: the %s %s has no playable tracks", p.name, ref.kind)
+		}
+		want = collection.Tracks[0]
+	}
+	return p.match(ctx, want)
+}
+
+// Open plays the stream URL of the match, or resolves the match again for
+// tracks stored without one.
+func (p *linkProvider) Open(ctx context.Context, track *Track) (Input, error) {
+	return p.finder.open(ctx, track)
+}
+
+func (p *linkProvider) ref(query string) (linkRef, error) {
+	query = strings.TrimSpace(query)
+	if parsed, ok := httpURL(query); ok {
+		if ref, ok := p.catalog.parse(parsed); ok {
+			return ref, nil
+		}
+	}
+	return linkRef{}, fmt.Errorf("media: %q is not a %s track, album or playlist link", query, p.name)
+}
+
+// match searches YouTube, then SoundCloud, for the track and resolves the
+// best scoring result.
+func (p *linkProvider) match(ctx context.Context, want LinkTrack) (*Track, error) {
+	query := want.Title
+	if len(want.Artists) > 0 {
+		query = want.Artists[0] + " - " + want.Title
+	}
+
+	var (
+		best      *Track
+		bestScore float64
+		searchErr error
+	)
+	for _, prefix := range []string{"", "sc "} {
+		candidates, err := p.finder.Search(ctx, prefix+query, linkCandidates)
+		if err != nil {
+			searchErr = err
+			continue
+		}
+		for _, candidate := range candidates {
+			if score := matchScore(want, candidate); score > bestScore {
+				best, bestScore = candidate, score
+			}
+		}
+		if bestScore >= minMatchScore {
+			break
+		}
+	}
+	if best == nil && searchErr != nil {
+		return nil, searchErr
+	}
+	if bestScore < minMatchScore {
+		return nil, fmt.Errorf("%w for %q", ErrNoMatch, query)
+	}
+	return p.finder.lookup(ctx, best.WebURL)
+}
+
+func (p *linkProvider) remember(tracks []LinkTrack) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.memo)+len(tracks) > linkMemoSize {
+		p.memo = make(map[string]LinkTrack)
+	}
+	for _, track := range tracks {
+		if track.URL != "" {
+			p.memo[track.URL] = track
+		}
+	}
+}
+
+func (p *linkProvider) recall(link string) (LinkTrack, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	track, ok := p.memo[link]
+	return track, ok
+}
+
+// matchScore rates from 0 to 1 how well a search result fits a linked track.
+// The title weighs most, then the artists, which uploads often name only in
+// the title, then the duration. Results that are another version of the
+// song, such as a live recording or a cover, are marked down.
+func matchScore(want LinkTrack, got *Track) float64 {
+	have := words(got.Title + " " + got.Author)
+	title := coverage(words(coreTitle(want.Title)), have)
+	if title < 0.5 {
+		return 0
+	}
+
+	artist := 0.5
+	if len(want.Artists) > 0 {
+		artist = 0
+		for _, name := range want.Artists {
+			artist = max(artist, coverage(words(name), have))
+		}
+	}
+
+	score := 0.5*title + 0.3*artist + 0.2*durationScore(want.Duration, got.Duration)
+	wanted := words(want.Title)
+	for _, tag := range versionTags {
+		if have[tag] && !wanted[tag] {
+			score -= 0.2
+		}
+	}
+	return max(score, 0)
+}
+
+// coreTitle drops what follows the song name, such as "(feat. …)" or
+// " - Remastered 2011", which uploads rarely repeat.
+func coreTitle(title string) string {
+	core := title
+	if i := strings.Index(core, " - "); i > 0 {
+		core = core[:i]
+	}
+	if i := strings.IndexAny(core, "(["); i > 0 {
+		core = core[:i]
+	}
+	if strings.TrimSpace(core) == "" {
+		return title
+	}
+	return core
+}
+
+// durationScore is 1 for durations within a few seconds of each other and 0
+// for those half a minute apart or more. Unknown durations score 0.5.
+func durationScore(want, got time.Duration) float64 {
+	if want <= 0 || got <= 0 {
+		return 0.5
+	}
+	diff := want - got
+	if diff < 0 {
+		diff = -diff
+	}
+	const close, far = 3 * time.Second, 30 * time.Second
+	switch {
+	case diff <= close:
+		return 1
+	case diff >= far:
+		return 0
+	}
+	return 1 - float64(diff-close)/float64(far-close)
+}
+
+// words splits text into a set of lowercase words.
+func words(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		set[word] = true
+	}
+	return set
+}
+
+// coverage is the share of want found in have.
+func coverage(want, have map[string]bool) float64 {
+	if len(want) == 0 {
+		return 0
+	}
+	found := 0
+	for word := range want {
+		if have[word] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(want))
+}
+
+// getJSON decodes the JSON answer to a GET request. A non-empty bearer is
+// sent as the access token.
+func getJSON(ctx context.Context, client *http.Client, rawURL, bearer string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("media: %s answered %s", req.URL.Host, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(v)
+}
+
+// pathSegments splits a URL path into its non-empty segments.
+func pathSegments(link *url.URL) []string {
+	return strings.FieldsFunc(link.Path, func(r rune) bool { return r == '/' })
+}
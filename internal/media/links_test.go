@@ -0,0 +1,241 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubFinder answers searches from a fixed table and resolves any URL.
+type stubFinder struct {
+	results map[string][]*Track
+}
+
+func (f *stubFinder) Search(_ context.Context, query string, _ int) ([]*Track, error) {
+	return f.results[query], nil
+}
+
+func (f *stubFinder) lookup(_ context.Context, query string) (*Track, error) {
+	return &Track{Title: "resolved " + query, WebURL: query, StreamURL: query + "/audio", Source: SourceYouTube}, nil
+}
+
+func (f *stubFinder) open(ctx context.Context, track *Track) (Input, error) {
+	return Input{URL: track.StreamURL, Headers: track.HTTPHeaders}, nil
+}
+
+// video is a search result.
+func video(title, author string, seconds int) *Track {
+	return &Track{Title: title, Author: author, WebURL: "https://youtu.be/" + strings.ReplaceAll(title, " ", ""), Duration: time.Duration(seconds) * time.Second}
+}
+
+func TestMatchScore(t *testing.T) {
+	want := LinkTrack{Title: "Orbit (feat. Zora) - Remastered 2011", Artists: []string{"Kosmos", "Zora"}, Duration: 200 * time.Second}
+	tests := []struct {
+		name       string
+		got        *Track
+		atLeast    float64
+		lessThan   float64
+		acceptable bool
+	}{
+		{"official upload", video("Kosmos - Orbit (Official Video)", "KosmosVEVO", 202), 0.95, 1.01, true},
+		{"topic channel", video("Orbit", "Zora - Topic", 200), 0.95, 1.01, true},
+		{"right song, long video", video("Kosmos - Orbit", "Fan", 260), 0.7, 0.85, true},
+		{"title only", video("Orbit", "someone", 0), 0.5, minMatchScore, false},
+		{"live version", video("Kosmos - Orbit (Live at Arena)", "Kosmos", 240), 0, minMatchScore, false},
+		{"another song", video("Kosmos - Gravity", "Kosmos", 200), 0, 0.01, false},
+	}
+	for _, tt := range tests {
+		score := matchScore(want, tt.got)
+		if score < tt.atLeast || score >= tt.lessThan {
+			t.Errorf("%s: score %.2f, want [%.2f, %.2f)", tt.name, score, tt.atLeast, tt.lessThan)
+		}
+		if (score >= minMatchScore) != tt.acceptable {
+			t.Errorf("%s: score %.2f accepted = %v", tt.name, score, !tt.acceptable)
+		}
+	}
+}
+
+// catalogServer stands in for the Spotify Web API, token endpoint and embed
+// pages, the iTunes lookup API and the Deezer API.
+func catalogServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/spotify/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "id" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"token","expires_in":3600}`)
+	})
+	api := func(pattern, body string) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				http.Error(w, "no token", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, strings.ReplaceAll(body, "$SERVER", server.URL))
+		})
+	}
+	api("/spotify/v1/tracks/orbit", `{"id":"orbit","name":"Orbit","duration_ms":200000,"artists":[{"name":"Kosmos"}],"album":{"name":"Nebula"}}`)
+	api("/spotify/v1/albums/nebula", `{"name":"Nebula","tracks":{"items":[{"id":"orbit","name":"Orbit","duration_ms":200000,"artists":[{"name":"Kosmos"}]}],"next":"$SERVER/spotify/v1/albums/nebula/tracks?offset=1"}}`)
+	api("/spotify/v1/albums/nebula/tracks", `{"items":[{"id":"gravity","name":"Gravity","duration_ms":180000,"artists":[{"name":"Kosmos"}]}],"next":null}`)
+	api("/spotify/v1/playlists/mix", `{"name":"Mix","tracks":{"items":[{"track":{"id":"gravity","name":"Gravity","duration_ms":180000,"artists":[{"name":"Kosmos"}]}},{"track":null}],"next":null}}`)
+	mux.HandleFunc("/embed/track/orbit", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><script id="__NEXT_DATA__" type="application/json">{"props":{"pageProps":{"state":{"data":{"entity":{"type":"track","name":"Orbit","duration":200000,"artists":[{"name":"Kosmos"}]}}}}}}</script></html>`)
+	})
+	mux.HandleFunc("/embed/playlist/mix", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><script id="__NEXT_DATA__" type="application/json">{"props":{"pageProps":{"state":{"data":{"entity":{"type":"playlist","name":"Mix","trackList":[`+
+			`{"uri":"spotify:track:orbit","title":"Orbit","subtitle":"Kosmos, Zora","duration":200000},`+
+			`{"uri":"spotify:episode:talk","title":"A podcast","subtitle":"Host","duration":3600000}]}}}}}}</script></html>`)
+	})
+	mux.HandleFunc("/itunes/lookup", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("entity") != "song" || r.FormValue("country") != "de" {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"results":[
+			{"wrapperType":"collection","collectionId":100,"collectionName":"Nebula","artistName":"Kosmos"},
+			{"wrapperType":"track","kind":"song","trackId":101,"trackName":"Orbit","artistName":"Kosmos & Zora","collectionName":"Nebula","trackTimeMillis":200000},
+			{"wrapperType":"track","kind":"song","trackId":102,"trackName":"Gravity","artistName":"Kosmos","collectionName":"Nebula","trackTimeMillis":180000}]}`)
+	})
+	mux.HandleFunc("/deezer/track/7", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":7,"title":"Orbit","duration":200,"artist":{"name":"Kosmos"},"album":{"title":"Nebula"}}`)
+	})
+	mux.HandleFunc("/deezer/track/8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"error":{"type":"DataException","message":"no data","code":800}}`)
+	})
+	mux.HandleFunc("/deezer/playlist/9", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"title":"Mix","tracks":{"data":[{"id":7,"title":"Orbit","duration":200,"artist":{"name":"Kosmos"}}],"next":"`+server.URL+`/deezer/playlist/9/tracks"}}`)
+	})
+	mux.HandleFunc("/deezer/playlist/9/tracks", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":[{"id":10,"title":"Gravity","duration":180,"artist":{"name":"Kosmos"}}]}`)
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLinkProviders(t *testing.T) {
+	server := catalogServer(t)
+	finder := &stubFinder{results: map[string][]*Track{
+		"Kosmos - Orbit": {video("Kosmos - Orbit (Live)", "Kosmos", 260), video("Kosmos - Orbit", "KosmosVEVO", 201)},
+		// Gravity is not on YouTube.
+		"sc Kosmos - Gravity": {video("gravity", "kosmos", 181)},
+	}}
+	spotifyAPI := newLinkProvider(ProviderSpotify, spotifyPatterns, &spotifyCatalog{
+		client: server.Client(), clientID: "id", clientSecret: "secret",
+		apiURL: server.URL + "/spotify/v1", authURL: server.URL + "/spotify/token",
+	}, finder)
+	spotifyEmbed := newLinkProvider(ProviderSpotify, spotifyPatterns, &spotifyCatalog{client: server.Client(), embedURL: server.URL + "/embed"}, finder)
+	apple := newLinkProvider(ProviderAppleMusic, appleMusicPatterns, &appleMusicCatalog{client: server.Client(), lookupURL: server.URL + "/itunes/lookup"}, finder)
+	deezer := newLinkProvider(ProviderDeezer, deezerPatterns, &deezerCatalog{client: server.Client(), apiURL: server.URL + "/deezer"}, finder)
+
+	tracks := func(collection *Collection) string {
+		var names []string
+		for _, track := range collection.Tracks {
+			names = append(names, fmt.Sprintf("%s by %s (%s)", track.Title, strings.Join(track.Artists, " & "), track.Duration))
+		}
+		return collection.Title + ": " + strings.Join(names, ", ")
+	}
+	expands := []struct {
+		name     string
+		provider *linkProvider
+		link     string
+		want     string
+	}{
+		{"spotify album", spotifyAPI, "https://open.spotify.com/intl-de/album/nebula?si=x", "Nebula: Orbit by Kosmos (3m20s), Gravity by Kosmos (3m0s)"},
+		{"spotify playlist", spotifyAPI, "https://open.spotify.com/playlist/mix", "Mix: Gravity by Kosmos (3m0s)"},
+		{"spotify embed playlist", spotifyEmbed, "https://open.spotify.com/playlist/mix", "Mix: Orbit by Kosmos & Zora (3m20s)"},
+		{"apple music album", apple, "https://music.apple.com/de/album/nebula/100", "Nebula: Orbit by Kosmos & Zora (3m20s), Gravity by Kosmos (3m0s)"},
+		{"deezer playlist", deezer, "https://www.deezer.com/en/playlist/9", "Mix: Orbit by Kosmos (3m20s), Gravity by Kosmos (3m0s)"},
+	}
+	for _, tt := range expands {
+		collection, err := tt.provider.Expand(context.Background(), tt.link)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := tracks(collection); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	for _, link := range []string{"https://open.spotify.com/track/orbit", "https://music.apple.com/de/album/nebula/100?i=101", "https://www.deezer.com/track/7"} {
+		for _, provider := range []*linkProvider{spotifyAPI, apple, deezer} {
+			if provider.Match(link) {
+				if collection, err := provider.Expand(context.Background(), link); collection != nil || err != nil {
+					t.Errorf("Expand(%q) = %v, %v; want nil for a track", link, collection, err)
+				}
+			}
+		}
+	}
+
+	resolves := []struct {
+		name     string
+		provider *linkProvider
+		link     string
+		want     string
+	}{
+		{"spotify track", spotifyAPI, "https://open.spotify.com/track/orbit", "https://youtu.be/Kosmos-Orbit"},
+		{"spotify embed track", spotifyEmbed, "https://open.spotify.com/embed/track/orbit", "https://youtu.be/Kosmos-Orbit"},
+		{"apple music song", apple, "https://music.apple.com/de/album/nebula/100?i=101", "https://youtu.be/Kosmos-Orbit"},
+		// Remembered from the album listing above; found on SoundCloud.
+		{"listed track", apple, "https://music.apple.com/de/song/102", "https://youtu.be/gravity"},
+		{"deezer track", deezer, "https://www.deezer.com/track/7", "https://youtu.be/Kosmos-Orbit"},
+	}
+	for _, tt := range resolves {
+		track, err := tt.provider.Resolve(context.Background(), tt.link)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if track.WebURL != tt.want {
+			t.Errorf("%s played %s, want %s", tt.name, track.WebURL, tt.want)
+		}
+	}
+
+	failures := []struct {
+		name     string
+		provider *linkProvider
+		link     string
+	}{
+		{"unknown deezer track", deezer, "https://www.deezer.com/track/8"},
+		{"apple music playlist", apple, "https://music.apple.com/us/playlist/mix/pl.u-123"},
+		{"spotify artist", spotifyAPI, "https://open.spotify.com/artist/kosmos"},
+	}
+	for _, tt := range failures {
+		if _, err := tt.provider.Resolve(context.Background(), tt.link); err == nil {
+			t.Errorf("%s resolved", tt.name)
+		}
+	}
+
+	finder.results = nil
+	if _, err := deezer.Resolve(context.Background(), "https://www.deezer.com/track/7"); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("track with no search results: err = %v", err)
+	}
+}
+
+func TestRegistryExpand(t *testing.T) {
+	server := catalogServer(t)
+	deezer := newLinkProvider(ProviderDeezer, deezerPatterns, &deezerCatalog{client: server.Client(), apiURL: server.URL + "/deezer"}, &stubFinder{})
+	registry, err := NewRegistry(stubProvider{name: "default"}, deezer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collection, err := registry.Expand(context.Background(), "", "https://deezer.com/playlist/9")
+	if err != nil || collection == nil || len(collection.Tracks) != 2 {
+		t.Fatalf("Expand = %+v, %v", collection, err)
+	}
+	for _, query := range []string{"https://deezer.com/track/7", "some song"} {
+		if collection, err := registry.Expand(context.Background(), "", query); collection != nil || err != nil {
+			t.Errorf("Expand(%q) = %+v, %v", query, collection, err)
+		}
+	}
+	if searchable := registry.Searchable(); len(searchable) != 1 || searchable[0].Name() != "default" {
+		t.Errorf("Searchable = %v", searchable)
+	}
+}
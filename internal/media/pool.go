@@ -0,0 +1,234 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"kvazar/internal/metrics"
+)
+
+// maxQueuedLookups bounds how many yt-dlp runs may wait for a worker.
+const maxQueuedLookups = 64
+
+// ErrResolverBusy is returned when too many lookups are already waiting for
+// yt-dlp.
+var ErrResolverBusy = errors.New("resolver: too many lookups waiting")
+
+// Priority orders lookups waiting for a yt-dlp worker.
+type Priority int
+
+const (
+	// PriorityInteractive is for lookups someone is waiting on, such as
+	// /play. It is the default.
+	PriorityInteractive Priority = iota
+	// PriorityBackground is for bulk and speculative lookups, such as
+	// playlist imports and autoplay, which yield to interactive ones.
+	PriorityBackground
+)
+
+func (p Priority) String() string {
+	if p == PriorityBackground {
+		return "background"
+	}
+	return "interactive"
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose lookups run at the given priority.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func priorityOf(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityInteractive
+}
+
+// workerPool lets a bounded number of yt-dlp runs go at once. The others
+// wait in line, interactive lookups first.
+type workerPool struct {
+	mu      sync.Mutex
+	limit   int
+	running int
+	waiting [2][]*poolTicket
+}
+
+// poolTicket is a place in line; ready is closed once it holds a worker.
+type poolTicket struct {
+	priority Priority
+	ready    chan struct{}
+	queued   bool
+}
+
+// newWorkerPool returns a pool of limit workers, or an unbounded one for a
+// limit of 0.
+func newWorkerPool(limit int) *workerPool {
+	return &workerPool{limit: limit}
+}
+
+// enqueue takes a worker, or a place in line when all are busy.
+func (p *workerPool) enqueue(priority Priority) (*poolTicket, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ticket := &poolTicket{priority: priority, ready: make(chan struct{})}
+	queued := len(p.waiting[PriorityInteractive]) + len(p.waiting[PriorityBackground])
+	if p.limit <= 0 || (p.running < p.limit && queued == 0) {
+		p.running++
+		close(ticket.ready)
+		return ticket, nil
+	}
+	if queued >= maxQueuedLookups {
+		return nil, ErrResolverBusy
+	}
+	ticket.queued = true
+	p.waiting[priority] = append(p.waiting[priority], ticket)
+	p.observe()
+	return ticket, nil
+}
+
+// promote moves a waiting background ticket to the interactive line.
+func (p *workerPool) promote(ticket *poolTicket) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !ticket.queued || ticket.priority == PriorityInteractive {
+		return
+	}
+	line := p.waiting[PriorityBackground]
+	for i, waiting := range line {
+		if waiting == ticket {
+			p.waiting[PriorityBackground] = append(line[:i:i], line[i+1:]...)
+			break
+		}
+	}
+	ticket.priority = PriorityInteractive
+	p.waiting[PriorityInteractive] = append(p.waiting[PriorityInteractive], ticket)
+	p.observe()
+}
+
+// release hands the worker to the next ticket in line, or frees it.
+func (p *workerPool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for priority, line := range p.waiting {
+		if len(line) > 0 {
+			next := line[0]
+			p.waiting[priority] = line[1:]
+			next.queued = false
+			close(next.ready)
+			p.observe()
+			return
+		}
+	}
+	p.running--
+}
+
+// withdraw takes a ticket out of line. It reports false when the ticket
+// already holds a worker, which the caller must then release.
+func (p *workerPool) withdraw(ticket *poolTicket) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !ticket.queued {
+		return false
+	}
+	line := p.waiting[ticket.priority]
+	for i, waiting := range line {
+		if waiting == ticket {
+			p.waiting[ticket.priority] = append(line[:i:i], line[i+1:]...)
+			break
+		}
+	}
+	ticket.queued = false
+	p.observe()
+	return true
+}
+
+func (p *workerPool) observe() {
+	for priority, line := range p.waiting {
+		metrics.SetResolveQueue(Priority(priority).String(), len(line))
+	}
+}
+
+// flight is a yt-dlp run shared by every caller asking the same thing while
+// it is waiting or running.
+type flight struct {
+	done    chan struct{}
+	ticket  *poolTicket
+	cancel  context.CancelFunc
+	waiters int
+	result  any
+	err     error
+}
+
+// shared runs fn on a pool worker, unless a run for the same key is already
+// under way, in which case it waits for that one. When every caller has given
+// up the run keeps going so its result still reaches the cache, or is
+// cancelled when there is no cache to fill.
+func (r *Resolver) shared(ctx context.Context, key string, fn func(context.Context) (any, error)) (any, error) {
+	r.once.Do(func() {
+		r.pool = newWorkerPool(r.Workers)
+		r.flights = make(map[string]*flight)
+	})
+	priority := priorityOf(ctx)
+
+	r.flightsMu.Lock()
+	f, ok := r.flights[key]
+	if ok {
+		metrics.ResolveCoalesced()
+		f.waiters++
+		if priority == PriorityInteractive {
+			r.pool.promote(f.ticket)
+		}
+	} else {
+		ticket, err := r.pool.enqueue(priority)
+		if err != nil {
+			r.flightsMu.Unlock()
+			return nil, err
+		}
+		runCtx, cancel := context.WithCancel(context.Background())
+		f = &flight{done: make(chan struct{}), ticket: ticket, cancel: cancel, waiters: 1}
+		r.flights[key] = f
+		go r.fly(runCtx, key, f, fn)
+	}
+	r.flightsMu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		r.flightsMu.Lock()
+		f.waiters--
+		if f.waiters == 0 && r.Cache == nil {
+			f.cancel()
+			if r.flights[key] == f {
+				delete(r.flights, key)
+			}
+		}
+		r.flightsMu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (r *Resolver) fly(ctx context.Context, key string, f *flight, fn func(context.Context) (any, error)) {
+	defer f.cancel()
+	select {
+	case <-f.ticket.ready:
+		f.result, f.err = fn(ctx)
+		r.pool.release()
+	case <-ctx.Done():
+		if !r.pool.withdraw(f.ticket) {
+			r.pool.release()
+		}
+		f.err = ctx.Err()
+	}
+
+	r.flightsMu.Lock()
+	if r.flights[key] == f {
+		delete(r.flights, key)
+	}
+	r.flightsMu.Unlock()
+	close(f.done)
+}
@@ -0,0 +1,150 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolOrder(t *testing.T) {
+	pool := newWorkerPool(1)
+	first, err := pool.enqueue(PriorityInteractive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	background, _ := pool.enqueue(PriorityBackground)
+	prefetch, _ := pool.enqueue(PriorityBackground)
+	interactive, _ := pool.enqueue(PriorityInteractive)
+	pool.promote(prefetch)
+
+	for _, want := range []*poolTicket{first, interactive, prefetch, background} {
+		select {
+		case <-want.ready:
+		default:
+			t.Fatalf("ticket %+v should hold the worker", want)
+		}
+		pool.release()
+	}
+	if pool.running != 0 {
+		t.Errorf("running = %d after every ticket was released", pool.running)
+	}
+}
+
+func TestWorkerPoolBusy(t *testing.T) {
+	pool := newWorkerPool(1)
+	for i := 0; i <= maxQueuedLookups; i++ {
+		if _, err := pool.enqueue(PriorityBackground); err != nil {
+			t.Fatalf("enqueue %d: %v", i, err)
+		}
+	}
+	if _, err := pool.enqueue(PriorityInteractive); !errors.Is(err, ErrResolverBusy) {
+		t.Errorf("err = %v, want ErrResolverBusy", err)
+	}
+}
+
+// TestResolverCoalesces checks that identical lookups made at once share one
+// yt-dlp run, and that no more runs than Workers go at once.
+func TestResolverCoalesces(t *testing.T) {
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	ytdlp := filepath.Join(dir, "yt-dlp")
+	script := `#!/bin/sh
+for last; do :; done
+echo "$last" >> ` + runs + `
+sleep 0.3
+printf '{"id":"%s","title":"Orbit","extractor_key":"Youtube","webpage_url":"https://www.youtube.com/watch?v=orbit","url":"https://media.example.com/orbit","duration":180}\n' "$last"
+`
+	if err := os.WriteFile(ytdlp, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewResolver(ytdlp)
+	resolver.Workers = 1
+
+	queries := []string{"Kosmos Orbit", "kosmos orbit", "Kosmos - Orbit!", "Kosmos Gravity", "kosmos orbit"}
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func(query string) {
+			defer wg.Done()
+			if _, err := resolver.Resolve(context.Background(), query, "tester", "channel"); err != nil {
+				t.Errorf("Resolve(%q): %v", query, err)
+			}
+		}(query)
+		if i == 0 {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	wg.Wait()
+
+	output, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 {
+		t.Errorf("yt-dlp ran %d times, want once per distinct search: %q", len(lines), lines)
+	}
+}
+
+func TestResolverGivesUpWithoutStoppingRun(t *testing.T) {
+	dir := t.TempDir()
+	ytdlp := filepath.Join(dir, "yt-dlp")
+	script := `#!/bin/sh
+sleep 0.3
+printf '{"id":"orbit","title":"Orbit","extractor_key":"Youtube","webpage_url":"https://www.youtube.com/watch?v=orbit","url":"https://media.example.com/orbit","duration":180}\n'
+`
+	if err := os.WriteFile(ytdlp, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewResolver(ytdlp)
+	resolver.Cache = NewResolveCache(10, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := resolver.Resolve(ctx, "Kosmos Orbit", "tester", "channel"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the caller's deadline", err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for resolver.Cache.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if resolver.Cache.Len() != 1 {
+		t.Error("the abandoned run did not reach the cache")
+	}
+}
+
+// TestResolverCancelsAbandonedRun checks that without a cache to fill, a run
+// every caller gave up on stops and frees its worker.
+func TestResolverCancelsAbandonedRun(t *testing.T) {
+	dir := t.TempDir()
+	ytdlp := filepath.Join(dir, "yt-dlp")
+	script := `#!/bin/sh
+for last; do :; done
+case "$last" in
+*Slow*) exec sleep 5 ;;
+esac
+printf '{"id":"orbit","title":"Orbit","extractor_key":"Youtube","webpage_url":"https://www.youtube.com/watch?v=orbit","url":"https://media.example.com/orbit","duration":180}\n'
+`
+	if err := os.WriteFile(ytdlp, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewResolver(ytdlp)
+	resolver.Workers = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := resolver.Resolve(ctx, "Kosmos Slow", "tester", "channel"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the caller's deadline", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := resolver.Resolve(ctx, "Kosmos Orbit", "tester", "channel"); err != nil {
+		t.Fatalf("the abandoned run still holds the worker: %v", err)
+	}
+}
@@ -7,10 +7,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os/exec"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"kvazar/internal/metrics"
 )
 
 const (
@@ -22,6 +27,72 @@ const (
 type Resolver struct {
 	Executable string
 	Timeout    time.Duration
+	// Cache, when set, answers repeated lookups without yt-dlp.
+	Cache *ResolveCache
+	// Workers caps how many yt-dlp processes run at once; 0 means no cap.
+	// Identical lookups made while one is waiting or running share it.
+	Workers int
+	// Helper, when set, answers lookups in place of a yt-dlp process each.
+	// Lookups it cannot take run yt-dlp as usual.
+	Helper *Helper
+	// Options are passed to every lookup.
+	Options YTDLPOptions
+
+	once      sync.Once
+	pool      *workerPool
+	flightsMu sync.Mutex
+	flights   map[string]*flight
+}
+
+// YTDLPOptions let yt-dlp reach videos that need a signed-in account or
+// another network, such as age-restricted or region-locked ones.
+type YTDLPOptions struct {
+	// CookiesFile is a cookies.txt file in the Netscape format, such as one
+	// exported from a signed-in browser. yt-dlp writes refreshed cookies
+	// back to it.
+	CookiesFile string
+	// Proxy is an http, https or socks URL yt-dlp connects through. ffmpeg
+	// uses it too when it is an http proxy.
+	Proxy string
+	// SourceAddress is the local IP address to connect from.
+	SourceAddress string
+	// ExtractorArgs maps an extractor to its arguments, as yt-dlp's
+	// --extractor-args takes them: "youtube" to
+	// "player_client=android,web", for example.
+	ExtractorArgs map[string]string
+}
+
+// args returns the options as yt-dlp flags.
+func (o YTDLPOptions) args() []string {
+	var args []string
+	if o.CookiesFile != "" {
+		args = append(args, "--cookies", o.CookiesFile)
+	}
+	if o.Proxy != "" {
+		args = append(args, "--proxy", o.Proxy)
+	}
+	if o.SourceAddress != "" {
+		args = append(args, "--source-address", o.SourceAddress)
+	}
+	extractors := make([]string, 0, len(o.ExtractorArgs))
+	for extractor := range o.ExtractorArgs {
+		extractors = append(extractors, extractor)
+	}
+	sort.Strings(extractors)
+	for _, extractor := range extractors {
+		args = append(args, "--extractor-args", extractor+":"+o.ExtractorArgs[extractor])
+	}
+	return args
+}
+
+// FFMpegProxy returns the proxy for ffmpeg to fetch streams through, which
+// must be an http proxy, or "" when there is none ffmpeg can use.
+func (o YTDLPOptions) FFMpegProxy() string {
+	parsed, err := url.Parse(o.Proxy)
+	if err != nil || !strings.EqualFold(parsed.Scheme, "http") {
+		return ""
+	}
+	return o.Proxy
 }
 
 // NewResolver constructs a Resolver with sane defaults.
@@ -37,10 +108,79 @@ func (r *Resolver) Resolve(ctx context.Context, query, requestedBy, channelID st
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	track, err := r.lookup(ctx, prepareQuery(query))
+	if err != nil {
+		return nil, err
+	}
+	track.RequestedBy = requestedBy
+	track.RequestChannelID = channelID
+	track.QueuedAt = time.Now()
+	return track, nil
+}
+
+// lookup resolves a query already in yt-dlp's form: a URL or a search such
+// as "ytsearch:...". Tracks served from the cache have no stream URL until
+// they are opened.
+func (r *Resolver) lookup(ctx context.Context, realQuery string) (*Track, error) {
+	return r.resolve(ctx, realQuery, false)
+}
+
+// lookupStream is lookup for playback: the track's stream URL is current.
+func (r *Resolver) lookupStream(ctx context.Context, realQuery string) (*Track, error) {
+	return r.resolve(ctx, realQuery, true)
+}
+
+// open returns the ffmpeg input of a track found with yt-dlp. Tracks stored
+// without a stream URL are resolved again.
+func (r *Resolver) open(ctx context.Context, track *Track) (Input, error) {
+	if strings.TrimSpace(track.StreamURL) == "" {
+		fresh, err := r.lookupStream(ctx, track.WebURL)
+		if err != nil {
+			return Input{}, err
+		}
+		track = fresh
+	}
+	return Input{URL: track.StreamURL, Headers: track.HTTPHeaders, Proxy: r.Options.FFMpegProxy()}, nil
+}
+
+func (r *Resolver) resolve(ctx context.Context, realQuery string, stream bool) (*Track, error) {
+	if item, ok := r.Cache.get(realQuery, stream); ok {
+		return mapPayloadToTrack(item), nil
+	}
+	result, err := r.shared(ctx, "lookup "+queryKey(realQuery), func(ctx context.Context) (any, error) {
+		item, err := r.run(ctx, realQuery)
+		if err != nil {
+			return nil, err
+		}
+		r.Cache.put(realQuery, item)
+		return item, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mapPayloadToTrack(result.(ytdlpItem)), nil
+}
+
+// run asks yt-dlp about the query.
+func (r *Resolver) run(ctx context.Context, realQuery string) (item ytdlpItem, err error) {
 	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
 	defer cancel()
 
-	realQuery := prepareQuery(query)
+	started := time.Now()
+	defer func() {
+		source := querySource(realQuery)
+		if err == nil {
+			source = mapPayloadToTrack(item).Source
+		}
+		metrics.ObserveResolve(strings.ToLower(string(source)), time.Since(started), err)
+	}()
+	if r.Helper != nil {
+		item, err = r.Helper.lookup(ctx, realQuery, r.Options)
+		if !errors.Is(err, errHelperUnavailable) {
+			return item, r.helperError(ctx, err)
+		}
+	}
+
 	args := []string{
 		"--no-playlist",
 		"--ignore-errors",
@@ -48,8 +188,9 @@ func (r *Resolver) Resolve(ctx context.Context, query, requestedBy, channelID st
 		"--no-warnings",
 		"-f", "bestaudio[ext=m4a]/bestaudio[ext=webm]/bestaudio/best",
 		"--audio-quality", "0",
-		realQuery,
 	}
+	args = append(args, r.Options.args()...)
+	args = append(args, realQuery)
 
 	cmd := exec.CommandContext(ctx, r.Executable, args...)
 	var stderr bytes.Buffer
@@ -57,45 +198,64 @@ func (r *Resolver) Resolve(ctx context.Context, query, requestedBy, channelID st
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("resolver: stdout pipe: %w", err)
+		return item, fmt.Errorf("resolver: stdout pipe: %w", err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("resolver: start yt-dlp: %w", err)
+		return item, r.failure(ctx, "", err)
 	}
 
 	dec := json.NewDecoder(bufio.NewReader(stdout))
-	var payload ytdlpItem
-	if err := dec.Decode(&payload); err != nil {
+	if err := dec.Decode(&item); err != nil {
+		if errors.Is(err, io.EOF) {
+			// yt-dlp found nothing; why is on stderr.
+			waitErr := cmd.Wait()
+			if waitErr == nil && strings.TrimSpace(stderr.String()) == "" {
+				// A search without results, which the helper reports the same way.
+				return item, &ResolveError{Kind: ResolveNotFound, Detail: "no results for " + realQuery}
+			}
+			return item, r.failure(ctx, stderr.String(), waitErr)
+		}
 		_ = cmd.Process.Kill()
-		return nil, fmt.Errorf("resolver: decode response: %w", err)
+		_ = cmd.Wait()
+		return item, fmt.Errorf("resolver: decode response: %w", err)
 	}
 
 	if err := cmd.Wait(); err != nil {
-		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-			return nil, fmt.Errorf("resolver: timeout reached after %s", r.Timeout)
-		}
-		return nil, fmt.Errorf("resolver: yt-dlp failed: %s", strings.TrimSpace(stderr.String()))
+		return item, r.failure(ctx, stderr.String(), err)
 	}
 
-	track := mapPayloadToTrack(payload)
-	track.RequestedBy = requestedBy
-	track.RequestChannelID = channelID
-	track.QueuedAt = time.Now()
+	return item, nil
+}
 
-	return track, nil
+// helperError reports a helper lookup that ran out of time as exec would.
+func (r *Resolver) helperError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return r.failure(ctx, "", err)
+	}
+	return err
 }
 
 type ytdlpItem struct {
 	ID          string            `json:"id"`
 	Title       string            `json:"title"`
 	Uploader    string            `json:"uploader"`
+	Channel     string            `json:"channel"`
 	WebpageURL  string            `json:"webpage_url"`
 	Duration    json.Number       `json:"duration"`
 	URL         string            `json:"url"`
 	Thumbnail   string            `json:"thumbnail"`
+	Thumbnails  []ytdlpThumbnail  `json:"thumbnails"`
 	Extractor   string            `json:"extractor_key"`
+	IEKey       string            `json:"ie_key"`
 	HTTPHeaders map[string]string `json:"http_headers"`
+	// Cookies are those yt-dlp used for URL, as a list of name=value pairs
+	// and their attributes.
+	Cookies string `json:"cookies"`
+}
+
+type ytdlpThumbnail struct {
+	URL string `json:"url"`
 }
 
 func mapPayloadToTrack(item ytdlpItem) *Track {
@@ -107,7 +267,8 @@ func mapPayloadToTrack(item ytdlpItem) *Track {
 	}
 
 	source := SourceUnknown
-	key := strings.ToLower(item.Extractor)
+	// Flat playlist entries name the extractor in ie_key instead.
+	key := strings.ToLower(firstNonEmpty(item.Extractor, item.IEKey))
 	switch {
 	case strings.Contains(key, "youtube"):
 		source = SourceYouTube
@@ -115,19 +276,50 @@ func mapPayloadToTrack(item ytdlpItem) *Track {
 		source = SourceSoundCloud
 	}
 
+	thumbnail := item.Thumbnail
+	if thumbnail == "" && len(item.Thumbnails) > 0 {
+		// yt-dlp lists thumbnails from the smallest to the largest.
+		thumbnail = item.Thumbnails[len(item.Thumbnails)-1].URL
+	}
+
 	return &Track{
 		ID:          item.ID,
 		Title:       item.Title,
-		Author:      item.Uploader,
+		Author:      firstNonEmpty(item.Uploader, item.Channel),
 		WebURL:      fallbackURL(item.WebpageURL, item.URL),
 		StreamURL:   item.URL,
-		Thumbnail:   item.Thumbnail,
+		Thumbnail:   thumbnail,
 		Duration:    duration,
 		Source:      source,
-		HTTPHeaders: item.HTTPHeaders,
+		HTTPHeaders: streamHeaders(item),
 	}
 }
 
+// cookieAttributes are the attributes yt-dlp lists after each cookie.
+var cookieAttributes = map[string]bool{"domain": true, "path": true, "secure": true, "expires": true, "version": true, "httponly": true, "max-age": true, "samesite": true}
+
+// streamHeaders returns the HTTP headers to fetch the stream with, including
+// the cookies yt-dlp used, which it reports apart from the headers.
+func streamHeaders(item ytdlpItem) map[string]string {
+	var pairs []string
+	for _, part := range strings.Split(item.Cookies, ";") {
+		part = strings.TrimSpace(part)
+		name, _, _ := strings.Cut(part, "=")
+		if part != "" && !cookieAttributes[strings.ToLower(name)] {
+			pairs = append(pairs, part)
+		}
+	}
+	if len(pairs) == 0 {
+		return item.HTTPHeaders
+	}
+	headers := make(map[string]string, len(item.HTTPHeaders)+1)
+	for name, value := range item.HTTPHeaders {
+		headers[name] = value
+	}
+	headers["Cookie"] = strings.Join(pairs, "; ")
+	return headers
+}
+
 func prepareQuery(q string) string {
 	trimmed := strings.TrimSpace(q)
 	if trimmed == "" {
@@ -136,13 +328,33 @@ func prepareQuery(q string) string {
 	if looksLikeURL(trimmed) {
 		return trimmed
 	}
-	lower := strings.ToLower(trimmed)
-	if strings.HasPrefix(lower, "sc ") {
-		return "scsearch:" + strings.TrimSpace(trimmed[3:])
+	if soundCloudPatterns.Match(trimmed) {
+		return "scsearch:" + soundCloudPatterns.TrimPrefix(trimmed)
 	}
 	return "ytsearch:" + trimmed
 }
 
+// querySource guesses where a prepared query will be looked up, for lookups
+// that fail before yt-dlp reports the extractor.
+func querySource(query string) Source {
+	lower := strings.ToLower(query)
+	switch {
+	case strings.HasPrefix(lower, "ytsearch"):
+		return SourceYouTube
+	case strings.HasPrefix(lower, "scsearch"):
+		return SourceSoundCloud
+	}
+	if parsed, err := url.Parse(lower); err == nil {
+		switch host := strings.TrimPrefix(parsed.Hostname(), "www."); {
+		case strings.HasSuffix(host, "youtube.com"), host == "youtu.be":
+			return SourceYouTube
+		case strings.HasSuffix(host, "soundcloud.com"):
+			return SourceSoundCloud
+		}
+	}
+	return SourceUnknown
+}
+
 func looksLikeURL(value string) bool {
 	if !strings.Contains(value, "://") {
 		return false
@@ -152,6 +364,10 @@ func looksLikeURL(value string) bool {
 }
 
 func fallbackURL(values ...string) string {
+	return firstNonEmpty(values...)
+}
+
+func firstNonEmpty(values ...string) string {
 	for _, v := range values {
 		if strings.TrimSpace(v) != "" {
 			return v
@@ -159,3 +375,139 @@ func fallbackURL(values ...string) string {
 	}
 	return ""
 }
+
+// relatedCandidates caps how many playlist or search entries Related inspects.
+const relatedCandidates = 15
+
+// Related finds a track to follow seed, skipping any URL for which seen reports
+// true and any resolved track that accept turns down; a nil accept takes the
+// first one found. YouTube tracks use the video's mix playlist; other sources
+// search by uploader.
+func (r *Resolver) Related(ctx context.Context, seed *Track, seen func(webURL string) bool, accept func(*Track) bool, requestedBy, channelID string) (*Track, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	entries, err := r.listEntries(ctx, relatedQuery(seed), relatedCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		candidate := fallbackURL(entry.WebpageURL, entry.URL)
+		if !looksLikeURL(candidate) || candidate == seed.WebURL || (seen != nil && seen(candidate)) {
+			continue
+		}
+		track, err := r.Resolve(ctx, candidate, requestedBy, channelID)
+		if err != nil || accept == nil || accept(track) {
+			return track, err
+		}
+	}
+	return nil, errors.New("resolver: no related tracks found")
+}
+
+// maxSearchResults caps Search so one request cannot start a huge listing.
+const maxSearchResults = 25
+
+// Search lists up to limit matches for a free-text query without resolving
+// their streams, so the results carry metadata only and must go through
+// Resolve before they can be played. The "sc " prefix searches SoundCloud,
+// as in /play.
+func (r *Resolver) Search(ctx context.Context, query string, limit int) ([]*Track, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if limit <= 0 || limit > maxSearchResults {
+		limit = maxSearchResults
+	}
+
+	realQuery := prepareQuery(query)
+	switch {
+	case realQuery == "":
+		return nil, errors.New("resolver: empty query")
+	case strings.HasPrefix(realQuery, "ytsearch:"):
+		realQuery = fmt.Sprintf("ytsearch%d:%s", limit, strings.TrimPrefix(realQuery, "ytsearch:"))
+	case strings.HasPrefix(realQuery, "scsearch:"):
+		realQuery = fmt.Sprintf("scsearch%d:%s", limit, strings.TrimPrefix(realQuery, "scsearch:"))
+	}
+
+	entries, err := r.listEntries(ctx, realQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+	tracks := make([]*Track, 0, len(entries))
+	for _, entry := range entries {
+		track := mapPayloadToTrack(entry)
+		if !looksLikeURL(track.WebURL) {
+			continue
+		}
+		track.StreamURL = ""
+		tracks = append(tracks, track)
+	}
+	return tracks, nil
+}
+
+// listEntries returns the entries of a playlist or search without resolving them.
+func (r *Resolver) listEntries(ctx context.Context, query string, limit int) ([]ytdlpItem, error) {
+	result, err := r.shared(ctx, fmt.Sprintf("list %d %s", limit, query), func(ctx context.Context) (any, error) {
+		return r.runList(ctx, query, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]ytdlpItem), nil
+}
+
+func (r *Resolver) runList(ctx context.Context, query string, limit int) ([]ytdlpItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if r.Helper != nil {
+		entries, err := r.Helper.list(ctx, query, limit, r.Options)
+		if !errors.Is(err, errHelperUnavailable) {
+			return entries, r.helperError(ctx, err)
+		}
+	}
+
+	args := []string{
+		"--flat-playlist",
+		"--dump-json",
+		"--no-warnings",
+		"--playlist-end", fmt.Sprintf("%d", limit),
+	}
+	args = append(args, r.Options.args()...)
+	cmd := exec.CommandContext(ctx, r.Executable, append(args, query)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, r.failure(ctx, stderr.String(), err)
+	}
+
+	var entries []ytdlpItem
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var entry ytdlpItem
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func relatedQuery(seed *Track) string {
+	if seed.Source == SourceYouTube && seed.ID != "" {
+		return fmt.Sprintf("https://www.youtube.com/watch?v=%s&list=RD%s", seed.ID, seed.ID)
+	}
+
+	term := strings.TrimSpace(seed.Author)
+	if term == "" {
+		term = strings.TrimSpace(seed.Title)
+	}
+	if seed.Source == SourceSoundCloud {
+		return fmt.Sprintf("scsearch%d:%s", relatedCandidates, term)
+	}
+	return fmt.Sprintf("ytsearch%d:%s", relatedCandidates, term)
+}
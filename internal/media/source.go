@@ -0,0 +1,330 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrUnknownSource is returned when a query names a provider that is not
+// registered.
+var ErrUnknownSource = errors.New("media: unknown source")
+
+// Provider finds tracks in one place, such as yt-dlp, a web radio or a local
+// library, and opens them for playback.
+type Provider interface {
+	// Name identifies the provider; it is the value of the /play source
+	// choice and is stored on the tracks it resolves.
+	Name() string
+	// Match reports whether the provider claims the query when no source was
+	// chosen explicitly.
+	Match(query string) bool
+	// Resolve turns a query or URL into a playable track.
+	Resolve(ctx context.Context, query string) (*Track, error)
+	// Open returns what ffmpeg should read the track's audio from. It is
+	// called right before playback, so it may refresh expired stream URLs.
+	Open(ctx context.Context, track *Track) (Input, error)
+}
+
+// Prober is implemented by providers that can tell whether they play a URL
+// only by requesting it. Probers are asked about URLs no provider claims
+// before the default provider gets them.
+type Prober interface {
+	Probe(ctx context.Context, query string) bool
+}
+
+// TitleWatcher is implemented by providers whose streams announce the song
+// being played, such as internet radio.
+type TitleWatcher interface {
+	// WatchTitles calls update with every new title until ctx is cancelled
+	// or the stream ends.
+	WatchTitles(ctx context.Context, track *Track, update func(title string)) error
+}
+
+// Expander is implemented by providers whose queries can stand for many
+// tracks, such as album and playlist links.
+type Expander interface {
+	// Expand lists the tracks of an album or playlist, and returns nil for a
+	// query that is a single track.
+	Expand(ctx context.Context, query string) (*Collection, error)
+}
+
+// LinkOnly is implemented by providers that take nothing but links to their
+// own service, and so cannot be chosen as the source of a search.
+type LinkOnly interface {
+	LinksOnly() bool
+}
+
+// Input is an ffmpeg input: a URL, or a file path, and the HTTP headers to
+// send with it.
+type Input struct {
+	URL     string
+	Headers map[string]string
+	// Proxy is the http proxy to fetch the URL through, if any.
+	Proxy string
+}
+
+// Credentialed reports whether fetching the input sends cookies or an
+// Authorization header, which must not show up on ffmpeg's command line.
+func (in Input) Credentialed() bool {
+	return sendsCredentials(in.Headers)
+}
+
+func sendsCredentials(headers map[string]string) bool {
+	for name := range headers {
+		if strings.EqualFold(name, "Cookie") || strings.EqualFold(name, "Authorization") {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch requests the input with its headers and through its proxy, and
+// returns the body for ffmpeg to read instead of the URL.
+func (in Input) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, in.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("media: %w", err)
+	}
+	for name, value := range in.Headers {
+		req.Header.Set(name, value)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if in.Proxy != "" {
+		proxy, err := url.Parse(in.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("media: bad proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("media: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("media: stream answered %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Patterns declares the queries a provider claims: those starting with one of
+// Prefixes, and URLs with one of Schemes or on one of Hosts or their
+// subdomains. Prefixes are matched without regard to case.
+type Patterns struct {
+	Prefixes []string
+	Schemes  []string
+	Hosts    []string
+}
+
+// Match reports whether the query fits any of the patterns.
+func (p Patterns) Match(query string) bool {
+	query = strings.TrimSpace(query)
+	if _, ok := p.trimPrefix(query); ok {
+		return true
+	}
+	if !looksLikeURL(query) {
+		return false
+	}
+	parsed, err := url.Parse(query)
+	if err != nil {
+		return false
+	}
+	for _, scheme := range p.Schemes {
+		if strings.EqualFold(parsed.Scheme, scheme) {
+			return true
+		}
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, want := range p.Hosts {
+		want = strings.ToLower(want)
+		if host == want || strings.HasSuffix(host, "."+want) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrimPrefix removes a matching prefix from the query.
+func (p Patterns) TrimPrefix(query string) string {
+	query = strings.TrimSpace(query)
+	if trimmed, ok := p.trimPrefix(query); ok {
+		return trimmed
+	}
+	return query
+}
+
+func (p Patterns) trimPrefix(query string) (string, bool) {
+	for _, prefix := range p.Prefixes {
+		if len(query) >= len(prefix) && strings.EqualFold(query[:len(prefix)], prefix) {
+			return strings.TrimSpace(query[len(prefix):]), true
+		}
+	}
+	return query, false
+}
+
+// Registry routes queries to providers. The first provider registered is the
+// default: it takes every query no other provider claims, and opens tracks
+// that do not name a provider.
+type Registry struct {
+	providers []Provider
+	byName    map[string]Provider
+}
+
+// NewRegistry registers the providers in order, the default first.
+func NewRegistry(providers ...Provider) (*Registry, error) {
+	r := &Registry{byName: make(map[string]Provider)}
+	for _, provider := range providers {
+		if err := r.Register(provider); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register adds a provider. Providers registered earlier win when several
+// match a query. Register must not be called once the registry is in use.
+func (r *Registry) Register(provider Provider) error {
+	name := provider.Name()
+	if name == "" {
+		return errors.New("media: provider without a name")
+	}
+	if _, exists := r.byName[name]; exists {
+		return fmt.Errorf("media: provider %q registered twice", name)
+	}
+	r.providers = append(r.providers, provider)
+	r.byName[name] = provider
+	return nil
+}
+
+// Providers lists the registered providers, the default first.
+func (r *Registry) Providers() []Provider {
+	return append([]Provider(nil), r.providers...)
+}
+
+// Searchable lists the providers that can be chosen as the source of a
+// search, the default first.
+func (r *Registry) Searchable() []Provider {
+	var providers []Provider
+	for _, provider := range r.providers {
+		if linkOnly, ok := provider.(LinkOnly); ok && linkOnly.LinksOnly() {
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// Lookup returns the provider with the given name.
+func (r *Registry) Lookup(name string) (Provider, bool) {
+	provider, ok := r.byName[name]
+	return provider, ok
+}
+
+// Pick returns the provider for a query: the first non-default provider
+// that matches it, or else the default.
+func (r *Registry) Pick(query string) Provider {
+	if len(r.providers) == 0 {
+		return nil
+	}
+	for _, provider := range r.providers[1:] {
+		if provider.Match(query) {
+			return provider
+		}
+	}
+	return r.providers[0]
+}
+
+// Resolve looks the query up with the named provider. When source is empty
+// it uses the one Pick chooses, except that URLs nothing claims go to the
+// first Prober that accepts them.
+func (r *Registry) Resolve(ctx context.Context, source, query, requestedBy, channelID string) (*Track, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	provider := r.Pick(query)
+	if source == "" && provider != nil && provider.Name() == r.providers[0].Name() && !provider.Match(query) && looksLikeURL(strings.TrimSpace(query)) {
+		if prober := r.probe(ctx, query); prober != nil {
+			provider = prober
+		}
+	}
+	if source != "" {
+		var ok bool
+		if provider, ok = r.Lookup(source); !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownSource, source)
+		}
+	}
+	if provider == nil {
+		return nil, fmt.Errorf("%w: no providers registered", ErrUnknownSource)
+	}
+
+	track, err := provider.Resolve(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	track.Provider = provider.Name()
+	track.RequestedBy = requestedBy
+	track.RequestChannelID = channelID
+	track.QueuedAt = time.Now()
+	return track, nil
+}
+
+// Expand lists the tracks of an album or playlist query when the provider
+// that would resolve it is an Expander. It returns nil for single tracks.
+func (r *Registry) Expand(ctx context.Context, source, query string) (*Collection, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	provider := r.Pick(query)
+	if source != "" {
+		var ok bool
+		if provider, ok = r.Lookup(source); !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownSource, source)
+		}
+	}
+	expander, ok := provider.(Expander)
+	if !ok {
+		return nil, nil
+	}
+	return expander.Expand(ctx, query)
+}
+
+func (r *Registry) probe(ctx context.Context, query string) Provider {
+	for _, provider := range r.providers[1:] {
+		if prober, ok := provider.(Prober); ok && prober.Probe(ctx, query) {
+			return provider
+		}
+	}
+	return nil
+}
+
+// Open opens the track with the provider that resolved it.
+func (r *Registry) Open(ctx context.Context, track *Track) (Input, error) {
+	provider, ok := r.Lookup(track.Provider)
+	if !ok {
+		if len(r.providers) == 0 {
+			return Input{}, fmt.Errorf("%w: no providers registered", ErrUnknownSource)
+		}
+		provider = r.providers[0]
+	}
+	return provider.Open(ctx, track)
+}
+
+// WatchTitles follows the song titles of the track's stream when its provider
+// is a TitleWatcher, and returns nil right away otherwise.
+func (r *Registry) WatchTitles(ctx context.Context, track *Track, update func(title string)) error {
+	provider, ok := r.Lookup(track.Provider)
+	if !ok {
+		return nil
+	}
+	watcher, ok := provider.(TitleWatcher)
+	if !ok {
+		return nil
+	}
+	return watcher.WatchTitles(ctx, track, update)
+}
@@ -0,0 +1,137 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stubProvider resolves every query to a track titled after it.
+type stubProvider struct {
+	Patterns
+	name string
+}
+
+func (p stubProvider) Name() string { return p.name }
+
+func (p stubProvider) Resolve(_ context.Context, query string) (*Track, error) {
+	return &Track{Title: p.TrimPrefix(query)}, nil
+}
+
+func (p stubProvider) Open(_ context.Context, track *Track) (Input, error) {
+	return Input{URL: p.name + ":" + track.Title}, nil
+}
+
+func TestPatternsMatch(t *testing.T) {
+	patterns := Patterns{
+		Prefixes: []string{"sc "},
+		Schemes:  []string{"icy"},
+		Hosts:    []string{"soundcloud.com"},
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"sc lofi beats", true},
+		{"SC lofi beats", true},
+		{"scary movie", false},
+		{"https://soundcloud.com/artist/track", true},
+		{"https://m.soundcloud.com/artist/track", true},
+		{"https://notsoundcloud.com/track", false},
+		{"icy://radio.example.com/stream", true},
+		{"https://www.youtube.com/watch?v=x", false},
+		{"soundcloud.com", false},
+	}
+	for _, tt := range tests {
+		if got := patterns.Match(tt.query); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	if got := patterns.TrimPrefix("  Sc  lofi "); got != "lofi" {
+		t.Errorf("TrimPrefix = %q", got)
+	}
+}
+
+func TestRegistryRouting(t *testing.T) {
+	registry, err := NewRegistry(
+		stubProvider{name: "default"},
+		stubProvider{name: "cloud", Patterns: Patterns{Prefixes: []string{"sc "}, Hosts: []string{"soundcloud.com"}}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source, query  string
+		provider, name string
+	}{
+		{"", "some song", "default", "some song"},
+		{"", "sc some song", "cloud", "some song"},
+		{"", "https://soundcloud.com/a/b", "cloud", "https://soundcloud.com/a/b"},
+		{"cloud", "some song", "cloud", "some song"},
+		{"default", "sc some song", "default", "sc some song"},
+	}
+	for _, tt := range tests {
+		track, err := registry.Resolve(context.Background(), tt.source, tt.query, "<@1>", "channel")
+		if err != nil {
+			t.Fatalf("Resolve(%q, %q): %v", tt.source, tt.query, err)
+		}
+		if track.Provider != tt.provider || track.Title != tt.name || track.RequestedBy != "<@1>" || track.RequestChannelID != "channel" {
+			t.Errorf("Resolve(%q, %q) = %+v, want %s / %q", tt.source, tt.query, track, tt.provider, tt.name)
+		}
+	}
+
+	if _, err := registry.Resolve(context.Background(), "jukebox", "song", "", ""); !errors.Is(err, ErrUnknownSource) {
+		t.Errorf("unknown source: err = %v", err)
+	}
+	if err := registry.Register(stubProvider{name: "cloud"}); err == nil {
+		t.Error("registered a provider name twice")
+	}
+
+	input, err := registry.Open(context.Background(), &Track{Title: "old", Provider: "gone"})
+	if err != nil || input.URL != "default:old" {
+		t.Errorf("Open for an unknown provider = %+v, %v; want the default provider", input, err)
+	}
+	input, err = registry.Open(context.Background(), &Track{Title: "x", Provider: "cloud"})
+	if err != nil || input.URL != "cloud:x" {
+		t.Errorf("Open = %+v, %v", input, err)
+	}
+}
+
+func TestInputFetch(t *testing.T) {
+	var gotCookie string
+	proxied := false
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.Host == "media.example.com"
+		gotCookie = r.Header.Get("Cookie")
+		if r.URL.Path == "/gone" {
+			http.Error(w, "expired", http.StatusForbidden)
+			return
+		}
+		io.WriteString(w, "audio")
+	}))
+	defer proxy.Close()
+
+	input := Input{URL: "http://media.example.com/orbit", Headers: map[string]string{"Cookie": "SID=secret"}, Proxy: proxy.URL}
+	if !input.Credentialed() || (Input{Headers: map[string]string{"User-Agent": "kvazar"}}).Credentialed() {
+		t.Error("Credentialed should only hold for cookies and Authorization")
+	}
+	body, err := input.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(body)
+	body.Close()
+	if string(content) != "audio" || gotCookie != "SID=secret" || !proxied {
+		t.Errorf("body = %q, cookie = %q, proxied = %t", content, gotCookie, proxied)
+	}
+
+	input.URL = "http://media.example.com/gone"
+	if _, err := input.Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("err = %v, want the stream's status", err)
+	}
+}
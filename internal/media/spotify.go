@@ -0,0 +1,337 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var spotifyPatterns = Patterns{
+	Hosts: []string{"open.spotify.com", "play.spotify.com"},
+}
+
+const (
+	spotifyAPIURL   = "https://api.spotify.com/v1"
+	spotifyAuthURL  = "https://accounts.spotify.com/api/token"
+	spotifyEmbedURL = "https://open.spotify.com/embed"
+	// spotifyTrackURL links to a track by its ID.
+	spotifyTrackURL = "https://open.spotify.com/track/"
+)
+
+// spotifyCatalog reads Spotify through the Web API when it has client
+// credentials, and from the public embed player pages without them.
+type spotifyCatalog struct {
+	client       *http.Client
+	clientID     string
+	clientSecret string
+	apiURL       string
+	authURL      string
+	embedURL     string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewSpotifyProvider returns the provider for Spotify track, album and
+// playlist links, which it plays from YouTube or SoundCloud. With a client
+// ID and secret it reads Spotify's Web API; without them, the embed player
+// pages, which list at most 100 tracks of a playlist. A nil client uses
+// http.DefaultClient.
+func NewSpotifyProvider(resolver *Resolver, client *http.Client, clientID, clientSecret string) Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return newLinkProvider(ProviderSpotify, spotifyPatterns, &spotifyCatalog{
+		client:       client,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		apiURL:       spotifyAPIURL,
+		authURL:      spotifyAuthURL,
+		embedURL:     spotifyEmbedURL,
+	}, resolver)
+}
+
+// parse reads links such as https://open.spotify.com/intl-de/album/<id>.
+func (c *spotifyCatalog) parse(link *url.URL) (linkRef, bool) {
+	segments := pathSegments(link)
+	for len(segments) > 0 && (strings.HasPrefix(segments[0], "intl-") || segments[0] == "embed") {
+		segments = segments[1:]
+	}
+	if len(segments) < 2 {
+		return linkRef{}, false
+	}
+	switch segments[0] {
+	case linkTrack, linkAlbum, linkPlaylist:
+		return linkRef{kind: segments[0], id: segments[1]}, true
+	}
+	return linkRef{}, false
+}
+
+func (c *spotifyCatalog) lookup(ctx context.Context, ref linkRef) (*Collection, error) {
+	if c.clientID == "" {
+		return c.lookupEmbed(ctx, ref)
+	}
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ref.kind {
+	case linkTrack:
+		var track spotifyTrack
+		if err := getJSON(ctx, c.client, c.apiURL+"/tracks/"+url.PathEscape(ref.id), token, &track); err != nil {
+			return nil, err
+		}
+		return &Collection{Tracks: []LinkTrack{track.linkTrack("")}}, nil
+	case linkAlbum:
+		var album struct {
+			Name   string                    `json:"name"`
+			Tracks spotifyPage[spotifyTrack] `json:"tracks"`
+		}
+		if err := getJSON(ctx, c.client, c.apiURL+"/albums/"+url.PathEscape(ref.id), token, &album); err != nil {
+			return nil, err
+		}
+		items, err := album.Tracks.all(ctx, c.client, token)
+		collection := &Collection{Title: album.Name}
+		for _, track := range items {
+			collection.Tracks = append(collection.Tracks, track.linkTrack(album.Name))
+		}
+		return collection, err
+	default:
+		var playlist struct {
+			Name   string                           `json:"name"`
+			Tracks spotifyPage[spotifyPlaylistItem] `json:"tracks"`
+		}
+		if err := getJSON(ctx, c.client, c.apiURL+"/playlists/"+url.PathEscape(ref.id), token, &playlist); err != nil {
+			return nil, err
+		}
+		items, err := playlist.Tracks.all(ctx, c.client, token)
+		collection := &Collection{Title: playlist.Name}
+		for _, item := range items {
+			// Local files and podcast episodes cannot be found elsewhere.
+			if item.Track != nil && item.Track.ID != "" {
+				collection.Tracks = append(collection.Tracks, item.Track.linkTrack(""))
+			}
+		}
+		return collection, err
+	}
+}
+
+// accessToken returns a client credentials token, requesting a new one
+// shortly before the last expires.
+func (c *spotifyCatalog) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.authURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.clientID, c.clientSecret)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("media: spotify sign-in failed: %s", resp.Status)
+	}
+
+	var grant struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(&grant); err != nil {
+		return "", fmt.Errorf("media: decode spotify token: %w", err)
+	}
+	if grant.AccessToken == "" {
+		return "", errors.New("media: spotify sent no access token")
+	}
+	c.token = grant.AccessToken
+	c.expires = time.Now().Add(time.Duration(grant.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+// lookupEmbed reads the data the embed player page is rendered from.
+func (c *spotifyCatalog) lookupEmbed(ctx context.Context, ref linkRef) (*Collection, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.embedURL+"/"+ref.kind+"/"+url.PathEscape(ref.id), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("media: spotify answered %s", resp.Status)
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := scriptData(string(page), `id="__NEXT_DATA__"`)
+	if !ok {
+		return nil, errors.New("media: the spotify embed page has no track data")
+	}
+	var next struct {
+		Props struct {
+			PageProps struct {
+				State struct {
+					Data struct {
+						Entity spotifyEntity `json:"entity"`
+					} `json:"data"`
+				} `json:"state"`
+			} `json:"pageProps"`
+		} `json:"props"`
+	}
+	if err := json.Unmarshal([]byte(data), &next); err != nil {
+		return nil, fmt.Errorf("media: decode spotify embed data: %w", err)
+	}
+
+	entity := next.Props.PageProps.State.Data.Entity
+	if ref.kind == linkTrack {
+		track := LinkTrack{
+			URL:      spotifyTrackURL + ref.id,
+			Title:    firstNonEmpty(entity.Name, entity.Title),
+			Duration: time.Duration(entity.Duration) * time.Millisecond,
+		}
+		for _, artist := range entity.Artists {
+			track.Artists = append(track.Artists, artist.Name)
+		}
+		if len(track.Artists) == 0 {
+			track.Artists = splitArtists(entity.Subtitle)
+		}
+		if track.Title == "" {
+			return nil, errors.New("media: the spotify embed page has no track data")
+		}
+		return &Collection{Tracks: []LinkTrack{track}}, nil
+	}
+
+	collection := &Collection{Title: firstNonEmpty(entity.Name, entity.Title)}
+	for _, item := range entity.TrackList {
+		id, ok := strings.CutPrefix(item.URI, "spotify:track:")
+		if !ok {
+			continue
+		}
+		collection.Tracks = append(collection.Tracks, LinkTrack{
+			URL:      spotifyTrackURL + id,
+			Title:    item.Title,
+			Artists:  splitArtists(item.Subtitle),
+			Duration: time.Duration(item.Duration) * time.Millisecond,
+		})
+	}
+	return collection, nil
+}
+
+type spotifyTrack struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	DurationMS int64  `json:"duration_ms"`
+	Artists    []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Album struct {
+		Name string `json:"name"`
+	} `json:"album"`
+}
+
+func (t spotifyTrack) linkTrack(album string) LinkTrack {
+	track := LinkTrack{
+		URL:      spotifyTrackURL + t.ID,
+		Title:    t.Name,
+		Album:    firstNonEmpty(album, t.Album.Name),
+		Duration: time.Duration(t.DurationMS) * time.Millisecond,
+	}
+	for _, artist := range t.Artists {
+		track.Artists = append(track.Artists, artist.Name)
+	}
+	return track
+}
+
+type spotifyPlaylistItem struct {
+	Track *spotifyTrack `json:"track"`
+}
+
+// spotifyPage is one page of a Web API listing.
+type spotifyPage[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next"`
+}
+
+// all follows the listing's next links until it has maxLinkTracks items.
+func (p spotifyPage[T]) all(ctx context.Context, client *http.Client, token string) ([]T, error) {
+	items := p.Items
+	for next := p.Next; next != "" && len(items) < maxLinkTracks; {
+		var page spotifyPage[T]
+		if err := getJSON(ctx, client, next, token, &page); err != nil {
+			return items, err
+		}
+		items = append(items, page.Items...)
+		next = page.Next
+	}
+	return items, nil
+}
+
+// spotifyEntity is the item an embed page shows. Tracks have their artists;
+// albums and playlists list their tracks with the artists as a subtitle.
+type spotifyEntity struct {
+	Name     string `json:"name"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle"`
+	Duration int64  `json:"duration"`
+	Artists  []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	TrackList []struct {
+		URI      string `json:"uri"`
+		Title    string `json:"title"`
+		Subtitle string `json:"subtitle"`
+		Duration int64  `json:"duration"`
+	} `json:"trackList"`
+}
+
+// scriptData returns the contents of the first script element whose start
+// tag contains marker.
+func scriptData(page, marker string) (string, bool) {
+	start := strings.Index(page, marker)
+	if start < 0 {
+		return "", false
+	}
+	page = page[start:]
+	open := strings.IndexByte(page, '>')
+	if open < 0 {
+		return "", false
+	}
+	page = page[open+1:]
+	end := strings.Index(page, "</script>")
+	if end < 0 {
+		return "", false
+	}
+	return page[:end], true
+}
+
+// splitArtists splits a list of artists such as "Kosmos, Zora".
+func splitArtists(list string) []string {
+	var artists []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			artists = append(artists, name)
+		}
+	}
+	return artists
+}
@@ -12,6 +12,9 @@ type Source string
 const (
     SourceYouTube    Source = "YouTube"
     SourceSoundCloud Source = "SoundCloud"
+    SourceRadio      Source = "Radio"
+    SourceDirect     Source = "Direct"
+    SourceLocal      Source = "Local"
     SourceUnknown    Source = "Unknown"
 )
 
@@ -25,6 +28,8 @@ type Track struct {
     Thumbnail        string
     Duration         time.Duration
     Source           Source
+    // Provider names the Provider that resolved the track and opens it.
+    Provider         string
     RequestedBy      string
     RequestChannelID string
     HTTPHeaders      map[string]string
@@ -0,0 +1,58 @@
+package media
+
+import "context"
+
+// Names of the providers backed by yt-dlp.
+const (
+	ProviderYouTube    = "youtube"
+	ProviderSoundCloud = "soundcloud"
+)
+
+var (
+	youTubePatterns = Patterns{
+		Hosts: []string{"youtube.com", "youtu.be", "youtube-nocookie.com"},
+	}
+	// soundCloudPatterns claims the "sc " prefix, which searches SoundCloud.
+	soundCloudPatterns = Patterns{
+		Prefixes: []string{"sc "},
+		Hosts:    []string{"soundcloud.com", "snd.sc"},
+	}
+)
+
+// ytdlpProvider plays anything yt-dlp can extract. Plain queries become a
+// search on one site.
+type ytdlpProvider struct {
+	Patterns
+	name     string
+	search   string
+	resolver *Resolver
+}
+
+// NewYouTubeProvider returns the provider that searches YouTube. As the
+// default provider it also takes every URL no other provider claims, which
+// yt-dlp supports for hundreds of sites.
+func NewYouTubeProvider(resolver *Resolver) Provider {
+	return &ytdlpProvider{Patterns: youTubePatterns, name: ProviderYouTube, search: "ytsearch", resolver: resolver}
+}
+
+// NewSoundCloudProvider returns the provider that searches SoundCloud. It
+// claims SoundCloud links and queries starting with "sc ".
+func NewSoundCloudProvider(resolver *Resolver) Provider {
+	return &ytdlpProvider{Patterns: soundCloudPatterns, name: ProviderSoundCloud, search: "scsearch", resolver: resolver}
+}
+
+func (p *ytdlpProvider) Name() string { return p.name }
+
+func (p *ytdlpProvider) Resolve(ctx context.Context, query string) (*Track, error) {
+	query = p.TrimPrefix(query)
+	if !looksLikeURL(query) {
+		query = p.search + ":" + query
+	}
+	return p.resolver.lookup(ctx, query)
+}
+
+// Open plays the stream URL found when the track was resolved, or resolves
+// the page again for tracks that were stored without one.
+func (p *ytdlpProvider) Open(ctx context.Context, track *Track) (Input, error) {
+	return p.resolver.open(ctx, track)
+}
@@ -0,0 +1,130 @@
+# Kvazar's yt-dlp helper. It imports yt_dlp once and answers lookups sent as
+# JSON lines on stdin with JSON lines on stdout, matched up by "id", so a
+# lookup does not pay for starting Python and loading the extractors.
+#
+# Requests:  {"id": 1, "op": "lookup", "query": "ytsearch:...", "options": {...}}
+#            {"id": 2, "op": "list", "query": "https://...", "limit": 15}
+# Replies:   {"id": 1, "item": {...}}
+#            {"id": 2, "entries": [{...}, ...]}
+#            {"id": 3, "error": "ERROR: ..."}
+# The options are cookies_file, proxy, source_address and extractor_args,
+# the last mapping an extractor to arguments as --extractor-args takes them.
+# The first line out is {"ready": true, "version": "..."}.
+
+import json
+import os
+import sys
+import threading
+from concurrent.futures import ThreadPoolExecutor
+
+out = sys.stdout
+sys.stdout = sys.stderr  # nothing but replies may reach the real stdout
+
+import yt_dlp
+
+try:
+    from yt_dlp.version import __version__ as version
+except ImportError:
+    version = ""
+
+FORMAT = "bestaudio[ext=m4a]/bestaudio[ext=webm]/bestaudio/best"
+FIELDS = (
+    "id", "title", "uploader", "channel", "webpage_url", "duration", "url",
+    "thumbnail", "thumbnails", "extractor_key", "ie_key", "http_headers",
+    "cookies",
+)
+
+lock = threading.Lock()
+
+
+def reply(message):
+    line = json.dumps(message, default=str)
+    with lock:
+        out.write(line + "\n")
+        out.flush()
+
+
+class Quiet:
+    # Errors are raised and replied; nothing needs printing.
+    def debug(self, msg):
+        pass
+
+    def warning(self, msg):
+        pass
+
+    def error(self, msg):
+        pass
+
+
+def trim(info):
+    info = yt_dlp.YoutubeDL.sanitize_info(info)
+    return {key: info[key] for key in FIELDS if info.get(key) is not None}
+
+
+def configure(opts, options):
+    if options.get("cookies_file"):
+        opts["cookiefile"] = options["cookies_file"]
+    if options.get("proxy"):
+        opts["proxy"] = options["proxy"]
+    if options.get("source_address"):
+        opts["source_address"] = options["source_address"]
+    extractor_args = {}
+    for extractor, args in (options.get("extractor_args") or {}).items():
+        parsed = extractor_args.setdefault(extractor.lower(), {})
+        for pair in args.split(";"):
+            key, _, values = pair.partition("=")
+            if key.strip():
+                parsed[key.strip().lower().replace("-", "_")] = values.split(",")
+    if extractor_args:
+        opts["extractor_args"] = extractor_args
+    return opts
+
+
+def lookup(query, options):
+    opts = {"format": FORMAT, "noplaylist": True, "quiet": True, "no_warnings": True, "logger": Quiet()}
+    configure(opts, options)
+    with yt_dlp.YoutubeDL(opts) as ydl:
+        info = ydl.extract_info(query, download=False)
+    if info and "entries" in info:
+        info = next((entry for entry in info["entries"] if entry), None)
+    if not info:
+        raise LookupError("ERROR: no results for " + query)
+    return trim(info)
+
+
+def listing(query, limit, options):
+    opts = {"extract_flat": "in_playlist", "playlistend": limit, "quiet": True, "no_warnings": True, "logger": Quiet()}
+    configure(opts, options)
+    with yt_dlp.YoutubeDL(opts) as ydl:
+        info = ydl.extract_info(query, download=False)
+    if not info:
+        return []
+    if "entries" not in info:
+        return [trim(info)]
+    return [trim(entry) for entry in info["entries"] if entry][:limit]
+
+
+def serve(request):
+    try:
+        options = request.get("options") or {}
+        if request.get("op") == "list":
+            reply({"id": request["id"], "entries": listing(request["query"], request.get("limit") or 25, options)})
+        else:
+            reply({"id": request["id"], "item": lookup(request["query"], options)})
+    except BaseException as err:
+        message = str(err)
+        if not message.startswith("ERROR:"):
+            message = "ERROR: " + message
+        reply({"id": request["id"], "error": message})
+
+
+def main():
+    workers = ThreadPoolExecutor(max_workers=16)
+    reply({"ready": True, "version": version})
+    for line in sys.stdin:
+        if line.strip():
+            workers.submit(serve, json.loads(line))
+    os._exit(0)
+
+
+main()
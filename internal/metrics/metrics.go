@@ -0,0 +1,214 @@
+// Package metrics defines Kvazar's Prometheus metrics. Everything is
+// registered on Registry, which the HTTP server exposes on /metrics.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "kvazar"
+
+// Registry holds every Kvazar metric plus the Go runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	commands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_total",
+		Help:      "Slash commands and buttons handled, by command and outcome (ok, error, denied).",
+	}, []string{"command", "outcome"})
+
+	commandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_duration_seconds",
+		Help:      "Time from receiving an interaction until its handler finished.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 20, 30},
+	}, []string{"command"})
+
+	resolveDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "resolve_duration_seconds",
+		Help:      "yt-dlp lookup latency by source.",
+		Buckets:   []float64{.25, .5, 1, 2, 4, 8, 15, 30},
+	}, []string{"source"})
+
+	resolveFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resolve_failures_total",
+		Help:      "Failed yt-dlp lookups by source.",
+	}, []string{"source"})
+
+	resolveCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resolve_cache_lookups_total",
+		Help:      "Resolver cache lookups by result: hit, stale (the stream URL had to be renewed) or miss.",
+	}, []string{"result"})
+
+	resolveCacheEntries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "resolve_cache_entries",
+		Help:      "Tracks held in the resolver cache.",
+	})
+
+	resolveCacheEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resolve_cache_evictions_total",
+		Help:      "Tracks dropped from the full resolver cache.",
+	})
+
+	resolveQueue = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "resolve_queue_length",
+		Help:      "yt-dlp lookups waiting for a worker, by priority.",
+	}, []string{"priority"})
+
+	resolveCoalesced = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resolve_coalesced_total",
+		Help:      "Lookups that joined an identical yt-dlp run instead of starting one.",
+	})
+
+	resolveHelperRestarts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resolve_helper_restarts_total",
+		Help:      "Times the yt-dlp helper was started again after it exited.",
+	})
+
+	ffmpegStart = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_start_seconds",
+		Help:      "Time from starting ffmpeg until the first audio frame was decoded.",
+		Buckets:   []float64{.1, .25, .5, 1, 2, 4, 8},
+	})
+
+	ffmpegExits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_exits_total",
+		Help:      "ffmpeg exits by exit code; \"signal\" means it was stopped by Kvazar.",
+	}, []string{"code"})
+
+	opusFrames = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "opus_frames_sent_total",
+		Help:      "Opus frames handed to the voice connection.",
+	})
+
+	frameLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "frame_send_lag_seconds",
+		Help:      "How much later than the 20ms frame interval each Opus frame was sent.",
+		Buckets:   []float64{.001, .005, .01, .02, .05, .1, .25, .5},
+	})
+
+	frameUnderruns = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "frame_underruns_total",
+		Help:      "Opus frames that arrived more than a full frame interval late, leaving a gap in the audio.",
+	})
+
+	voiceReconnects = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "voice_reconnects_total",
+		Help:      "Voice connections dropped and joined again, including moves between channels.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		commands, commandDuration,
+		resolveDuration, resolveFailures,
+		resolveCacheLookups, resolveCacheEntries, resolveCacheEvictions,
+		resolveQueue, resolveCoalesced, resolveHelperRestarts,
+		ffmpegStart, ffmpegExits,
+		opusFrames, frameLag, frameUnderruns,
+		voiceReconnects,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveCommand records a finished interaction.
+func ObserveCommand(command, outcome string, elapsed time.Duration) {
+	commands.WithLabelValues(command, outcome).Inc()
+	commandDuration.WithLabelValues(command).Observe(elapsed.Seconds())
+}
+
+// ObserveResolve records a yt-dlp lookup.
+func ObserveResolve(source string, elapsed time.Duration, err error) {
+	resolveDuration.WithLabelValues(source).Observe(elapsed.Seconds())
+	if err != nil {
+		resolveFailures.WithLabelValues(source).Inc()
+	}
+}
+
+// ObserveResolveCache records a resolver cache lookup.
+func ObserveResolveCache(result string) {
+	resolveCacheLookups.WithLabelValues(result).Inc()
+}
+
+// SetResolveCacheEntries records the size of the resolver cache.
+func SetResolveCacheEntries(n int) {
+	resolveCacheEntries.Set(float64(n))
+}
+
+// ResolveCacheEvicted records tracks dropped from the resolver cache.
+func ResolveCacheEvicted(n int) {
+	resolveCacheEvictions.Add(float64(n))
+}
+
+// SetResolveQueue records how many lookups of a priority wait for yt-dlp.
+func SetResolveQueue(priority string, n int) {
+	resolveQueue.WithLabelValues(priority).Set(float64(n))
+}
+
+// ResolveCoalesced records a lookup that shared another's yt-dlp run.
+func ResolveCoalesced() {
+	resolveCoalesced.Inc()
+}
+
+// ResolveHelperRestarted records a restart of the yt-dlp helper.
+func ResolveHelperRestarted() {
+	resolveHelperRestarts.Inc()
+}
+
+// ObserveFFMpegStart records how long ffmpeg took to produce audio.
+func ObserveFFMpegStart(elapsed time.Duration) {
+	ffmpegStart.Observe(elapsed.Seconds())
+}
+
+// ObserveFFMpegExit records an ffmpeg exit code; -1 means it was killed by a signal.
+func ObserveFFMpegExit(code int) {
+	label := strconv.Itoa(code)
+	if code < 0 {
+		label = "signal"
+	}
+	ffmpegExits.WithLabelValues(label).Inc()
+}
+
+// ObserveFrameSent records an Opus frame sent after the given lag.
+func ObserveFrameSent(lag, frame time.Duration) {
+	opusFrames.Inc()
+	if lag < 0 {
+		lag = 0
+	}
+	frameLag.Observe(lag.Seconds())
+	if lag > frame {
+		frameUnderruns.Inc()
+	}
+}
+
+// VoiceReconnected records a voice connection that was replaced.
+func VoiceReconnected() {
+	voiceReconnects.Inc()
+}
@@ -0,0 +1,103 @@
+package playlist
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"kvazar/internal/media"
+)
+
+const jsonVersion = 1
+
+type jsonDocument struct {
+	Version int         `json:"version"`
+	Queue   []jsonEntry `json:"queue"`
+	History []jsonEntry `json:"history"`
+}
+
+type jsonEntry struct {
+	ID          string       `json:"id,omitempty"`
+	Title       string       `json:"title,omitempty"`
+	Author      string       `json:"author,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Thumbnail   string       `json:"thumbnail,omitempty"`
+	Duration    float64      `json:"duration,omitempty"`
+	Source      media.Source `json:"source,omitempty"`
+	RequestedBy string       `json:"requested_by,omitempty"`
+	QueuedAt    *time.Time   `json:"queued_at,omitempty"`
+}
+
+func encodeJSON(w io.Writer, doc Document) error {
+	out := jsonDocument{
+		Version: jsonVersion,
+		Queue:   toJSONEntries(doc.Queue),
+		History: toJSONEntries(doc.History),
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("playlist: encode json: %w", err)
+	}
+	return nil
+}
+
+func decodeJSON(r io.Reader) (Document, error) {
+	var in jsonDocument
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return Document{}, fmt.Errorf("playlist: decode json: %w", err)
+	}
+	if in.Version > jsonVersion {
+		return Document{}, fmt.Errorf("playlist: unsupported json version %d", in.Version)
+	}
+	return Document{
+		Queue:   fromJSONEntries(in.Queue),
+		History: fromJSONEntries(in.History),
+	}, nil
+}
+
+func toJSONEntries(entries []Entry) []jsonEntry {
+	out := make([]jsonEntry, 0, len(entries))
+	for _, entry := range entries {
+		item := jsonEntry{
+			ID:          entry.ID,
+			Title:       entry.Title,
+			Author:      entry.Author,
+			URL:         entry.URL,
+			Thumbnail:   entry.Thumbnail,
+			Duration:    entry.Duration.Seconds(),
+			Source:      entry.Source,
+			RequestedBy: entry.RequestedBy,
+		}
+		if !entry.QueuedAt.IsZero() {
+			queuedAt := entry.QueuedAt.UTC()
+			item.QueuedAt = &queuedAt
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func fromJSONEntries(items []jsonEntry) []Entry {
+	var out []Entry
+	for _, item := range items {
+		entry := Entry{
+			ID:          item.ID,
+			Title:       item.Title,
+			Author:      item.Author,
+			URL:         item.URL,
+			Thumbnail:   item.Thumbnail,
+			Source:      item.Source,
+			RequestedBy: item.RequestedBy,
+		}
+		if item.Duration > 0 {
+			entry.Duration = time.Duration(item.Duration * float64(time.Second))
+		}
+		if item.QueuedAt != nil {
+			entry.QueuedAt = *item.QueuedAt
+		}
+		out = append(out, entry)
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package playlist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sectionQueue   = "queue"
+	sectionHistory = "history"
+)
+
+func encodeM3U(w io.Writer, doc Document) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	fmt.Fprintln(bw, "#PLAYLIST:Kvazar")
+
+	writeSection := func(name string, entries []Entry) {
+		if len(entries) == 0 {
+			return
+		}
+		fmt.Fprintf(bw, "#EXTGRP:%s\n", name)
+		for _, entry := range entries {
+			seconds := -1
+			if entry.Duration > 0 {
+				seconds = int(entry.Duration.Round(time.Second) / time.Second)
+			}
+			fmt.Fprintf(bw, "#EXTINF:%d,%s\n", seconds, oneLine(entry.Title))
+			if entry.Author != "" {
+				fmt.Fprintf(bw, "#EXTART:%s\n", oneLine(entry.Author))
+			}
+			if entry.Thumbnail != "" {
+				fmt.Fprintf(bw, "#EXTIMG:%s\n", oneLine(entry.Thumbnail))
+			}
+			fmt.Fprintln(bw, oneLine(entry.URL))
+		}
+	}
+
+	writeSection(sectionHistory, doc.History)
+	writeSection(sectionQueue, doc.Queue)
+	return bw.Flush()
+}
+
+func decodeM3U(r io.Reader) (Document, error) {
+	var doc Document
+	section := sectionQueue
+	var pending Entry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\xef\xbb\xbf"))
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "#") {
+			pending.URL = line
+			if section == sectionHistory {
+				doc.History = append(doc.History, pending)
+			} else {
+				doc.Queue = append(doc.Queue, pending)
+			}
+			pending = Entry{}
+			continue
+		}
+
+		tag, value, _ := strings.Cut(line, ":")
+		switch strings.ToUpper(tag) {
+		case "#EXTGRP":
+			if strings.EqualFold(strings.TrimSpace(value), sectionHistory) {
+				section = sectionHistory
+			} else {
+				section = sectionQueue
+			}
+		case "#EXTINF":
+			length, title, _ := strings.Cut(value, ",")
+			// Attributes such as tvg-logo="..." may follow the duration.
+			if fields := strings.Fields(length); len(fields) > 0 {
+				length = fields[0]
+			}
+			if seconds, err := strconv.ParseFloat(length, 64); err == nil && seconds > 0 {
+				pending.Duration = time.Duration(seconds * float64(time.Second))
+			}
+			pending.Title = strings.TrimSpace(title)
+		case "#EXTART":
+			pending.Author = strings.TrimSpace(value)
+		case "#EXTIMG":
+			pending.Thumbnail = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Document{}, fmt.Errorf("playlist: read m3u: %w", err)
+	}
+	return doc, nil
+}
+
+func oneLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,171 @@
+package playlist
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"kvazar/internal/media"
+)
+
+// Format identifies a supported playlist serialisation.
+type Format string
+
+const (
+	FormatM3U  Format = "m3u"
+	FormatXSPF Format = "xspf"
+	FormatJSON Format = "json"
+)
+
+// ErrUnknownFormat is returned when a playlist format cannot be determined.
+var ErrUnknownFormat = errors.New("playlist: unknown format")
+
+// Entry describes a single playlist item independent of the wire format.
+type Entry struct {
+	ID          string
+	Title       string
+	Author      string
+	URL         string
+	Thumbnail   string
+	Duration    time.Duration
+	Source      media.Source
+	RequestedBy string
+	QueuedAt    time.Time
+}
+
+// Document bundles the upcoming queue with the recently played history.
+type Document struct {
+	Queue   []Entry
+	History []Entry
+}
+
+// FromTrack converts a media track into a playlist entry.
+func FromTrack(track *media.Track) Entry {
+	return Entry{
+		ID:          track.ID,
+		Title:       track.Title,
+		Author:      track.Author,
+		URL:         track.WebURL,
+		Thumbnail:   track.Thumbnail,
+		Duration:    track.Duration,
+		Source:      track.Source,
+		RequestedBy: track.RequestedBy,
+		QueuedAt:    track.QueuedAt,
+	}
+}
+
+// FromTracks converts a slice of media tracks into playlist entries.
+func FromTracks(tracks []*media.Track) []Entry {
+	entries := make([]Entry, 0, len(tracks))
+	for _, track := range tracks {
+		if track == nil {
+			continue
+		}
+		entries = append(entries, FromTrack(track))
+	}
+	return entries
+}
+
+// Query returns the string that should be handed to the resolver for this entry.
+func (e Entry) Query() string {
+	if strings.TrimSpace(e.URL) != "" {
+		return strings.TrimSpace(e.URL)
+	}
+	title := strings.TrimSpace(e.Title)
+	author := strings.TrimSpace(e.Author)
+	switch {
+	case title != "" && author != "":
+		return author + " - " + title
+	default:
+		return title
+	}
+}
+
+// ParseFormat maps user input such as "m3u8" or "XSPF" onto a Format.
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(value), ".")) {
+	case "m3u", "m3u8":
+		return FormatM3U, nil
+	case "xspf":
+		return FormatXSPF, nil
+	case "json":
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, value)
+}
+
+// Extension returns the canonical file extension for the format.
+func (f Format) Extension() string {
+	switch f {
+	case FormatM3U:
+		return "m3u8"
+	case FormatXSPF:
+		return "xspf"
+	case FormatJSON:
+		return "json"
+	}
+	return ""
+}
+
+// ContentType returns the MIME type used when uploading the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatM3U:
+		return "audio/x-mpegurl"
+	case FormatXSPF:
+		return "application/xspf+xml"
+	case FormatJSON:
+		return "application/json"
+	}
+	return "application/octet-stream"
+}
+
+// DetectFormat guesses the format from a file name, falling back to sniffing the content.
+func DetectFormat(name string, content []byte) (Format, error) {
+	if ext := path.Ext(strings.ToLower(name)); ext != "" {
+		if format, err := ParseFormat(ext); err == nil {
+			return format, nil
+		}
+	}
+
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("#EXTM3U")):
+		return FormatM3U, nil
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return FormatJSON, nil
+	case bytes.HasPrefix(trimmed, []byte("<")) && bytes.Contains(trimmed, []byte("xspf.org")):
+		return FormatXSPF, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Encode writes the document to w in the requested format.
+func Encode(w io.Writer, format Format, doc Document) error {
+	switch format {
+	case FormatM3U:
+		return encodeM3U(w, doc)
+	case FormatXSPF:
+		return encodeXSPF(w, doc)
+	case FormatJSON:
+		return encodeJSON(w, doc)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// Decode parses a document in the requested format from r.
+func Decode(r io.Reader, format Format) (Document, error) {
+	switch format {
+	case FormatM3U:
+		return decodeM3U(r)
+	case FormatXSPF:
+		return decodeXSPF(r)
+	case FormatJSON:
+		return decodeJSON(r)
+	}
+	return Document{}, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
@@ -0,0 +1,148 @@
+package playlist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"kvazar/internal/media"
+)
+
+func sampleDocument() Document {
+	queuedAt := time.Date(2024, 5, 1, 20, 15, 0, 0, time.UTC)
+	return Document{
+		History: []Entry{
+			{
+				ID:          "abc123",
+				Title:       "Cosmic Intro",
+				Author:      "Kvazar Band",
+				URL:         "https://www.youtube.com/watch?v=abc123",
+				Thumbnail:   "https://i.ytimg.com/vi/abc123/hq.jpg",
+				Duration:    3*time.Minute + 25*time.Second,
+				Source:      media.SourceYouTube,
+				RequestedBy: "<@1>",
+				QueuedAt:    queuedAt,
+			},
+		},
+		Queue: []Entry{
+			{
+				ID:          "sc-42",
+				Title:       "Nebula - Extended Mix",
+				Author:      "Stardust",
+				URL:         "https://soundcloud.com/stardust/nebula",
+				Duration:    7 * time.Minute,
+				Source:      media.SourceSoundCloud,
+				RequestedBy: "<@2>",
+				QueuedAt:    queuedAt.Add(time.Minute),
+			},
+			{
+				Title:  "Live Radio",
+				URL:    "https://radio.example.com/stream",
+				Source: media.SourceUnknown,
+			},
+		},
+	}
+}
+
+func TestRoundTripJSON(t *testing.T) {
+	doc := sampleDocument()
+	got := roundTrip(t, FormatJSON, doc)
+	if !reflect.DeepEqual(got, doc) {
+		t.Fatalf("json round trip mismatch\n got: %#v\nwant: %#v", got, doc)
+	}
+}
+
+func TestRoundTripXSPF(t *testing.T) {
+	doc := sampleDocument()
+	got := roundTrip(t, FormatXSPF, doc)
+	if !reflect.DeepEqual(got, doc) {
+		t.Fatalf("xspf round trip mismatch\n got: %#v\nwant: %#v", got, doc)
+	}
+}
+
+func TestRoundTripM3U(t *testing.T) {
+	doc := sampleDocument()
+	got := roundTrip(t, FormatM3U, doc)
+
+	// M3U only carries title, artist, artwork, duration and location.
+	want := Document{}
+	for _, entry := range doc.History {
+		want.History = append(want.History, m3uView(entry))
+	}
+	for _, entry := range doc.Queue {
+		want.Queue = append(want.Queue, m3uView(entry))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("m3u round trip mismatch\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestDecodeForeignM3U(t *testing.T) {
+	input := "#EXTM3U\n#EXTINF:123 tvg-logo=\"x\",Some Artist - Some Song\nhttps://example.com/a.mp3\n\nhttps://example.com/b.mp3\n"
+	doc, err := Decode(strings.NewReader(input), FormatM3U)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(doc.History) != 0 || len(doc.Queue) != 2 {
+		t.Fatalf("unexpected sections: %+v", doc)
+	}
+	if doc.Queue[0].Duration != 123*time.Second || doc.Queue[0].Title != "Some Artist - Some Song" {
+		t.Fatalf("unexpected first entry: %+v", doc.Queue[0])
+	}
+	if doc.Queue[1].URL != "https://example.com/b.mp3" {
+		t.Fatalf("unexpected second entry: %+v", doc.Queue[1])
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    Format
+	}{
+		{"queue.m3u8", "", FormatM3U},
+		{"queue.XSPF", "", FormatXSPF},
+		{"queue.json", "", FormatJSON},
+		{"upload", "#EXTM3U\n", FormatM3U},
+		{"upload", "  {\"version\":1}", FormatJSON},
+		{"upload", `<?xml version="1.0"?><playlist xmlns="http://xspf.org/ns/0/">`, FormatXSPF},
+	}
+	for _, tc := range cases {
+		got, err := DetectFormat(tc.name, []byte(tc.content))
+		if err != nil || got != tc.want {
+			t.Errorf("DetectFormat(%q) = %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+	if _, err := DetectFormat("notes.txt", []byte("hello")); err == nil {
+		t.Errorf("expected error for unknown content")
+	}
+}
+
+func roundTrip(t *testing.T, format Format, doc Document) Document {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Encode(&buf, format, doc); err != nil {
+		t.Fatalf("encode %s: %v", format, err)
+	}
+	detected, err := DetectFormat("", buf.Bytes())
+	if err != nil || detected != format {
+		t.Fatalf("detect %s: got %q, %v", format, detected, err)
+	}
+	got, err := Decode(&buf, format)
+	if err != nil {
+		t.Fatalf("decode %s: %v", format, err)
+	}
+	return got
+}
+
+func m3uView(entry Entry) Entry {
+	return Entry{
+		Title:     entry.Title,
+		Author:    entry.Author,
+		URL:       entry.URL,
+		Thumbnail: entry.Thumbnail,
+		Duration:  entry.Duration,
+	}
+}
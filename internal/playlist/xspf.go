@@ -0,0 +1,127 @@
+package playlist
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"kvazar/internal/media"
+)
+
+const (
+	xspfNamespace       = "http://xspf.org/ns/0/"
+	xspfMetaSection     = "https://github.com/crnobog69/kvazar#section"
+	xspfMetaSource      = "https://github.com/crnobog69/kvazar#source"
+	xspfMetaRequestedBy = "https://github.com/crnobog69/kvazar#requested-by"
+	xspfMetaQueuedAt    = "https://github.com/crnobog69/kvazar#queued-at"
+)
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location   string     `xml:"location,omitempty"`
+	Identifier string     `xml:"identifier,omitempty"`
+	Title      string     `xml:"title,omitempty"`
+	Creator    string     `xml:"creator,omitempty"`
+	Image      string     `xml:"image,omitempty"`
+	Duration   int64      `xml:"duration,omitempty"`
+	Meta       []xspfMeta `xml:"meta"`
+}
+
+type xspfMeta struct {
+	Rel   string `xml:"rel,attr"`
+	Value string `xml:",chardata"`
+}
+
+func encodeXSPF(w io.Writer, doc Document) error {
+	out := xspfPlaylist{Version: "1", XMLNS: xspfNamespace, Title: "Kvazar"}
+	appendSection := func(section string, entries []Entry) {
+		for _, entry := range entries {
+			track := xspfTrack{
+				Location:   entry.URL,
+				Identifier: entry.ID,
+				Title:      entry.Title,
+				Creator:    entry.Author,
+				Image:      entry.Thumbnail,
+				Duration:   entry.Duration.Milliseconds(),
+				Meta:       []xspfMeta{{Rel: xspfMetaSection, Value: section}},
+			}
+			if entry.Source != "" {
+				track.Meta = append(track.Meta, xspfMeta{Rel: xspfMetaSource, Value: string(entry.Source)})
+			}
+			if entry.RequestedBy != "" {
+				track.Meta = append(track.Meta, xspfMeta{Rel: xspfMetaRequestedBy, Value: entry.RequestedBy})
+			}
+			if !entry.QueuedAt.IsZero() {
+				track.Meta = append(track.Meta, xspfMeta{Rel: xspfMetaQueuedAt, Value: entry.QueuedAt.UTC().Format(time.RFC3339Nano)})
+			}
+			out.Tracks = append(out.Tracks, track)
+		}
+	}
+	appendSection(sectionHistory, doc.History)
+	appendSection(sectionQueue, doc.Queue)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("playlist: encode xspf: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func decodeXSPF(r io.Reader) (Document, error) {
+	var in xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&in); err != nil {
+		return Document{}, fmt.Errorf("playlist: decode xspf: %w", err)
+	}
+
+	var doc Document
+	for _, track := range in.Tracks {
+		entry := Entry{
+			ID:        strings.TrimSpace(track.Identifier),
+			Title:     strings.TrimSpace(track.Title),
+			Author:    strings.TrimSpace(track.Creator),
+			URL:       strings.TrimSpace(track.Location),
+			Thumbnail: strings.TrimSpace(track.Image),
+		}
+		if track.Duration > 0 {
+			entry.Duration = time.Duration(track.Duration) * time.Millisecond
+		}
+
+		section := sectionQueue
+		for _, meta := range track.Meta {
+			value := strings.TrimSpace(meta.Value)
+			switch meta.Rel {
+			case xspfMetaSection:
+				section = value
+			case xspfMetaSource:
+				entry.Source = media.Source(value)
+			case xspfMetaRequestedBy:
+				entry.RequestedBy = value
+			case xspfMetaQueuedAt:
+				if ts, err := time.Parse(time.RFC3339Nano, value); err == nil {
+					entry.QueuedAt = ts
+				}
+			}
+		}
+
+		if section == sectionHistory {
+			doc.History = append(doc.History, entry)
+		} else {
+			doc.Queue = append(doc.Queue, entry)
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,370 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"kvazar/internal/bot"
+)
+
+const (
+	apiPrefix = "/api/v1/"
+	// maxRequestBody bounds API request bodies; they only carry a few fields.
+	maxRequestBody = 64 << 10
+)
+
+// Controller is the part of the bot the REST API drives. *bot.Kvazar
+// implements it on top of the same Player methods the slash commands use.
+type Controller interface {
+	Players() []bot.PlayerState
+	PlayerState(guildID string) (bot.PlayerState, error)
+	EnqueueQuery(ctx context.Context, guildID string, opts bot.EnqueueOptions) (bot.TrackInfo, int, error)
+	SkipTrack(guildID string) (bot.PlayerState, error)
+	TogglePause(guildID string) (bot.PlayerState, error)
+	StopPlayer(guildID string) (bot.PlayerState, error)
+	SetLoop(guildID string, enabled bool) (bot.PlayerState, error)
+	MoveTrack(guildID string, from, to int) (bot.PlayerState, error)
+	SetAudio(guildID string, update bot.AudioUpdate) (bot.PlayerState, error)
+	Search(ctx context.Context, query string, limit int) ([]bot.TrackInfo, error)
+	Guilds() []bot.GuildInfo
+}
+
+// GuildList is the response of GET /api/v1/guilds: the active players, and
+// every guild the bot is in with the voice channels it can join.
+type GuildList struct {
+	Guilds    []bot.PlayerState `json:"guilds"`
+	Available []bot.GuildInfo   `json:"available"`
+}
+
+// SearchResponse is the response of GET /api/v1/search.
+type SearchResponse struct {
+	Results []bot.TrackInfo `json:"results"`
+}
+
+// EnqueueRequest is the body of POST /api/v1/guilds/{id}/queue.
+type EnqueueRequest struct {
+	Query          string `json:"query"`
+	VoiceChannelID string `json:"voice_channel_id,omitempty"`
+	TextChannelID  string `json:"text_channel_id,omitempty"`
+	UserID         string `json:"user_id,omitempty"`
+}
+
+// EnqueueResponse reports the queued track and its one-based position.
+type EnqueueResponse struct {
+	Track    bot.TrackInfo `json:"track"`
+	Position int           `json:"position"`
+}
+
+// LoopRequest is the body of PUT /api/v1/guilds/{id}/loop.
+type LoopRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// AudioRequest is the body of PUT /api/v1/guilds/{id}/audio. Omitted fields
+// keep their value.
+type AudioRequest struct {
+	Volume         *int     `json:"volume"`
+	Normalize      *bool    `json:"normalize"`
+	LoudnessTarget *float64 `json:"loudness_target"`
+}
+
+// MoveRequest is the body of POST /api/v1/guilds/{id}/queue/move. Positions
+// are one-based, as shown by /queue.
+type MoveRequest struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// ErrorResponse is returned with every non-2xx status.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// defaultSearchResults is how many results GET /api/v1/search returns
+// without a limit.
+const defaultSearchResults = 10
+
+// api serves the REST API and the event stream under /api/v1/.
+type api struct {
+	bot interface {
+		Controller
+		EventSource
+	}
+	// token is empty when only Discord sign-in is enabled.
+	token    string
+	sessions *sessionStore
+}
+
+// principal is whom a request acts for.
+type principal struct {
+	// userID is the Discord user of a dashboard session signed in with
+	// Discord; it is credited for the tracks they queue.
+	userID string
+	// guilds limits a Discord session to the guilds where the user is a DJ.
+	// Nil allows every guild.
+	guilds map[string]bool
+}
+
+func (p principal) allows(guildID string) bool {
+	return p.guilds == nil || p.guilds[guildID]
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	who, ok := a.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="kvazar"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid API token")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix), "/"), "/")
+	if len(parts) == 1 {
+		switch parts[0] {
+		case "events":
+			if allowMethod(w, r, http.MethodGet) {
+				a.streamEvents(w, r, who)
+			}
+			return
+		case "search":
+			if allowMethod(w, r, http.MethodGet) {
+				a.search(w, r)
+			}
+			return
+		}
+	}
+	if parts[0] != "guilds" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if len(parts) == 1 {
+		if allowMethod(w, r, http.MethodGet) {
+			a.listGuilds(w, who)
+		}
+		return
+	}
+
+	guildID := parts[1]
+	if !who.allows(guildID) {
+		writeError(w, http.StatusForbidden, "you are not a DJ in this guild")
+		return
+	}
+	switch strings.Join(parts[2:], "/") {
+	case "player":
+		if allowMethod(w, r, http.MethodGet) {
+			a.respond(w)(a.bot.PlayerState(guildID))
+		}
+	case "queue":
+		if allowMethod(w, r, http.MethodPost) {
+			a.enqueue(w, r, guildID, who)
+		}
+	case "queue/move":
+		if allowMethod(w, r, http.MethodPost) {
+			a.move(w, r, guildID)
+		}
+	case "skip":
+		if allowMethod(w, r, http.MethodPost) {
+			a.respond(w)(a.bot.SkipTrack(guildID))
+		}
+	case "pause":
+		if allowMethod(w, r, http.MethodPost) {
+			a.respond(w)(a.bot.TogglePause(guildID))
+		}
+	case "stop":
+		if allowMethod(w, r, http.MethodPost) {
+			a.respond(w)(a.bot.StopPlayer(guildID))
+		}
+	case "loop":
+		if allowMethod(w, r, http.MethodPut) {
+			a.loop(w, r, guildID)
+		}
+	case "audio":
+		if allowMethod(w, r, http.MethodPut) {
+			a.audio(w, r, guildID)
+		}
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// authenticate accepts the bearer token, checked in constant time, or a
+// dashboard session cookie. The event stream also accepts the token as the
+// access_token query parameter, because browsers cannot set headers on an
+// EventSource.
+func (a *api) authenticate(r *http.Request) (principal, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return principal{}, a.validToken(token)
+	}
+	if token := r.URL.Query().Get("access_token"); token != "" && r.URL.Path == apiPrefix+"events" {
+		return principal{}, a.validToken(token)
+	}
+	// The session cookie is SameSite=Strict, so other sites cannot make
+	// requests with it.
+	if session, ok := a.sessions.fromRequest(r); ok {
+		return session.principal, true
+	}
+	return principal{}, false
+}
+
+func (a *api) validToken(token string) bool {
+	token = strings.TrimSpace(token)
+	return a.token != "" && token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+}
+
+func (a *api) listGuilds(w http.ResponseWriter, who principal) {
+	list := GuildList{Guilds: []bot.PlayerState{}, Available: []bot.GuildInfo{}}
+	for _, state := range a.bot.Players() {
+		if who.allows(state.GuildID) {
+			list.Guilds = append(list.Guilds, state)
+		}
+	}
+	for _, guild := range a.bot.Guilds() {
+		if who.allows(guild.ID) {
+			list.Available = append(list.Available, guild)
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (a *api) search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	limit := defaultSearchResults
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		limit = n
+	}
+
+	results, err := a.bot.Search(r.Context(), query, limit)
+	if err != nil {
+		writeControlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, SearchResponse{Results: results})
+}
+
+func (a *api) enqueue(w http.ResponseWriter, r *http.Request, guildID string, who principal) {
+	var req EnqueueRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+	if who.userID != "" {
+		// Discord sign-ins queue as themselves.
+		req.UserID = who.userID
+	}
+
+	track, position, err := a.bot.EnqueueQuery(r.Context(), guildID, bot.EnqueueOptions{
+		Query:          req.Query,
+		VoiceChannelID: req.VoiceChannelID,
+		TextChannelID:  req.TextChannelID,
+		UserID:         req.UserID,
+	})
+	if err != nil {
+		writeControlError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, EnqueueResponse{Track: track, Position: position})
+}
+
+func (a *api) move(w http.ResponseWriter, r *http.Request, guildID string) {
+	var req MoveRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.From < 1 || req.To < 1 {
+		writeError(w, http.StatusBadRequest, "from and to must be queue positions starting at 1")
+		return
+	}
+	a.respond(w)(a.bot.MoveTrack(guildID, req.From, req.To))
+}
+
+func (a *api) loop(w http.ResponseWriter, r *http.Request, guildID string) {
+	var req LoopRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+	a.respond(w)(a.bot.SetLoop(guildID, *req.Enabled))
+}
+
+func (a *api) audio(w http.ResponseWriter, r *http.Request, guildID string) {
+	var req AudioRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	a.respond(w)(a.bot.SetAudio(guildID, bot.AudioUpdate{
+		Volume:         req.Volume,
+		Normalize:      req.Normalize,
+		LoudnessTarget: req.LoudnessTarget,
+	}))
+}
+
+// respond writes the player state an action returned, or its error.
+func (a *api) respond(w http.ResponseWriter) func(bot.PlayerState, error) {
+	return func(state bot.PlayerState, err error) {
+		if err != nil {
+			writeControlError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, state)
+	}
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("use %s", method))
+	return false
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %v", err))
+		return false
+	}
+	return true
+}
+
+// writeControlError maps the bot's errors onto HTTP statuses.
+func writeControlError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, bot.ErrNoPlayer):
+		code = http.StatusNotFound
+	case errors.Is(err, bot.ErrNotConnected), errors.Is(err, bot.ErrInvalidPosition), errors.Is(err, bot.ErrInvalidSetting):
+		code = http.StatusBadRequest
+	case errors.Is(err, bot.ErrNothingPlaying), errors.Is(err, bot.ErrQueueLimit):
+		code = http.StatusConflict
+	case errors.Is(err, bot.ErrResolve):
+		code = http.StatusUnprocessableEntity
+	}
+	writeError(w, code, err.Error())
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, ErrorResponse{Error: message})
+}
@@ -0,0 +1,442 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kvazar/internal/bot"
+)
+
+const testToken = "test-token-0123456789"
+
+// fakeBot records the controls the API invoked.
+type fakeBot struct {
+	players map[string]*bot.PlayerState
+	calls   []string
+
+	enqueueErr error
+	enqueued   bot.EnqueueOptions
+
+	events *bot.EventBus
+	// djs holds "guild/user" pairs for IsDJ.
+	djs map[string]bool
+	// status is what Status reports, a healthy bot if nil.
+	status *bot.Status
+}
+
+func newFakeBot() *fakeBot {
+	return &fakeBot{events: bot.NewEventBus(), players: map[string]*bot.PlayerState{
+		"100": {
+			GuildID: "100",
+			Playing: true,
+			Current: &bot.TrackInfo{Title: "Current", URL: "https://example.com/current"},
+			Queue: []bot.TrackInfo{
+				{Title: "First", URL: "https://example.com/1"},
+				{Title: "Second", URL: "https://example.com/2"},
+			},
+		},
+	}}
+}
+
+func (f *fakeBot) LibraryCover(_ context.Context, id string) ([]byte, error) {
+	if id != "cover" {
+		return nil, errors.New("no cover")
+	}
+	return []byte("\x89PNG\r\n\x1a\n"), nil
+}
+
+func (f *fakeBot) Status(context.Context) bot.Status {
+	if f.status != nil {
+		return *f.status
+	}
+	return bot.Status{Healthy: true, Ready: true}
+}
+
+func (f *fakeBot) Players() []bot.PlayerState {
+	var states []bot.PlayerState
+	for _, state := range f.players {
+		states = append(states, *state)
+	}
+	return states
+}
+
+func (f *fakeBot) PlayerState(guildID string) (bot.PlayerState, error) {
+	state, ok := f.players[guildID]
+	if !ok {
+		return bot.PlayerState{}, bot.ErrNoPlayer
+	}
+	return *state, nil
+}
+
+func (f *fakeBot) EnqueueQuery(_ context.Context, guildID string, opts bot.EnqueueOptions) (bot.TrackInfo, int, error) {
+	f.calls = append(f.calls, "enqueue "+guildID)
+	f.enqueued = opts
+	if f.enqueueErr != nil {
+		return bot.TrackInfo{}, 0, f.enqueueErr
+	}
+	return bot.TrackInfo{Title: opts.Query}, 3, nil
+}
+
+func (f *fakeBot) control(name, guildID string, fn func(*bot.PlayerState) error) (bot.PlayerState, error) {
+	f.calls = append(f.calls, name+" "+guildID)
+	state, ok := f.players[guildID]
+	if !ok {
+		return bot.PlayerState{}, bot.ErrNoPlayer
+	}
+	if err := fn(state); err != nil {
+		return bot.PlayerState{}, err
+	}
+	return *state, nil
+}
+
+func (f *fakeBot) SkipTrack(guildID string) (bot.PlayerState, error) {
+	return f.control("skip", guildID, func(*bot.PlayerState) error { return nil })
+}
+
+func (f *fakeBot) TogglePause(guildID string) (bot.PlayerState, error) {
+	return f.control("pause", guildID, func(s *bot.PlayerState) error {
+		s.Paused = !s.Paused
+		return nil
+	})
+}
+
+func (f *fakeBot) StopPlayer(guildID string) (bot.PlayerState, error) {
+	return f.control("stop", guildID, func(s *bot.PlayerState) error {
+		if s.Current == nil {
+			return bot.ErrNothingPlaying
+		}
+		s.Current, s.Queue = nil, nil
+		return nil
+	})
+}
+
+func (f *fakeBot) SetLoop(guildID string, enabled bool) (bot.PlayerState, error) {
+	return f.control(fmt.Sprintf("loop %t", enabled), guildID, func(s *bot.PlayerState) error {
+		s.Loop = enabled
+		return nil
+	})
+}
+
+func (f *fakeBot) MoveTrack(guildID string, from, to int) (bot.PlayerState, error) {
+	return f.control(fmt.Sprintf("move %d %d", from, to), guildID, func(s *bot.PlayerState) error {
+		if from > len(s.Queue) || to > len(s.Queue) {
+			return bot.ErrInvalidPosition
+		}
+		track := s.Queue[from-1]
+		s.Queue = append(s.Queue[:from-1], s.Queue[from:]...)
+		s.Queue = append(s.Queue[:to-1], append([]bot.TrackInfo{track}, s.Queue[to-1:]...)...)
+		return nil
+	})
+}
+
+func (f *fakeBot) SetAudio(guildID string, update bot.AudioUpdate) (bot.PlayerState, error) {
+	return f.control("audio", guildID, func(s *bot.PlayerState) error {
+		if update.Volume != nil {
+			if *update.Volume > 200 {
+				return bot.ErrInvalidSetting
+			}
+			s.Audio.Volume = *update.Volume
+		}
+		if update.Normalize != nil {
+			s.Audio.Normalize = *update.Normalize
+		}
+		return nil
+	})
+}
+
+func (f *fakeBot) Search(_ context.Context, query string, limit int) ([]bot.TrackInfo, error) {
+	f.calls = append(f.calls, fmt.Sprintf("search %s %d", query, limit))
+	return []bot.TrackInfo{{Title: query, URL: "https://example.com/" + query}}, nil
+}
+
+func (f *fakeBot) Guilds() []bot.GuildInfo {
+	return []bot.GuildInfo{
+		{ID: "100", Name: "Cosmos", Active: true},
+		{ID: "200", Name: "Nebula"},
+	}
+}
+
+func (f *fakeBot) IsDJ(guildID, userID string) bool {
+	return f.djs[guildID+"/"+userID]
+}
+
+func (f *fakeBot) Subscribe(guildIDs ...string) (<-chan bot.Event, func()) {
+	return f.events.Subscribe(guildIDs...)
+}
+
+func serve(t *testing.T, handler http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	handler := New(":0", newFakeBot(), Options{APIToken: testToken}).Handler()
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", "Basic " + testToken, http.StatusUnauthorized},
+		{"valid", "Bearer " + testToken, http.StatusOK},
+		{"case-insensitive scheme", "bearer " + testToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/guilds", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestAPIDisabledWithoutToken(t *testing.T) {
+	handler := New(":0", newFakeBot(), Options{}).Handler()
+	if rec := serve(t, handler, http.MethodGet, "/api/v1/guilds", "", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestAPIReadState(t *testing.T) {
+	handler := New(":0", newFakeBot(), Options{APIToken: testToken}).Handler()
+
+	rec := serve(t, handler, http.MethodGet, "/api/v1/guilds", testToken, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d: %s", rec.Code, rec.Body)
+	}
+	list := decode[GuildList](t, rec)
+	if len(list.Guilds) != 1 || list.Guilds[0].GuildID != "100" {
+		t.Fatalf("guilds = %+v", list.Guilds)
+	}
+	if len(list.Available) != 2 {
+		t.Fatalf("available = %+v", list.Available)
+	}
+
+	rec = serve(t, handler, http.MethodGet, "/api/v1/guilds/100/player", testToken, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("player status = %d: %s", rec.Code, rec.Body)
+	}
+	if state := decode[bot.PlayerState](t, rec); state.Current == nil || len(state.Queue) != 2 {
+		t.Fatalf("state = %+v", state)
+	}
+
+	rec = serve(t, handler, http.MethodGet, "/api/v1/guilds/999/player", testToken, "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown guild status = %d, want 404", rec.Code)
+	}
+	if resp := decode[ErrorResponse](t, rec); resp.Error == "" {
+		t.Error("error response without message")
+	}
+}
+
+func TestAPIEnqueue(t *testing.T) {
+	fake := newFakeBot()
+	handler := New(":0", fake, Options{APIToken: testToken}).Handler()
+
+	rec := serve(t, handler, http.MethodPost, "/api/v1/guilds/100/queue", testToken,
+		`{"query":"daft punk","voice_channel_id":"200","text_channel_id":"300","user_id":"400"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	resp := decode[EnqueueResponse](t, rec)
+	if resp.Position != 3 || resp.Track.Title != "daft punk" {
+		t.Fatalf("response = %+v", resp)
+	}
+	want := bot.EnqueueOptions{Query: "daft punk", VoiceChannelID: "200", TextChannelID: "300", UserID: "400"}
+	if fake.enqueued != want {
+		t.Fatalf("options = %+v, want %+v", fake.enqueued, want)
+	}
+}
+
+func TestAPIEnqueueErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"empty query", `{"query":"  "}`, nil, http.StatusBadRequest},
+		{"malformed JSON", `{"query":`, nil, http.StatusBadRequest},
+		{"unknown field", `{"query":"x","volume":3}`, nil, http.StatusBadRequest},
+		{"not connected", `{"query":"x"}`, bot.ErrNotConnected, http.StatusBadRequest},
+		{"queue limit", `{"query":"x"}`, fmt.Errorf("wrapped: %w", bot.ErrQueueLimit), http.StatusConflict},
+		{"resolve failure", `{"query":"x"}`, fmt.Errorf("%w: no results", bot.ErrResolve), http.StatusUnprocessableEntity},
+		{"unexpected", `{"query":"x"}`, fmt.Errorf("voice join: boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeBot()
+			fake.enqueueErr = tt.err
+			handler := New(":0", fake, Options{APIToken: testToken}).Handler()
+
+			rec := serve(t, handler, http.MethodPost, "/api/v1/guilds/100/queue", testToken, tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestAPIControls(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		want     int
+		wantCall string
+		check    func(t *testing.T, state bot.PlayerState)
+	}{
+		{
+			name: "skip", method: http.MethodPost, path: "/api/v1/guilds/100/skip",
+			want: http.StatusOK, wantCall: "skip 100",
+		},
+		{
+			name: "pause", method: http.MethodPost, path: "/api/v1/guilds/100/pause",
+			want: http.StatusOK, wantCall: "pause 100",
+			check: func(t *testing.T, state bot.PlayerState) {
+				if !state.Paused {
+					t.Error("player not paused")
+				}
+			},
+		},
+		{
+			name: "stop", method: http.MethodPost, path: "/api/v1/guilds/100/stop",
+			want: http.StatusOK, wantCall: "stop 100",
+			check: func(t *testing.T, state bot.PlayerState) {
+				if state.Current != nil || len(state.Queue) != 0 {
+					t.Errorf("player still has tracks: %+v", state)
+				}
+			},
+		},
+		{
+			name: "loop", method: http.MethodPut, path: "/api/v1/guilds/100/loop", body: `{"enabled":true}`,
+			want: http.StatusOK, wantCall: "loop true 100",
+			check: func(t *testing.T, state bot.PlayerState) {
+				if !state.Loop {
+					t.Error("loop not enabled")
+				}
+			},
+		},
+		{
+			name: "loop without enabled", method: http.MethodPut, path: "/api/v1/guilds/100/loop", body: `{}`,
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "move", method: http.MethodPost, path: "/api/v1/guilds/100/queue/move", body: `{"from":2,"to":1}`,
+			want: http.StatusOK, wantCall: "move 2 1 100",
+			check: func(t *testing.T, state bot.PlayerState) {
+				if state.Queue[0].Title != "Second" || state.Queue[1].Title != "First" {
+					t.Errorf("queue = %+v", state.Queue)
+				}
+			},
+		},
+		{
+			name: "move to zero", method: http.MethodPost, path: "/api/v1/guilds/100/queue/move", body: `{"from":1,"to":0}`,
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "move past the end", method: http.MethodPost, path: "/api/v1/guilds/100/queue/move", body: `{"from":1,"to":9}`,
+			want: http.StatusBadRequest, wantCall: "move 1 9 100",
+		},
+		{
+			name: "audio", method: http.MethodPut, path: "/api/v1/guilds/100/audio", body: `{"volume":150,"normalize":false}`,
+			want: http.StatusOK, wantCall: "audio 100",
+			check: func(t *testing.T, state bot.PlayerState) {
+				if state.Audio.Volume != 150 || state.Audio.Normalize {
+					t.Errorf("audio = %+v", state.Audio)
+				}
+			},
+		},
+		{
+			name: "audio out of range", method: http.MethodPut, path: "/api/v1/guilds/100/audio", body: `{"volume":500}`,
+			want: http.StatusBadRequest, wantCall: "audio 100",
+		},
+		{
+			name: "search", method: http.MethodGet, path: "/api/v1/search?q=nebula&limit=5",
+			want: http.StatusOK, wantCall: "search nebula 5",
+		},
+		{
+			name: "search without query", method: http.MethodGet, path: "/api/v1/search",
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "skip without player", method: http.MethodPost, path: "/api/v1/guilds/999/skip",
+			want: http.StatusNotFound, wantCall: "skip 999",
+		},
+		{
+			name: "wrong method", method: http.MethodGet, path: "/api/v1/guilds/100/skip",
+			want: http.StatusMethodNotAllowed,
+		},
+		{
+			name: "unknown action", method: http.MethodPost, path: "/api/v1/guilds/100/shuffle",
+			want: http.StatusNotFound,
+		},
+		{
+			name: "unknown resource", method: http.MethodGet, path: "/api/v1/users",
+			want: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeBot()
+			handler := New(":0", fake, Options{APIToken: testToken}).Handler()
+
+			rec := serve(t, handler, tt.method, tt.path, testToken, tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if got := strings.Join(fake.calls, ","); got != tt.wantCall {
+				t.Errorf("calls = %q, want %q", got, tt.wantCall)
+			}
+			if tt.want == http.StatusMethodNotAllowed && rec.Header().Get("Allow") != http.MethodPost {
+				t.Errorf("Allow = %q, want POST", rec.Header().Get("Allow"))
+			}
+			if tt.check != nil {
+				tt.check(t, decode[bot.PlayerState](t, rec))
+			}
+		})
+	}
+}
+
+func TestAPIStopWhenIdle(t *testing.T) {
+	fake := newFakeBot()
+	fake.players["100"].Current = nil
+	handler := New(":0", fake, Options{APIToken: testToken}).Handler()
+
+	if rec := serve(t, handler, http.MethodPost, "/api/v1/guilds/100/stop", testToken, ""); rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409", rec.Code)
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"kvazar/internal/bot"
+)
+
+// coverTimeout bounds extracting a cover from an audio file.
+const coverTimeout = 15 * time.Second
+
+// CoverSource serves the cover art of local library tracks.
+type CoverSource interface {
+	LibraryCover(ctx context.Context, id string) ([]byte, error)
+}
+
+// handleCover serves library cover art without authentication, since Discord
+// fetches it to show on the now-playing cards.
+func (s *Server) handleCover(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, bot.LibraryCoverPath)
+	if id == "" || strings.Contains(id, "/") || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), coverTimeout)
+	defer cancel()
+	image, err := s.bot.LibraryCover(ctx, id)
+	if err != nil || len(image) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(image))
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(image)
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLibraryCovers(t *testing.T) {
+	// Covers are public, even with the API disabled.
+	handler := New(":0", newFakeBot(), Options{}).Handler()
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/library/covers/cover", http.StatusOK},
+		{"/library/covers/missing", http.StatusNotFound},
+		{"/library/covers/", http.StatusNotFound},
+		{"/library/covers/cover/extra", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/library/covers/cover", nil))
+	if got := rec.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", got)
+	}
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"embed"
+	"io/fs"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	dashboardPrefix  = "/dashboard/"
+	oauthStateCookie = "kvazar_oauth_state"
+	// loginTimeout bounds the calls to Discord while signing in.
+	loginTimeout = 20 * time.Second
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// Authorizer decides which guilds a Discord user may control from the
+// dashboard.
+type Authorizer interface {
+	IsDJ(guildID, userID string) bool
+}
+
+// LoginRequest is the body of POST /dashboard/auth/login.
+type LoginRequest struct {
+	Token string `json:"token"`
+}
+
+// SessionInfo is the response of GET /dashboard/auth/session. It tells the
+// dashboard who is signed in and which ways of signing in are available.
+type SessionInfo struct {
+	Authenticated bool   `json:"authenticated"`
+	Name          string `json:"name,omitempty"`
+	Method        string `json:"method,omitempty"`
+	TokenLogin    bool   `json:"token_login"`
+	DiscordLogin  bool   `json:"discord_login"`
+}
+
+// dashboard serves the single-page dashboard and its sign-in endpoints. The
+// page itself talks to the REST API with the session cookie.
+func (s *Server) dashboard() http.Handler {
+	web, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	static := http.StripPrefix(dashboardPrefix, http.FileServer(http.FS(web)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(dashboardPrefix+"auth/session", s.handleSession)
+	mux.HandleFunc(dashboardPrefix+"auth/login", s.handleLogin)
+	mux.HandleFunc(dashboardPrefix+"auth/logout", s.handleLogout)
+	mux.HandleFunc(dashboardPrefix+"auth/discord", s.handleDiscordLogin)
+	mux.HandleFunc(dashboardPrefix+"auth/callback", s.handleDiscordCallback)
+	mux.Handle(dashboardPrefix, static)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy",
+			"default-src 'self'; img-src 'self' https: data:; frame-ancestors 'none'; base-uri 'none'; form-action 'self'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "same-origin")
+		w.Header().Set("Cache-Control", "no-cache")
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	info := SessionInfo{TokenLogin: s.opts.APIToken != "", DiscordLogin: s.oauth != nil}
+	if sess, ok := s.sessions.fromRequest(r); ok {
+		info.Authenticated = true
+		info.Name = sess.name
+		info.Method = "token"
+		if sess.userID != "" {
+			info.Method = "discord"
+		}
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// handleLogin starts a session for whoever knows the API token.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	if s.opts.APIToken == "" {
+		writeError(w, http.StatusNotFound, "token login is disabled")
+		return
+	}
+	var req LoginRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(req.Token)), []byte(s.opts.APIToken)) != 1 {
+		writeError(w, http.StatusUnauthorized, "wrong token")
+		return
+	}
+	s.startSession(w, r, session{})
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	s.sessions.end(r)
+	clearCookie(w, r, sessionCookie, "/")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDiscordLogin sends the browser to Discord to approve the sign-in.
+func (s *Server) handleDiscordLogin(w http.ResponseWriter, r *http.Request) {
+	if s.oauth == nil {
+		http.NotFound(w, r)
+		return
+	}
+	state, err := randomToken()
+	if err != nil {
+		log.Printf("failed to start a Discord sign-in: %v", err)
+		http.Error(w, "could not start the sign-in", http.StatusInternalServerError)
+		return
+	}
+	// Lax, unlike the session cookie, so that it comes back with the
+	// redirect from Discord.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     dashboardPrefix + "auth/",
+		MaxAge:   int((10 * time.Minute) / time.Second),
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, s.oauth.authCodeURL(state), http.StatusFound)
+}
+
+// handleDiscordCallback finishes a Discord sign-in. The user gets access to
+// the guilds they share with the bot in which they are a DJ.
+func (s *Server) handleDiscordCallback(w http.ResponseWriter, r *http.Request) {
+	if s.oauth == nil {
+		http.NotFound(w, r)
+		return
+	}
+	fail := func(reason string) {
+		http.Redirect(w, r, dashboardPrefix+"?error="+reason, http.StatusFound)
+	}
+
+	query := r.URL.Query()
+	cookie, err := r.Cookie(oauthStateCookie)
+	clearCookie(w, r, oauthStateCookie, dashboardPrefix+"auth/")
+	if err != nil || query.Get("state") == "" ||
+		subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(cookie.Value)) != 1 {
+		fail("login_expired")
+		return
+	}
+	if query.Get("error") != "" || query.Get("code") == "" {
+		fail("login_denied")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), loginTimeout)
+	defer cancel()
+	accessToken, err := s.oauth.exchange(ctx, query.Get("code"))
+	if err != nil {
+		log.Printf("failed to sign in with Discord: %v", err)
+		fail("login_failed")
+		return
+	}
+	user, userGuilds, err := s.oauth.user(ctx, accessToken)
+	if err != nil {
+		log.Printf("failed to sign in with Discord: %v", err)
+		fail("login_failed")
+		return
+	}
+
+	shared := make(map[string]bool)
+	for _, guild := range s.bot.Guilds() {
+		shared[guild.ID] = true
+	}
+	allowed := make(map[string]bool)
+	for _, guildID := range userGuilds {
+		if shared[guildID] && s.bot.IsDJ(guildID, user.ID) {
+			allowed[guildID] = true
+		}
+	}
+	if len(allowed) == 0 {
+		fail("no_guilds")
+		return
+	}
+
+	s.startSession(w, r, session{
+		principal: principal{userID: user.ID, guilds: allowed},
+		name:      user.displayName(),
+	})
+}
+
+// startSession signs the browser in. JSON callers get 204, the OAuth
+// redirect lands on the dashboard.
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, sess session) {
+	id, err := s.sessions.create(sess)
+	if err != nil {
+		log.Printf("failed to create a dashboard session: %v", err)
+		writeError(w, http.StatusInternalServerError, "could not create a session")
+		return
+	}
+	setSessionCookie(w, r, id)
+	if r.Method == http.MethodGet {
+		http.Redirect(w, r, dashboardPrefix, http.StatusFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
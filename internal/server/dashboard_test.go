@@ -0,0 +1,220 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"kvazar/internal/config"
+)
+
+// send serves a request carrying the given cookies.
+func send(t *testing.T, handler http.Handler, method, path, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func cookieNamed(t *testing.T, rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	t.Helper()
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	t.Fatalf("no %s cookie in %v", name, rec.Header()["Set-Cookie"])
+	return nil
+}
+
+func TestDashboardPage(t *testing.T) {
+	handler := New(":0", newFakeBot(), Options{APIToken: testToken}).Handler()
+
+	rec := send(t, handler, http.MethodGet, "/dashboard/", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<title>Kvazar</title>") {
+		t.Fatalf("page: status = %d: %.200s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Content-Security-Policy") == "" {
+		t.Error("page served without a Content-Security-Policy")
+	}
+	if rec := send(t, handler, http.MethodGet, "/dashboard/app.js", ""); rec.Code != http.StatusOK {
+		t.Errorf("app.js: status = %d", rec.Code)
+	}
+	if rec := send(t, handler, http.MethodGet, "/", ""); rec.Code != http.StatusFound || rec.Header().Get("Location") != "/dashboard/" {
+		t.Errorf("index: status = %d, location %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	disabled := New(":0", newFakeBot(), Options{}).Handler()
+	if rec := send(t, disabled, http.MethodGet, "/dashboard/", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("without a token or OAuth: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestDashboardTokenLogin(t *testing.T) {
+	handler := New(":0", newFakeBot(), Options{APIToken: testToken}).Handler()
+
+	info := decode[SessionInfo](t, send(t, handler, http.MethodGet, "/dashboard/auth/session", ""))
+	if info.Authenticated || !info.TokenLogin || info.DiscordLogin {
+		t.Fatalf("anonymous session = %+v", info)
+	}
+
+	if rec := send(t, handler, http.MethodPost, "/dashboard/auth/login", `{"token":"nope"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: status = %d", rec.Code)
+	}
+	rec := send(t, handler, http.MethodPost, "/dashboard/auth/login", `{"token":"`+testToken+`"}`)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("login: status = %d: %s", rec.Code, rec.Body)
+	}
+	cookie := cookieNamed(t, rec, sessionCookie)
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode {
+		t.Errorf("session cookie = %+v", cookie)
+	}
+
+	info = decode[SessionInfo](t, send(t, handler, http.MethodGet, "/dashboard/auth/session", "", cookie))
+	if !info.Authenticated || info.Method != "token" {
+		t.Fatalf("session = %+v", info)
+	}
+	if rec := send(t, handler, http.MethodGet, "/api/v1/guilds", "", cookie); rec.Code != http.StatusOK {
+		t.Fatalf("API with session: status = %d", rec.Code)
+	}
+
+	if rec := send(t, handler, http.MethodPost, "/dashboard/auth/logout", "", cookie); rec.Code != http.StatusNoContent {
+		t.Fatalf("logout: status = %d", rec.Code)
+	}
+	if rec := send(t, handler, http.MethodGet, "/api/v1/guilds", "", cookie); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("API after logout: status = %d", rec.Code)
+	}
+}
+
+// fakeDiscord answers the OAuth endpoints the dashboard calls.
+func fakeDiscord(t *testing.T, guilds ...string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "client" || secret != "secret" || r.FormValue("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access"})
+	})
+	mux.HandleFunc("/users/@me", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"id": "42", "username": "astro", "global_name": "Astro"})
+	})
+	mux.HandleFunc("/users/@me/guilds", func(w http.ResponseWriter, r *http.Request) {
+		var list []map[string]string
+		for _, id := range guilds {
+			list = append(list, map[string]string{"id": id})
+		}
+		json.NewEncoder(w).Encode(list)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// discordLogin runs the OAuth redirect dance and returns the callback response.
+func discordLogin(t *testing.T, handler http.Handler, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := send(t, handler, http.MethodGet, "/dashboard/auth/discord", "")
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login: status = %d", rec.Code)
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := location.Query().Get("state")
+	if state == "" || location.Query().Get("client_id") != "client" {
+		t.Fatalf("authorize URL = %s", location)
+	}
+
+	callback := "/dashboard/auth/callback?" + url.Values{"code": {code}, "state": {state}}.Encode()
+	return send(t, handler, http.MethodGet, callback, "", cookieNamed(t, rec, oauthStateCookie))
+}
+
+func TestDashboardDiscordLogin(t *testing.T) {
+	discord := fakeDiscord(t, "100", "200", "300")
+	fake := newFakeBot()
+	fake.djs = map[string]bool{"100/42": true}
+
+	srv := New(":0", fake, Options{OAuth: config.OAuth{
+		ClientID: "client", ClientSecret: "secret", RedirectURL: "https://kvazar.example.com/dashboard/auth/callback",
+	}})
+	srv.oauth.apiBase = discord.URL
+	handler := srv.Handler()
+
+	rec := discordLogin(t, handler, "good-code")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/dashboard/" {
+		t.Fatalf("callback: status = %d, location %q", rec.Code, rec.Header().Get("Location"))
+	}
+	cookie := cookieNamed(t, rec, sessionCookie)
+
+	info := decode[SessionInfo](t, send(t, handler, http.MethodGet, "/dashboard/auth/session", "", cookie))
+	if !info.Authenticated || info.Method != "discord" || info.Name != "Astro" || info.TokenLogin {
+		t.Fatalf("session = %+v", info)
+	}
+
+	// Guild 200 is shared with the bot, but the user is not a DJ there.
+	list := decode[GuildList](t, send(t, handler, http.MethodGet, "/api/v1/guilds", "", cookie))
+	if len(list.Available) != 1 || list.Available[0].ID != "100" {
+		t.Fatalf("available = %+v", list.Available)
+	}
+	if rec := send(t, handler, http.MethodGet, "/api/v1/guilds/200/player", "", cookie); rec.Code != http.StatusForbidden {
+		t.Fatalf("other guild: status = %d, want 403", rec.Code)
+	}
+
+	rec = send(t, handler, http.MethodPost, "/api/v1/guilds/100/queue", `{"query":"song","user_id":"999"}`, cookie)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("enqueue: status = %d: %s", rec.Code, rec.Body)
+	}
+	if fake.enqueued.UserID != "42" {
+		t.Errorf("enqueued as %q, want the signed-in user", fake.enqueued.UserID)
+	}
+
+	// The token login is not available without an API token.
+	if rec := send(t, handler, http.MethodPost, "/dashboard/auth/login", `{"token":""}`); rec.Code != http.StatusNotFound {
+		t.Errorf("token login: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestDashboardDiscordLoginFailures(t *testing.T) {
+	discord := fakeDiscord(t, "300")
+	srv := New(":0", newFakeBot(), Options{OAuth: config.OAuth{
+		ClientID: "client", ClientSecret: "secret", RedirectURL: "https://kvazar.example.com/dashboard/auth/callback",
+	}})
+	srv.oauth.apiBase = discord.URL
+	handler := srv.Handler()
+
+	tests := []struct {
+		name string
+		run  func() *httptest.ResponseRecorder
+		want string
+	}{
+		{"bad code", func() *httptest.ResponseRecorder { return discordLogin(t, handler, "bad-code") }, "login_failed"},
+		{"no shared guilds", func() *httptest.ResponseRecorder { return discordLogin(t, handler, "good-code") }, "no_guilds"},
+		{"missing state", func() *httptest.ResponseRecorder {
+			return send(t, handler, http.MethodGet, "/dashboard/auth/callback?code=good-code&state=forged", "")
+		}, "login_expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.run()
+			if want := "/dashboard/?error=" + tt.want; rec.Header().Get("Location") != want {
+				t.Fatalf("location = %q, want %q", rec.Header().Get("Location"), want)
+			}
+			for _, cookie := range rec.Result().Cookies() {
+				if cookie.Name == sessionCookie && cookie.MaxAge >= 0 {
+					t.Fatal("failed sign-in created a session")
+				}
+			}
+		})
+	}
+}
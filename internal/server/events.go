@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"kvazar/internal/bot"
+)
+
+// keepAliveInterval keeps proxies from closing idle event streams.
+const keepAliveInterval = 25 * time.Second
+
+// EventSource is the part of the bot that streams player events.
+type EventSource interface {
+	Subscribe(guildIDs ...string) (<-chan bot.Event, func())
+}
+
+// streamEvents serves GET /api/v1/events as Server-Sent Events. The guild
+// query parameter, repeated or comma-separated, limits the stream to those
+// guilds. When the bot drops a slow client the stream ends and the browser's
+// EventSource reconnects by itself.
+func (a *api) streamEvents(w http.ResponseWriter, r *http.Request, who principal) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	var guilds []string
+	for _, value := range r.URL.Query()["guild"] {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				guilds = append(guilds, id)
+			}
+		}
+	}
+	if who.guilds != nil {
+		// A Discord session only ever sees the guilds it may control.
+		var allowed []string
+		for _, id := range guilds {
+			if who.allows(id) {
+				allowed = append(allowed, id)
+			}
+		}
+		if len(guilds) == 0 {
+			for id := range who.guilds {
+				allowed = append(allowed, id)
+			}
+		}
+		if len(allowed) == 0 {
+			writeError(w, http.StatusForbidden, "you are not a DJ in these guilds")
+			return
+		}
+		guilds = allowed
+	}
+
+	events, unsubscribe := a.bot.Subscribe(guilds...)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("failed to encode %s event: %v", event.Type, err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
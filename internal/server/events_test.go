@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kvazar/internal/bot"
+)
+
+// readEvent reads one SSE frame, skipping comments, and returns its fields.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			if len(fields) > 0 {
+				return fields
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		name, value, _ := strings.Cut(line, ": ")
+		fields[name] = value
+	}
+}
+
+func openStream(t *testing.T, url string, header bool) *bufio.Reader {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header {
+		req.Header.Set("Authorization", "Bearer "+testToken)
+	}
+	// The timeout also bounds reading the body, so a missing event fails
+	// the test instead of hanging it.
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	// The handler subscribes before writing the greeting comment.
+	if line, err := r.ReadString('\n'); err != nil || !strings.HasPrefix(line, ":") {
+		t.Fatalf("greeting = %q, %v", line, err)
+	}
+	return r
+}
+
+func TestEventStream(t *testing.T) {
+	fake := newFakeBot()
+	srv := httptest.NewServer(New(":0", fake, Options{APIToken: testToken}).Handler())
+	// Registered first so it runs after the stream bodies are closed.
+	t.Cleanup(srv.Close)
+
+	all := openStream(t, srv.URL+"/api/v1/events", true)
+	filtered := openStream(t, srv.URL+"/api/v1/events?guild=200,300&access_token="+testToken, false)
+
+	fake.events.Publish("100", bot.EventPaused, nil)
+	fake.events.Publish("200", bot.EventLoopChanged, bot.LoopEvent{Enabled: true})
+
+	if got := readEvent(t, all); got["event"] != "paused" || got["id"] != "1" {
+		t.Errorf("first event = %v", got)
+	}
+	if got := readEvent(t, all); got["event"] != "loop_changed" {
+		t.Errorf("second event = %v", got)
+	}
+
+	got := readEvent(t, filtered)
+	if got["event"] != "loop_changed" || got["id"] != "2" {
+		t.Errorf("filtered event = %v", got)
+	}
+	var event struct {
+		GuildID string `json:"guild_id"`
+		Data    struct {
+			Enabled bool `json:"enabled"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(got["data"]), &event); err != nil {
+		t.Errorf("decode %q: %v", got["data"], err)
+	}
+	if event.GuildID != "200" || !event.Data.Enabled {
+		t.Errorf("payload = %+v", event)
+	}
+}
+
+func TestEventStreamAuthentication(t *testing.T) {
+	handler := New(":0", newFakeBot(), Options{APIToken: testToken}).Handler()
+
+	if rec := serve(t, handler, http.MethodGet, "/api/v1/events?access_token=nope", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong query token: status = %d", rec.Code)
+	}
+	// The query parameter only works for the event stream.
+	if rec := serve(t, handler, http.MethodGet, "/api/v1/guilds?access_token="+testToken, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("query token on guilds: status = %d", rec.Code)
+	}
+	if rec := serve(t, handler, http.MethodPost, "/api/v1/events", testToken, ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST: status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"kvazar/internal/config"
+)
+
+const (
+	discordAuthorizeURL = "https://discord.com/oauth2/authorize"
+	discordAPIBase      = "https://discord.com/api/v10"
+	// discordScopes lets the dashboard see who signed in and which guilds
+	// they are in.
+	discordScopes = "identify guilds"
+)
+
+// discordOAuth signs dashboard users in with Discord's authorization code
+// flow.
+type discordOAuth struct {
+	cfg          config.OAuth
+	authorizeURL string
+	apiBase      string
+	client       *http.Client
+}
+
+// discordUser is the part of GET /users/@me the dashboard uses.
+type discordUser struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+}
+
+func (u discordUser) displayName() string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+func newDiscordOAuth(cfg config.OAuth) *discordOAuth {
+	return &discordOAuth{
+		cfg:          cfg,
+		authorizeURL: discordAuthorizeURL,
+		apiBase:      discordAPIBase,
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// authCodeURL is where the browser is sent to approve the sign-in.
+func (o *discordOAuth) authCodeURL(state string) string {
+	query := url.Values{
+		"client_id":     {o.cfg.ClientID},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"response_type": {"code"},
+		"scope":         {discordScopes},
+		"state":         {state},
+		"prompt":        {"none"},
+	}
+	return o.authorizeURL + "?" + query.Encode()
+}
+
+// exchange trades the authorization code for an access token.
+func (o *discordOAuth) exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {o.cfg.RedirectURL},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.apiBase+"/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(o.cfg.ClientID, o.cfg.ClientSecret)
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := o.do(req, &token); err != nil {
+		return "", fmt.Errorf("oauth: exchange code: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("oauth: exchange code: no access token in the response")
+	}
+	return token.AccessToken, nil
+}
+
+// user returns the signed-in user and the IDs of their guilds.
+func (o *discordOAuth) user(ctx context.Context, accessToken string) (discordUser, []string, error) {
+	var user discordUser
+	if err := o.get(ctx, accessToken, "/users/@me", &user); err != nil {
+		return discordUser{}, nil, fmt.Errorf("oauth: fetch user: %w", err)
+	}
+
+	var guilds []struct {
+		ID string `json:"id"`
+	}
+	if err := o.get(ctx, accessToken, "/users/@me/guilds", &guilds); err != nil {
+		return discordUser{}, nil, fmt.Errorf("oauth: fetch guilds: %w", err)
+	}
+	ids := make([]string, 0, len(guilds))
+	for _, guild := range guilds {
+		ids = append(ids, guild.ID)
+	}
+	return user, ids, nil
+}
+
+func (o *discordOAuth) get(ctx context.Context, accessToken, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.apiBase+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return o.do(req, v)
+}
+
+func (o *discordOAuth) do(req *http.Request, v any) error {
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("discord returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
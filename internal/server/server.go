@@ -0,0 +1,172 @@
+// Package server exposes Kvazar's HTTP endpoints.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"kvazar/internal/bot"
+	"kvazar/internal/config"
+	"kvazar/internal/metrics"
+)
+
+// statusTimeout bounds a status snapshot, which may have to probe the external tools.
+const statusTimeout = 10 * time.Second
+
+// StatusSource is the part of the bot the health endpoints depend on.
+type StatusSource interface {
+	Status(ctx context.Context) bot.Status
+}
+
+// Backend is everything the server needs from the bot.
+type Backend interface {
+	StatusSource
+	Controller
+	EventSource
+	Authorizer
+	CoverSource
+}
+
+// Options configure the parts of the server that are off by default.
+type Options struct {
+	// APIToken enables the REST API and the dashboard, where it doubles as
+	// the shared login password.
+	APIToken string
+	// OAuth enables the dashboard with Discord sign-in, with or without an
+	// API token.
+	OAuth config.OAuth
+}
+
+// Server serves the health, readiness, metrics and informational endpoints,
+// and the REST API and dashboard when they are enabled.
+type Server struct {
+	bot      Backend
+	opts     Options
+	sessions *sessionStore
+	oauth    *discordOAuth
+	http     *http.Server
+}
+
+// New prepares a server listening on addr. New does not start listening.
+func New(addr string, backend Backend, opts Options) *Server {
+	s := &Server{bot: backend, opts: opts, sessions: newSessionStore()}
+	if opts.OAuth.Enabled() {
+		s.oauth = newDiscordOAuth(opts.OAuth)
+	}
+	s.http = &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Handler returns the routes served by the server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/ready", s.handleReady)
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc(bot.LibraryCoverPath, s.handleCover)
+	if s.apiEnabled() {
+		mux.Handle(apiPrefix, &api{bot: s.bot, token: s.opts.APIToken, sessions: s.sessions})
+		mux.Handle(dashboardPrefix, s.dashboard())
+		mux.Handle(strings.TrimSuffix(dashboardPrefix, "/"), http.RedirectHandler(dashboardPrefix, http.StatusMovedPermanently))
+	}
+	mux.HandleFunc("/", s.handleIndex)
+	return mux
+}
+
+func (s *Server) apiEnabled() bool {
+	return s.opts.APIToken != "" || s.oauth != nil
+}
+
+// ListenAndServe blocks until the server fails or is shut down.
+func (s *Server) ListenAndServe() error {
+	log.Printf("HTTP server listening on %s", s.http.Addr)
+	if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("http server: %w", err)
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections and waits for active requests.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+// healthResponse is the short liveness answer.
+type healthResponse struct {
+	State    string            `json:"status"`
+	Service  string            `json:"service"`
+	Problems []string          `json:"problems,omitempty"`
+	Gateway  bot.GatewayStatus `json:"gateway"`
+}
+
+// readyResponse carries the full snapshot.
+type readyResponse struct {
+	State   string `json:"status"`
+	Service string `json:"service"`
+	bot.Status
+}
+
+// handleHealth answers liveness probes: 503 means the bot should be restarted.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	status := s.status(r)
+
+	resp := healthResponse{State: "ok", Service: "kvazar", Gateway: status.Gateway}
+	code := http.StatusOK
+	if !status.Healthy {
+		resp.State = "unhealthy"
+		resp.Problems = status.Problems
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, resp)
+}
+
+// handleReady answers readiness probes: 503 means the bot is degraded.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	status := s.status(r)
+
+	resp := readyResponse{State: "ready", Service: "kvazar", Status: status}
+	code := http.StatusOK
+	if !status.Ready {
+		resp.State = "degraded"
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, resp)
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if s.apiEnabled() {
+		http.Redirect(w, r, dashboardPrefix, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "Kvazar Discord Bot — see /health, /ready and /metrics")
+}
+
+func (s *Server) status(r *http.Request) bot.Status {
+	ctx, cancel := context.WithTimeout(r.Context(), statusTimeout)
+	defer cancel()
+	return s.bot.Status(ctx)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
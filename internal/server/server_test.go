@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"kvazar/internal/bot"
+)
+
+func TestHealthAndReady(t *testing.T) {
+	problems := []string{"yt-dlp unavailable: not found"}
+	tests := []struct {
+		name   string
+		status bot.Status
+		health int
+		ready  int
+	}{
+		{"healthy", bot.Status{Healthy: true, Ready: true}, http.StatusOK, http.StatusOK},
+		{"degraded", bot.Status{Healthy: true, Problems: problems}, http.StatusOK, http.StatusServiceUnavailable},
+		{"unhealthy", bot.Status{Problems: problems}, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeBot()
+			fake.status = &tt.status
+			handler := New(":0", fake, Options{}).Handler()
+
+			rec := serve(t, handler, http.MethodGet, "/health", "", "")
+			if rec.Code != tt.health {
+				t.Errorf("/health = %d, want %d", rec.Code, tt.health)
+			}
+			health := decode[healthResponse](t, rec)
+			if wantState := map[bool]string{true: "ok", false: "unhealthy"}[tt.status.Healthy]; health.State != wantState {
+				t.Errorf("/health status = %q, want %q", health.State, wantState)
+			}
+			if !tt.status.Healthy && !reflect.DeepEqual(health.Problems, problems) {
+				t.Errorf("/health problems = %v, want %v", health.Problems, problems)
+			}
+
+			rec = serve(t, handler, http.MethodGet, "/ready", "", "")
+			if rec.Code != tt.ready {
+				t.Errorf("/ready = %d, want %d", rec.Code, tt.ready)
+			}
+			ready := decode[readyResponse](t, rec)
+			if wantState := map[bool]string{true: "ready", false: "degraded"}[tt.status.Ready]; ready.State != wantState {
+				t.Errorf("/ready status = %q, want %q", ready.State, wantState)
+			}
+			if !reflect.DeepEqual(ready.Problems, tt.status.Problems) {
+				t.Errorf("/ready problems = %v, want %v", ready.Problems, tt.status.Problems)
+			}
+		})
+	}
+}
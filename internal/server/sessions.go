@@ -0,0 +1,122 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCookie = "kvazar_session"
+	// sessionTTL bounds how long a dashboard login lasts. Discord sign-ins
+	// keep the guilds they were granted until then.
+	sessionTTL = 12 * time.Hour
+)
+
+// session is a dashboard login. Sessions live in memory, so a restart signs
+// everyone out.
+type session struct {
+	principal
+	// name is shown in the dashboard: the Discord user name, or empty for a
+	// token login.
+	name    string
+	expires time.Time
+}
+
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{sessions: make(map[string]*session)}
+}
+
+// create stores a session and returns its ID.
+func (s *sessionStore) create(sess session) (string, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	sess.expires = time.Now().Add(sessionTTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for other, existing := range s.sessions {
+		if now.After(existing.expires) {
+			delete(s.sessions, other)
+		}
+	}
+	s.sessions[id] = &sess
+	return id, nil
+}
+
+// fromRequest returns the unexpired session named by the request's cookie.
+func (s *sessionStore) fromRequest(r *http.Request) (session, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return session{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[cookie.Value]
+	if !ok {
+		return session{}, false
+	}
+	if time.Now().After(sess.expires) {
+		delete(s.sessions, cookie.Value)
+		return session{}, false
+	}
+	return *sess, true
+}
+
+// end deletes the request's session, if any.
+func (s *sessionStore) end(r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		s.mu.Lock()
+		delete(s.sessions, cookie.Value)
+		s.mu.Unlock()
+	}
+}
+
+// setSessionCookie hands a new session to the browser. SameSite=Strict keeps
+// other sites from using it to call the API.
+func setSessionCookie(w http.ResponseWriter, r *http.Request, id string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(sessionTTL / time.Second),
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func clearCookie(w http.ResponseWriter, r *http.Request, name, path string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Path:     path,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+	})
+}
+
+// isHTTPS reports whether the browser reached us over HTTPS, directly or
+// through a reverse proxy.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,396 @@
+"use strict";
+
+// Kvazar dashboard. It signs in through /dashboard/auth/ and then drives the
+// REST API under /api/v1/ with the session cookie, following the player
+// through the event stream.
+
+const $ = (id) => document.getElementById(id);
+
+const loginErrors = {
+  login_expired: "The sign-in took too long. Please try again.",
+  login_denied: "The sign-in was cancelled.",
+  login_failed: "Discord sign-in failed. Please try again later.",
+  no_guilds: "You are not a DJ in any server Kvazar is in.",
+};
+
+const playerEvents = [
+  "track_started", "track_ended", "track_skipped", "queue_changed", "paused", "resumed",
+  "loop_changed", "voice_connected", "voice_disconnected", "error", "stream_title",
+];
+
+const state = {
+  guilds: [],
+  guildID: localStorage.getItem("kvazar.guild") || "",
+  player: null,
+  position: 0,
+  fetchedAt: 0,
+  events: null,
+  refreshTimer: 0,
+};
+
+async function request(method, path, body) {
+  const options = { method, headers: {} };
+  if (body !== undefined) {
+    options.headers["Content-Type"] = "application/json";
+    options.body = JSON.stringify(body);
+  }
+  const res = await fetch(path, options);
+  if (res.status === 204) {
+    return null;
+  }
+  const data = await res.json().catch(() => ({}));
+  if (!res.ok) {
+    if (res.status === 401 && path.startsWith("/api/")) {
+      showLogin(await request("GET", "auth/session"));
+    }
+    const err = new Error(data.error || res.statusText);
+    err.status = res.status;
+    throw err;
+  }
+  return data;
+}
+
+const api = (method, path, body) => request(method, "/api/v1/" + path, body);
+const guildPath = (path) => `guilds/${encodeURIComponent(state.guildID)}/${path}`;
+
+function showError(message) {
+  $("error").textContent = message;
+  $("error").hidden = !message;
+}
+
+// run performs a user action and reports its failure.
+async function run(action) {
+  showError("");
+  try {
+    await action();
+  } catch (err) {
+    showError(err.message);
+  }
+}
+
+function formatTime(seconds) {
+  seconds = Math.max(0, Math.floor(seconds));
+  const h = Math.floor(seconds / 3600);
+  const m = Math.floor((seconds % 3600) / 60);
+  const s = String(seconds % 60).padStart(2, "0");
+  return h > 0 ? `${h}:${String(m).padStart(2, "0")}:${s}` : `${m}:${s}`;
+}
+
+function trackItem(track) {
+  const item = $("track-template").content.firstElementChild.cloneNode(true);
+  const art = item.querySelector("img");
+  if (track.thumbnail) {
+    art.src = track.thumbnail;
+  } else {
+    art.hidden = true;
+  }
+  const title = item.querySelector(".title");
+  title.textContent = track.title || track.url;
+  title.href = track.url;
+  const parts = [track.author, track.live ? "LIVE" : formatTime(track.duration_seconds)];
+  item.querySelector(".meta").textContent = parts.filter(Boolean).join(" · ");
+  return item;
+}
+
+// Sign-in
+
+function showLogin(session) {
+  closeEvents();
+  $("app").hidden = true;
+  $("guild").hidden = true;
+  $("logout").hidden = true;
+  $("user").textContent = "";
+  $("login").hidden = false;
+  $("token-form").hidden = !session.token_login;
+  $("discord-login").hidden = !session.discord_login;
+}
+
+$("token-form").addEventListener("submit", (event) => {
+  event.preventDefault();
+  run(async () => {
+    await request("POST", "auth/login", { token: $("token").value });
+    $("token").value = "";
+    await start();
+  });
+});
+
+$("logout").addEventListener("click", () => run(async () => {
+  await request("POST", "auth/logout");
+  showLogin(await request("GET", "auth/session"));
+}));
+
+async function start() {
+  const session = await request("GET", "auth/session");
+  if (!session.authenticated) {
+    showLogin(session);
+    return;
+  }
+  $("login").hidden = true;
+  $("app").hidden = false;
+  $("logout").hidden = false;
+  $("user").textContent = session.name || "";
+  await loadGuilds();
+}
+
+// Guilds
+
+async function loadGuilds() {
+  const list = await api("GET", "guilds");
+  state.guilds = list.available;
+  const active = new Set(list.guilds.map((player) => player.guild_id));
+
+  const select = $("guild");
+  select.replaceChildren(...state.guilds.map((guild) => new Option(guild.name + (active.has(guild.id) ? " ▶" : ""), guild.id)));
+  select.hidden = state.guilds.length === 0;
+  if (state.guilds.length === 0) {
+    showError("Kvazar is not in any server yet.");
+    return;
+  }
+
+  if (!state.guilds.some((guild) => guild.id === state.guildID)) {
+    const playing = state.guilds.find((guild) => active.has(guild.id));
+    state.guildID = (playing || state.guilds[0]).id;
+  }
+  select.value = state.guildID;
+  await selectGuild(state.guildID);
+}
+
+$("guild").addEventListener("change", (event) => run(() => selectGuild(event.target.value)));
+
+async function selectGuild(guildID) {
+  state.guildID = guildID;
+  localStorage.setItem("kvazar.guild", guildID);
+  $("results").replaceChildren();
+  openEvents();
+  await refresh();
+}
+
+function currentGuild() {
+  return state.guilds.find((guild) => guild.id === state.guildID);
+}
+
+// Player
+
+async function refresh() {
+  try {
+    render(await api("GET", guildPath("player")));
+  } catch (err) {
+    if (err.status !== 404) {
+      throw err;
+    }
+    render(null);
+  }
+}
+
+// scheduleRefresh coalesces the bursts of events a single action produces.
+function scheduleRefresh() {
+  clearTimeout(state.refreshTimer);
+  state.refreshTimer = setTimeout(() => run(refresh), 150);
+}
+
+function render(player) {
+  state.player = player;
+  state.position = player ? player.position_seconds : 0;
+  state.fetchedAt = Date.now();
+
+  const current = player && player.current;
+  $("now-status").textContent = !current ? "Nothing is playing" : player.paused ? "Paused" : "Now playing";
+  $("now-title").textContent = current ? current.title : "";
+  $("now-title").href = current ? current.url : "#";
+  $("now-author").textContent = current ? current.author || "" : "";
+  $("now-stream").textContent = player && player.stream_title ? `On air: ${player.stream_title}` : "";
+  $("now-art").hidden = !(current && current.thumbnail);
+  if (current && current.thumbnail) {
+    $("now-art").src = current.thumbnail;
+  }
+  $("duration").textContent = current ? (current.live ? "LIVE" : formatTime(current.duration_seconds)) : "";
+  for (const id of ["pause", "skip", "stop", "loop"]) {
+    $(id).disabled = !current;
+  }
+  $("loop").classList.toggle("active", Boolean(player && player.loop));
+  tick();
+
+  const queue = player ? player.queue : [];
+  $("queue-count").textContent = queue.length ? `(${queue.length})` : "";
+  $("queue").replaceChildren(...queue.map((track, i) => {
+    const item = trackItem(track);
+    item.draggable = true;
+    item.dataset.position = String(i + 1);
+    return item;
+  }));
+  $("history").replaceChildren(...(player ? player.history : []).map((track) => trackItem(track)));
+
+  // Do not move a slider while it is being dragged.
+  if (player && !$("audio-form").contains(document.activeElement)) {
+    $("volume").value = player.audio.volume;
+    $("normalize").checked = player.audio.normalize;
+    $("loudness").value = player.audio.loudness_target;
+    updateAudioLabels();
+  }
+  for (const input of $("audio-form").querySelectorAll("input")) {
+    input.disabled = !player || (input === $("loudness") && !player.audio.normalize);
+  }
+
+  renderVoiceChannels();
+}
+
+// tick advances the progress bar between refreshes.
+function tick() {
+  const current = state.player && state.player.current;
+  let position = state.position;
+  if (current && !state.player.paused) {
+    position += (Date.now() - state.fetchedAt) / 1000;
+  }
+  const duration = current ? current.duration_seconds : 0;
+  if (duration > 0) {
+    position = Math.min(position, duration);
+  }
+  $("elapsed").textContent = current ? formatTime(position) : "";
+  $("progress-bar").style.width = duration > 0 ? `${(position / duration) * 100}%` : "0";
+}
+setInterval(tick, 1000);
+
+const control = (method, path, body) => run(async () => render(await api(method, guildPath(path), body)));
+
+$("pause").addEventListener("click", () => control("POST", "pause"));
+$("skip").addEventListener("click", () => control("POST", "skip"));
+$("stop").addEventListener("click", () => {
+  if (confirm("Stop playback and clear the queue?")) {
+    control("POST", "stop");
+  }
+});
+$("loop").addEventListener("click", () => control("PUT", "loop", { enabled: !(state.player && state.player.loop) }));
+
+// Queue reordering
+
+let dragFrom = 0;
+
+$("queue").addEventListener("dragstart", (event) => {
+  const item = event.target.closest("li");
+  dragFrom = Number(item.dataset.position);
+  event.dataTransfer.effectAllowed = "move";
+  item.classList.add("dragging");
+});
+
+$("queue").addEventListener("dragend", (event) => {
+  event.target.closest("li").classList.remove("dragging");
+});
+
+$("queue").addEventListener("dragover", (event) => {
+  if (dragFrom) {
+    event.preventDefault();
+  }
+});
+
+$("queue").addEventListener("drop", (event) => {
+  event.preventDefault();
+  const target = event.target.closest("li");
+  const from = dragFrom;
+  dragFrom = 0;
+  if (!target || !from || Number(target.dataset.position) === from) {
+    return;
+  }
+  control("POST", "queue/move", { from, to: Number(target.dataset.position) });
+});
+
+// Search and add
+
+function renderVoiceChannels() {
+  const select = $("voice");
+  const needsChannel = !(state.player && state.player.voice_channel_id);
+  const guild = currentGuild();
+  const channels = guild ? guild.voice_channels : [];
+  if (needsChannel && select.dataset.guild !== state.guildID) {
+    select.replaceChildren(...channels.map((channel) => new Option("🔊 " + channel.name, channel.id)));
+    select.dataset.guild = state.guildID;
+  }
+  select.hidden = !needsChannel;
+}
+
+async function enqueue(query) {
+  const body = { query };
+  if (!$("voice").hidden) {
+    body.voice_channel_id = $("voice").value;
+  }
+  await api("POST", guildPath("queue"), body);
+  $("results").replaceChildren();
+  $("query").value = "";
+  await refresh();
+}
+
+$("search-form").addEventListener("submit", (event) => {
+  event.preventDefault();
+  const query = $("query").value.trim();
+  run(async () => {
+    if (/^https?:\/\//i.test(query)) {
+      await enqueue(query);
+      return;
+    }
+    $("results").replaceChildren(Object.assign(document.createElement("li"), { className: "muted", textContent: "Searching…" }));
+    const { results } = await api("GET", "search?q=" + encodeURIComponent(query));
+    $("results").replaceChildren(...results.map((track) => {
+      const item = trackItem(track);
+      const add = Object.assign(document.createElement("button"), { textContent: "Add" });
+      add.addEventListener("click", () => {
+        add.disabled = true;
+        run(() => enqueue(track.url)).finally(() => { add.disabled = false; });
+      });
+      item.append(add);
+      return item;
+    }));
+    if (results.length === 0) {
+      $("results").replaceChildren(Object.assign(document.createElement("li"), { className: "muted", textContent: "No results." }));
+    }
+  });
+});
+
+// Audio settings
+
+function updateAudioLabels() {
+  $("volume-value").textContent = `${$("volume").value}%`;
+  $("loudness-value").textContent = `${$("loudness").value} LUFS`;
+}
+
+$("volume").addEventListener("input", updateAudioLabels);
+$("loudness").addEventListener("input", updateAudioLabels);
+$("volume").addEventListener("change", () => control("PUT", "audio", { volume: Number($("volume").value) }));
+$("loudness").addEventListener("change", () => control("PUT", "audio", { loudness_target: Number($("loudness").value) }));
+$("normalize").addEventListener("change", () => control("PUT", "audio", { normalize: $("normalize").checked }));
+
+// Live updates
+
+function openEvents() {
+  closeEvents();
+  const events = new EventSource("/api/v1/events?guild=" + encodeURIComponent(state.guildID));
+  for (const type of playerEvents) {
+    events.addEventListener(type, scheduleRefresh);
+  }
+  events.addEventListener("error", (event) => {
+    // The EventSource reports its own connection errors without data.
+    if (event.data) {
+      showError(JSON.parse(event.data).data.message);
+    }
+  });
+  // Catch up on whatever happened while disconnected.
+  events.addEventListener("open", scheduleRefresh);
+  state.events = events;
+}
+
+function closeEvents() {
+  if (state.events) {
+    state.events.close();
+    state.events = null;
+  }
+}
+
+// Start
+
+const params = new URLSearchParams(location.search);
+history.replaceState(null, "", location.pathname);
+start()
+  .then(() => {
+    if (params.has("error")) {
+      showError(loginErrors[params.get("error")] || "Sign-in failed.");
+    }
+  })
+  .catch((err) => showError(err.message));
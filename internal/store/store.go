@@ -0,0 +1,186 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Store persists small JSON documents grouped into buckets. Each document is
+// stored as <dir>/<bucket>/<key>.json; an empty directory keeps everything in memory.
+type Store struct {
+	dir    string
+	mu     sync.Mutex
+	memory map[string]map[string][]byte
+}
+
+// Open prepares a store rooted at dir, creating the directory when needed.
+func Open(dir string) (*Store, error) {
+	s := &Store{dir: strings.TrimSpace(dir)}
+	if s.dir == "" {
+		s.memory = make(map[string]map[string][]byte)
+		return s, nil
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("store: create %s: %w", s.dir, err)
+	}
+	return s, nil
+}
+
+// Dir returns the directory backing the store, or an empty string for in-memory stores.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Load decodes the document into v. It reports false when the document does not exist.
+func (s *Store) Load(bucket, key string, v any) (bool, error) {
+	if err := checkNames(bucket, key); err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var raw []byte
+	if s.memory != nil {
+		data, ok := s.memory[bucket][key]
+		if !ok {
+			return false, nil
+		}
+		raw = data
+	} else {
+		data, err := os.ReadFile(s.path(bucket, key))
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("store: read %s/%s: %w", bucket, key, err)
+		}
+		raw = data
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("store: decode %s/%s: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+// Save encodes v and replaces the stored document atomically.
+func (s *Store) Save(bucket, key string, v any) error {
+	if err := checkNames(bucket, key); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("store: encode %s/%s: %w", bucket, key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.memory != nil {
+		if s.memory[bucket] == nil {
+			s.memory[bucket] = make(map[string][]byte)
+		}
+		s.memory[bucket][key] = raw
+		return nil
+	}
+
+	dir := filepath.Join(s.dir, bucket)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("store: create %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("store: temp file: %w", err)
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("store: write %s/%s: %w", bucket, key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("store: write %s/%s: %w", bucket, key, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(bucket, key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("store: replace %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// Delete removes a document; deleting a missing document is not an error.
+func (s *Store) Delete(bucket, key string) error {
+	if err := checkNames(bucket, key); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.memory != nil {
+		delete(s.memory[bucket], key)
+		return nil
+	}
+	if err := os.Remove(s.path(bucket, key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("store: delete %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// Keys lists the documents stored in a bucket in lexical order.
+func (s *Store) Keys(bucket string) ([]string, error) {
+	if err := checkNames(bucket, "_"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	if s.memory != nil {
+		for key := range s.memory[bucket] {
+			keys = append(keys, key)
+		}
+	} else {
+		entries, err := os.ReadDir(filepath.Join(s.dir, bucket))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("store: list %s: %w", bucket, err)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+				continue
+			}
+			keys = append(keys, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *Store) path(bucket, key string) string {
+	return filepath.Join(s.dir, bucket, key+".json")
+}
+
+func checkNames(bucket, key string) error {
+	if !validName.MatchString(bucket) || strings.HasPrefix(bucket, ".") {
+		return fmt.Errorf("store: invalid bucket %q", bucket)
+	}
+	if !validName.MatchString(key) || strings.HasPrefix(key, ".") {
+		return fmt.Errorf("store: invalid key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type document struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	for name, dir := range map[string]string{"disk": t.TempDir(), "memory": ""} {
+		t.Run(name, func(t *testing.T) {
+			s, err := Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var doc document
+			if found, err := s.Load("guilds", "missing", &doc); found || err != nil {
+				t.Fatalf("Load(missing) = %t, %v", found, err)
+			}
+			for _, key := range []string{"b", "a"} {
+				if err := s.Save("guilds", key, document{Name: key, Count: 1}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.Save("guilds", "a", document{Name: "a", Count: 2}); err != nil {
+				t.Fatal(err)
+			}
+
+			if found, err := s.Load("guilds", "a", &doc); !found || err != nil || doc != (document{Name: "a", Count: 2}) {
+				t.Errorf("Load(a) = %t, %v, %+v", found, err, doc)
+			}
+			if keys, err := s.Keys("guilds"); err != nil || !reflect.DeepEqual(keys, []string{"a", "b"}) {
+				t.Errorf("Keys = %v, %v", keys, err)
+			}
+			if keys, err := s.Keys("empty"); err != nil || len(keys) != 0 {
+				t.Errorf("Keys(empty) = %v, %v", keys, err)
+			}
+
+			if err := s.Delete("guilds", "b"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Delete("guilds", "b"); err != nil {
+				t.Errorf("deleting a missing document: %v", err)
+			}
+			if found, _ := s.Load("guilds", "b", &doc); found {
+				t.Error("b was found after it was deleted")
+			}
+		})
+	}
+}
+
+// TestStoreSaveLeavesNoTempFiles checks that Save replaces documents through
+// a temporary file that it cleans up, and that Keys lists only documents.
+func TestStoreSaveLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.Save("favorites", "alice", document{Count: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "favorites", "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "favorites"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"alice.json", "nested"}) {
+		t.Errorf("bucket holds %v", names)
+	}
+	if keys, err := s.Keys("favorites"); err != nil || !reflect.DeepEqual(keys, []string{"alice"}) {
+		t.Errorf("Keys = %v, %v", keys, err)
+	}
+}
+
+func TestStoreRejectsBadNames(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"", "..", ".hidden", "a/b", `a\b`, "guild id"} {
+		if err := s.Save(bad, "key", document{}); err == nil {
+			t.Errorf("Save accepted bucket %q", bad)
+		}
+		if err := s.Save("guilds", bad, document{}); err == nil {
+			t.Errorf("Save accepted key %q", bad)
+		}
+		if _, err := s.Load("guilds", bad, &document{}); err == nil {
+			t.Errorf("Load accepted key %q", bad)
+		}
+		if err := s.Delete("guilds", bad); err == nil {
+			t.Errorf("Delete accepted key %q", bad)
+		}
+		if _, err := s.Keys(bad); err == nil {
+			t.Errorf("Keys accepted bucket %q", bad)
+		}
+	}
+	if entries, _ := os.ReadDir(s.Dir()); len(entries) != 0 {
+		t.Errorf("rejected names left %d entries behind", len(entries))
+	}
+}